@@ -726,6 +726,16 @@ func runCIRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Only one run at a time may use the pipeline's output directory
+	if !dryRun {
+		pipelineLock, err := pipeline.Lock()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+		defer pipelineLock.Release()
+	}
+
 	// Initialize Podman client
 	podmanClient, err := podman.NewClient()
 	if err != nil {
@@ -733,7 +743,8 @@ func runCIRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	ctx := context.Background()
+	// The command context is cancelled on Ctrl-C, which stops the running stage
+	ctx := cmd.Context()
 
 	// Execute stages
 	if len(stagesToRun) == 0 {
@@ -1285,8 +1296,11 @@ func runTestStage(ctx context.Context, pipeline *ci.Pipeline, imageTag string, d
 	// Check if hypervisor is available (platform-specific)
 	if !dryRun {
 		// Create a temporary driver to check availability
-		vmType := vm.GetDefaultVMType()
 		tempOpts := vm.VMOptions{Name: "check"}
+		if cfg, err := config.Load(""); err == nil {
+			tempOpts.Driver, tempOpts.LibvirtURI = cfg.VM.Driver, cfg.VM.LibvirtURI
+		}
+		vmType := vm.DriverVMType(tempOpts.Driver)
 		driver, err := vm.NewDriver(tempOpts, false)
 		if err != nil {
 			fmt.Printf("❌ Failed to create VM driver: %v\n", err)
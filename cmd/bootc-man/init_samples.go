@@ -12,6 +12,7 @@ import (
 	"github.com/tnk4on/bootc-man/internal/config"
 	"github.com/tnk4on/bootc-man/internal/podman"
 	"github.com/tnk4on/bootc-man/internal/registry"
+	"github.com/tnk4on/bootc-man/internal/vm"
 )
 
 // defaultSSHPubKey is a placeholder used when no SSH key is selected or found.
@@ -35,19 +36,27 @@ func escapeSSHPubKeyForShell(key string) string {
 }
 
 // sampleContainerfile returns the Containerfile content for the given distro, with SSH key and username injected.
+// An empty sshPublicKey produces a generic image with cloud-init; the user and
+// key are then provisioned when the VM boots.
 func sampleContainerfile(distro, sshPublicKey, username string) string {
-	escapedKey := escapeSSHPubKeyForShell(sshPublicKey)
-	if escapedKey == "" {
-		escapedKey = escapeSSHPubKeyForShell(defaultSSHPubKey)
-	}
-
-	runBlock := fmt.Sprintf(`RUN useradd -G wheel %s && \
+	var runBlock string
+	if sshPublicKey == "" {
+		runBlock = `# The login user and SSH key are provisioned at boot by cloud-init
+# (test.boot.provisioning in bootc-ci.yaml), so the image stays generic
+RUN dnf -y install cloud-init && \
+    ln -s ../cloud-init.target /usr/lib/systemd/system/default.target.wants/cloud-init.target && \
+    dnf clean all
+`
+	} else {
+		escapedKey := escapeSSHPubKeyForShell(sshPublicKey)
+		runBlock = fmt.Sprintf(`RUN useradd -G wheel %s && \
     mkdir -m 0700 -p /home/%s/.ssh && \
     echo "%s" > /home/%s/.ssh/authorized_keys && \
     chmod 0600 /home/%s/.ssh/authorized_keys && \
     chown -R %s:%s /home/%s && \
     echo "%s ALL=(ALL) NOPASSWD: ALL" > /etc/sudoers.d/%s
 `, username, username, escapedKey, username, username, username, username, username, username, username)
+	}
 
 	var header string
 	switch distro {
@@ -88,7 +97,12 @@ RUN bootc container lint
 }
 
 // sampleBootcCI returns the bootc-ci.yaml content for the given distro.
-func sampleBootcCI(distro, imageTag, pipelineName string) string {
+// provisioning is written to test.boot.provisioning when set.
+func sampleBootcCI(distro, imageTag, pipelineName, provisioning string) string {
+	provisioningLine := ""
+	if provisioning != "" {
+		provisioningLine = fmt.Sprintf("\n      provisioning: %s", provisioning)
+	}
 	return fmt.Sprintf(`apiVersion: bootc-man/v1
 kind: Pipeline
 metadata:
@@ -134,7 +148,7 @@ spec:
     boot:
       enabled: true
       timeout: 30
-      gui: true
+      gui: true%s
       checks:
         - "sudo bootc status"
 
@@ -147,7 +161,7 @@ spec:
       - "latest"
     sign:
       enabled: false
-`, pipelineName, distro, imageTag, pipelineName)
+`, pipelineName, distro, imageTag, provisioningLine, pipelineName)
 }
 
 // sshKeyEntry holds path and content of an SSH public key
@@ -188,6 +202,7 @@ func discoverSSHKeys() ([]sshKeyEntry, error) {
 }
 
 // promptSSHKeySelection lists keys and lets the user select one; returns content or default.
+// An empty result means the key is provisioned at VM boot instead.
 func promptSSHKeySelection(keys []sshKeyEntry) string {
 	if len(keys) == 0 {
 		fmt.Println("  No SSH public keys found in ~/.ssh")
@@ -200,6 +215,7 @@ func promptSSHKeySelection(keys []sshKeyEntry) string {
 		fmt.Printf("    %d) %s\n", i+1, k.Path)
 	}
 	fmt.Printf("    %d) Use default (inject your key later)\n", len(keys)+1)
+	fmt.Printf("    %d) Provision at VM boot with cloud-init (keeps the image generic)\n", len(keys)+2)
 	fmt.Printf("  Select key [1]: ")
 
 	choice, err := promptLine("1")
@@ -212,6 +228,9 @@ func promptSSHKeySelection(keys []sshKeyEntry) string {
 			return strings.TrimSpace(k.Content)
 		}
 	}
+	if choice == fmt.Sprintf("%d", len(keys)+2) {
+		return ""
+	}
 	fmt.Println("  ⚠️  Using placeholder. Edit Containerfile to add your SSH key.")
 	return defaultSSHPubKey
 }
@@ -260,7 +279,11 @@ func writeSample(outputDir, distro, sshPublicKey, username string) error {
 		return fmt.Errorf("failed to write Containerfile: %w", err)
 	}
 
-	bootcCI := sampleBootcCI(distro, imageTag, pipelineName)
+	provisioning := ""
+	if sshPublicKey == "" {
+		provisioning = vm.ProvisioningCloudInit
+	}
+	bootcCI := sampleBootcCI(distro, imageTag, pipelineName, provisioning)
 	if err := os.WriteFile(filepath.Join(dir, config.DefaultPipelineFileName), []byte(bootcCI), 0644); err != nil {
 		return fmt.Errorf("failed to write bootc-ci.yaml: %w", err)
 	}
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/ci"
+	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var labCmd = &cobra.Command{
+	Use:   "lab",
+	Short: "Manage groups of VMs on a shared network",
+	Long: `Run several VMs as a lab on a shared virtual network.
+
+Lab members get a second network interface on an isolated segment with a
+stable IP address and a DNS name (<vm>.<lab>.internal, or just <vm>) that
+the other members resolve through /etc/hosts. Each member keeps its own
+gvproxy for internet access, SSH and port forwards. Labs require the QEMU
+driver (Linux) and NetworkManager in the guest.
+
+A lab is declared in bootc-lab.yaml and started and stopped as a unit, or
+VMs join a lab with 'bootc-man vm start --network <lab>'.
+
+Example bootc-lab.yaml:
+  apiVersion: bootc-man/v1
+  kind: Lab
+  metadata:
+    name: cluster
+  spec:
+    subnet: 10.89.0.0/24
+    vms:
+      - name: registry
+        pipeline: registry/bootc-ci.yaml
+        ip: 10.89.0.5
+      - name: node1
+        pipeline: node/bootc-ci.yaml
+      - name: node2
+        pipeline: node/bootc-ci.yaml`,
+}
+
+var labStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start all VMs of a lab",
+	Long: `Start the VMs declared in a lab file in order.
+
+VMs that are already running are left alone. Each VM boots the disk image
+of its pipeline, like 'bootc-man vm start' in the pipeline's directory.`,
+	Args: cobra.NoArgs,
+	RunE: runLabStart,
+}
+
+var labStopCmd = &cobra.Command{
+	Use:               "stop [lab]",
+	Short:             "Stop all VMs of a lab",
+	Args:              cobra.MaximumNArgs(1),
+	RunE:              runLabStop,
+	ValidArgsFunction: completeLabNames,
+}
+
+var labListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List labs",
+	Args:    cobra.NoArgs,
+	RunE:    runLabList,
+}
+
+var labStatusCmd = &cobra.Command{
+	Use:               "status [lab]",
+	Short:             "Show the members of a lab",
+	Args:              cobra.MaximumNArgs(1),
+	RunE:              runLabStatus,
+	ValidArgsFunction: completeLabNames,
+}
+
+var labRemoveCmd = &cobra.Command{
+	Use:   "rm <lab>",
+	Short: "Remove a lab network",
+	Long: `Remove a lab network and detach its VMs.
+
+The VMs themselves are kept; they start without the lab network from now on.
+All members must be stopped first.`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runLabRemove,
+	ValidArgsFunction: completeLabNames,
+}
+
+var (
+	labFile        string
+	vmStartNetwork string
+)
+
+func init() {
+	rootCmd.AddCommand(labCmd)
+	labCmd.AddCommand(labStartCmd)
+	labCmd.AddCommand(labStopCmd)
+	labCmd.AddCommand(labListCmd)
+	labCmd.AddCommand(labStatusCmd)
+	labCmd.AddCommand(labRemoveCmd)
+
+	fileHelp := fmt.Sprintf("Lab file path (default: %s)", config.DefaultLabFileName)
+	labStartCmd.Flags().StringVarP(&labFile, "file", "f", "", fileHelp)
+	labStopCmd.Flags().StringVarP(&labFile, "file", "f", "", fileHelp)
+	labStatusCmd.Flags().StringVarP(&labFile, "file", "f", "", fileHelp)
+
+	for _, c := range []*cobra.Command{vmStartCmd, vmCreateCmd} {
+		c.Flags().StringVar(&vmStartNetwork, "network", "", "Attach the VM to a lab network, created if needed (QEMU only)")
+		_ = c.RegisterFlagCompletionFunc("network", completeLabNames)
+	}
+}
+
+// completeLabNames completes the names of existing labs
+func completeLabNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	labs, _ := vm.ListLabs()
+	var names []string
+	for _, l := range labs {
+		names = append(names, l.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// loadLabDefinition loads the lab file given by --file or the default file
+func loadLabDefinition() (*ci.LabDefinition, error) {
+	path := labFile
+	if path == "" {
+		path = config.DefaultLabFileName
+	}
+	return ci.LoadLabDefinition(path)
+}
+
+// labNameArg returns the lab name from the arguments or the lab file
+func labNameArg(cmd *cobra.Command, args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	def, err := loadLabDefinition()
+	if err != nil {
+		return "", fmt.Errorf("lab name required: %w\n  Specify lab name: bootc-man lab %s <lab>\n  List available labs: bootc-man lab ls", err, cmd.Name())
+	}
+	return def.Metadata.Name, nil
+}
+
+// joinVMLab adds a VM to a lab, creating the lab with the default subnet
+// if it does not exist, and returns the saved lab (nil without a lab)
+func joinVMLab(vmName, labName string) (*vm.Lab, error) {
+	if labName == "" {
+		return nil, nil
+	}
+	// Only a missing lab is created; a lab file that cannot be read is an error
+	lab, err := vm.LoadLab(labName)
+	if errors.Is(err, os.ErrNotExist) {
+		lab, err = vm.NewLab(labName, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := lab.AddMember(vmName, ""); err != nil {
+		return nil, err
+	}
+	if err := vm.SaveLab(lab); err != nil {
+		return nil, err
+	}
+	return lab, nil
+}
+
+// applyVMLab configures the lab interface and hosts entries in the guest and
+// adds the VM to the hosts of the other running members; failures are
+// reported as warnings
+func applyVMLab(ctx context.Context, driver vm.Driver, lab *vm.Lab, vmName string) {
+	if lab == nil {
+		return
+	}
+	member := lab.Member(vmName)
+	if member == nil {
+		return
+	}
+	if output, err := driver.SSH(ctx, lab.ConfigureCommand(*member)); err != nil {
+		fmt.Printf("⚠️  Warning: failed to configure lab network %s: %v\n", lab.Name, err)
+		if output != "" {
+			fmt.Printf("   %s\n", strings.TrimSpace(output))
+		}
+		return
+	}
+	fmt.Printf("🔗 Lab network %s: %s (%s)\n", lab.Name, member.IP, lab.Hostname(vmName))
+	syncLabHosts(ctx, lab, vmName)
+}
+
+// syncLabHosts rewrites the lab's hosts entries in running members other
+// than skip
+func syncLabHosts(ctx context.Context, lab *vm.Lab, skip string) {
+	for _, m := range lab.Members {
+		if m.Name == skip {
+			continue
+		}
+		info, err := vm.LoadVMInfo(m.Name)
+		if err != nil || !vm.IsVMRunning(info) {
+			continue
+		}
+		client := vm.NewSSHClient(m.Name, vm.SSHConfig{
+			Host:    info.SSHHost,
+			Port:    info.SSHPort,
+			User:    vmSSHUserFor(info),
+			KeyPath: info.SSHKeyPath,
+		})
+		if _, err := client.CombinedOutput(ctx, lab.HostsCommand()); err != nil {
+			fmt.Printf("⚠️  Warning: failed to update hosts of %s: %v\n", m.Name, err)
+		} else if verbose {
+			fmt.Printf("   Updated hosts of %s\n", m.Name)
+		}
+		client.Close()
+	}
+}
+
+// labVMStartArgs returns the vm start arguments for a VM of a lab
+func labVMStartArgs(def *ci.LabDefinition, v ci.LabVM) []string {
+	args := []string{"vm", "start", v.Name, "--pipeline", def.PipelinePath(v), "--network", def.Metadata.Name}
+	if v.CPUs > 0 {
+		args = append(args, "--cpus", strconv.Itoa(v.CPUs))
+	}
+	if v.Memory > 0 {
+		args = append(args, "--memory", strconv.Itoa(v.Memory))
+	}
+	if verbose {
+		args = append(args, "--verbose")
+	}
+	return args
+}
+
+// runSelf runs a bootc-man subcommand in dir with output passed through
+func runSelf(dir string, args ...string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate bootc-man: %w", err)
+	}
+	cmd := exec.Command(self, args...)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func runLabStart(cmd *cobra.Command, args []string) error {
+	def, err := loadLabDefinition()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	labName := def.Metadata.Name
+
+	// Dry-run mode
+	if dryRun {
+		fmt.Println("📋 Equivalent command (start lab):")
+		for _, v := range def.Spec.VMs {
+			fmt.Printf("   (cd %s && bootc-man %s)\n", filepath.Dir(def.PipelinePath(v)), strings.Join(labVMStartArgs(def, v), " "))
+		}
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	// Reserve all addresses up front so that every member knows the others;
+	// members of an existing lab keep their addresses
+	lab, err := vm.LoadLab(labName)
+	if err != nil {
+		if lab, err = def.NewLab(); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+	} else {
+		if def.Spec.Subnet != "" && def.Spec.Subnet != lab.Subnet {
+			err := fmt.Errorf("lab %s uses subnet %s; remove it with 'bootc-man lab rm %s' to change the subnet", labName, lab.Subnet, labName)
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+		if err := def.AssignAddresses(lab); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+	}
+	if err := vm.SaveLab(lab); err != nil {
+		return err
+	}
+
+	fmt.Printf("🧪 Starting lab '%s' (%d VMs, %s)\n", labName, len(def.Spec.VMs), lab.Subnet)
+	for i, v := range def.Spec.VMs {
+		fmt.Println()
+		if info, err := vm.LoadVMInfo(v.Name); err == nil && vm.IsVMRunning(info) {
+			fmt.Printf("[%d/%d] ✅ VM '%s' is already running\n", i+1, len(def.Spec.VMs), v.Name)
+			continue
+		}
+		fmt.Printf("[%d/%d] 🚀 Starting VM '%s'...\n", i+1, len(def.Spec.VMs), v.Name)
+		if err := runSelf(filepath.Dir(def.PipelinePath(v)), labVMStartArgs(def, v)...); err != nil {
+			fmt.Printf("❌ Failed to start VM '%s'; stop the started VMs with: bootc-man lab stop %s\n", v.Name, labName)
+			return fmt.Errorf("failed to start VM %s: %w", v.Name, err)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("✅ Lab '%s' is running\n", labName)
+	printLabMembers(lab)
+	return nil
+}
+
+func runLabStop(cmd *cobra.Command, args []string) error {
+	labName, err := labNameArg(cmd, args)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	// Dry-run mode
+	if dryRun {
+		fmt.Println("📋 Equivalent command (stop lab):")
+		fmt.Printf("   bootc-man vm stop <vm>  # for each running VM of lab: %s, in reverse order\n", labName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	lab, err := vm.LoadLab(labName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	// Stop in reverse start order, so that e.g. nodes stop before their registry
+	var failed []string
+	for _, m := range slices.Backward(lab.Members) {
+		info, err := vm.LoadVMInfo(m.Name)
+		if err != nil || !vm.IsVMRunning(info) {
+			continue
+		}
+		if err := runSelf("", "vm", "stop", m.Name); err != nil {
+			failed = append(failed, m.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to stop VMs: %s", strings.Join(failed, ", "))
+	}
+	fmt.Printf("✅ Lab '%s' stopped\n", labName)
+	return nil
+}
+
+// labMemberEntry is a lab member with its current state
+type labMemberEntry struct {
+	Name     string `json:"name"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	MAC      string `json:"mac"`
+	State    string `json:"state"`
+}
+
+// labMemberEntries returns the members of a lab with their VM state
+func labMemberEntries(lab *vm.Lab) []labMemberEntry {
+	var entries []labMemberEntry
+	for _, m := range lab.Members {
+		state := "Not created"
+		if info, err := vm.LoadVMInfo(m.Name); err == nil {
+			state = string(vm.GetVMState(context.Background(), info))
+		}
+		entries = append(entries, labMemberEntry{
+			Name:     m.Name,
+			IP:       m.IP,
+			Hostname: lab.Hostname(m.Name),
+			MAC:      m.MAC,
+			State:    state,
+		})
+	}
+	return entries
+}
+
+// printLabMembers prints the members of a lab as a table
+func printLabMembers(lab *vm.Lab) {
+	fmt.Println()
+	fmt.Printf("%-20s %-16s %-40s %s\n", "NAME", "IP", "HOSTNAME", "STATE")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, e := range labMemberEntries(lab) {
+		fmt.Printf("%-20s %-16s %-40s %s\n", e.Name, e.IP, e.Hostname, e.State)
+	}
+}
+
+func runLabList(cmd *cobra.Command, args []string) error {
+	// Dry-run mode
+	if dryRun {
+		fmt.Println("📋 Equivalent command (list labs):")
+		fmt.Println("   ls ~/.local/share/bootc-man/labs/*.json")
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	labs, err := vm.ListLabs()
+	if err != nil {
+		return fmt.Errorf("failed to list labs: %w", err)
+	}
+
+	type labListEntry struct {
+		Name    string `json:"name"`
+		Subnet  string `json:"subnet"`
+		Members int    `json:"members"`
+		Running int    `json:"running"`
+	}
+	entries := []labListEntry{}
+	for _, l := range labs {
+		entry := labListEntry{Name: l.Name, Subnet: l.Subnet, Members: len(l.Members)}
+		for _, m := range l.Members {
+			if info, err := vm.LoadVMInfo(m.Name); err == nil && vm.IsVMRunning(info) {
+				entry.Running++
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	// JSON output
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No labs found")
+		return nil
+	}
+	fmt.Printf("%-20s %-18s %s\n", "NAME", "SUBNET", "VMS")
+	fmt.Println(strings.Repeat("-", 50))
+	for _, e := range entries {
+		fmt.Printf("%-20s %-18s %d (%d running)\n", e.Name, e.Subnet, e.Members, e.Running)
+	}
+	return nil
+}
+
+func runLabStatus(cmd *cobra.Command, args []string) error {
+	labName, err := labNameArg(cmd, args)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	// Dry-run mode
+	if dryRun {
+		fmt.Println("📋 Equivalent command (show lab):")
+		fmt.Printf("   cat ~/.local/share/bootc-man/labs/%s.json\n", labName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	lab, err := vm.LoadLab(labName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	// JSON output
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]any{
+			"name":    lab.Name,
+			"subnet":  lab.Subnet,
+			"members": labMemberEntries(lab),
+		})
+	}
+
+	fmt.Printf("Lab: %s\n", lab.Name)
+	fmt.Printf("  Subnet: %s\n", lab.Subnet)
+	fmt.Printf("  Segment: multicast %s\n", lab.Multicast)
+	if len(lab.Members) == 0 {
+		fmt.Println()
+		fmt.Println("No members")
+		return nil
+	}
+	printLabMembers(lab)
+	return nil
+}
+
+func runLabRemove(cmd *cobra.Command, args []string) error {
+	labName := args[0]
+
+	// Dry-run mode
+	if dryRun {
+		fmt.Println("📋 Equivalent command (remove lab):")
+		fmt.Printf("   rm ~/.local/share/bootc-man/labs/%s.json\n", labName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	lab, err := vm.LoadLab(labName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	var running []string
+	for _, m := range lab.Members {
+		if info, err := vm.LoadVMInfo(m.Name); err == nil && vm.IsVMRunning(info) {
+			running = append(running, m.Name)
+		}
+	}
+	if len(running) > 0 {
+		err := fmt.Errorf("lab %s has running VMs: %s", labName, strings.Join(running, ", "))
+		fmt.Printf("❌ %v\n", err)
+		fmt.Printf("   Stop them first: bootc-man lab stop %s\n", labName)
+		return err
+	}
+
+	// Detach the VMs so that they do not rejoin the lab on their next start
+	for _, m := range lab.Members {
+		if info, err := vm.LoadVMInfo(m.Name); err != nil || info.Lab != labName {
+			continue
+		}
+		_, err := vm.UpdateVMInfo(m.Name, func(info *vm.VMInfo) error {
+			if info.Lab == labName {
+				info.Lab = ""
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("⚠️  Warning: Failed to update VM '%s': %v\n", m.Name, err)
+		}
+	}
+	if err := vm.DeleteLab(labName); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	fmt.Printf("✅ Lab '%s' removed\n", labName)
+	return nil
+}
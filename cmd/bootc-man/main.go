@@ -5,9 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
+	"github.com/tnk4on/bootc-man/internal/cleanup"
 	"github.com/tnk4on/bootc-man/internal/podman"
 	"github.com/tnk4on/bootc-man/internal/registry"
 )
@@ -20,21 +19,37 @@ func main() {
 	defer cancel()
 
 	// Handle interrupt signals (SIGINT, SIGTERM)
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		<-sigChan
-		cancel()
-	}()
+	// The first signal cancels the context and runs the registered cleanup
+	// actions; a second one force-quits
+	cleanupDone := cleanup.HandleSignals(cancel, cleanup.DefaultTimeout)
 
 	// Execute with context
-	if err := ExecuteWithContext(ctx); err != nil {
+	err := ExecuteWithContext(ctx)
+	if ctx.Err() != nil {
+		// Interrupted: wait for cleanup so nothing is left running
+		<-cleanupDone
+		os.Exit(130)
+	}
+	if err != nil {
+		var statusErr *exitStatusError
+		if errors.As(err, &statusErr) {
+			os.Exit(statusErr.code)
+		}
 		printError(err)
 		os.Exit(1)
 	}
 }
 
+// exitStatusError makes bootc-man exit with code without printing an error
+// Used by commands that pass through the exit status of a remote command.
+type exitStatusError struct {
+	code int
+}
+
+func (e *exitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
 // printError formats and prints errors with clear separation between bootc-man and podman errors
 func printError(err error) {
 	var regErr *registry.RegistryError
@@ -27,6 +27,10 @@ and executes bootc commands. The remote host must:
   - Have bootc installed
   - Be a bootc-enabled system
 
+HostName, User, Port and IdentityFile are read from ~/.ssh/config; keys that
+are protected by a passphrase must be loaded into ssh-agent. Host keys are
+checked against ~/.ssh/known_hosts and recorded on first connect.
+
 Alternatively, use --vm to connect to a bootc-man managed VM.
 
 Example:
@@ -146,6 +146,7 @@ func hideUnsupportedFlags(cmd *cobra.Command) {
 		"list":    true, // vm list, container image list
 		"show":    true, // config show
 		"inspect": true, // container image inspect
+		"exec":    true, // vm exec
 	}
 
 	// Commands that support --dry-run (almost all action commands)
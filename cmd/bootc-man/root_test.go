@@ -18,6 +18,8 @@ func TestRootCommandStructure(t *testing.T) {
 		"vm":         false,
 		"completion": false,
 		"container":  false,
+		"lab":        false,
+		"system":     false,
 	}
 
 	for _, cmd := range subcommands {
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var systemCmd = &cobra.Command{
+	Use:   "system",
+	Short: "Manage bootc-man's own state",
+}
+
+var systemPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove everything left behind by crashed VMs and test runs",
+	Long: `Remove orphaned resources left behind by crashed or interrupted VMs and
+CI test runs:
+
+  - VM definitions, disks and SSH keypairs of removed VMs (like 'vm prune')
+  - gvproxy, virtiofsd and swtpm processes whose VM is gone; a helper is
+    only stopped if no running QEMU or vfkit process uses its sockets, and
+    only if its command line is unchanged when it is stopped
+  - sockets, PID files, logs and EFI variable stores in the runtime
+    directory (/var/tmp/bootc-man on Linux, /tmp/bootc-man on macOS)
+  - bootc-man-test-* disks in ~/.local/share/bootc-man/tmp
+  - port allocations in podman's port-alloc.dat that no VM or podman
+    machine uses
+  - files and processes that an interrupted run recorded as not cleaned
+    up (e.g. a convert stage's root-owned .tmp-* directory)
+
+Running VMs, stopped VMs' logs and EFI variable stores, and anything younger
+than 10 minutes are never touched.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPrune(vm.PruneScopeSystem)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(systemCmd)
+	systemCmd.AddCommand(systemPruneCmd)
+	systemPruneCmd.Flags().BoolVarP(&pruneForce, "force", "f", false, "Do not prompt for confirmation")
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -16,6 +19,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tnk4on/bootc-man/internal/ci"
 	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/podman"
+	"github.com/tnk4on/bootc-man/internal/sshclient"
 	"github.com/tnk4on/bootc-man/internal/vm"
 )
 
@@ -37,7 +42,12 @@ This command requires:
 The VM will be started with vfkit (macOS) and can be accessed via SSH.
 By default, the VM name is derived from the pipeline name in bootc-ci.yaml.
 You can specify a VM name as an argument or use --name flag.
-Use --gui to show the VM console in a GUI window (macOS only).`,
+Use --gui to show the VM console in a GUI window (macOS only).
+Use --image <ref> to boot a bootc container image without a pipeline: the
+image is pulled (unless it is a local localhost/ image), converted to a raw
+disk once and cached by image ID. The VM name defaults to the image name.
+Use --mount <hostdir>:<tag>[:ro] to share host directories with the VM via
+virtio-fs (virtiofsd is required on Linux).`,
 	Args:              cobra.RangeArgs(0, 1),
 	RunE:              runVMStart,
 	ValidArgsFunction: completeStartableVMNames,
@@ -65,12 +75,35 @@ var vmStopCmd = &cobra.Command{
 	Use:   "stop [name]",
 	Short: "Stop a VM",
 	Long: `Stop a running VM.
+The guest OS is asked to power off so that it can shut down cleanly. If it
+does not power off within --timeout seconds, the VM is stopped forcibly.
 If name is omitted and bootc-ci.yaml exists in current directory,
 uses the pipeline name as default VM name.`,
 	RunE:              runVMStop,
 	ValidArgsFunction: completeRunningVMNames,
 }
 
+var vmPauseCmd = &cobra.Command{
+	Use:   "pause [name]",
+	Short: "Pause a running VM",
+	Long: `Suspend execution of a running VM. The VM keeps its memory and can be
+continued with 'bootc-man vm resume'.
+If name is omitted and bootc-ci.yaml exists in current directory,
+uses the pipeline name as default VM name.`,
+	RunE:              runVMPause,
+	ValidArgsFunction: completeRunningVMNames,
+}
+
+var vmResumeCmd = &cobra.Command{
+	Use:   "resume [name]",
+	Short: "Resume a paused VM",
+	Long: `Continue execution of a paused VM.
+If name is omitted and bootc-ci.yaml exists in current directory,
+uses the pipeline name as default VM name.`,
+	RunE:              runVMResume,
+	ValidArgsFunction: completeRunningVMNames,
+}
+
 var vmSSHCmd = &cobra.Command{
 	Use:   "ssh [name]",
 	Short: "Connect to VM via SSH",
@@ -98,6 +131,14 @@ var (
 	vmStartMemory       int
 	vmStartGUI          bool
 	vmRemoveForce       bool
+	vmStopTimeout       int
+	vmStartProvision    string
+	vmStartArch         string
+	vmStartSecureBoot   bool
+	vmStartTPM          bool
+	vmStartFirmware     string
+	vmStartProfile      string
+	vmStartImage        string
 	vmSSHUser           string
 	// Shared pipeline file flag for VM subcommands
 	vmPipelineFile string
@@ -153,23 +194,46 @@ func init() {
 	vmCmd.AddCommand(vmListCmd)
 	vmCmd.AddCommand(vmStatusCmd)
 	vmCmd.AddCommand(vmStopCmd)
+	vmCmd.AddCommand(vmPauseCmd)
+	vmCmd.AddCommand(vmResumeCmd)
 	vmCmd.AddCommand(vmSSHCmd)
 	vmCmd.AddCommand(vmRemoveCmd)
 
-	vmStartCmd.Flags().StringVar(&vmStartName, "name", "", "VM name (default: derived from pipeline name, can also be specified as argument)")
-	vmStartCmd.Flags().StringVarP(&vmStartPipelineFile, "pipeline", "p", "", "Pipeline file path (default: bootc-ci.yaml)")
-	vmStartCmd.Flags().IntVar(&vmStartCPUs, "cpus", 2, "Number of CPUs")
-	vmStartCmd.Flags().IntVar(&vmStartMemory, "memory", 4096, "Memory size in MB")
+	// vm create takes the VM spec flags of vm start and stores them
+	for _, c := range []*cobra.Command{vmStartCmd, vmCreateCmd} {
+		c.Flags().StringVar(&vmStartName, "name", "", "VM name (default: derived from pipeline name, can also be specified as argument)")
+		c.Flags().StringVarP(&vmStartPipelineFile, "pipeline", "p", "", "Pipeline file path (default: bootc-ci.yaml)")
+		c.Flags().IntVar(&vmStartCPUs, "cpus", 2, "Number of CPUs")
+		c.Flags().IntVar(&vmStartMemory, "memory", 4096, "Memory size in MB")
+		c.Flags().StringVar(&vmStartArch, "arch", "", "Guest architecture: amd64 or arm64 (default: the pipeline's image architecture, else the host's)")
+		c.Flags().BoolVar(&vmStartSecureBoot, "secure-boot", false, "Boot with UEFI secure boot enforced (QEMU only, default: test.boot.secureBoot from the pipeline)")
+		c.Flags().BoolVar(&vmStartTPM, "tpm", false, "Attach an emulated TPM 2.0 device via swtpm (QEMU only, default: test.boot.tpm from the pipeline)")
+		c.Flags().StringVar(&vmStartFirmware, "firmware", "", "Boot firmware: uefi or bios (QEMU only, bios for x86_64 guests, default: test.boot.firmware from the pipeline, else uefi)")
+		c.Flags().StringVar(&vmStartProvision, "provision", "", "Provision the SSH key at first boot: ignition, cloud-init or none (default: test.boot.provisioning from the pipeline)")
+		c.Flags().StringVar(&vmStartProfile, "profile", "", "VM profile from the config (vm.profiles) setting CPUs, memory, disk size, firmware and NIC model (default: test.boot.profile from the pipeline)")
+
+		_ = c.RegisterFlagCompletionFunc("provision", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return vm.ProvisioningMethods, cobra.ShellCompDirectiveNoFileComp
+		})
+		_ = c.RegisterFlagCompletionFunc("firmware", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return vm.FirmwareTypes, cobra.ShellCompDirectiveNoFileComp
+		})
+		_ = c.RegisterFlagCompletionFunc("profile", completeVMProfiles)
+	}
 	vmStartCmd.Flags().BoolVar(&vmStartGUI, "gui", false, "Display VM console in GUI window (macOS only)")
+	vmStartCmd.Flags().StringVar(&vmStartImage, "image", "", "Boot a bootc container image (registry reference or local podman image) instead of the pipeline's disk")
 
 	// Register completion for --name flag
 	_ = vmStartCmd.RegisterFlagCompletionFunc("name", completeStartableVMNames)
 
+	vmStopCmd.Flags().IntVar(&vmStopTimeout, "timeout", config.DefaultVMStopTimeout, "Seconds to wait for the guest to power off before stopping it forcibly")
 	vmRemoveCmd.Flags().BoolVarP(&vmRemoveForce, "force", "f", false, "Force removal even if VM is running")
 
 	// Add --pipeline flag to VM subcommands that need pipeline file
 	pipelineHelp := "Pipeline file path (default: bootc-ci.yaml in current directory)"
 	vmStopCmd.Flags().StringVarP(&vmPipelineFile, "pipeline", "p", "", pipelineHelp)
+	vmPauseCmd.Flags().StringVarP(&vmPipelineFile, "pipeline", "p", "", pipelineHelp)
+	vmResumeCmd.Flags().StringVarP(&vmPipelineFile, "pipeline", "p", "", pipelineHelp)
 	vmStatusCmd.Flags().StringVarP(&vmPipelineFile, "pipeline", "p", "", pipelineHelp)
 	vmSSHCmd.Flags().StringVarP(&vmPipelineFile, "pipeline", "p", "", pipelineHelp)
 	vmSSHCmd.Flags().StringVarP(&vmSSHUser, "user", "u", "", "SSH user name (default: from config or 'user')")
@@ -177,15 +241,183 @@ func init() {
 	vmListCmd.Flags().StringVarP(&vmPipelineFile, "pipeline", "p", "", pipelineHelp)
 }
 
+// vmStartExtras holds vm start flags that are validated before anything runs
+type vmStartExtras struct {
+	published  []vm.PortForward
+	mounts     []vm.SharedDir
+	arch       string // empty unless --arch is given
+	secureBoot bool
+	tpm        bool
+	firmware   string // empty unless --firmware is given
+	network    string // lab name, empty unless --network is given
+	cpus       int    // 0 unless --cpus or a profile is given
+	memory     int    // 0 unless --memory or a profile is given
+	profile    string // VM profile name, empty unless --profile is given
+	diskSize   int    // GB, from the profile
+	nicModel   string // from the profile
+	driver     string // VM driver from the config (qemu or libvirt)
+	libvirtURI string // libvirt connection of the libvirt driver
+	image      string // container image of --image, empty otherwise
+	// firmwareSet records whether --secure-boot or --tpm was given, so that a
+	// restart keeps the VM's previous settings otherwise
+	firmwareSet bool
+}
+
+// parseVMStartExtras parses the --publish, --mount, --arch, --secure-boot,
+// --tpm, --firmware, --network, --cpus, --memory and --profile flags of vm start
+func parseVMStartExtras(cmd *cobra.Command) (vmStartExtras, error) {
+	published, err := parsePortForwards(vmStartPublish)
+	if err != nil {
+		return vmStartExtras{}, err
+	}
+	mounts, err := parseSharedDirs(vmStartMounts)
+	if err != nil {
+		return vmStartExtras{}, err
+	}
+	extras := vmStartExtras{
+		published:   published,
+		mounts:      mounts,
+		secureBoot:  vmStartSecureBoot,
+		tpm:         vmStartTPM,
+		firmwareSet: cmd.Flags().Changed("secure-boot") || cmd.Flags().Changed("tpm"),
+		firmware:    vmStartFirmware,
+		network:     vmStartNetwork,
+		image:       vmStartImage,
+	}
+	if cmd.Flags().Changed("cpus") {
+		extras.cpus = vmStartCPUs
+	}
+	if cmd.Flags().Changed("memory") {
+		extras.memory = vmStartMemory
+	}
+	if vmStartProfile != "" {
+		if err := applyVMProfile(&extras, vmStartProfile); err != nil {
+			return vmStartExtras{}, err
+		}
+	}
+	if extras.network != "" && vm.SanitizeVMName(extras.network) != extras.network {
+		return vmStartExtras{}, fmt.Errorf("invalid lab name %q (use letters, digits and hyphens)", extras.network)
+	}
+	// Architecture and secure boot compatibility is checked by the driver
+	if extras.firmware != "" && !slices.Contains(vm.FirmwareTypes, extras.firmware) {
+		return vmStartExtras{}, fmt.Errorf("unsupported firmware %q (supported: uefi, bios)", extras.firmware)
+	}
+	if err := vm.ValidateNICModel(extras.nicModel); err != nil {
+		return vmStartExtras{}, err
+	}
+	if vmStartArch != "" {
+		if extras.arch, err = vm.NormalizeArch(vmStartArch); err != nil {
+			return vmStartExtras{}, err
+		}
+	}
+	if cfg, err := config.Load(""); err == nil {
+		extras.driver, extras.libvirtURI = cfg.VM.Driver, cfg.VM.LibvirtURI
+	}
+	return extras, nil
+}
+
+// vmType returns the VM type the configured driver starts VMs with
+func (e vmStartExtras) vmType() vm.VMType {
+	return vm.DriverVMType(e.driver)
+}
+
+// applyDriver selects the configured VM driver in opts
+// libvirt and external drivers forward published ports when the VM starts;
+// the others forward them through gvproxy once the VM is up.
+func (e vmStartExtras) applyDriver(opts *vm.VMOptions) {
+	opts.Driver = e.driver
+	opts.LibvirtURI = e.libvirtURI
+	if !e.vmType().UsesGvproxy() {
+		opts.Ports = e.published
+	}
+}
+
+// applyVMProfile fills in the settings of a VM profile from the config that
+// were not given as flags
+func applyVMProfile(extras *vmStartExtras, name string) error {
+	cfg, err := config.Load("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	profile, err := cfg.VM.Profile(name)
+	if err != nil {
+		return err
+	}
+	extras.profile = name
+	if extras.cpus == 0 {
+		extras.cpus = profile.CPUs
+	}
+	if extras.memory == 0 {
+		extras.memory = profile.Memory
+	}
+	if extras.firmware == "" {
+		extras.firmware = profile.Firmware
+	}
+	extras.diskSize = profile.DiskSize
+	extras.nicModel = profile.NICModel
+	return nil
+}
+
+// completeVMProfiles returns the VM profile names of the config
+func completeVMProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.Load("")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return slices.Sorted(maps.Keys(cfg.VM.Profiles)), cobra.ShellCompDirectiveNoFileComp
+}
+
+// growVMDisk grows the VM's own disk to the profile's disk size
+// The source artifact (an ISO installer used in place) is never modified.
+func growVMDisk(ctx context.Context, vmDiskPath, srcPath string, sizeGB int) error {
+	if sizeGB == 0 || vmDiskPath == srcPath {
+		return nil
+	}
+	if err := vm.GrowDisk(ctx, vmDiskPath, sizeGB, verbose); err != nil {
+		return fmt.Errorf("failed to grow VM disk: %w", err)
+	}
+	return nil
+}
+
 func runVMStart(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	if err := vm.ValidateProvisioning(vmStartProvision); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	extras, err := parseVMStartExtras(cmd)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
 	// Dry-run mode: show commands that would be executed
 	if dryRun {
-		vmType := vm.GetDefaultVMType()
+		vmType := extras.vmType()
+		if vmStartImage != "" {
+			fmt.Println("📋 Equivalent command (convert image):")
+			if !strings.HasPrefix(vmStartImage, "localhost/") {
+				fmt.Printf("   podman pull %s\n", vmStartImage)
+			}
+			fmt.Printf("   podman run --rm --privileged -v /var/lib/containers/storage:/var/lib/containers/storage -v <cache>:/output %s --type raw --rootfs ext4 --output /output %s\n", config.DefaultBootcImageBuilder, vmStartImage)
+			fmt.Println("   # cached in ~/.local/share/bootc-man/images/<image-id>/ and reused while the image ID is unchanged")
+			fmt.Println()
+		}
 		fmt.Println("📋 Equivalent command (start VM):")
 
 		switch vmType {
+		case vm.ExternalVM:
+			fmt.Printf("   echo '{\"version\":%d,\"method\":\"start\",\"vm\":\"<vm>\",\"options\":{...}}' | %s%s\n", vm.PluginProtocolVersion, config.VMDriverPluginPrefix, extras.driver)
+		case vm.LibvirtVM:
+			fmt.Printf("   virsh -c %s create <domain.xml>\n", cmp.Or(extras.libvirtURI, config.DefaultLibvirtURI))
+			fmt.Println("   # transient domain bootc-man-<vm>: virtio disk, passt networking with portForward 127.0.0.1:<port> -> 22")
+			if extras.secureBoot {
+				fmt.Println("   # secure boot: <os firmware='efi'> with the secure-boot and enrolled-keys firmware features")
+			}
+			if extras.tpm {
+				fmt.Println("   # TPM 2.0: <tpm model='tpm-crb'><backend type='emulator' version='2.0'/></tpm>")
+			}
 		case vm.VfkitVM:
 			fmt.Println("   vfkit --cpus <n> --memory <mb> --bootloader efi,variable-store=<efi-store> \\")
 			fmt.Println("         --device virtio-blk,path=<disk.raw> --device virtio-net,nat,natLocalhost")
@@ -193,6 +425,61 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 			fmt.Println("   qemu-system-x86_64 -enable-kvm -m <mb> -smp <n> \\")
 			fmt.Println("         -drive file=<disk.raw>,format=raw,if=virtio \\")
 			fmt.Println("         -netdev user,id=net0,hostfwd=tcp::<port>-:22")
+			if extras.arch != "" && extras.arch != vm.HostArch() {
+				machine, firmware := "accel=tcg", "OVMF"
+				if extras.arch == vm.ArchARM64 {
+					machine, firmware = "virt,accel=tcg", "AAVMF"
+				}
+				fmt.Printf("   # emulated %s guest: qemu-system-%s -M %s -cpu max (%s firmware)\n", extras.arch, vm.QemuArch(extras.arch), machine, firmware)
+			}
+			if extras.secureBoot {
+				fmt.Println("   # secure boot: -M q35,smm=on -global driver=cfi.pflash01,property=secure,value=on (OVMF_CODE.secboot.fd, enrolled OVMF_VARS)")
+			}
+			if extras.firmware == vm.FirmwareBIOS {
+				fmt.Println("   # BIOS boot: no pflash drives, QEMU boots the BIOS boot partition with SeaBIOS")
+			}
+			if extras.network != "" {
+				fmt.Printf("   # lab network %s: -netdev socket,id=lab0,mcast=<group:port>,localaddr=127.0.0.1 -device virtio-net-pci,netdev=lab0\n", extras.network)
+				fmt.Println("   # then over SSH: nmcli connection add ... ipv4.addresses <lab-ip>; /etc/hosts entries for all members")
+			}
+			if extras.tpm {
+				fmt.Println("   # TPM 2.0: swtpm socket --tpm2 --tpmstate dir=<vm>-tpm --ctrl type=unixio,path=<tpm.sock>; -tpmdev emulator -device tpm-crb")
+			}
+		}
+		switch vmStartProvision {
+		case vm.ProvisioningIgnition:
+			fmt.Println("   # Ignition: -fw_cfg name=opt/com.coreos/config,file=<vm>.ign (QEMU), --ignition <vm>.ign (vfkit)")
+		case vm.ProvisioningCloudInit:
+			fmt.Println("   # cloud-init: NoCloud seed disk <vm>-seed.iso (label cidata) attached as virtio-blk")
+		}
+		if extras.profile != "" {
+			fmt.Printf("   # profile %s: %d CPUs, %d MB", extras.profile, extras.cpus, extras.memory)
+			if extras.diskSize > 0 {
+				fmt.Printf(", disk grown to %d GB", extras.diskSize)
+			}
+			if extras.nicModel != "" {
+				fmt.Printf(", -device %s,netdev=net0", extras.nicModel)
+			}
+			fmt.Println()
+		}
+		for _, p := range extras.published {
+			if !vmType.UsesGvproxy() {
+				fmt.Printf("   # %s forward: %s -> %d/%s\n", vmType, p.Local(), p.GuestPort, p.Protocol)
+				continue
+			}
+			fmt.Printf("   # gvproxy forward: %s -> <vm-ip>:%d/%s\n", p.Local(), p.GuestPort, p.Protocol)
+		}
+		for _, m := range extras.mounts {
+			switch vmType {
+			case vm.VfkitVM:
+				fmt.Printf("   # virtio-fs: --device virtio-fs,sharedDir=%s,mountTag=%s\n", m.Source, m.Tag)
+			case vm.QemuVM, vm.LibvirtVM:
+				readonly := ""
+				if m.ReadOnly {
+					readonly = " --readonly"
+				}
+				fmt.Printf("   # virtio-fs: virtiofsd --socket-path <fs.sock> --shared-dir %s --sandbox none%s; -device vhost-user-fs-pci,tag=%s\n", m.Source, readonly, m.Tag)
+			}
 		}
 		fmt.Println()
 		fmt.Println("(dry-run mode - command not executed)")
@@ -200,8 +487,9 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 	}
 
 	// Check if hypervisor is available (platform-specific)
-	vmType := vm.GetDefaultVMType()
-	tempOpts := vm.VMOptions{Name: "check"}
+	vmType := extras.vmType()
+	tempOpts := vm.VMOptions{Name: "check", Arch: extras.arch}
+	extras.applyDriver(&tempOpts)
 	tempDriver, err := vm.NewDriver(tempOpts, false)
 	if err != nil {
 		fmt.Printf("❌ %s is not available on this platform\n", vmType.String())
@@ -212,23 +500,16 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Generate VM name: argument takes precedence, then --name flag, then pipeline name
-	var vmName string
-	if len(args) > 0 && args[0] != "" {
-		vmName = args[0]
-	} else if vmStartName != "" {
-		vmName = vmStartName
-	} else {
-		// Use default VM name from pipeline
-		var err error
-		vmName, err = getDefaultVMName(vmStartPipelineFile)
-		if err != nil {
-			fmt.Printf("❌ %v\n", err)
-			fmt.Println("   Specify VM name as argument, use --name flag, or use --pipeline-file to specify pipeline file")
-			return err
-		}
+	vmName, err := vmStartVMName(args)
+	if err != nil {
+		return err
 	}
-	vmName = vm.SanitizeVMName(vmName)
+	vmLock, err := vm.LockVM(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	defer vmLock.Release()
 
 	// First, check if we're restarting an existing stopped VM
 	// In this case, we don't need podman (skip prerequisites check)
@@ -262,16 +543,20 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 			if absDiskPath == "" {
 				absDiskPath = existingVM.DiskImage
 			}
-			fmt.Printf("🔄 Restarting existing VM '%s'...\n", vmName)
+			if existingVM.State == string(vm.VMStateCreated) {
+				fmt.Printf("🚀 Starting VM '%s'...\n", vmName)
+			} else {
+				fmt.Printf("🔄 Restarting existing VM '%s'...\n", vmName)
+			}
 			fmt.Printf("   VM disk: %s\n", absDiskPath)
 			fmt.Println()
 
 			// Use existing VM info to restart
-			return restartExistingVM(ctx, existingVM)
+			return restartExistingVM(ctx, existingVM, extras)
 		}
 		// Disk image doesn't exist, fall through to create new VM
 		fmt.Printf("⚠️  VM '%s' exists but disk image not found, will create new VM\n", vmName)
-	} else {
+	} else if vmStartImage == "" {
 		// VM info doesn't exist, but check if disk image exists in artifacts
 		// This handles the case where VM was removed but disk image still exists
 		// Look in current directory for pipeline artifacts
@@ -279,33 +564,175 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to get current directory: %w", err)
 		}
-		diskImagePath := findDiskImageInArtifacts(wd)
+		diskImagePath := findDiskImageInArtifacts(wd, extras.vmType())
 		if diskImagePath != "" {
 			// Display absolute paths for clarity when running from different directories
 			absSourcePath, _ := filepath.Abs(diskImagePath)
 			if absSourcePath == "" {
 				absSourcePath = diskImagePath
 			}
-			vmsDir, _ := vm.GetVMsDir()
-			absVMDiskPath := filepath.Join(vmsDir, vmName+".raw")
+			absVMDiskPath, _, err := vmDiskPathFor(absSourcePath, vmName, extras.vmType())
+			if err != nil {
+				return err
+			}
 			fmt.Printf("🚀 Starting new VM '%s'...\n", vmName)
 			fmt.Printf("   Source image: %s\n", absSourcePath)
 			fmt.Printf("   VM disk:      %s\n", absVMDiskPath)
 			fmt.Println()
 
 			// Create a new VM info and start without podman
-			return startVMWithDiskImage(ctx, vmName, diskImagePath)
+			return startVMWithDiskImage(ctx, vmName, diskImagePath, extras)
 		}
 	}
 
-	// Find pipeline file (needed for new VM creation)
+	if vmStartImage != "" {
+		return startVMFromImage(ctx, vmName, vmStartImage, extras)
+	}
+
+	// Find and load the pipeline (needed for new VM creation)
+	source, err := loadVMPipelineSource(ctx)
+	if err != nil {
+		return err
+	}
+	pipeline, pipelineFile, imageTag := source.pipeline, source.pipelineFile, source.imageTag
+
+	// Guest architecture, provisioning and firmware default to the pipeline's
+	arch, provisionMethod, err := applyVMPipelineDefaults(pipeline, &extras)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	// Get SSH key path
+	sshKeyPath, err := resolveVMSSHKey(vmName, provisionMethod, "")
+	if err != nil {
+		return err
+	}
+
+	// Prepare disk image path
+	diskImagePath := source.diskImage
+
+	// Copy disk image to VM directory
+	vmDiskPath, diskMethod, err := prepareVMDisk(ctx, diskImagePath, vmName, extras.vmType())
+	if err != nil {
+		return fmt.Errorf("failed to copy disk image: %w", err)
+	}
+	if err := growVMDisk(ctx, vmDiskPath, diskImagePath, extras.diskSize); err != nil {
+		return err
+	}
+
+	// The VM's spec is that of its first start
+	vmInfo := newVMSpec(vmName, arch, extras)
+	vmInfo.PipelineName = pipeline.Metadata.Name
+	vmInfo.PipelineFile = pipelineFile
+	vmInfo.ImageTag = imageTag
+	vmInfo.DiskImage = vmDiskPath
+	vmInfo.BaseImage = baseImagePath(diskImagePath, vmDiskPath)
+	vmInfo.DiskMethod = diskMethod
+	vmInfo.SSHKeyPath = sshKeyPath
+	return startVM(ctx, vmInfo, extras, provisionMethod)
+}
+
+// vmStartVMName returns the VM name of vm start and vm create
+// The argument takes precedence, then --name, then the --image name, then
+// the pipeline name.
+func vmStartVMName(args []string) (string, error) {
+	var vmName string
+	if len(args) > 0 && args[0] != "" {
+		vmName = args[0]
+	} else if vmStartName != "" {
+		vmName = vmStartName
+	} else if vmStartImage != "" {
+		vmName = imageVMName(vmStartImage)
+	} else {
+		// Use default VM name from pipeline
+		var err error
+		vmName, err = getDefaultVMName(vmStartPipelineFile)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			fmt.Println("   Specify VM name as argument, use --name flag, or use --pipeline-file to specify pipeline file")
+			return "", err
+		}
+	}
+	return vm.SanitizeVMName(vmName), nil
+}
+
+// imageVMName derives a VM name from a container image reference
+// quay.io/org/fedora-bootc:42 and quay.io/org/fedora-bootc@sha256:... both
+// give fedora-bootc.
+func imageVMName(ref string) string {
+	name, _, _ := strings.Cut(ref, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return vm.SanitizeVMName(name[strings.LastIndex(name, "/")+1:])
+}
+
+// startVMFromImage converts a container image to a disk (or reuses the
+// cached disk) and starts a new VM from it
+func startVMFromImage(ctx context.Context, vmName, imageRef string, extras vmStartExtras) error {
+	cfg, err := config.Load("")
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	podmanClient, err := podman.NewClient()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	opts := ci.ImageDiskOptions{
+		BootcImageBuilder: cmp.Or(cfg.CI.BootcImageBuilder, config.DefaultBootcImageBuilder),
+		Verbose:           verbose,
+	}
+	// Without first-boot provisioning the SSH login is baked into the disk
+	if vmProvisioningMethod("") == "" {
+		sshKeyPath, err := resolveVMSSHKey(vmName, "", "")
+		if err != nil {
+			return err
+		}
+		opts.SSHUser = getSSHUser()
+		opts.SSHPublicKey = sshKeyPath + ".pub"
+	}
+
+	fmt.Printf("🚀 Starting new VM '%s' from image %s\n", vmName, imageRef)
+	disk, err := ci.ImageDisk(ctx, podmanClient, imageRef, opts)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	if extras.arch == "" {
+		extras.arch = disk.Arch
+	}
+	if disk.Cached {
+		fmt.Printf("   Cached disk: %s\n", disk.Path)
+	} else {
+		fmt.Printf("✅ Disk image cached: %s\n", disk.Path)
+	}
+	fmt.Println()
+
+	return startVMWithDiskImage(ctx, vmName, disk.Path, extras)
+}
+
+// vmPipelineSource is a pipeline whose build and convert stages produced the
+// disk image a new VM boots from
+type vmPipelineSource struct {
+	pipeline     *ci.Pipeline
+	pipelineFile string
+	imageTag     string
+	diskImage    string
+}
+
+// loadVMPipelineSource loads the --pipeline file (default: bootc-ci.yaml) and
+// checks that its disk image has been built (requires podman)
+func loadVMPipelineSource(ctx context.Context) (*vmPipelineSource, error) {
 	pipelineFile := vmStartPipelineFile
 	if pipelineFile == "" {
 		var err error
 		pipelineFile, err = findPipelineFile("")
 		if err != nil {
 			fmt.Println("❌", err)
-			return err
+			return nil, err
 		}
 	}
 
@@ -313,7 +740,7 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 	pipeline, err := ci.LoadPipeline(pipelineFile)
 	if err != nil {
 		fmt.Printf("❌ Failed to load pipeline: %v\n", err)
-		return err
+		return nil, err
 	}
 
 	// Generate image tag
@@ -328,7 +755,7 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 	prereq, err := vm.CheckPrerequisites(ctx, pipeline.BaseDir(), imageTag)
 	if err != nil {
 		fmt.Printf("❌ Failed to check prerequisites: %v\n", err)
-		return err
+		return nil, err
 	}
 
 	if !prereq.BuildCompleted || !prereq.ConvertCompleted {
@@ -336,56 +763,186 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 		for _, errMsg := range prereq.Errors {
 			fmt.Printf("   %s\n", errMsg)
 		}
-		return fmt.Errorf("prerequisites not met")
+		return nil, fmt.Errorf("prerequisites not met")
 	}
 
 	fmt.Println("✅ Prerequisites met")
 	fmt.Println()
 
-	// Get SSH key path
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-	sshKeyPath := filepath.Join(homeDir, ".ssh", "id_ed25519")
-	if _, err := os.Stat(sshKeyPath); err != nil {
-		// Try RSA key
-		sshKeyPath = filepath.Join(homeDir, ".ssh", "id_rsa")
-		if _, err := os.Stat(sshKeyPath); err != nil {
-			return fmt.Errorf("no SSH private key found. Please ensure ~/.ssh/id_ed25519 or ~/.ssh/id_rsa exists")
+	return &vmPipelineSource{
+		pipeline:     pipeline,
+		pipelineFile: pipelineFile,
+		imageTag:     imageTag,
+		diskImage:    prereq.DiskImagePath,
+	}, nil
+}
+
+// applyVMPipelineDefaults fills in the settings of test.boot that were not
+// given as flags and returns the guest architecture and provisioning method
+func applyVMPipelineDefaults(pipeline *ci.Pipeline, extras *vmStartExtras) (string, string, error) {
+	// Guest architecture (--arch or the architecture the pipeline builds)
+	arch := extras.arch
+	if arch == "" {
+		var err error
+		if arch, err = vm.NormalizeArch(pipeline.ImageArch()); err != nil {
+			return "", "", err
 		}
 	}
 
-	// Prepare disk image path
-	diskImagePath := prereq.DiskImagePath
+	// First-boot provisioning method (--provision or pipeline setting)
+	pipelineProvisioning := ""
+	if pipeline.Spec.Test != nil && pipeline.Spec.Test.Boot != nil {
+		pipelineProvisioning = pipeline.Spec.Test.Boot.Provisioning
+		// Secure boot and TPM are enabled by the flags or the pipeline
+		extras.secureBoot = extras.secureBoot || pipeline.Spec.Test.Boot.SecureBoot
+		extras.tpm = extras.tpm || pipeline.Spec.Test.Boot.TPM
+		if extras.firmware == "" {
+			extras.firmware = pipeline.Spec.Test.Boot.Firmware
+		}
+		// The pipeline's profile applies unless --profile is given
+		if extras.profile == "" && pipeline.Spec.Test.Boot.Profile != "" {
+			if err := applyVMProfile(extras, pipeline.Spec.Test.Boot.Profile); err != nil {
+				return "", "", fmt.Errorf("spec.test.boot.profile: %w", err)
+			}
+		}
+	}
+	return arch, vmProvisioningMethod(pipelineProvisioning), nil
+}
 
-	// Copy disk image to VM directory
-	vmDiskPath, err := copyDiskImageToVMs(diskImagePath, vmName)
+// restartExistingVM restarts an existing stopped VM using its saved info
+// This does not require podman - uses platform-specific hypervisor
+func restartExistingVM(ctx context.Context, existingVM *vm.VMInfo, extras vmStartExtras) error {
+	vmName := existingVM.Name
+	diskImagePath := existingVM.DiskImage
+
+	// Provisioning data is regenerated so that key changes are picked up
+	previousProvisioning := ""
+	if existingVM.Provisioning != nil {
+		previousProvisioning = existingVM.Provisioning.Method
+	}
+	provisionMethod := vmProvisioningMethod(previousProvisioning)
+
+	// Reuse the VM's SSH key, falling back if it no longer exists
+	sshKeyPath, err := resolveVMSSHKey(vmName, provisionMethod, existingVM.SSHKeyPath)
 	if err != nil {
-		return fmt.Errorf("failed to copy disk image: %w", err)
+		return err
 	}
 
-	// Create driver options
-	// SSHPort is set to 0 to allow dynamic allocation by the driver
-	driverOpts := vm.VMOptions{
+	// An overlay disk on top of a rebuilt base image would boot a corrupted disk
+	if existingVM.BaseImageChanged() {
+		err := fmt.Errorf("base image %s changed after VM '%s' was created", existingVM.BaseImage, vmName)
+		fmt.Printf("❌ %v\n", err)
+		fmt.Printf("   The VM disk is an overlay on that image and cannot be used with the new one\n")
+		fmt.Printf("   Recreate the VM: bootc-man vm rm %s && bootc-man vm start %s\n", vmName, vmName)
+		return err
+	}
+
+	// A profile given now can grow the disk (a VM disk that is the source
+	// artifact itself has no base image and is left alone)
+	if err := growVMDisk(ctx, diskImagePath, cmp.Or(existingVM.BaseImage, diskImagePath), extras.diskSize); err != nil {
+		return err
+	}
+
+	// The flags override the VM's spec for this run only
+	vmInfo := *existingVM
+	vmInfo.SSHKeyPath = sshKeyPath
+	return startVM(ctx, &vmInfo, extras, provisionMethod)
+}
+
+// newVMSpec returns the VM info of a new VM, holding the spec of its first
+// start; later starts use the stored spec unless flags override it
+func newVMSpec(vmName, arch string, extras vmStartExtras) *vm.VMInfo {
+	return &vm.VMInfo{
 		Name:       vmName,
-		DiskImage:  vmDiskPath,
-		CPUs:       vmStartCPUs,
-		Memory:     vmStartMemory,
-		SSHKeyPath: sshKeyPath,
 		SSHUser:    getSSHUser(),
-		SSHPort:    0, // Dynamic allocation
-		GUI:        vmStartGUI,
+		Mounts:     extras.mounts,
+		Lab:        extras.network,
+		CPUs:       cmp.Or(extras.cpus, vmStartCPUs),
+		Memory:     cmp.Or(extras.memory, vmStartMemory),
+		NICModel:   extras.nicModel,
+		Profile:    extras.profile,
+		VMType:     extras.vmType().String(),
+		Arch:       arch,
+		SecureBoot: extras.secureBoot,
+		TPM:        extras.tpm,
+		Firmware:   extras.firmware,
+	}
+}
+
+// vmOptionsFor returns the driver options of one start of a VM
+// The VM's stored spec applies unless extras override it for this run.
+func vmOptionsFor(info *vm.VMInfo, extras vmStartExtras, provisioning *vm.Provisioning, lab *vm.Lab) vm.VMOptions {
+	mounts := extras.mounts
+	if len(mounts) == 0 {
+		mounts = info.Mounts
+	}
+	// Firmware settings are kept too; the TPM state stays in <vm>-tpm
+	secureBoot, tpm := info.SecureBoot, info.TPM
+	if extras.firmwareSet {
+		secureBoot, tpm = extras.secureBoot, extras.tpm
+	}
+
+	// SSHPort is set to 0 to allow dynamic allocation by the driver
+	opts := vm.VMOptions{
+		Name:         info.Name,
+		DiskImage:    info.DiskImage,
+		InstallDisk:  vmInstallDiskPath(info.DiskImage, info.Name),
+		CPUs:         cmp.Or(extras.cpus, info.CPUs, vmStartCPUs),
+		Memory:       cmp.Or(extras.memory, info.Memory, vmStartMemory),
+		SSHKeyPath:   info.SSHKeyPath,
+		SSHUser:      cmp.Or(info.SSHUser, getSSHUser()),
+		SSHPort:      0, // Dynamic allocation
+		GUI:          vmStartGUI,
+		Provisioning: provisioning,
+		Mounts:       mounts,
+		Arch:         cmp.Or(extras.arch, info.Arch), // the disk was installed for this architecture
+		SecureBoot:   secureBoot,
+		TPM:          tpm,
+		Firmware:     cmp.Or(extras.firmware, info.Firmware),
+		Lab:          lab,
+		NICModel:     cmp.Or(extras.nicModel, info.NICModel),
+	}
+	// ISO installers install onto a blank disk of the profile's size
+	opts.InstallDiskSize = extras.diskSize
+	extras.applyDriver(&opts)
+	if !extras.vmType().UsesGvproxy() {
+		// The saved forwards are passed to the driver as well
+		saved := &vm.VMInfo{Ports: slices.Clone(info.Ports)}
+		for _, p := range extras.published {
+			_ = saved.AddPortForward(p)
+		}
+		opts.Ports = saved.Ports
+	}
+	return opts
+}
+
+// startVM starts a VM, waits until it is up and saves its VM info
+// info is the stored VM info, or that of a new VM (see newVMSpec). The
+// first-boot provisioning data is regenerated so that key changes are
+// picked up.
+func startVM(ctx context.Context, info *vm.VMInfo, extras vmStartExtras, provisionMethod string) error {
+	vmName := info.Name
+	provisioning, err := prepareVMProvisioning(vmName, info.SSHKeyPath, cmp.Or(info.SSHUser, getSSHUser()), provisionMethod)
+	if err != nil {
+		return err
+	}
+
+	// Lab network membership (the address is kept in the lab state)
+	lab, err := joinVMLab(vmName, cmp.Or(extras.network, info.Lab))
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
 	}
 
 	// Create platform-specific driver
+	driverOpts := vmOptionsFor(info, extras, provisioning, lab)
 	driver, err := vm.NewDriver(driverOpts, verbose)
 	if err != nil {
 		return fmt.Errorf("failed to create VM driver: %w", err)
 	}
 
 	// Start VM
-	fmt.Printf("🚀 Starting VM with %s...\n", vmType.String())
+	fmt.Printf("🚀 Starting VM with %s...\n", extras.vmType().String())
 	if err := driver.Start(ctx, driverOpts); err != nil {
 		return fmt.Errorf("failed to start VM: %w", err)
 	}
@@ -402,6 +959,7 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 	fmt.Println("⏳ Waiting for SSH to be available...")
 	if err := driver.WaitForSSH(ctx); err != nil {
 		fmt.Printf("⚠️  Warning: SSH not available: %v\n", err)
+		printConsoleHint(driver, vmName)
 		fmt.Println("   VM is running but SSH may not be ready yet")
 	} else {
 		fmt.Println("✅ SSH connection established")
@@ -410,10 +968,14 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 	// Get SSH config from driver
 	sshConfig := driver.GetSSHConfig()
 
-	// Save VM info using driver
-	vmInfo := driver.ToVMInfo(vmName, pipeline.Metadata.Name, pipelineFile, imageTag)
+	// Save VM info using driver; the stored spec is kept
+	updatedInfo := driver.ToVMInfo(vmName, info.PipelineName, info.PipelineFile, info.ImageTag)
+	preserveVMMetadata(updatedInfo, info)
+	preserveVMSpec(updatedInfo, info)
+	applyVMPortForwards(ctx, updatedInfo, extras.published)
+	applyVMLab(ctx, driver, lab, vmName)
 
-	if err := vm.SaveVMInfo(vmInfo); err != nil {
+	if err := vm.SaveVMInfo(updatedInfo); err != nil {
 		fmt.Printf("⚠️  Warning: Failed to save VM info: %v\n", err)
 	}
 
@@ -431,6 +993,7 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Alternative (direct SSH):\n")
 	fmt.Printf("  ssh -i %s -p %d %s@%s\n", sshConfig.KeyPath, sshConfig.Port, sshConfig.User, sshConfig.Host)
 	fmt.Println()
+	printSharedDirHints(driverOpts.Mounts)
 	fmt.Printf("To stop:\n")
 	fmt.Printf("  bootc-man vm stop %s\n", vmName)
 
@@ -440,295 +1003,236 @@ func runVMStart(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// restartExistingVM restarts an existing stopped VM using its saved info
-// This does not require podman - uses platform-specific hypervisor
-func restartExistingVM(ctx context.Context, existingVM *vm.VMInfo) error {
-	vmName := existingVM.Name
-	diskImagePath := existingVM.DiskImage
-	sshKeyPath := existingVM.SSHKeyPath
+// findDiskImageInArtifacts searches for disk images in the artifacts directory
+// Only formats VMs of vmType can boot are considered; raw is preferred, then
+// qcow2, vmdk and iso
+func findDiskImageInArtifacts(baseDir string, vmType vm.VMType) string {
+	artifactsDir := filepath.Join(baseDir, "output", "images")
+	return vm.FindDiskImageInDir(artifactsDir, "", vmType.SupportedImageFormats())
+}
+
+// vmDiskPathFor returns the path of the VM's own disk for a source artifact
+// VM disks live in ~/.local/share/bootc-man/vms/<vmName>.<format> and share
+// the source artifact as a read-only base where possible (see vm.PlanVMDisk);
+// ISO installers are attached read-only and used in place. The planned disk
+// method, which depends on the VM type, is returned as well.
+func vmDiskPathFor(srcPath, vmName string, vmType vm.VMType) (string, string, error) {
+	vmsDir, err := vm.GetVMsDir()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get VMs directory: %w", err)
+	}
+
+	destPath, method, err := vm.PlanVMDisk(srcPath, filepath.Join(vmsDir, vmName), vmType)
+	if err != nil {
+		return "", "", fmt.Errorf("%w\n   Add a raw format to the convert stage", err)
+	}
+	return destPath, method, nil
+}
+
+// vmProvisioningMethod returns the first-boot provisioning method for a VM
+// The --provision flag takes precedence over defaultMethod (from the pipeline
+// or the VM's previous run). "none" is returned as an empty string.
+func vmProvisioningMethod(defaultMethod string) string {
+	method := vmStartProvision
+	if method == "" {
+		method = defaultMethod
+	}
+	if method == vm.ProvisioningNone {
+		return ""
+	}
+	return method
+}
 
-	// Verify SSH key exists
-	if _, err := os.Stat(sshKeyPath); err != nil {
-		// Try to find alternative SSH key
-		homeDir, err := os.UserHomeDir()
+// resolveVMSSHKey selects the SSH private key for a VM
+// previousKey (from an existing VM) is reused while it exists. When the key
+// is provisioned at boot, a dedicated keypair is generated for the VM;
+// otherwise the image must already trust one of the user's keys.
+func resolveVMSSHKey(vmName, method, previousKey string) (string, error) {
+	if previousKey != "" {
+		if _, err := os.Stat(previousKey); err == nil {
+			return previousKey, nil
+		}
+	}
+	if method != "" {
+		keyPath, err := vm.EnsureVMSSHKey(vmName)
 		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+			return "", err
 		}
-		sshKeyPath = filepath.Join(homeDir, ".ssh", "id_ed25519")
-		if _, err := os.Stat(sshKeyPath); err != nil {
-			sshKeyPath = filepath.Join(homeDir, ".ssh", "id_rsa")
-			if _, err := os.Stat(sshKeyPath); err != nil {
-				return fmt.Errorf("no SSH private key found")
-			}
+		if verbose {
+			fmt.Printf("Using per-VM SSH key: %s\n", keyPath)
 		}
+		return keyPath, nil
 	}
 
-	sshUser := existingVM.SSHUser
-	if sshUser == "" {
-		sshUser = getSSHUser()
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	for _, name := range []string{"id_ed25519", "id_rsa"} {
+		keyPath := filepath.Join(homeDir, ".ssh", name)
+		if _, err := os.Stat(keyPath); err == nil {
+			return keyPath, nil
+		}
 	}
+	return "", fmt.Errorf("no SSH private key found. Please ensure ~/.ssh/id_ed25519 or ~/.ssh/id_rsa exists\n   Or provision a dedicated per-VM key at boot: bootc-man vm start --provision cloud-init")
+}
 
-	// Create driver options
-	// SSHPort is set to 0 to allow dynamic allocation by the driver
-	vmType := vm.GetDefaultVMType()
-	driverOpts := vm.VMOptions{
-		Name:       vmName,
-		DiskImage:  diskImagePath,
-		CPUs:       vmStartCPUs,
-		Memory:     vmStartMemory,
-		SSHKeyPath: sshKeyPath,
-		SSHUser:    sshUser,
-		SSHPort:    0, // Dynamic allocation
-		GUI:        vmStartGUI,
+// prepareVMProvisioning writes the first-boot provisioning data for a VM
+// The files are stored next to the VM disk. Returns nil when method is empty.
+func prepareVMProvisioning(vmName, sshKeyPath, sshUser, method string) (*vm.Provisioning, error) {
+	if method == "" {
+		return nil, nil
 	}
 
-	// Create platform-specific driver
-	driver, err := vm.NewDriver(driverOpts, verbose)
+	vmsDir, err := vm.GetVMsDir()
 	if err != nil {
-		return fmt.Errorf("failed to create VM driver: %w", err)
+		return nil, fmt.Errorf("failed to get VMs directory: %w", err)
 	}
-
-	// Start VM
-	fmt.Printf("🚀 Starting VM with %s...\n", vmType.String())
-	if err := driver.Start(ctx, driverOpts); err != nil {
-		return fmt.Errorf("failed to start VM: %w", err)
+	provisioning, err := ci.PrepareProvisioning(method, filepath.Join(vmsDir, vmName), vmName, sshKeyPath+".pub", sshUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare %s provisioning: %w", method, err)
 	}
+	fmt.Printf("🔑 SSH key will be provisioned at boot via %s\n", method)
+	return provisioning, nil
+}
 
-	// Wait for VM to be ready
-	fmt.Println("⏳ Waiting for VM to boot...")
-	if err := driver.WaitForReady(ctx); err != nil {
-		_ = driver.Cleanup()
-		return fmt.Errorf("VM failed to boot: %w", err)
+// vmInstallDiskPath returns the blank target disk path used when a VM boots
+// from an ISO installer, or an empty string for regular disk images
+func vmInstallDiskPath(diskImagePath, vmName string) string {
+	format, err := vm.DetectImageFormat(diskImagePath)
+	if err != nil || format != vm.ImageFormatISO {
+		return ""
 	}
-	fmt.Println("✅ VM is running")
-
-	// Wait for SSH
-	fmt.Println("⏳ Waiting for SSH to be available...")
-	if err := driver.WaitForSSH(ctx); err != nil {
-		fmt.Printf("⚠️  Warning: SSH not available: %v\n", err)
-	} else {
-		fmt.Println("✅ SSH connection established")
+	vmsDir, err := vm.GetVMsDir()
+	if err != nil {
+		return ""
 	}
+	return filepath.Join(vmsDir, fmt.Sprintf("%s-install.qcow2", vmName))
+}
 
-	// Get SSH config from driver
-	sshConfig := driver.GetSSHConfig()
-
-	// Update VM info using driver
-	updatedInfo := driver.ToVMInfo(vmName, existingVM.PipelineName, existingVM.PipelineFile, existingVM.ImageTag)
-
-	if err := vm.SaveVMInfo(updatedInfo); err != nil {
-		fmt.Printf("⚠️  Warning: Failed to save VM info: %v\n", err)
+// preserveVMMetadata carries over VM info that outlives a single VM run
+func preserveVMMetadata(updated, existing *vm.VMInfo) {
+	updated.BaseImage = existing.BaseImage
+	updated.DiskMethod = existing.DiskMethod
+	updated.Snapshots = existing.Snapshots
+	updated.Ports = existing.Ports
+	if !existing.Created.IsZero() {
+		updated.Created = existing.Created
 	}
-
-	// Display SSH connection information
-	fmt.Println()
-	fmt.Println("SSH connection information:")
-	fmt.Printf("  Host: %s\n", sshConfig.Host)
-	fmt.Printf("  Port: %d\n", sshConfig.Port)
-	fmt.Printf("  User: %s\n", sshConfig.User)
-	fmt.Printf("  Key: %s\n", sshConfig.KeyPath)
-	fmt.Println()
-	fmt.Printf("To connect:\n")
-	fmt.Printf("  bootc-man vm ssh %s\n", vmName)
-	fmt.Println()
-	fmt.Printf("To stop:\n")
-	fmt.Printf("  bootc-man vm stop %s\n", vmName)
-
-	return nil
 }
 
-// findDiskImageInArtifacts searches for disk images in the artifacts directory
-func findDiskImageInArtifacts(baseDir string) string {
-	artifactsDir := filepath.Join(baseDir, "output", "images")
+// preserveVMSpec keeps the stored VM spec when a VM is restarted
+// --cpus, --memory, --profile and the firmware flags only apply to the run
+// they are given for; VMs saved before a field was stored take it from the
+// driver.
+func preserveVMSpec(updated, existing *vm.VMInfo) {
+	updated.CPUs = cmp.Or(existing.CPUs, updated.CPUs)
+	updated.Memory = cmp.Or(existing.Memory, updated.Memory)
+	updated.NICModel = cmp.Or(existing.NICModel, updated.NICModel)
+	updated.Profile = existing.Profile
+	updated.Arch = cmp.Or(existing.Arch, updated.Arch)
+	updated.Firmware = cmp.Or(existing.Firmware, updated.Firmware)
+	updated.SecureBoot = existing.SecureBoot
+	updated.TPM = existing.TPM
+}
 
-	// Check common locations for disk images
-	// First, try raw format (preferred for vfkit)
-	rawPath := filepath.Join(artifactsDir, "image", "disk.raw")
-	if _, err := os.Stat(rawPath); err == nil {
-		return rawPath
+// baseImagePath returns the absolute path of the shared base image of a VM
+// disk, or an empty string when the VM disk is the source artifact itself
+func baseImagePath(srcPath, vmDiskPath string) string {
+	if srcPath == vmDiskPath {
+		return ""
 	}
-
-	// Try qcow2 format
-	qcow2Path := filepath.Join(artifactsDir, "qcow2", "disk.qcow2")
-	if _, err := os.Stat(qcow2Path); err == nil {
-		return qcow2Path
+	absPath, err := filepath.Abs(srcPath)
+	if err != nil {
+		return srcPath
 	}
-
-	// Search recursively for any raw or qcow2 file
-	var foundPath string
-	_ = filepath.Walk(artifactsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !info.IsDir() {
-			if strings.HasSuffix(info.Name(), ".raw") {
-				foundPath = path
-				return filepath.SkipAll
-			}
-			if strings.HasSuffix(info.Name(), ".qcow2") && foundPath == "" {
-				foundPath = path
-			}
-		}
-		return nil
-	})
-
-	return foundPath
+	return absPath
 }
 
-// copyDiskImageToVMs copies the source disk image to output/vms/<vmName>.raw
-// If the file already exists, it is reused (no copy performed)
-// Returns the path to the VM disk image
-func copyDiskImageToVMs(srcPath, vmName string) (string, error) {
+// prepareVMDisk creates the VM's own disk in ~/.local/share/bootc-man/vms/
+// On QEMU this is a qcow2 overlay backed by the source artifact, so starting
+// a VM is near-instant and several VMs share one base image read-only.
+// Elsewhere the source is cloned with a reflink, falling back to a full copy.
+// If the VM disk already exists, it is reused.
+// Returns the path to the VM disk image and the method it was created with
+// (see vm.DiskMethodOverlay)
+func prepareVMDisk(ctx context.Context, srcPath, vmName string, vmType vm.VMType) (string, string, error) {
 	// Get global VMs directory
 	vmsDir, err := vm.GetVMsDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get VMs directory: %w", err)
+		return "", "", fmt.Errorf("failed to get VMs directory: %w", err)
 	}
 	if err := os.MkdirAll(vmsDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create vms directory: %w", err)
+		return "", "", fmt.Errorf("failed to create vms directory: %w", err)
 	}
 
-	// Destination path: ~/.local/share/bootc-man/vms/<vmName>.raw
-	destPath := filepath.Join(vmsDir, fmt.Sprintf("%s.raw", vmName))
+	// Destination path: ~/.local/share/bootc-man/vms/<vmName>.<format>
+	destPath, method, err := vmDiskPathFor(srcPath, vmName, vmType)
+	if err != nil {
+		return "", "", err
+	}
 
 	// Check if destination already exists
-	if _, err := os.Stat(destPath); err == nil {
-		if verbose {
-			fmt.Printf("Using existing VM disk image: %s\n", destPath)
+	if destPath != srcPath {
+		if _, err := os.Stat(destPath); err == nil {
+			if verbose {
+				fmt.Printf("Using existing VM disk image: %s\n", destPath)
+			}
+			return destPath, method, nil
 		}
-		return destPath, nil
-	}
-
-	// Copy the disk image
-	if verbose {
-		fmt.Printf("Copying disk image to VM directory...\n")
-		fmt.Printf("  Source: %s\n", srcPath)
-		fmt.Printf("  Dest:   %s\n", destPath)
-	}
-
-	// Open source file
-	src, err := os.Open(srcPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open source file: %w", err)
 	}
-	defer src.Close()
 
-	// Create destination file
-	dst, err := os.Create(destPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create destination file: %w", err)
+	if verbose {
+		fmt.Printf("Preparing VM disk image...\n")
+		fmt.Printf("  Base: %s\n", srcPath)
+		fmt.Printf("  Dest: %s\n", destPath)
 	}
-	defer dst.Close()
 
-	// Copy with progress indication for large files
-	srcInfo, _ := src.Stat()
-	if srcInfo != nil && srcInfo.Size() > 1024*1024*100 { // > 100MB
-		fmt.Printf("Copying %.1f GB disk image (this may take a while)...\n", float64(srcInfo.Size())/(1024*1024*1024))
+	destPath, method, err = vm.CreateVMDisk(ctx, srcPath, filepath.Join(vmsDir, vmName), vmType, verbose)
+	if err != nil {
+		return "", "", err
 	}
 
-	if _, err := io.Copy(dst, src); err != nil {
-		os.Remove(destPath) // Clean up partial file
-		return "", fmt.Errorf("failed to copy disk image: %w", err)
+	// A fresh disk generates new host keys on first boot
+	if err := vm.ForgetHostKey(vmName); err != nil && verbose {
+		fmt.Printf("⚠️  Warning: failed to reset known host key: %v\n", err)
 	}
 
 	if verbose {
-		fmt.Println("✅ Disk image copied")
+		fmt.Println("✅ VM disk image ready")
 	}
 
-	return destPath, nil
+	return destPath, method, nil
 }
 
 // startVMWithDiskImage starts a new VM using only the disk image (no VM info required)
-func startVMWithDiskImage(ctx context.Context, vmName, diskImagePath string) error {
-	// Get SSH key path
-	homeDir, err := os.UserHomeDir()
+func startVMWithDiskImage(ctx context.Context, vmName, diskImagePath string, extras vmStartExtras) error {
+	// Get SSH key path (a dedicated key is generated with --provision)
+	provisionMethod := vmProvisioningMethod("")
+	sshKeyPath, err := resolveVMSSHKey(vmName, provisionMethod, "")
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-	sshKeyPath := filepath.Join(homeDir, ".ssh", "id_ed25519")
-	if _, err := os.Stat(sshKeyPath); err != nil {
-		sshKeyPath = filepath.Join(homeDir, ".ssh", "id_rsa")
-		if _, err := os.Stat(sshKeyPath); err != nil {
-			return fmt.Errorf("no SSH private key found")
-		}
+		return err
 	}
 
 	// Copy disk image to global VMs directory if not already there
 	// This allows the original image to remain unchanged and enables multiple VMs
-	vmDiskPath, err := copyDiskImageToVMs(diskImagePath, vmName)
+	vmDiskPath, diskMethod, err := prepareVMDisk(ctx, diskImagePath, vmName, extras.vmType())
 	if err != nil {
 		return fmt.Errorf("failed to prepare VM disk image: %w", err)
 	}
-
-	sshUser := getSSHUser()
-	vmType := vm.GetDefaultVMType()
-
-	// Create driver options
-	// SSHPort is set to 0 to allow dynamic allocation by the driver
-	driverOpts := vm.VMOptions{
-		Name:       vmName,
-		DiskImage:  vmDiskPath,
-		CPUs:       vmStartCPUs,
-		Memory:     vmStartMemory,
-		SSHKeyPath: sshKeyPath,
-		SSHUser:    sshUser,
-		SSHPort:    0, // Dynamic allocation
-		GUI:        vmStartGUI,
-	}
-
-	// Create platform-specific driver
-	driver, err := vm.NewDriver(driverOpts, verbose)
-	if err != nil {
-		return fmt.Errorf("failed to create VM driver: %w", err)
-	}
-
-	// Start VM
-	fmt.Printf("🚀 Starting VM with %s...\n", vmType.String())
-	if err := driver.Start(ctx, driverOpts); err != nil {
-		return fmt.Errorf("failed to start VM: %w", err)
-	}
-
-	// Wait for VM to be ready
-	fmt.Println("⏳ Waiting for VM to boot...")
-	if err := driver.WaitForReady(ctx); err != nil {
-		_ = driver.Cleanup()
-		return fmt.Errorf("VM failed to boot: %w", err)
-	}
-	fmt.Println("✅ VM is running")
-
-	// Wait for SSH
-	fmt.Println("⏳ Waiting for SSH to be available...")
-	if err := driver.WaitForSSH(ctx); err != nil {
-		fmt.Printf("⚠️  Warning: SSH not available: %v\n", err)
-	} else {
-		fmt.Println("✅ SSH connection established")
-	}
-
-	// Get SSH config from driver
-	sshConfig := driver.GetSSHConfig()
-
-	// Create and save VM info using driver
-	vmInfo := driver.ToVMInfo(vmName, "unknown", "", "")
-
-	if err := vm.SaveVMInfo(vmInfo); err != nil {
-		fmt.Printf("⚠️  Warning: Failed to save VM info: %v\n", err)
+	if err := growVMDisk(ctx, vmDiskPath, diskImagePath, extras.diskSize); err != nil {
+		return err
 	}
 
-	// Display SSH connection information
-	fmt.Println()
-	fmt.Println("SSH connection information:")
-	fmt.Printf("  Host: %s\n", sshConfig.Host)
-	fmt.Printf("  Port: %d\n", sshConfig.Port)
-	fmt.Printf("  User: %s\n", sshConfig.User)
-	fmt.Printf("  Key: %s\n", sshConfig.KeyPath)
-	fmt.Println()
-	fmt.Printf("To connect:\n")
-	fmt.Printf("  bootc-man vm ssh %s\n", vmName)
-	fmt.Println()
-	fmt.Printf("To stop:\n")
-	fmt.Printf("  bootc-man vm stop %s\n", vmName)
-
-	return nil
+	// The VM's spec is that of its first start
+	vmInfo := newVMSpec(vmName, extras.arch, extras)
+	vmInfo.PipelineName = "unknown"
+	vmInfo.ImageTag = extras.image
+	vmInfo.DiskImage = vmDiskPath
+	vmInfo.BaseImage = baseImagePath(diskImagePath, vmDiskPath)
+	vmInfo.DiskMethod = diskMethod
+	vmInfo.SSHKeyPath = sshKeyPath
+	return startVM(ctx, vmInfo, extras, provisionMethod)
 }
 
 // Note: findBaseDir was removed as it is currently unused.
@@ -830,10 +1334,7 @@ func runVMList(cmd *cobra.Command, args []string) error {
 
 	var entries []VMListEntry
 	for _, info := range vmInfos {
-		state := "Stopped"
-		if isVMRunning(info) {
-			state = "Running"
-		}
+		state := string(vm.GetVMState(context.Background(), info))
 		entries = append(entries, VMListEntry{
 			Name:     info.Name,
 			State:    state,
@@ -865,6 +1366,9 @@ func runVMList(cmd *cobra.Command, args []string) error {
 
 	for _, entry := range entries {
 		sshInfo := fmt.Sprintf("%s@%s:%d", entry.SSHUser, entry.SSHHost, entry.SSHPort)
+		if entry.SSHPort == 0 {
+			sshInfo = "-" // Created but never started
+		}
 		created := entry.Created[:19] // Trim timezone for table display
 		if len(entry.Created) >= 19 {
 			created = strings.Replace(entry.Created[:19], "T", " ", 1)
@@ -923,16 +1427,15 @@ func runVMStatus(cmd *cobra.Command, args []string) error {
 	if mainPID == 0 {
 		mainPID = vmInfo.VfkitPID // Fallback for old VM info format
 	}
-	vmRunning := isProcessRunning(mainPID)
-	var currentState string
-	if vmRunning {
-		currentState = "Running"
-	} else {
-		currentState = "Stopped"
-	}
+	vmRunning := vm.IsVMRunning(vmInfo)
+	currentState := string(vm.GetVMState(context.Background(), vmInfo))
 
 	// Check gvproxy state (macOS specific)
+	// libvirt and external driver VMs are networked by their driver
 	gvproxyRunning := isProcessRunning(vmInfo.GvproxyPID)
+	if !vmInfo.UsesGvproxy() {
+		gvproxyRunning = vmRunning
+	}
 
 	// Determine VM type
 	vmType := vmInfo.VMType
@@ -943,6 +1446,38 @@ func runVMStatus(cmd *cobra.Command, args []string) error {
 	// Display VM status
 	fmt.Printf("VM: %s\n", vmInfo.Name)
 	fmt.Printf("  Type: %s\n", vmType)
+	if vmInfo.Arch != "" {
+		if vmInfo.Accelerator != "" {
+			fmt.Printf("  Arch: %s (%s)\n", vmInfo.Arch, vmInfo.Accelerator)
+		} else {
+			fmt.Printf("  Arch: %s\n", vmInfo.Arch)
+		}
+	}
+	if vmInfo.CPUs > 0 {
+		fmt.Printf("  Resources: %d CPUs, %d MB\n", vmInfo.CPUs, vmInfo.Memory)
+	}
+	if vmInfo.Profile != "" {
+		fmt.Printf("  Profile: %s\n", vmInfo.Profile)
+	}
+	if vmInfo.NICModel != "" && vmInfo.NICModel != vm.NICModelVirtio {
+		fmt.Printf("  NIC: %s\n", vmInfo.NICModel)
+	}
+	if vmInfo.Firmware == vm.FirmwareBIOS {
+		fmt.Printf("  Firmware: BIOS (SeaBIOS)\n")
+	}
+	if vmInfo.Lab != "" {
+		if lab, err := vm.LoadLab(vmInfo.Lab); err == nil && lab.Member(vmName) != nil {
+			fmt.Printf("  Lab: %s (%s, %s)\n", lab.Name, lab.Member(vmName).IP, lab.Hostname(vmName))
+		} else {
+			fmt.Printf("  Lab: %s\n", vmInfo.Lab)
+		}
+	}
+	if vmInfo.SecureBoot {
+		fmt.Printf("  Secure Boot: enabled\n")
+	}
+	if vmInfo.TPM {
+		fmt.Printf("  TPM: 2.0 (swtpm)\n")
+	}
 	fmt.Printf("  State: %s\n", currentState)
 	fmt.Printf("  Pipeline: %s\n", vmInfo.PipelineName)
 	fmt.Printf("  Image Tag: %s\n", vmInfo.ImageTag)
@@ -958,7 +1493,11 @@ func runVMStatus(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  %s: Stopped\n", vmType)
 	}
 	// Show gvproxy status (required for all platforms)
-	if gvproxyRunning {
+	if vmInfo.LibvirtDomain != "" {
+		fmt.Printf("  libvirt: domain %s on %s\n", vmInfo.LibvirtDomain, vmInfo.LibvirtURI)
+	} else if vmInfo.DriverPlugin != "" {
+		fmt.Printf("  driver: %s%s\n", config.VMDriverPluginPrefix, vmInfo.DriverPlugin)
+	} else if gvproxyRunning {
 		fmt.Printf("  gvproxy: Running (PID %d)\n", vmInfo.GvproxyPID)
 	} else {
 		fmt.Printf("  gvproxy: Stopped ⚠️  (SSH not available)\n")
@@ -1003,67 +1542,127 @@ func runVMStop(cmd *cobra.Command, args []string) error {
 	// Dry-run mode
 	if dryRun {
 		fmt.Println("📋 Equivalent command (stop VM):")
-		fmt.Printf("   kill -SIGINT <vm-process-id>  # for VM: %s\n", vmName)
+		fmt.Printf("   echo '{\"execute\":\"system_powerdown\"}' | socat - UNIX-CONNECT:<qmp-socket>  # for VM: %s (QEMU)\n", vmName)
+		fmt.Printf("   curl -X POST -d '{\"state\":\"Stop\"}' <vfkit-endpoint>/vm/state  # for VM: %s (vfkit)\n", vmName)
 		fmt.Println()
 		fmt.Println("(dry-run mode - command not executed)")
 		return nil
 	}
 
+	vmLock, err := vm.LockVM(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	defer vmLock.Release()
+
 	vmInfo, err := vm.LoadVMInfo(vmName)
 	if err != nil {
 		fmt.Printf("❌ %v\n", err)
 		return err
 	}
 
-	// Helper function to stop a process gracefully
-	stopProcess := func(pid int, name string) {
-		if pid <= 0 {
-			return
+	// Power off the guest so it can flush its filesystems (including /var)
+	fmt.Printf("⏹️  Shutting down VM '%s' (timeout: %ds)...\n", vmName, vmStopTimeout)
+	timeout := time.Duration(vmStopTimeout) * time.Second
+	if err := vm.ShutdownVM(context.Background(), vmInfo, timeout); err != nil {
+		if !errors.Is(err, vm.ErrShutdownTimeout) {
+			fmt.Printf("❌ %v\n", err)
+			return err
 		}
-		process, err := os.FindProcess(pid)
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+
+	// Update VM state (a VM that was only created keeps its state)
+	if vmInfo.State != string(vm.VMStateCreated) {
+		vmInfo.State = "Stopped"
+	}
+	if err := vm.SaveVMInfo(vmInfo); err != nil {
+		fmt.Printf("⚠️  Warning: Failed to update VM state: %v\n", err)
+	}
+
+	fmt.Printf("✅ VM '%s' stopped\n", vmName)
+	return nil
+}
+
+// printConsoleHint suggests the serial console when SSH could not be reached
+func printConsoleHint(driver vm.Driver, vmName string) {
+	if driver.Type() == vm.QemuVM {
+		fmt.Printf("   Debug via the serial console: bootc-man vm console %s\n", vmName)
+	}
+}
+
+func runVMPause(cmd *cobra.Command, args []string) error {
+	return changeVMRunState(args, "pause")
+}
+
+func runVMResume(cmd *cobra.Command, args []string) error {
+	return changeVMRunState(args, "resume")
+}
+
+// changeVMRunState pauses or resumes a VM
+// action is either "pause" or "resume"
+func changeVMRunState(args []string, action string) error {
+	var vmName string
+	if len(args) > 0 {
+		vmName = args[0]
+	} else {
+		// Try to get default VM name from pipeline file
+		var err error
+		vmName, err = getDefaultVMName(vmPipelineFile)
 		if err != nil {
-			return
-		}
-		// Try graceful shutdown first
-		if err := process.Signal(os.Interrupt); err == nil {
-			// Wait for process to exit
-			done := make(chan bool, 1)
-			go func() {
-				_, _ = process.Wait()
-				done <- true
-			}()
-			select {
-			case <-done:
-				// Process exited
-			case <-time.After(3 * time.Second):
-				// Force kill if still running
-				_ = process.Kill()
-				_, _ = process.Wait()
-			}
-		} else {
-			// If signal failed, try kill directly
-			_ = process.Kill()
-			_, _ = process.Wait()
+			return fmt.Errorf("VM name required: no bootc-ci.yaml found in current directory\n  Specify VM name: bootc-man vm %s <name>\n  List available VMs: bootc-man vm list", action)
 		}
 	}
 
-	// Stop main VM process (use ProcessID first, fallback to VfkitPID for compatibility)
-	mainPID := vmInfo.ProcessID
-	if mainPID == 0 {
-		mainPID = vmInfo.VfkitPID
+	qmpCmd, vfkitState := "stop", "Pause"
+	if action == "resume" {
+		qmpCmd, vfkitState = "cont", "Resume"
 	}
-	stopProcess(mainPID, "VM")
 
-	// Stop gvproxy (required for all platforms)
-	stopProcess(vmInfo.GvproxyPID, config.BinaryGvproxy)
+	// Dry-run mode
+	if dryRun {
+		fmt.Printf("📋 Equivalent command (%s VM):\n", action)
+		fmt.Printf("   echo '{\"execute\":\"%s\"}' | socat - UNIX-CONNECT:<qmp-socket>  # for VM: %s (QEMU)\n", qmpCmd, vmName)
+		fmt.Printf("   curl -X POST -d '{\"state\":\"%s\"}' <vfkit-endpoint>/vm/state  # for VM: %s (vfkit)\n", vfkitState, vmName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
 
-	// Update VM state
-	vmInfo.State = "Stopped"
-	if err := vm.SaveVMInfo(vmInfo); err != nil {
+	vmInfo, err := vm.LoadVMInfo(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	ctx := context.Background()
+	if action == "pause" {
+		err = vm.PauseVM(ctx, vmInfo)
+	} else {
+		err = vm.ResumeVM(ctx, vmInfo)
+	}
+	if err != nil {
+		fmt.Printf("❌ Failed to %s VM '%s': %v\n", action, vmName, err)
+		return err
+	}
+
+	_, err = vm.UpdateVMInfo(vmName, func(info *vm.VMInfo) error {
+		info.State = string(vm.GetVMState(ctx, info))
+		return nil
+	})
+	if err != nil {
 		fmt.Printf("⚠️  Warning: Failed to update VM state: %v\n", err)
 	}
 
-	fmt.Printf("✅ VM '%s' stopped\n", vmName)
+	if action == "pause" {
+		fmt.Printf("⏸️  VM '%s' paused\n", vmName)
+		fmt.Println()
+		fmt.Printf("To resume:\n")
+		fmt.Printf("  bootc-man vm resume %s\n", vmName)
+	} else {
+		fmt.Printf("▶️  VM '%s' resumed\n", vmName)
+	}
 	return nil
 }
 
@@ -1095,6 +1694,47 @@ func runVMSSH(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if err := ensureVMSSHReachable(vmName, vmInfo); err != nil {
+		return err
+	}
+	sshUser := vmSSHUserFor(vmInfo)
+
+	// Build SSH arguments
+	sshArgs := []string{"-i", vmInfo.SSHKeyPath}
+	sshArgs = append(sshArgs, vm.SSHHostKeyOptions(vmName)...)
+	sshArgs = append(sshArgs,
+		"-p", fmt.Sprintf("%d", vmInfo.SSHPort),
+		fmt.Sprintf("%s@%s", sshUser, vmInfo.SSHHost),
+	)
+
+	// Display connection message
+	if verbose {
+		// Verbose mode: show detailed SSH connection information
+		fmt.Println("SSH connection information:")
+		fmt.Printf("  Host: %s\n", vmInfo.SSHHost)
+		fmt.Printf("  Port: %d\n", vmInfo.SSHPort)
+		fmt.Printf("  User: %s\n", sshUser)
+		fmt.Printf("  Key: %s\n", vmInfo.SSHKeyPath)
+		fmt.Println()
+		fmt.Printf("To connect:\n")
+		fmt.Printf("  ssh -i %s -p %d %s@%s\n", vmInfo.SSHKeyPath, vmInfo.SSHPort, sshUser, vmInfo.SSHHost)
+		fmt.Println()
+	} else {
+		// Normal mode: show brief connection message like podman machine ssh
+		fmt.Printf("Connecting to vm %s. To close connection, use `exit`\n", vmName)
+	}
+
+	sshCmd := exec.Command("ssh", sshArgs...)
+	sshCmd.Stdin = os.Stdin
+	sshCmd.Stdout = os.Stdout
+	sshCmd.Stderr = os.Stderr
+
+	return sshCmd.Run()
+}
+
+// ensureVMSSHReachable checks that the VM and its network proxy are running and
+// makes sure the SSH port is forwarded
+func ensureVMSSHReachable(vmName string, vmInfo *vm.VMInfo) error {
 	// Helper function to check if process is running
 	isProcessRunning := func(pid int) bool {
 		if pid <= 0 {
@@ -1110,12 +1750,8 @@ func runVMSSH(cmd *cobra.Command, args []string) error {
 		return true
 	}
 
-	// Check if VM is running (use ProcessID first, fallback to VfkitPID)
-	mainPID := vmInfo.ProcessID
-	if mainPID == 0 {
-		mainPID = vmInfo.VfkitPID
-	}
-	if !isProcessRunning(mainPID) {
+	// Check if VM is running
+	if !vm.IsVMRunning(vmInfo) {
 		fmt.Printf("❌ VM '%s' is not running\n", vmName)
 		return fmt.Errorf("VM is not running")
 	}
@@ -1126,6 +1762,11 @@ func runVMSSH(cmd *cobra.Command, args []string) error {
 		vmType = config.BinaryVfkit // Default for old VM info format
 	}
 
+	// libvirt and external driver VMs have SSH forwarded since they started
+	if !vmInfo.UsesGvproxy() {
+		return nil
+	}
+
 	// Check if gvproxy is running (required for all platforms)
 	gvproxyRunning := isProcessRunning(vmInfo.GvproxyPID)
 	if !gvproxyRunning {
@@ -1141,68 +1782,62 @@ func runVMSSH(cmd *cobra.Command, args []string) error {
 	// (QEMU sets up port forwarding during vm start)
 	if vmType == config.BinaryVfkit {
 		ctx := context.Background()
-		vmIP := ""
-		if vmInfo.LogFile != "" {
-			logContent, err := os.ReadFile(vmInfo.LogFile)
-			if err == nil && len(logContent) > 0 {
-				vmIP = ci.ExtractVMIPFromLog(string(logContent))
-			}
-		}
 
 		// Create gvproxy client to set up port forwarding
 		gvproxy, err := ci.NewGvproxyClient(vmName, false)
 		if err == nil {
-			if vmIP != "" {
-				if err := gvproxy.ExposePort(ctx, vmIP, 22); err != nil {
-					_ = gvproxy.ExposePort(ctx, "192.168.127.2", 22)
-				}
-			} else {
-				_ = gvproxy.ExposePort(ctx, "192.168.127.2", 22)
+			if err := gvproxy.ExposePort(ctx, vmGuestIP(vmInfo), 22); err != nil {
+				_ = gvproxy.ExposePort(ctx, defaultGuestIP, 22)
+			}
+		}
+	}
+	return nil
+}
+
+// defaultGuestIP is the address gvproxy's DHCP server hands out first
+const defaultGuestIP = "192.168.127.2"
+
+// vmGuestIP returns the VM's address in the gvproxy network, taken from the
+// serial log, or defaultGuestIP if it is not found
+func vmGuestIP(vmInfo *vm.VMInfo) string {
+	if vmInfo.LogFile != "" {
+		logContent, err := os.ReadFile(vmInfo.LogFile)
+		if err == nil && len(logContent) > 0 {
+			if vmIP := ci.ExtractVMIPFromLog(string(logContent)); vmIP != "" {
+				return vmIP
 			}
 		}
 	}
+	return defaultGuestIP
+}
 
-	// Determine SSH user: --user flag > VM info > config default
-	sshUser := vmInfo.SSHUser
+// vmSSHUserFor returns the SSH user for the VM: --user flag > VM info > config default
+func vmSSHUserFor(vmInfo *vm.VMInfo) string {
 	if vmSSHUser != "" {
-		sshUser = vmSSHUser
+		return vmSSHUser
 	}
-	if sshUser == "" {
-		sshUser = getSSHUser()
+	if vmInfo.SSHUser != "" {
+		return vmInfo.SSHUser
 	}
+	return getSSHUser()
+}
 
-	// Build SSH arguments
-	sshArgs := []string{
-		"-i", vmInfo.SSHKeyPath,
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-p", fmt.Sprintf("%d", vmInfo.SSHPort),
-		fmt.Sprintf("%s@%s", sshUser, vmInfo.SSHHost),
+// newVMSSHClient returns an SSH client for a running VM
+func newVMSSHClient(vmName string) (*sshclient.Client, error) {
+	vmInfo, err := vm.LoadVMInfo(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return nil, err
 	}
-
-	// Display connection message
-	if verbose {
-		// Verbose mode: show detailed SSH connection information
-		fmt.Println("SSH connection information:")
-		fmt.Printf("  Host: %s\n", vmInfo.SSHHost)
-		fmt.Printf("  Port: %d\n", vmInfo.SSHPort)
-		fmt.Printf("  User: %s\n", sshUser)
-		fmt.Printf("  Key: %s\n", vmInfo.SSHKeyPath)
-		fmt.Println()
-		fmt.Printf("To connect:\n")
-		fmt.Printf("  ssh -i %s -p %d %s@%s\n", vmInfo.SSHKeyPath, vmInfo.SSHPort, sshUser, vmInfo.SSHHost)
-		fmt.Println()
-	} else {
-		// Normal mode: show brief connection message like podman machine ssh
-		fmt.Printf("Connecting to vm %s. To close connection, use `exit`\n", vmName)
+	if err := ensureVMSSHReachable(vmName, vmInfo); err != nil {
+		return nil, err
 	}
-
-	sshCmd := exec.Command("ssh", sshArgs...)
-	sshCmd.Stdin = os.Stdin
-	sshCmd.Stdout = os.Stdout
-	sshCmd.Stderr = os.Stderr
-
-	return sshCmd.Run()
+	return vm.NewSSHClient(vmName, vm.SSHConfig{
+		Host:    vmInfo.SSHHost,
+		Port:    vmInfo.SSHPort,
+		User:    vmSSHUserFor(vmInfo),
+		KeyPath: vmInfo.SSHKeyPath,
+	}), nil
 }
 
 func runVMRemove(cmd *cobra.Command, args []string) error {
@@ -1222,7 +1857,9 @@ func runVMRemove(cmd *cobra.Command, args []string) error {
 	if dryRun {
 		fmt.Println("📋 Equivalent command (remove VM):")
 		fmt.Printf("   rm ~/.local/share/bootc-man/vms/%s.json\n", vmName)
-		fmt.Printf("   rm ~/.local/share/bootc-man/vms/%s.raw\n", vmName)
+		fmt.Printf("   rm ~/.local/share/bootc-man/vms/%s.{raw,qcow2,vmdk}\n", vmName)
+		fmt.Printf("   rm -r ~/.local/share/bootc-man/ssh/%s\n", vmName)
+		fmt.Printf("   ssh-keygen -R %s -f ~/.local/share/bootc-man/ssh/known_hosts\n", vm.HostKeyAlias(vmName))
 		fmt.Println()
 		fmt.Println("(dry-run mode - command not executed)")
 		return nil
@@ -1248,9 +1885,40 @@ func runVMRemove(cmd *cobra.Command, args []string) error {
 	filesToDelete = append(filesToDelete, vmInfoFile)
 
 	// VM disk image in global VMs directory
-	vmDiskPath := filepath.Join(vmsDir, fmt.Sprintf("%s.raw", vmName))
-	if _, err := os.Stat(vmDiskPath); err == nil {
-		filesToDelete = append(filesToDelete, vmDiskPath)
+	// ISO installers live outside the VMs directory and are left untouched
+	for _, format := range vm.DiskImageFormats {
+		vmDiskPath := filepath.Join(vmsDir, fmt.Sprintf("%s.%s", vmName, format))
+		if _, err := os.Stat(vmDiskPath); err == nil {
+			filesToDelete = append(filesToDelete, vmDiskPath)
+		}
+	}
+
+	// First-boot provisioning data (Ignition config or cloud-init seed)
+	for _, f := range vmInfo.Provisioning.Files() {
+		if _, err := os.Stat(f); err == nil {
+			filesToDelete = append(filesToDelete, f)
+		}
+	}
+
+	// Per-VM SSH keypair generated by bootc-man
+	if keyDir, err := vm.VMSSHKeyDir(vmName); err == nil {
+		if _, err := os.Stat(keyDir); err == nil {
+			filesToDelete = append(filesToDelete, keyDir)
+		}
+	}
+
+	// swtpm state (TPM-enabled VMs)
+	if tpmDir, err := vm.VMTPMStateDir(vmName); err == nil {
+		if _, err := os.Stat(tpmDir); err == nil {
+			filesToDelete = append(filesToDelete, tpmDir)
+		}
+	}
+
+	// Install target disk (ISO-booted VMs)
+	if vmInfo.InstallDisk != "" {
+		if _, err := os.Stat(vmInfo.InstallDisk); err == nil {
+			filesToDelete = append(filesToDelete, vmInfo.InstallDisk)
+		}
 	}
 
 	// EFI store
@@ -1296,24 +1964,33 @@ func runVMRemove(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Stop processes if still running
-	if vmInfo.VfkitPID > 0 {
-		process, err := os.FindProcess(vmInfo.VfkitPID)
-		if err == nil {
-			_ = process.Kill()
-			_, _ = process.Wait()
-		}
+	// vm stop above holds the lock while it runs, so take it afterwards
+	vmLock, err := vm.LockVM(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
 	}
+	defer vmLock.Release()
 
-	if vmInfo.GvproxyPID > 0 {
-		process, err := os.FindProcess(vmInfo.GvproxyPID)
-		if err == nil {
-			_ = process.Kill()
-			_, _ = process.Wait()
+	// Stop whatever is still running through the VM's driver (QEMU, vfkit,
+	// libvirt or a plugin), and gvproxy, before its files are deleted
+	ctx := cmd.Context()
+	if vm.IsVMRunning(vmInfo) || vmInfo.GvproxyPID > 0 {
+		timeout := config.DefaultVMStopTimeout * time.Second
+		if vmRemoveForce {
+			timeout = 5 * time.Second
+		}
+		if err := vm.ShutdownVM(ctx, vmInfo, timeout); err != nil && !errors.Is(err, vm.ErrShutdownTimeout) {
+			fmt.Printf("❌ Failed to stop VM '%s': %v\n", vmName, err)
+			return fmt.Errorf("failed to stop VM: %w", err)
 		}
 	}
+	if err := vm.CleanupVMDriver(ctx, vmInfo); err != nil {
+		fmt.Printf("❌ Failed to clean up VM '%s': %v\n", vmName, err)
+		return fmt.Errorf("failed to clean up VM: %w", err)
+	}
 
-	// Delete all files in the list (includes VM info file, disk image, EFI store, log file)
+	// Delete all files in the list (includes VM info file, disk image, SSH key, EFI store, log file)
 	for _, file := range filesToDelete {
 		if err := os.RemoveAll(file); err != nil {
 			if verbose {
@@ -1322,6 +1999,20 @@ func runVMRemove(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Drop the recorded host key so a new VM with this name is trusted afresh
+	if err := vm.ForgetHostKey(vmName); err != nil && verbose {
+		fmt.Printf("⚠️  Warning: failed to remove known host key: %v\n", err)
+	}
+
+	// Release the VM's lab address
+	if vmInfo.Lab != "" {
+		if lab, err := vm.LoadLab(vmInfo.Lab); err == nil && lab.RemoveMember(vmName) {
+			if err := vm.SaveLab(lab); err != nil && verbose {
+				fmt.Printf("⚠️  Warning: failed to update lab %s: %v\n", lab.Name, err)
+			}
+		}
+	}
+
 	fmt.Printf("✅ VM '%s' removed\n", vmName)
 	return nil
 }
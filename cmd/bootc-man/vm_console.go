@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var vmConsoleCmd = &cobra.Command{
+	Use:   "console [name]",
+	Short: "Attach to the serial console of a VM",
+	Long: `Attach the terminal to the serial console of a running VM.
+
+This allows logging in and debugging a VM whose network or sshd is broken.
+The console output is also written to the VM's serial log file.
+
+Press Ctrl-] to detach from the console.
+
+If name is omitted and bootc-ci.yaml exists in current directory,
+uses the pipeline name as default VM name.`,
+	Args:              cobra.MaximumNArgs(1),
+	RunE:              runVMConsole,
+	ValidArgsFunction: completeRunningVMNames,
+}
+
+func init() {
+	vmCmd.AddCommand(vmConsoleCmd)
+	vmConsoleCmd.Flags().StringVarP(&vmPipelineFile, "pipeline", "p", "", "Pipeline file path (default: bootc-ci.yaml in current directory)")
+}
+
+func runVMConsole(cmd *cobra.Command, args []string) error {
+	var vmName string
+	if len(args) > 0 {
+		vmName = args[0]
+	} else {
+		// Try to get default VM name from pipeline file
+		var err error
+		vmName, err = getDefaultVMName(vmPipelineFile)
+		if err != nil {
+			return fmt.Errorf("VM name required: no bootc-ci.yaml found in current directory\n  Specify VM name: bootc-man vm console <name>\n  List available VMs: bootc-man vm list")
+		}
+	}
+
+	// Dry-run mode
+	if dryRun {
+		fmt.Println("📋 Equivalent command (attach serial console):")
+		fmt.Printf("   socat -,raw,echo=0,escape=0x1d UNIX-CONNECT:<console-socket>  # for VM: %s\n", vmName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	vmInfo, err := vm.LoadVMInfo(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if !isVMRunning(vmInfo) {
+		err := fmt.Errorf("VM '%s' is not running", vmName)
+		fmt.Printf("❌ %v\n", err)
+		fmt.Printf("   Start it with: bootc-man vm start %s\n", vmName)
+		return err
+	}
+
+	// libvirt owns the serial console of its domains; virsh detaches on Ctrl-] as well
+	if vmInfo.LibvirtDomain != "" {
+		console := exec.Command(config.BinaryVirsh, "-c", vmInfo.LibvirtURI, "console", vmInfo.LibvirtDomain)
+		console.Stdin = os.Stdin
+		console.Stdout = os.Stdout
+		console.Stderr = os.Stderr
+		if err := console.Run(); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+		fmt.Printf("👋 Detached from VM '%s'\n", vmName)
+		return nil
+	}
+
+	if vmInfo.ConsoleSocket == "" {
+		err := fmt.Errorf("VM '%s' has no interactive serial console", vmName)
+		fmt.Printf("❌ %v\n", err)
+		if vmInfo.LogFile != "" {
+			fmt.Printf("   Serial log: %s\n", vmInfo.LogFile)
+		}
+		return err
+	}
+
+	fmt.Printf("🔌 Connected to serial console of VM '%s' (press Ctrl-] to detach)\n", vmName)
+	fmt.Println("   Press Enter to get a login prompt")
+
+	err = vm.AttachConsole(context.Background(), vmInfo.ConsoleSocket, os.Stdin, os.Stdout)
+	fmt.Println()
+	switch {
+	case errors.Is(err, vm.ErrConsoleDetached):
+		fmt.Printf("👋 Detached from VM '%s'\n", vmName)
+		return nil
+	case err != nil:
+		fmt.Printf("❌ %v\n", err)
+		return err
+	default:
+		fmt.Printf("ℹ️  Console of VM '%s' closed\n", vmName)
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/sshclient"
+)
+
+var vmCopyCmd = &cobra.Command{
+	Use:   "cp <src> <dst>",
+	Short: "Copy files between the host and a VM",
+	Long: `Copy files or directories between the host and a running VM.
+
+One of src and dst must be a VM path written as <vm>:<path>. Directories are
+copied recursively. If dst is an existing directory, src is copied into it;
+otherwise src is copied as dst. Relative VM paths are relative to the SSH
+user's home directory.
+
+The copy runs over the VM's SSH connection and needs tar in the VM.
+
+Example:
+  bootc-man vm cp ./tests my-vm:/var/tmp/
+  bootc-man vm cp my-vm:/var/log/test-report.xml .
+  bootc-man vm cp my-vm:/etc/containers ./vm-containers-config`,
+	Args:         cobra.ExactArgs(2),
+	RunE:         runVMCopy,
+	SilenceUsage: true,
+}
+
+func init() {
+	vmCmd.AddCommand(vmCopyCmd)
+	vmCopyCmd.Flags().StringVarP(&vmSSHUser, "user", "u", "", "SSH user name (default: from config or 'user')")
+}
+
+// parseCopyArg splits a vm cp argument into VM name and path
+// The VM name is empty for local paths. A prefix containing a path separator
+// or a Windows volume name (C:) is not a VM name.
+func parseCopyArg(arg string) (vmName, p string) {
+	if filepath.VolumeName(arg) != "" {
+		return "", arg
+	}
+	i := strings.Index(arg, ":")
+	if i <= 0 || strings.ContainsAny(arg[:i], `/\`) {
+		return "", arg
+	}
+	return arg[:i], arg[i+1:]
+}
+
+func runVMCopy(cmd *cobra.Command, args []string) error {
+	srcVM, srcPath := parseCopyArg(args[0])
+	dstVM, dstPath := parseCopyArg(args[1])
+	switch {
+	case srcVM != "" && dstVM != "":
+		return fmt.Errorf("copying between two VMs is not supported; copy to the host first")
+	case srcVM == "" && dstVM == "":
+		return fmt.Errorf("one of src and dst must be a VM path (<vm>:<path>)")
+	}
+
+	upload := dstVM != ""
+	vmName, remotePath, localPath := srcVM, srcPath, dstPath
+	if upload {
+		vmName, remotePath, localPath = dstVM, dstPath, srcPath
+	}
+	if remotePath == "" {
+		// "<vm>:" refers to the home directory
+		remotePath = "."
+	}
+
+	// Dry-run mode
+	if dryRun {
+		if upload {
+			fmt.Println("📋 Equivalent command (copy to VM):")
+			fmt.Printf("   tar -C %s -cf - %s | ssh -i <key> -p <port> user@localhost tar -xf - -C %s  # for VM: %s\n",
+				filepath.Dir(localPath), filepath.Base(localPath), sshclient.ShellQuote(remotePath), vmName)
+		} else {
+			fmt.Println("📋 Equivalent command (copy from VM):")
+			fmt.Printf("   ssh -i <key> -p <port> user@localhost tar -C %s -cf - %s | tar -xf - -C %s  # for VM: %s\n",
+				sshclient.ShellQuote(path.Dir(remotePath)), sshclient.ShellQuote(path.Base(remotePath)), localPath, vmName)
+		}
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	client, err := newVMSSHClient(vmName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := cmd.Context()
+	if upload {
+		fmt.Printf("📤 Copying %s to %s:%s...\n", localPath, vmName, remotePath)
+		err = client.CopyTo(ctx, localPath, remotePath)
+	} else {
+		fmt.Printf("📥 Copying %s:%s to %s...\n", vmName, remotePath, localPath)
+		err = client.CopyFrom(ctx, remotePath, localPath)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Println("✅ Copy completed")
+	return nil
+}
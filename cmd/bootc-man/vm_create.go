@@ -0,0 +1,205 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/cleanup"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var vmCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Define a VM without starting it",
+	Long: `Create a VM from build and convert artifacts without booting it.
+
+The VM disk is prepared and the full VM spec (CPUs, memory, architecture,
+firmware, port forwards, shared directories and lab network) is stored in
+~/.local/share/bootc-man/vms/<name>.json. 'bootc-man vm start <name>' and
+'bootc-man vm stop <name>' then operate on that spec; flags given to vm start
+override it for that run.
+
+Use 'bootc-man vm generate systemd <name>' to keep the VM running across
+host reboots.`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: runVMCreate,
+}
+
+func init() {
+	vmCmd.AddCommand(vmCreateCmd)
+}
+
+func runVMCreate(cmd *cobra.Command, args []string) (err error) {
+	ctx := context.Background()
+
+	if err := vm.ValidateProvisioning(vmStartProvision); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	extras, err := parseVMStartExtras(cmd)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if dryRun {
+		fmt.Println("📋 Equivalent command (create VM):")
+		fmt.Println("   qemu-img create -f qcow2 -b <disk-image> -F raw ~/.local/share/bootc-man/vms/<name>.qcow2  # QEMU")
+		fmt.Println("   cp -c <disk-image> ~/.local/share/bootc-man/vms/<name>.raw  # vfkit")
+		fmt.Printf("   # VM spec: cpus=%d memory=%dMB -> ~/.local/share/bootc-man/vms/<name>.json\n", cmp.Or(extras.cpus, vmStartCPUs), cmp.Or(extras.memory, vmStartMemory))
+		if extras.diskSize > 0 {
+			fmt.Printf("   qemu-img resize <vm-disk> %dG  # profile %s\n", extras.diskSize, extras.profile)
+		}
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	vmName, err := vmStartVMName(args)
+	if err != nil {
+		return err
+	}
+	vmLock, err := vm.LockVM(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	defer vmLock.Release()
+	if _, err := vm.LoadVMInfo(vmName); err == nil {
+		err := fmt.Errorf("VM '%s' already exists", vmName)
+		fmt.Printf("❌ %v\n", err)
+		fmt.Printf("   Start it with 'bootc-man vm start %s' or remove it with 'bootc-man vm rm %s'\n", vmName, vmName)
+		return err
+	}
+
+	// Check the published ports before anything is created for the VM
+	info := &vm.VMInfo{Name: vmName}
+	for _, p := range extras.published {
+		if err := info.AddPortForward(p); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+	}
+
+	// The disk image comes from the pipeline; without a pipeline file, the
+	// artifacts directory is used like vm start does
+	arch, provisionMethod := extras.arch, vmProvisioningMethod("")
+	diskImagePath := ""
+	if _, err := findPipelineFile(vmStartPipelineFile); err != nil {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		diskImagePath = findDiskImageInArtifacts(wd, extras.vmType())
+	}
+	if diskImagePath == "" {
+		source, err := loadVMPipelineSource(ctx)
+		if err != nil {
+			return err
+		}
+		if arch, provisionMethod, err = applyVMPipelineDefaults(source.pipeline, &extras); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+		info.PipelineName = source.pipeline.Metadata.Name
+		info.PipelineFile = source.pipelineFile
+		info.ImageTag = source.imageTag
+		diskImagePath = source.diskImage
+	}
+
+	// Check the firmware settings now rather than at the first start
+	if err := vm.ValidateFirmware(extras.firmware, cmp.Or(arch, vm.HostArch()), extras.secureBoot); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	sshKeyPath, err := resolveVMSSHKey(vmName, provisionMethod, "")
+	if err != nil {
+		return err
+	}
+
+	vmDiskPath, diskMethod, err := prepareVMDisk(ctx, diskImagePath, vmName, extras.vmType())
+	if err != nil {
+		return fmt.Errorf("failed to prepare VM disk image: %w", err)
+	}
+
+	// Until the VM info is saved, nothing refers to the files made for the
+	// VM, so remove them and its lab address if creating it fails
+	var resources []cleanup.Resource
+	if vmDiskPath != diskImagePath {
+		resources = append(resources, cleanup.Resource{Path: vmDiskPath})
+	}
+	if provisionMethod != "" {
+		resources = append(resources, cleanup.Resource{Path: sshKeyPath}, cleanup.Resource{Path: sshKeyPath + ".pub"})
+	}
+	undo := cleanup.Register("remove partly created VM "+vmName, func(context.Context) error {
+		for _, r := range resources {
+			_ = os.Remove(r.Path)
+		}
+		if extras.network != "" {
+			if lab, err := vm.LoadLab(extras.network); err == nil && lab.RemoveMember(vmName) {
+				return vm.SaveLab(lab)
+			}
+		}
+		return nil
+	}, resources...)
+	defer func() {
+		if err != nil {
+			_ = undo.Run(context.Background())
+		} else {
+			undo.Release()
+		}
+	}()
+
+	if err := growVMDisk(ctx, vmDiskPath, diskImagePath, extras.diskSize); err != nil {
+		return err
+	}
+
+	provisioning, err := prepareVMProvisioning(vmName, sshKeyPath, getSSHUser(), provisionMethod)
+	if err != nil {
+		return err
+	}
+	for _, f := range provisioning.Files() {
+		r := cleanup.Resource{Path: f}
+		resources = append(resources, r)
+		undo.AddResource(r)
+	}
+
+	// Reserve the lab address so that it is stable from the first start
+	if _, err := joinVMLab(vmName, extras.network); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	// The VM spec is stored like that of a VM's first start
+	spec := newVMSpec(vmName, arch, extras)
+	spec.PipelineName, spec.PipelineFile, spec.ImageTag = info.PipelineName, info.PipelineFile, info.ImageTag
+	spec.Ports = info.Ports
+	info = spec
+	info.DiskImage = vmDiskPath
+	info.InstallDisk = vmInstallDiskPath(vmDiskPath, vmName)
+	info.BaseImage = baseImagePath(diskImagePath, vmDiskPath)
+	info.DiskMethod = diskMethod
+	info.Created = time.Now()
+	info.SSHKeyPath = sshKeyPath
+	info.State = string(vm.VMStateCreated)
+	info.Provisioning = provisioning
+
+	if err := vm.SaveVMInfo(info); err != nil {
+		return fmt.Errorf("failed to save VM info: %w", err)
+	}
+
+	fmt.Printf("✅ VM '%s' created\n", vmName)
+	fmt.Printf("   CPUs: %d, Memory: %d MB\n", info.CPUs, info.Memory)
+	fmt.Printf("   VM disk: %s\n", vmDiskPath)
+	fmt.Println()
+	fmt.Printf("To start:\n")
+	fmt.Printf("  bootc-man vm start %s\n", vmName)
+	fmt.Printf("To keep it running across host reboots:\n")
+	fmt.Printf("  bootc-man vm generate systemd %s --files\n", vmName)
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/sshclient"
+)
+
+var vmExecCmd = &cobra.Command{
+	Use:   "exec <name> [--] <command> [args...]",
+	Short: "Run a command in a VM",
+	Long: `Run a command in a running VM over SSH and exit with its exit code.
+
+The arguments are passed to the VM as separate words; use 'sh -c' for pipes
+and other shell syntax. Output is streamed as it is produced, or collected
+into a JSON object with --json.
+
+Exit codes:
+  <n>   exit code of the command
+  124   the command did not finish within --timeout
+  130   bootc-man was interrupted
+  255   the command could not be run (e.g. the SSH connection failed)
+
+Example:
+  bootc-man vm exec my-vm -- systemctl is-active sshd
+  bootc-man vm exec --sudo my-vm -- bootc status --format json
+  bootc-man vm exec -e APP_ENV=test --timeout 5m my-vm -- /usr/local/bin/run-tests
+  bootc-man vm exec --json my-vm -- sh -c 'rpm -qa | wc -l'`,
+	Args:              cobra.MinimumNArgs(2),
+	RunE:              runVMExec,
+	ValidArgsFunction: completeRunningVMNames,
+	SilenceUsage:      true,
+}
+
+var (
+	vmExecTimeout     time.Duration
+	vmExecSudo        bool
+	vmExecEnv         []string
+	vmExecInteractive bool
+)
+
+func init() {
+	vmCmd.AddCommand(vmExecCmd)
+	// Flags after the VM name belong to the command
+	vmExecCmd.Flags().SetInterspersed(false)
+	vmExecCmd.Flags().DurationVar(&vmExecTimeout, "timeout", 0, "Kill the command if it runs longer than this (e.g. 30s, 5m; 0 = no timeout)")
+	vmExecCmd.Flags().BoolVar(&vmExecSudo, "sudo", false, "Run the command as root with sudo")
+	vmExecCmd.Flags().StringArrayVarP(&vmExecEnv, "env", "e", nil, "Set an environment variable (KEY=VALUE, or KEY to pass the host's value)")
+	vmExecCmd.Flags().BoolVarP(&vmExecInteractive, "interactive", "i", false, "Forward stdin to the command")
+	vmExecCmd.Flags().StringVarP(&vmSSHUser, "user", "u", "", "SSH user name (default: from config or 'user')")
+}
+
+// execResult is the --json output of vm exec
+type execResult struct {
+	VM              string  `json:"vm"`
+	Command         string  `json:"command"`
+	ExitCode        int     `json:"exitCode"`
+	Stdout          string  `json:"stdout"`
+	Stderr          string  `json:"stderr"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	TimedOut        bool    `json:"timedOut,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Exit codes for failures other than the command's own exit status,
+// following timeout(1) and ssh(1)
+const (
+	execExitTimeout     = 124
+	execExitInterrupted = 130
+	execExitFailure     = 255
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// execEnv resolves --env entries to KEY=VALUE pairs
+// An entry without '=' takes its value from the host environment and is
+// dropped if the variable is not set.
+func execEnv(entries []string, lookup func(string) (string, bool)) ([]string, error) {
+	var env []string
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, "=")
+		if !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment variable %q: expected KEY=VALUE", entry)
+		}
+		if !ok {
+			if value, ok = lookup(name); !ok {
+				continue
+			}
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// buildExecCommand builds the remote command line for args
+// Each argument is quoted so that the remote shell passes it through unchanged.
+func buildExecCommand(args, env []string, sudo bool) string {
+	var words []string
+	if sudo {
+		// -n fails instead of prompting for a password nobody can type
+		words = append(words, "sudo", "-n")
+	}
+	if len(env) > 0 {
+		// Set after sudo, which would otherwise reset the environment
+		words = append(words, "env")
+		for _, e := range env {
+			words = append(words, sshclient.ShellQuote(e))
+		}
+	}
+	for _, arg := range args {
+		words = append(words, sshclient.ShellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+func runVMExec(cmd *cobra.Command, args []string) error {
+	vmName := args[0]
+	args = args[1:]
+	if args[0] == "--" {
+		args = args[1:]
+		if len(args) == 0 {
+			return fmt.Errorf("command required: bootc-man vm exec %s -- <command> [args...]", vmName)
+		}
+	}
+
+	env, err := execEnv(vmExecEnv, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	remoteCmd := buildExecCommand(args, env, vmExecSudo)
+
+	// Dry-run mode
+	if dryRun {
+		fmt.Println("📋 Equivalent command (run command in VM):")
+		fmt.Printf("   ssh -i <key> -p <port> user@localhost %s  # for VM: %s\n", remoteCmd, vmName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	client, err := newVMSSHClient(vmName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := cmd.Context()
+	if vmExecTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, vmExecTimeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	opts := sshclient.RunOptions{Stdout: os.Stdout, Stderr: os.Stderr}
+	if jsonOut {
+		opts.Stdout, opts.Stderr = &stdout, &stderr
+	}
+	if vmExecInteractive {
+		opts.Stdin = os.Stdin
+	}
+
+	if verbose && !jsonOut {
+		fmt.Fprintf(os.Stderr, "Running on %s (%s): %s\n", vmName, client.Config(), remoteCmd)
+	}
+
+	start := time.Now()
+	runErr := client.RunWithOptions(ctx, remoteCmd, opts)
+	result := execResult{
+		VM:              vmName,
+		Command:         remoteCmd,
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		DurationSeconds: time.Since(start).Seconds(),
+	}
+
+	var exitErr *sshclient.ExitError
+	switch {
+	case runErr == nil:
+	case errors.As(runErr, &exitErr):
+		result.ExitCode = exitErr.Code
+	case errors.Is(runErr, context.DeadlineExceeded):
+		result.ExitCode = execExitTimeout
+		result.TimedOut = true
+		result.Error = fmt.Sprintf("command timed out after %s", vmExecTimeout)
+	case errors.Is(runErr, context.Canceled):
+		result.ExitCode = execExitInterrupted
+		result.Error = "interrupted"
+	default:
+		result.ExitCode = execExitFailure
+		result.Error = runErr.Error()
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	} else if result.Error != "" {
+		// stdout belongs to the command; report on stderr
+		fmt.Fprintf(os.Stderr, "❌ %s\n", result.Error)
+	}
+
+	if result.ExitCode != 0 {
+		return &exitStatusError{code: result.ExitCode}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/bootc"
+	"github.com/tnk4on/bootc-man/internal/cleanup"
+	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var (
+	vmExportFormat   string
+	vmExportOutput   string
+	vmExportCompress bool
+	vmExportStop     bool
+)
+
+var vmExportCmd = &cobra.Command{
+	Use:   "export <vm>",
+	Short: "Export a VM's disk with its current state",
+	Long: `Export the disk of a VM, including everything changed since it booted.
+
+The disk is converted with qemu-img into a standalone image (the VM's base
+image is merged in) and compressed: raw disks are gzipped, qcow2 disks are
+compressed internally and vmdk disks are written as streamOptimized. The ova
+format packs a streamOptimized disk and an OVF descriptor for VMware.
+
+A running QEMU VM is paused via QMP while its disk is read (after the guest
+flushed its filesystems) and resumed afterwards; use --stop to shut it down
+instead. Running VMs of other drivers are shut down.
+
+A manifest <output>.manifest.json records the VM's source image tag, the
+bootc image and digest the VM had booted (read with bootc status while it
+runs) and the checksum of the artifact.
+
+Example:
+  bootc-man vm export my-vm --format qcow2 -o my-vm.qcow2
+  bootc-man vm export my-vm --format ova`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runVMExport,
+	ValidArgsFunction: completeVMNamesFirstArg,
+	SilenceUsage:      true,
+}
+
+func init() {
+	vmCmd.AddCommand(vmExportCmd)
+	vmExportCmd.Flags().StringVar(&vmExportFormat, "format", vm.ImageFormatQcow2, "Export format: raw, qcow2, vmdk or ova")
+	vmExportCmd.Flags().StringVarP(&vmExportOutput, "output", "o", "", "Output file (default: <vm>.<format> in the current directory, <vm>.raw.gz for compressed raw)")
+	vmExportCmd.Flags().BoolVar(&vmExportCompress, "compress", true, "Compress the exported disk")
+	vmExportCmd.Flags().BoolVar(&vmExportStop, "stop", false, "Shut a running VM down instead of pausing it")
+	_ = vmExportCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return vm.ExportFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+func runVMExport(cmd *cobra.Command, args []string) error {
+	vmName := args[0]
+	if err := vm.ValidateExportFormat(vmExportFormat); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	output := vmExportOutput
+	if output == "" {
+		output = vm.ExportFileName(vmName, vmExportFormat, vmExportCompress)
+	}
+
+	// Dry-run mode
+	if dryRun {
+		fmt.Println("📋 Equivalent command (export VM):")
+		fmt.Printf("   echo '{\"execute\":\"stop\"}' | socat - UNIX-CONNECT:<qmp-socket>  # pause a running QEMU VM: %s\n", vmName)
+		convert := "qemu-img convert -U"
+		switch {
+		case vmExportFormat == vm.ExportFormatOVA, vmExportFormat == vm.ImageFormatVMDK && vmExportCompress:
+			convert += " -O vmdk -o subformat=streamOptimized"
+		case vmExportFormat == vm.ImageFormatQcow2 && vmExportCompress:
+			convert += " -O qcow2 -c"
+		default:
+			convert += " -O " + vmExportFormat
+		}
+		switch {
+		case vmExportFormat == vm.ExportFormatOVA:
+			base := strings.TrimSuffix(filepath.Base(output), ".ova")
+			fmt.Printf("   %s ~/.local/share/bootc-man/vms/%s.qcow2 %s-disk1.vmdk\n", convert, vmName, base)
+			fmt.Printf("   tar -cf %s %s.ovf %s.mf %s-disk1.vmdk  # OVF descriptor and SHA256 manifest\n", output, base, base, base)
+		case vmExportFormat == vm.ImageFormatRaw && vmExportCompress:
+			fmt.Printf("   %s ~/.local/share/bootc-man/vms/%s.qcow2 disk.raw && gzip -c disk.raw > %s\n", convert, vmName, output)
+		default:
+			fmt.Printf("   %s ~/.local/share/bootc-man/vms/%s.qcow2 %s\n", convert, vmName, output)
+		}
+		fmt.Printf("   # manifest: %s\n", vm.ExportManifestPath(output))
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	ctx := cmd.Context()
+	vmLock, err := vm.LockVM(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+	defer vmLock.Release()
+
+	vmInfo, err := vm.LoadVMInfo(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	opts := vm.ExportOptions{
+		Format:   vmExportFormat,
+		Output:   output,
+		Compress: vmExportCompress,
+		Verbose:  verbose,
+	}
+	if vm.IsVMRunning(vmInfo) {
+		if vm.GetVMState(ctx, vmInfo) == vm.VMStatePaused {
+			fmt.Printf("⚠️  Warning: VM '%s' is paused; the booted bootc digest is not recorded\n", vmName)
+		} else {
+			opts.BootedImage, opts.BootedDigest = vmBootedImage(ctx, vmInfo)
+		}
+		live, resume, err := quiesceVMForExport(ctx, vmInfo)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return err
+		}
+		if resume != nil {
+			defer func() { _ = resume.Run(context.Background()) }()
+		}
+		opts.Live = live
+	} else {
+		fmt.Printf("⚠️  Warning: VM '%s' is not running; the booted bootc digest is not recorded\n", vmName)
+	}
+
+	fmt.Printf("📦 Exporting VM '%s' as %s...\n", vmName, vmExportFormat)
+	manifest, err := vm.ExportVM(ctx, vmInfo, opts)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✅ VM '%s' exported: %s (%.1f GB)\n", vmName, output, float64(manifest.Size)/(1024*1024*1024))
+	fmt.Printf("   Manifest: %s\n", vm.ExportManifestPath(output))
+	if manifest.BootedDigest != "" {
+		fmt.Printf("   Booted:   %s@%s\n", manifest.BootedImage, manifest.BootedDigest)
+	}
+	return nil
+}
+
+// vmBootedImage returns the image and digest the running VM has booted,
+// as reported by bootc status; both are empty if the VM cannot be reached
+func vmBootedImage(ctx context.Context, vmInfo *vm.VMInfo) (string, string) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	driver := bootc.NewVMDriver(bootc.VMDriverOptions{
+		VMName:     vmInfo.Name,
+		SSHHost:    vmInfo.SSHHost,
+		SSHPort:    vmInfo.SSHPort,
+		SSHUser:    vmSSHUserFor(vmInfo),
+		SSHKeyPath: vmInfo.SSHKeyPath,
+		Verbose:    verbose,
+	})
+	status, err := driver.Status(ctx)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: failed to read bootc status, the booted digest is not recorded: %v\n", err)
+		return "", ""
+	}
+	if status.Status.Booted == nil || status.Status.Booted.Image == nil {
+		return "", ""
+	}
+	booted := status.Status.Booted.Image
+	return booted.Image.Image, booted.ImageDigest
+}
+
+// quiesceVMForExport makes the disk of a running VM consistent for export
+// QEMU VMs with a QMP socket are paused after the guest flushed its
+// filesystems and their disk is read while QEMU keeps it open (live); the
+// returned handle resumes them, unless the VM was already paused. Other VMs,
+// and all VMs with --stop, are shut down.
+func quiesceVMForExport(ctx context.Context, vmInfo *vm.VMInfo) (live bool, resume *cleanup.Handle, err error) {
+	vmName := vmInfo.Name
+	if vmInfo.QMPSocket != "" && !vmExportStop {
+		if vm.GetVMState(ctx, vmInfo) == vm.VMStatePaused {
+			return true, nil, nil
+		}
+		client := vm.NewSSHClient(vmName, vm.SSHConfig{
+			Host:    vmInfo.SSHHost,
+			Port:    vmInfo.SSHPort,
+			User:    vmSSHUserFor(vmInfo),
+			KeyPath: vmInfo.SSHKeyPath,
+		})
+		syncCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err = client.Run(syncCtx, "sync", nil, nil)
+		cancel()
+		if err != nil {
+			fmt.Printf("⚠️  Warning: failed to flush the guest filesystems: %v\n", err)
+		}
+
+		fmt.Printf("⏸️  Pausing VM '%s' while its disk is exported...\n", vmName)
+		if err := vm.PauseVM(ctx, vmInfo); err != nil {
+			return false, nil, fmt.Errorf("failed to pause VM '%s': %w", vmName, err)
+		}
+		// Resume the VM even if the export is interrupted
+		return true, cleanup.Register("resume VM "+vmName, func(ctx context.Context) error {
+			if err := vm.ResumeVM(ctx, vmInfo); err != nil {
+				fmt.Printf("⚠️  Warning: failed to resume VM '%s': %v\n", vmName, err)
+				return err
+			}
+			fmt.Printf("▶️  VM '%s' resumed\n", vmName)
+			return nil
+		}), nil
+	}
+
+	// Power off the guest so it can flush its filesystems (including /var)
+	fmt.Printf("⏹️  Shutting down VM '%s' to export its disk...\n", vmName)
+	if err := vm.ShutdownVM(ctx, vmInfo, config.DefaultVMStopTimeout*time.Second); err != nil {
+		if !errors.Is(err, vm.ErrShutdownTimeout) {
+			return false, nil, err
+		}
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+	vmInfo.State = "Stopped"
+	if err := vm.SaveVMInfo(vmInfo); err != nil {
+		fmt.Printf("⚠️  Warning: Failed to update VM state: %v\n", err)
+	}
+	return false, nil, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var vmGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate files for running VMs",
+}
+
+var vmGenerateSystemdCmd = &cobra.Command{
+	Use:   "systemd <name>",
+	Short: "Generate a systemd user unit for a VM",
+	Long: `Generate a systemd user unit that starts a VM with the user's service
+manager and shuts it down cleanly when the manager stops.
+
+The unit is printed to stdout, or written to bootc-man-vm-<name>.service in
+the current directory with --files. Install it as a user unit:
+
+  cp bootc-man-vm-<name>.service ~/.config/systemd/user/
+  systemctl --user daemon-reload
+  systemctl --user enable --now bootc-man-vm-<name>.service
+
+To start the VM at boot without logging in, enable lingering:
+
+  loginctl enable-linger $USER`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runVMGenerateSystemd,
+	ValidArgsFunction: completeVMNamesFirstArg,
+}
+
+var vmGenerateFiles bool
+
+func init() {
+	vmCmd.AddCommand(vmGenerateCmd)
+	vmGenerateCmd.AddCommand(vmGenerateSystemdCmd)
+
+	vmGenerateSystemdCmd.Flags().BoolVar(&vmGenerateFiles, "files", false, "Write the unit to a file in the current directory instead of stdout")
+	vmGenerateSystemdCmd.Flags().IntVar(&vmStopTimeout, "stop-timeout", config.DefaultVMStopTimeout, "Seconds the unit waits for the guest to power off")
+}
+
+func runVMGenerateSystemd(cmd *cobra.Command, args []string) error {
+	vmName := args[0]
+	unitName := vm.SystemdUnitName(vmName)
+
+	if dryRun {
+		fmt.Println("📋 Equivalent command (generate systemd unit):")
+		fmt.Printf("   cat > %s  # ExecStart=bootc-man vm start %s, ExecStop=bootc-man vm stop %s\n", unitName, vmName, vmName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	if _, err := vm.LoadVMInfo(vmName); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		fmt.Println("   Define the VM first: bootc-man vm create <name>")
+		return err
+	}
+
+	// The unit runs this binary by absolute path
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate bootc-man executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	// The user manager's PATH is minimal; keep the current one so QEMU,
+	// gvproxy and the other helpers are found
+	unit := vm.SystemdUnit(vmName, executable, os.Getenv("PATH"), vmStopTimeout)
+	if !vmGenerateFiles {
+		fmt.Print(unit)
+		return nil
+	}
+
+	if err := os.WriteFile(unitName, []byte(unit), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", unitName, err)
+	}
+	fmt.Printf("✅ Wrote %s\n", unitName)
+	fmt.Println()
+	fmt.Println("To install:")
+	fmt.Printf("  mkdir -p ~/.config/systemd/user && cp %s ~/.config/systemd/user/\n", unitName)
+	fmt.Println("  systemctl --user daemon-reload")
+	fmt.Printf("  systemctl --user enable --now %s\n", unitName)
+	fmt.Println("To start the VM at boot without logging in:")
+	fmt.Println("  loginctl enable-linger $USER")
+	return nil
+}
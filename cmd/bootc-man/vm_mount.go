@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var vmStartMounts []string
+
+func init() {
+	for _, c := range []*cobra.Command{vmStartCmd, vmCreateCmd} {
+		c.Flags().StringArrayVar(&vmStartMounts, "mount", nil, "Share a host directory with the VM via virtio-fs (<hostdir>:<tag>[:ro], can be repeated)")
+	}
+}
+
+// parseSharedDirs parses mount specs and checks that tags are unique
+func parseSharedDirs(specs []string) ([]vm.SharedDir, error) {
+	var mounts []vm.SharedDir
+	for _, spec := range specs {
+		m, err := vm.ParseSharedDir(spec)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, m)
+	}
+	if err := vm.ValidateSharedDirs(mounts); err != nil {
+		return nil, err
+	}
+	return mounts, nil
+}
+
+// printSharedDirHints shows how to mount the shared directories in the VM
+func printSharedDirHints(mounts []vm.SharedDir) {
+	if len(mounts) == 0 {
+		return
+	}
+	fmt.Printf("Shared directories (mount inside the VM):\n")
+	for _, m := range mounts {
+		mode := ""
+		if m.ReadOnly {
+			mode = " (read-only)"
+		}
+		fmt.Printf("  %s%s\n", m.Source, mode)
+		target := path.Join("/mnt", m.Tag)
+		fmt.Printf("    sudo mkdir -p %s && %s\n", target, m.MountCommand(target))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/ci"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var vmPortCmd = &cobra.Command{
+	Use:   "port",
+	Short: "Manage port forwarding of VMs",
+	Long: `Forward host ports to ports of a VM through gvproxy.
+
+Forwards are saved with the VM and restored when it starts again. Forwards
+added to a running VM take effect immediately. Host ports listen on
+127.0.0.1 unless a host IP is given.
+
+Port forward format: [hostIP:]hostPort:guestPort[/tcp|/udp]
+
+Example:
+  bootc-man vm port add my-vm 8080:80
+  bootc-man vm port add my-vm 9090:9090 5353:53/udp
+  bootc-man vm port ls my-vm
+  bootc-man vm port rm my-vm 8080`,
+}
+
+var vmPortAddCmd = &cobra.Command{
+	Use:               "add <vm> <hostPort:guestPort[/udp]>...",
+	Short:             "Forward host ports to a VM",
+	Args:              cobra.MinimumNArgs(2),
+	RunE:              runVMPortAdd,
+	ValidArgsFunction: completeVMNamesFirstArg,
+}
+
+var vmPortRemoveCmd = &cobra.Command{
+	Use:               "rm <vm> <hostPort[/udp]>...",
+	Short:             "Remove port forwards of a VM",
+	Args:              cobra.MinimumNArgs(2),
+	RunE:              runVMPortRemove,
+	ValidArgsFunction: completeVMPortArgs,
+}
+
+var vmPortListCmd = &cobra.Command{
+	Use:               "ls <vm>",
+	Aliases:           []string{"list"},
+	Short:             "List port forwards of a VM",
+	Args:              cobra.ExactArgs(1),
+	RunE:              runVMPortList,
+	ValidArgsFunction: completeVMNamesFirstArg,
+}
+
+var vmStartPublish []string
+
+func init() {
+	vmCmd.AddCommand(vmPortCmd)
+	vmPortCmd.AddCommand(vmPortAddCmd)
+	vmPortCmd.AddCommand(vmPortRemoveCmd)
+	vmPortCmd.AddCommand(vmPortListCmd)
+
+	for _, c := range []*cobra.Command{vmStartCmd, vmCreateCmd} {
+		c.Flags().StringArrayVar(&vmStartPublish, "publish", nil, "Forward a host port to the VM ([hostIP:]hostPort:guestPort[/udp], can be repeated)")
+	}
+}
+
+// parsePortForwards parses port forward specs
+func parsePortForwards(specs []string) ([]vm.PortForward, error) {
+	var forwards []vm.PortForward
+	for _, spec := range specs {
+		p, err := vm.ParsePortForward(spec)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, p)
+	}
+	return forwards, nil
+}
+
+// parseHostPort parses hostPort[/udp], as used by vm port rm
+// A full port forward spec is accepted as well; only its host side is used.
+func parseHostPort(spec string) (int, string, error) {
+	if p, err := vm.ParsePortForward(spec); err == nil && strings.Contains(spec, ":") {
+		return p.HostPort, p.Protocol, nil
+	}
+	port, protocol, _ := strings.Cut(spec, "/")
+	if protocol == "" {
+		protocol = vm.ProtocolTCP
+	}
+	protocol = strings.ToLower(protocol)
+	if protocol != vm.ProtocolTCP && protocol != vm.ProtocolUDP {
+		return 0, "", fmt.Errorf("invalid port %q: protocol must be tcp or udp", spec)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, "", fmt.Errorf("invalid port %q", spec)
+	}
+	return n, protocol, nil
+}
+
+// vmGvproxy returns a client for the gvproxy API of a running VM, or nil if
+// the VM is not running
+func vmGvproxy(vmInfo *vm.VMInfo) *ci.GvproxyClient {
+	if !isVMRunning(vmInfo) || vmInfo.GvproxyServiceSocket == "" {
+		return nil
+	}
+	return ci.NewGvproxyServiceClient(vmInfo.GvproxyServiceSocket, verbose)
+}
+
+// exposePortForward applies a forward through gvproxy
+func exposePortForward(ctx context.Context, gvproxy *ci.GvproxyClient, guestIP string, p vm.PortForward) error {
+	remote := fmt.Sprintf("%s:%d", guestIP, p.GuestPort)
+	return gvproxy.ExposeForward(ctx, p.Local(), remote, p.Protocol)
+}
+
+// unexposePortForwards removes forwards from gvproxy; failures are reported
+// as warnings
+func unexposePortForwards(ctx context.Context, gvproxy *ci.GvproxyClient, forwards []vm.PortForward) {
+	for _, p := range forwards {
+		if err := gvproxy.UnexposeForward(ctx, p.Local(), p.Protocol); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+	}
+}
+
+// checkPortsChangeable returns an error for running libvirt and external
+// driver VMs, whose port forwards are fixed when the VM starts
+func checkPortsChangeable(vmInfo *vm.VMInfo) error {
+	if !vmInfo.UsesGvproxy() && isVMRunning(vmInfo) {
+		return fmt.Errorf("port forwards of running VM '%s' cannot be changed; stop it first, the forwards take effect when it starts", vmInfo.Name)
+	}
+	return nil
+}
+
+// applyVMPortForwards adds published ports to the VM info and forwards all of
+// the VM's ports; failures are reported as warnings
+func applyVMPortForwards(ctx context.Context, vmInfo *vm.VMInfo, published []vm.PortForward) {
+	for _, p := range published {
+		if err := vmInfo.AddPortForward(p); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+	}
+	if len(vmInfo.Ports) == 0 {
+		return
+	}
+	if !vmInfo.UsesGvproxy() {
+		// The driver forwards the ports given when the VM was started
+		for _, p := range vmInfo.Ports {
+			fmt.Printf("🔌 Forwarding %s -> %s:%d/%s\n", p.Local(), vmInfo.Name, p.GuestPort, p.Protocol)
+		}
+		return
+	}
+
+	gvproxy := vmGvproxy(vmInfo)
+	if gvproxy == nil {
+		fmt.Println("⚠️  Warning: gvproxy is not running; ports are not forwarded")
+		return
+	}
+	guestIP := vmGuestIP(vmInfo)
+	for _, p := range vmInfo.Ports {
+		if err := exposePortForward(ctx, gvproxy, guestIP, p); err != nil {
+			fmt.Printf("⚠️  Warning: failed to forward %s: %v\n", p, err)
+			continue
+		}
+		fmt.Printf("🔌 Forwarding %s -> %s:%d/%s\n", p.Local(), vmInfo.Name, p.GuestPort, p.Protocol)
+	}
+}
+
+func runVMPortAdd(cmd *cobra.Command, args []string) error {
+	vmName := args[0]
+	forwards, err := parsePortForwards(args[1:])
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if dryRun {
+		fmt.Println("📋 Equivalent command (add port forward):")
+		for _, p := range forwards {
+			fmt.Printf("   curl --unix-socket <gvproxy-service.sock> http://unix/services/forwarder/expose \\\n")
+			fmt.Printf("        -d '{\"local\":\"%s\",\"remote\":\"<vm-ip>:%d\",\"protocol\":\"%s\"}'  # for VM: %s\n", p.Local(), p.GuestPort, p.Protocol, vmName)
+		}
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	// Forwards are exposed while the VM info is locked, so that a concurrent
+	// vm port command cannot save a stale list over them
+	var gvproxy *ci.GvproxyClient
+	exposed := false
+	ctx := cmd.Context()
+	_, err = vm.UpdateVMInfo(vmName, func(vmInfo *vm.VMInfo) error {
+		if err := checkPortsChangeable(vmInfo); err != nil {
+			return err
+		}
+		for _, p := range forwards {
+			if err := vmInfo.AddPortForward(p); err != nil {
+				return err
+			}
+		}
+
+		gvproxy = vmGvproxy(vmInfo)
+		if gvproxy == nil {
+			return nil
+		}
+		guestIP := vmGuestIP(vmInfo)
+		for i, p := range forwards {
+			if err := exposePortForward(ctx, gvproxy, guestIP, p); err != nil {
+				unexposePortForwards(ctx, gvproxy, forwards[:i])
+				return fmt.Errorf("failed to forward %s: %w", p, err)
+			}
+		}
+		exposed = true
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		if exposed {
+			// Saving failed; do not leave forwards the VM does not record
+			unexposePortForwards(ctx, gvproxy, forwards)
+		}
+		return err
+	}
+
+	for _, p := range forwards {
+		fmt.Printf("✅ Added port forward %s -> %s:%d/%s\n", p.Local(), vmName, p.GuestPort, p.Protocol)
+	}
+	if gvproxy == nil {
+		fmt.Printf("   VM '%s' is not running; the forwards take effect when it starts\n", vmName)
+	}
+	return nil
+}
+
+func runVMPortRemove(cmd *cobra.Command, args []string) error {
+	vmName := args[0]
+
+	if dryRun {
+		fmt.Println("📋 Equivalent command (remove port forward):")
+		for _, spec := range args[1:] {
+			fmt.Printf("   curl --unix-socket <gvproxy-service.sock> http://unix/services/forwarder/unexpose \\\n")
+			fmt.Printf("        -d '{\"local\":\"<host-ip>:%s\",\"protocol\":\"tcp\"}'  # for VM: %s\n", spec, vmName)
+		}
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	var removed []vm.PortForward
+	_, err := vm.UpdateVMInfo(vmName, func(vmInfo *vm.VMInfo) error {
+		if err := checkPortsChangeable(vmInfo); err != nil {
+			return err
+		}
+		for _, spec := range args[1:] {
+			hostPort, protocol, err := parseHostPort(spec)
+			if err != nil {
+				return err
+			}
+			p, err := vmInfo.RemovePortForward(hostPort, protocol)
+			if err != nil {
+				return err
+			}
+			removed = append(removed, p)
+		}
+
+		if gvproxy := vmGvproxy(vmInfo); gvproxy != nil {
+			unexposePortForwards(cmd.Context(), gvproxy, removed)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	for _, p := range removed {
+		fmt.Printf("✅ Removed port forward %s\n", p)
+	}
+	return nil
+}
+
+func runVMPortList(cmd *cobra.Command, args []string) error {
+	vmName := args[0]
+
+	if dryRun {
+		fmt.Println("📋 Equivalent command (list port forwards):")
+		fmt.Println("   curl --unix-socket <gvproxy-service.sock> http://unix/services/forwarder/all")
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	vmInfo, err := vm.LoadVMInfo(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	// Mark forwards that gvproxy currently serves
+	active := map[string]bool{}
+	if gvproxy := vmGvproxy(vmInfo); gvproxy != nil {
+		forwarders, err := gvproxy.GetForwarders(cmd.Context())
+		if err != nil && verbose {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+		for _, f := range forwarders {
+			active[f.Local+"/"+f.Protocol] = true
+		}
+	}
+
+	type portEntry struct {
+		vm.PortForward
+		Active bool `json:"active"`
+	}
+	entries := []portEntry{}
+	for _, p := range vmInfo.Ports {
+		entries = append(entries, portEntry{PortForward: p, Active: active[p.Local()+"/"+p.Protocol]})
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No port forwards for VM '%s'\n", vmName)
+		return nil
+	}
+
+	fmt.Printf("Port forwards of VM '%s':\n", vmName)
+	fmt.Println()
+	fmt.Printf("%-24s %-10s %-10s %s\n", "HOST", "GUEST", "PROTOCOL", "STATE")
+	fmt.Println(strings.Repeat("-", 56))
+	for _, e := range entries {
+		state := "inactive"
+		if e.Active {
+			state = "active"
+		}
+		fmt.Printf("%-24s %-10d %-10s %s\n", e.Local(), e.GuestPort, e.Protocol, state)
+	}
+	return nil
+}
+
+// completeVMPortArgs completes a VM name, then its forwarded host ports
+func completeVMPortArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeVMNames(cmd, args, toComplete)
+	}
+	vmInfo, err := vm.LoadVMInfo(args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var ports []string
+	for _, p := range vmInfo.Ports {
+		spec := fmt.Sprintf("%d/%s", p.HostPort, p.Protocol)
+		if strings.HasPrefix(spec, toComplete) {
+			ports = append(ports, spec)
+		}
+	}
+	return ports, cobra.ShellCompDirectiveNoFileComp
+}
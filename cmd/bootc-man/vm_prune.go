@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/format"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var vmPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove VM definitions and files left behind by removed VMs",
+	Long: `Remove VM definitions whose disk image no longer exists, disks and
+provisioning files in ~/.local/share/bootc-man/vms that belong to no VM,
+and SSH keypairs of VMs that no longer exist.
+
+Running VMs and files younger than 10 minutes are never touched. Use
+'bootc-man system prune' to also clean up helper processes, runtime files,
+test disks and port allocations.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPrune(vm.PruneScopeVMs)
+	},
+}
+
+var pruneForce bool
+
+func init() {
+	vmCmd.AddCommand(vmPruneCmd)
+	vmPruneCmd.Flags().BoolVarP(&pruneForce, "force", "f", false, "Do not prompt for confirmation")
+}
+
+// runPrune lists the orphaned resources in scope and removes them after
+// confirmation
+func runPrune(scope vm.PruneScope) error {
+	items, err := vm.FindOrphans(scope)
+	if err != nil {
+		fmt.Printf("❌ Failed to look for orphaned resources: %v\n", err)
+		return err
+	}
+	if len(items) == 0 {
+		fmt.Println("✅ Nothing to prune")
+		return nil
+	}
+
+	var total int64
+	for _, it := range items {
+		total += it.Size
+	}
+
+	if dryRun {
+		fmt.Println("📋 Equivalent commands (prune orphaned resources):")
+		for _, it := range items {
+			fmt.Printf("   %s\n", pruneCommand(it))
+		}
+		fmt.Printf("   # %s would be reclaimed\n", format.Size(total))
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	if !pruneForce {
+		fmt.Println("The following resources will be removed:")
+		printPruneItems(items)
+		fmt.Printf("Total: %s\n", format.Size(total))
+		fmt.Println()
+
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Print("Are you sure you want to continue? [y/N] ")
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		if answer == "" || answer[0] != 'y' {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	reclaimed, err := vm.PruneOrphans(items)
+	if err != nil {
+		// Report what was removed before failing on what was not
+		fmt.Printf("⚠️  Pruned some resources, reclaimed %s\n", format.Size(reclaimed))
+		fmt.Printf("❌ Some resources could not be removed:\n%v\n", err)
+		return fmt.Errorf("some resources could not be removed: %w", err)
+	}
+	fmt.Printf("✅ Pruned %d resources, reclaimed %s\n", len(items), format.Size(reclaimed))
+	return nil
+}
+
+// printPruneItems prints prune items grouped by kind
+func printPruneItems(items []vm.PruneItem) {
+	groups := []struct {
+		kind  vm.PruneKind
+		title string
+	}{
+		{vm.PruneKindProcess, "Helper processes"},
+		{vm.PruneKindVM, "VM definitions"},
+		{vm.PruneKindFile, "Files"},
+		{vm.PruneKindPort, "Port allocations"},
+	}
+	for _, g := range groups {
+		printed := false
+		for _, it := range items {
+			if it.Kind != g.kind {
+				continue
+			}
+			if !printed {
+				fmt.Println()
+				fmt.Printf("%s:\n", g.title)
+				printed = true
+			}
+			if it.Kind == vm.PruneKindFile {
+				fmt.Printf("  %10s  %s\n", format.Size(it.Size), it.Path)
+			} else {
+				fmt.Printf("  %s\n", it.Description())
+			}
+		}
+	}
+	fmt.Println()
+}
+
+// pruneCommand returns the shell equivalent of removing a prune item
+func pruneCommand(it vm.PruneItem) string {
+	switch it.Kind {
+	case vm.PruneKindProcess:
+		return fmt.Sprintf("kill %d  # %s", it.PID, it.Description())
+	case vm.PruneKindVM:
+		return fmt.Sprintf("rm %s  # %s", it.Path, it.Description())
+	case vm.PruneKindPort:
+		return fmt.Sprintf("# release port %d in port-alloc.dat", it.Port)
+	default:
+		return fmt.Sprintf("rm -r %s  # %s", it.Path, format.Size(it.Size))
+	}
+}
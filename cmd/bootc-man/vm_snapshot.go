@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+var vmSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Manage VM snapshots",
+	Long: `Manage disk snapshots of a VM.
+
+Snapshots are stored inside the VM's qcow2 overlay disk, so the shared base
+image is never modified. A typical flow is to boot a VM, configure it,
+save a snapshot, and then test upgrade/rollback repeatedly by reverting to it.
+
+The VM must be stopped to save, revert or delete a snapshot.`,
+}
+
+var vmSnapshotSaveCmd = &cobra.Command{
+	Use:               "save <vm> <snapshot>",
+	Short:             "Save a snapshot of a stopped VM",
+	Args:              cobra.ExactArgs(2),
+	RunE:              runVMSnapshotSave,
+	ValidArgsFunction: completeVMNamesFirstArg,
+}
+
+var vmSnapshotListCmd = &cobra.Command{
+	Use:               "list <vm>",
+	Short:             "List snapshots of a VM",
+	Args:              cobra.ExactArgs(1),
+	RunE:              runVMSnapshotList,
+	ValidArgsFunction: completeVMNamesFirstArg,
+}
+
+var vmSnapshotRevertCmd = &cobra.Command{
+	Use:               "revert <vm> <snapshot>",
+	Short:             "Revert a stopped VM to a snapshot",
+	Args:              cobra.ExactArgs(2),
+	RunE:              runVMSnapshotRevert,
+	ValidArgsFunction: completeVMSnapshotArgs,
+}
+
+var vmSnapshotRemoveCmd = &cobra.Command{
+	Use:               "rm <vm> <snapshot>",
+	Short:             "Delete a snapshot",
+	Args:              cobra.ExactArgs(2),
+	RunE:              runVMSnapshotRemove,
+	ValidArgsFunction: completeVMSnapshotArgs,
+}
+
+func init() {
+	vmCmd.AddCommand(vmSnapshotCmd)
+	vmSnapshotCmd.AddCommand(vmSnapshotSaveCmd)
+	vmSnapshotCmd.AddCommand(vmSnapshotListCmd)
+	vmSnapshotCmd.AddCommand(vmSnapshotRevertCmd)
+	vmSnapshotCmd.AddCommand(vmSnapshotRemoveCmd)
+}
+
+func runVMSnapshotSave(cmd *cobra.Command, args []string) error {
+	vmName, snapshotName := args[0], args[1]
+
+	if dryRun {
+		fmt.Println("📋 Equivalent command (save snapshot):")
+		fmt.Printf("   qemu-img snapshot -c %s ~/.local/share/bootc-man/vms/%s.qcow2\n", snapshotName, vmName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	vmInfo, err := vm.LoadVMInfo(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Printf("📸 Saving snapshot '%s' of VM '%s'...\n", snapshotName, vmName)
+	if err := vm.SaveSnapshot(context.Background(), vmInfo, snapshotName, verbose); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✅ Snapshot '%s' saved\n", snapshotName)
+	fmt.Println()
+	fmt.Printf("To revert:\n")
+	fmt.Printf("  bootc-man vm snapshot revert %s %s\n", vmName, snapshotName)
+	return nil
+}
+
+func runVMSnapshotList(cmd *cobra.Command, args []string) error {
+	vmName := args[0]
+
+	if dryRun {
+		fmt.Println("📋 Equivalent command (list snapshots):")
+		fmt.Printf("   qemu-img snapshot -l ~/.local/share/bootc-man/vms/%s.qcow2\n", vmName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	vmInfo, err := vm.LoadVMInfo(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if jsonOut {
+		snapshots := vmInfo.Snapshots
+		if snapshots == nil {
+			snapshots = []vm.SnapshotInfo{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snapshots)
+	}
+
+	if len(vmInfo.Snapshots) == 0 {
+		fmt.Printf("No snapshots found for VM '%s'\n", vmName)
+		return nil
+	}
+
+	fmt.Printf("Snapshots of VM '%s':\n", vmName)
+	fmt.Println()
+	fmt.Printf("%-30s %s\n", "NAME", "CREATED")
+	fmt.Println(strings.Repeat("-", 52))
+	for _, s := range vmInfo.Snapshots {
+		fmt.Printf("%-30s %s\n", s.Name, s.Created.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+func runVMSnapshotRevert(cmd *cobra.Command, args []string) error {
+	vmName, snapshotName := args[0], args[1]
+
+	if dryRun {
+		fmt.Println("📋 Equivalent command (revert snapshot):")
+		fmt.Printf("   qemu-img snapshot -a %s ~/.local/share/bootc-man/vms/%s.qcow2\n", snapshotName, vmName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	vmInfo, err := vm.LoadVMInfo(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Printf("⏪ Reverting VM '%s' to snapshot '%s'...\n", vmName, snapshotName)
+	if err := vm.RevertSnapshot(context.Background(), vmInfo, snapshotName, verbose); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✅ VM '%s' reverted to snapshot '%s'\n", vmName, snapshotName)
+	fmt.Println()
+	fmt.Printf("To start:\n")
+	fmt.Printf("  bootc-man vm start %s\n", vmName)
+	return nil
+}
+
+func runVMSnapshotRemove(cmd *cobra.Command, args []string) error {
+	vmName, snapshotName := args[0], args[1]
+
+	if dryRun {
+		fmt.Println("📋 Equivalent command (delete snapshot):")
+		fmt.Printf("   qemu-img snapshot -d %s ~/.local/share/bootc-man/vms/%s.qcow2\n", snapshotName, vmName)
+		fmt.Println()
+		fmt.Println("(dry-run mode - command not executed)")
+		return nil
+	}
+
+	vmInfo, err := vm.LoadVMInfo(vmName)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if err := vm.DeleteSnapshot(context.Background(), vmInfo, snapshotName, verbose); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✅ Snapshot '%s' deleted\n", snapshotName)
+	return nil
+}
+
+// completeVMNamesFirstArg completes VM names for the first positional argument only
+func completeVMNamesFirstArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeVMNames(cmd, args, toComplete)
+}
+
+// completeVMSnapshotArgs completes a VM name, then one of its snapshot names
+func completeVMSnapshotArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeVMNames(cmd, args, toComplete)
+	case 1:
+		vmInfo, err := vm.LoadVMInfo(args[0])
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for _, s := range vmInfo.Snapshots {
+			if strings.HasPrefix(s.Name, toComplete) {
+				names = append(names, s.Name)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	default:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/tnk4on/bootc-man/internal/vm"
 )
 
 func TestVMCommandStructure(t *testing.T) {
@@ -9,12 +17,23 @@ func TestVMCommandStructure(t *testing.T) {
 	subcommands := vmCmd.Commands()
 
 	expectedCmds := map[string]bool{
-		"start":  false,
-		"list":   false,
-		"status": false,
-		"stop":   false,
-		"ssh":    false,
-		"rm":     false,
+		"start":    false,
+		"list":     false,
+		"status":   false,
+		"stop":     false,
+		"pause":    false,
+		"resume":   false,
+		"ssh":      false,
+		"console":  false,
+		"cp":       false,
+		"exec":     false,
+		"port":     false,
+		"rm":       false,
+		"snapshot": false,
+		"create":   false,
+		"generate": false,
+		"prune":    false,
+		"export":   false,
 	}
 
 	for _, cmd := range subcommands {
@@ -46,7 +65,7 @@ func TestVMCommandMetadata(t *testing.T) {
 
 func TestVMStartFlags(t *testing.T) {
 	// Test that vm start has expected flags
-	expectedFlags := []string{"name", "pipeline", "cpus", "memory", "gui"}
+	expectedFlags := []string{"name", "pipeline", "cpus", "memory", "gui", "publish", "mount", "arch", "secure-boot", "tpm", "firmware", "network", "profile", "image"}
 
 	for _, flagName := range expectedFlags {
 		flag := vmStartCmd.Flags().Lookup(flagName)
@@ -67,6 +86,18 @@ func TestVMStartFlags(t *testing.T) {
 	}
 }
 
+func TestVMCreateFlags(t *testing.T) {
+	// vm create stores the VM spec flags of vm start
+	for _, flagName := range []string{"name", "pipeline", "cpus", "memory", "publish", "mount", "arch", "secure-boot", "tpm", "firmware", "network", "provision", "profile"} {
+		if vmCreateCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("expected flag %q not found on vm create", flagName)
+		}
+	}
+	if vmCreateCmd.Flags().Lookup("gui") != nil {
+		t.Error("vm create should not have the runtime-only --gui flag")
+	}
+}
+
 func TestVMRemoveFlags(t *testing.T) {
 	// Test that vm rm has expected flags
 	flag := vmRemoveCmd.Flags().Lookup("force")
@@ -119,6 +150,14 @@ func TestVMStopMetadata(t *testing.T) {
 	if vmStopCmd.Short == "" {
 		t.Error("vmStopCmd.Short should not be empty")
 	}
+
+	timeoutFlag := vmStopCmd.Flags().Lookup("timeout")
+	if timeoutFlag == nil {
+		t.Fatal("expected flag 'timeout' not found on vm stop")
+	}
+	if timeoutFlag.DefValue != "60" {
+		t.Errorf("vm stop --timeout default = %q, want %q", timeoutFlag.DefValue, "60")
+	}
 }
 
 func TestGetSSHUser(t *testing.T) {
@@ -158,11 +197,20 @@ func TestVMCommandsHaveDryRunSupport(t *testing.T) {
 		cmd  interface{ HasParent() bool }
 	}{
 		{"start", vmStartCmd},
+		{"create", vmCreateCmd},
+		{"generate systemd", vmGenerateSystemdCmd},
 		{"list", vmListCmd},
 		{"status", vmStatusCmd},
 		{"stop", vmStopCmd},
+		{"pause", vmPauseCmd},
+		{"resume", vmResumeCmd},
 		{"ssh", vmSSHCmd},
+		{"console", vmConsoleCmd},
+		{"cp", vmCopyCmd},
+		{"exec", vmExecCmd},
 		{"rm", vmRemoveCmd},
+		{"prune", vmPruneCmd},
+		{"export", vmExportCmd},
 	}
 
 	for _, sub := range vmSubcommands {
@@ -188,3 +236,276 @@ func TestVMListDryRunOutput(t *testing.T) {
 		}
 	}
 }
+
+func TestVMSnapshotCommandStructure(t *testing.T) {
+	expected := map[string]bool{
+		"save":   false,
+		"list":   false,
+		"revert": false,
+		"rm":     false,
+	}
+
+	for _, cmd := range vmSnapshotCmd.Commands() {
+		if _, ok := expected[cmd.Name()]; ok {
+			expected[cmd.Name()] = true
+		}
+	}
+
+	for name, found := range expected {
+		if !found {
+			t.Errorf("expected subcommand %q not found on vm snapshot command", name)
+		}
+	}
+}
+
+func TestParseCopyArg(t *testing.T) {
+	tests := []struct {
+		arg      string
+		wantVM   string
+		wantPath string
+	}{
+		{"my-vm:/var/tmp", "my-vm", "/var/tmp"},
+		{"my-vm:", "my-vm", ""},
+		{"my-vm:report.xml", "my-vm", "report.xml"},
+		{"./tests", "", "./tests"},
+		{"/tmp/a:b", "", "/tmp/a:b"},
+		{"dir/file:name", "", "dir/file:name"},
+		{":path", "", ":path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			gotVM, gotPath := parseCopyArg(tt.arg)
+			if gotVM != tt.wantVM || gotPath != tt.wantPath {
+				t.Errorf("parseCopyArg(%q) = %q, %q, want %q, %q", tt.arg, gotVM, gotPath, tt.wantVM, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestImageVMName(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"quay.io/fedora/fedora-bootc:42", "fedora-bootc"},
+		{"quay.io/centos-bootc/centos-bootc:stream10", "centos-bootc"},
+		{"localhost/my-image", "my-image"},
+		{"localhost:5000/org/app:v1", "app"},
+		{"quay.io/org/img@sha256:0123456789abcdef", "img"},
+		{"my_image:latest", "my-image"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := imageVMName(tt.ref); got != tt.want {
+				t.Errorf("imageVMName(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecEnv(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "HOST_VAR" {
+			return "from host", true
+		}
+		return "", false
+	}
+
+	got, err := execEnv([]string{"A=1", "B=", "HOST_VAR", "UNSET_VAR", "C=x=y"}, lookup)
+	if err != nil {
+		t.Fatalf("execEnv() error = %v", err)
+	}
+	want := []string{"A=1", "B=", "HOST_VAR=from host", "C=x=y"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("execEnv() = %v, want %v", got, want)
+	}
+
+	for _, invalid := range []string{"=value", "1A=b", "A-B=c"} {
+		if _, err := execEnv([]string{invalid}, lookup); err == nil {
+			t.Errorf("execEnv(%q) error = nil, want error", invalid)
+		}
+	}
+}
+
+func TestBuildExecCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  []string
+		sudo bool
+		want string
+	}{
+		{"plain", []string{"systemctl", "is-active", "sshd"}, nil, false, "systemctl is-active sshd"},
+		{"quoted", []string{"sh", "-c", "rpm -qa | wc -l"}, nil, false, "sh -c 'rpm -qa | wc -l'"},
+		{"sudo", []string{"bootc", "status"}, nil, true, "sudo -n bootc status"},
+		{"env", []string{"run-tests"}, []string{"MSG=hello world"}, false, "env 'MSG=hello world' run-tests"},
+		{"sudo and env", []string{"run-tests"}, []string{"A=1"}, true, "sudo -n env A=1 run-tests"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildExecCommand(tt.args, tt.env, tt.sudo); got != tt.want {
+				t.Errorf("buildExecCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHostPort(t *testing.T) {
+	tests := []struct {
+		spec         string
+		wantPort     int
+		wantProtocol string
+		wantErr      bool
+	}{
+		{"8080", 8080, "tcp", false},
+		{"5353/udp", 5353, "udp", false},
+		{"8080:80", 8080, "tcp", false},
+		{"127.0.0.1:5353:53/udp", 5353, "udp", false},
+		{"8080/sctp", 0, "", true},
+		{"http", 0, "", true},
+		{"0", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			port, protocol, err := parseHostPort(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHostPort(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if port != tt.wantPort || protocol != tt.wantProtocol {
+				t.Errorf("parseHostPort(%q) = %d, %q, want %d, %q", tt.spec, port, protocol, tt.wantPort, tt.wantProtocol)
+			}
+		})
+	}
+}
+
+func TestPruneCommands(t *testing.T) {
+	for _, c := range []*cobra.Command{vmPruneCmd, systemPruneCmd} {
+		if f := c.Flags().Lookup("force"); f == nil || f.Shorthand != "f" {
+			t.Errorf("%s: missing --force/-f flag", c.CommandPath())
+		}
+	}
+
+	tests := []struct {
+		item vm.PruneItem
+		want string
+	}{
+		{vm.PruneItem{Kind: vm.PruneKindProcess, Name: "gvproxy", PID: 42, Reason: "no VM uses /run/x.sock"}, "kill 42  # gvproxy (PID 42): no VM uses /run/x.sock"},
+		{vm.PruneItem{Kind: vm.PruneKindVM, Name: "dev", Path: "/vms/dev.json", Reason: "disk image missing"}, "rm /vms/dev.json  # dev: disk image missing"},
+		{vm.PruneItem{Kind: vm.PruneKindFile, Path: "/tmp/a.raw", Size: 2048}, "rm -r /tmp/a.raw  # 2.00 KB"},
+		{vm.PruneItem{Kind: vm.PruneKindPort, Port: 40000}, "# release port 40000 in port-alloc.dat"},
+	}
+	for _, tt := range tests {
+		if got := pruneCommand(tt.item); got != tt.want {
+			t.Errorf("pruneCommand(%+v) = %q, want %q", tt.item, got, tt.want)
+		}
+	}
+}
+
+func TestPreserveVMSpec(t *testing.T) {
+	existing := &vm.VMInfo{CPUs: 2, Memory: 4096, NICModel: "e1000e", Profile: "small", Arch: "amd64", Firmware: "uefi", SecureBoot: true}
+	// A restart with --cpus 8 --memory 16384 --profile large
+	updated := &vm.VMInfo{CPUs: 8, Memory: 16384, NICModel: "virtio-net-pci", Profile: "large", Arch: "amd64", Firmware: "uefi", ProcessID: 42, SSHPort: 2222}
+	preserveVMSpec(updated, existing)
+	if updated.CPUs != 2 || updated.Memory != 4096 || updated.NICModel != "e1000e" || updated.Profile != "small" || !updated.SecureBoot {
+		t.Errorf("stored spec overwritten: %+v", updated)
+	}
+	if updated.ProcessID != 42 || updated.SSHPort != 2222 {
+		t.Errorf("runtime fields lost: %+v", updated)
+	}
+
+	// VMs saved before resources were stored take them from the driver
+	updated = &vm.VMInfo{CPUs: 4, Memory: 8192}
+	preserveVMSpec(updated, &vm.VMInfo{})
+	if updated.CPUs != 4 || updated.Memory != 8192 {
+		t.Errorf("driver resources lost for legacy VM: %+v", updated)
+	}
+}
+
+func TestVMOptionsFor(t *testing.T) {
+	stored := &vm.VMInfo{
+		Name: "dev", DiskImage: "/nonexistent/dev.qcow2", SSHKeyPath: "/keys/dev", SSHUser: "core",
+		CPUs: 2, Memory: 4096, Arch: "arm64", Firmware: "uefi", SecureBoot: true, TPM: true,
+		Mounts: []vm.SharedDir{{Source: "/src", Tag: "src"}},
+		Ports:  []vm.PortForward{{HostPort: 8080, GuestPort: 80, Protocol: vm.ProtocolTCP}},
+	}
+
+	// A restart without flags uses the stored spec
+	opts := vmOptionsFor(stored, vmStartExtras{}, nil, nil)
+	if opts.CPUs != 2 || opts.Memory != 4096 || opts.Arch != "arm64" || opts.SSHUser != "core" || opts.SSHKeyPath != "/keys/dev" {
+		t.Errorf("stored spec not used: %+v", opts)
+	}
+	if !opts.SecureBoot || !opts.TPM || opts.Firmware != "uefi" || len(opts.Mounts) != 1 {
+		t.Errorf("stored firmware or mounts not used: %+v", opts)
+	}
+
+	// Flags override it for the run
+	extras := vmStartExtras{cpus: 8, firmwareSet: true, mounts: []vm.SharedDir{{Source: "/a", Tag: "a"}, {Source: "/b", Tag: "b"}}}
+	opts = vmOptionsFor(stored, extras, nil, nil)
+	if opts.CPUs != 8 || opts.Memory != 4096 || opts.SecureBoot || opts.TPM || len(opts.Mounts) != 2 {
+		t.Errorf("flags not applied: %+v", opts)
+	}
+
+	// libvirt forwards the stored and the published ports when it starts
+	extras = vmStartExtras{driver: "libvirt", published: []vm.PortForward{{HostPort: 9090, GuestPort: 90, Protocol: vm.ProtocolTCP}}}
+	opts = vmOptionsFor(stored, extras, nil, nil)
+	if opts.Driver != "libvirt" || len(opts.Ports) != 2 {
+		t.Errorf("libvirt options = driver %q, ports %v, want both forwards", opts.Driver, opts.Ports)
+	}
+	if len(stored.Ports) != 1 {
+		t.Errorf("vmOptionsFor() changed the stored forwards: %v", stored.Ports)
+	}
+}
+
+func TestVMDiskPathForDriver(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	vmsDir, err := vm.GetVMsDir()
+	if err != nil {
+		t.Fatalf("GetVMsDir() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	rawPath := filepath.Join(dir, "disk.raw")
+	if err := os.WriteFile(rawPath, []byte("raw disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	qcow2Path := filepath.Join(dir, "disk.qcow2")
+	if err := os.WriteFile(qcow2Path, []byte{'Q', 'F', 'I', 0xfb, 0, 0, 0, 3}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name       string
+		driver     string
+		base       string
+		wantPath   string
+		wantMethod string
+		wantErr    bool
+	}
+	tests := []testCase{
+		{"libvirt raw is cloned", "libvirt", rawPath, "dev.raw", vm.DiskMethodReflink, false},
+		{"libvirt qcow2 is cloned", "libvirt", qcow2Path, "dev.qcow2", vm.DiskMethodReflink, false},
+		{"plugin raw is cloned", "my-hypervisor", rawPath, "dev.raw", vm.DiskMethodReflink, false},
+		{"plugin rejects qcow2", "my-hypervisor", qcow2Path, "", "", true},
+	}
+	// The built-in driver shares the base through an overlay on QEMU
+	if _, err := exec.LookPath("qemu-img"); err == nil && runtime.GOOS == "linux" {
+		tests = append(tests, testCase{"qemu raw gets an overlay", "", rawPath, "dev.qcow2", vm.DiskMethodOverlay, false})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extras := vmStartExtras{driver: tt.driver}
+			path, method, err := vmDiskPathFor(tt.base, "dev", extras.vmType())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("vmDiskPathFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if want := filepath.Join(vmsDir, tt.wantPath); path != want || method != tt.wantMethod {
+				t.Errorf("vmDiskPathFor() = %s, %s, want %s, %s", path, method, want, tt.wantMethod)
+			}
+		})
+	}
+}
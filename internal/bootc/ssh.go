@@ -5,18 +5,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"os"
 	"strings"
+	"time"
 
-	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/sshclient"
 )
 
 // SSHDriver implements Driver for remote bootc operations via SSH
-// It uses the system's ssh command and ~/.ssh/config for connection settings
+// Connection settings (HostName, User, Port, IdentityFile) are read from
+// ~/.ssh/config; one connection is reused for all commands.
 type SSHDriver struct {
-	host    string // SSH host name (as defined in ~/.ssh/config)
-	verbose bool   // Show commands being executed
-	dryRun  bool   // Show commands without executing
+	host    string            // SSH host name (as defined in ~/.ssh/config)
+	verbose bool              // Show commands being executed
+	dryRun  bool              // Show commands without executing
+	client  *sshclient.Client // Created on first use
 }
 
 // SSHDriverOptions contains options for creating an SSH driver
@@ -41,45 +44,65 @@ func (d *SSHDriver) Host() string {
 	return d.host
 }
 
-// run executes a command on the remote host via SSH
-func (d *SSHDriver) run(ctx context.Context, args ...string) ([]byte, error) {
-	// Build the remote command
-	remoteCmd := "sudo bootc " + strings.Join(args, " ")
-
-	// Build equivalent command for display
-	equivalentCmd := fmt.Sprintf("ssh %s %s", d.host, remoteCmd)
+// sshClient returns the SSH client, resolving the host from ~/.ssh/config
+func (d *SSHDriver) sshClient() (*sshclient.Client, error) {
+	if d.client == nil {
+		cfg, err := sshclient.ResolveHost(d.host)
+		if err != nil {
+			return nil, err
+		}
+		d.client = sshclient.New(cfg)
+	}
+	return d.client, nil
+}
 
+// prepare shows the equivalent command and reports whether to execute it
+func (d *SSHDriver) prepare(remoteCmd string) bool {
 	// Show command in verbose mode or dry-run
 	if d.verbose || d.dryRun {
-		fmt.Printf("📋 Equivalent command:\n   %s\n\n", equivalentCmd)
+		fmt.Printf("📋 Equivalent command:\n   ssh %s %s\n\n", d.host, remoteCmd)
 	}
+	return !d.dryRun
+}
 
-	// In dry-run mode, don't execute
-	if d.dryRun {
+// run executes a bootc command on the remote host and returns its output
+func (d *SSHDriver) run(ctx context.Context, args ...string) ([]byte, error) {
+	remoteCmd := bootcCommand(args)
+	if !d.prepare(remoteCmd) {
 		return []byte{}, nil
 	}
 
-	// Use ssh with BatchMode to ensure non-interactive execution
-	sshArgs := []string{
-		"-o", "BatchMode=yes",
-		"-o", config.SSHOptionStrictHostKeyCheckingAcceptNew,
-		d.host,
-		remoteCmd,
+	client, err := d.sshClient()
+	if err != nil {
+		return nil, err
 	}
 
-	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	if err != nil {
+	if err := client.Run(ctx, remoteCmd, &stdout, &stderr); err != nil {
 		return nil, fmt.Errorf("ssh %s bootc %s failed: %w\nstderr: %s",
 			d.host, strings.Join(args, " "), err, stderr.String())
 	}
 	return stdout.Bytes(), nil
 }
 
+// stream executes a bootc command on the remote host, printing its output live
+func (d *SSHDriver) stream(ctx context.Context, args ...string) error {
+	remoteCmd := bootcCommand(args)
+	if !d.prepare(remoteCmd) {
+		return nil
+	}
+
+	client, err := d.sshClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Run(ctx, remoteCmd, os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("ssh %s bootc %s failed: %w", d.host, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
 // IsDryRun returns whether the driver is in dry-run mode
 func (d *SSHDriver) IsDryRun() bool {
 	return d.dryRun
@@ -87,40 +110,40 @@ func (d *SSHDriver) IsDryRun() bool {
 
 // CheckConnection verifies SSH connectivity to the remote host
 func (d *SSHDriver) CheckConnection(ctx context.Context) error {
-	sshArgs := []string{
-		"-o", "BatchMode=yes",
-		"-o", config.SSHOptionStrictHostKeyCheckingAcceptNew,
-		"-o", config.SSHOptionConnectTimeout10,
-		d.host,
-		"echo ok",
+	client, err := d.sshClient()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		err = client.Run(ctx, "echo ok", nil, nil)
 	}
-
-	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("SSH connection to %s failed: %w\nstderr: %s\n\nMake sure:\n  1. Host '%s' is defined in ~/.ssh/config\n  2. SSH key authentication is configured\n  3. The remote host is reachable",
-			d.host, err, stderr.String(), d.host)
+	if err != nil {
+		return fmt.Errorf("SSH connection to %s failed: %w\n\nMake sure:\n  1. Host '%s' is defined in ~/.ssh/config\n  2. SSH key authentication is configured\n  3. The remote host is reachable",
+			d.host, err, d.host)
 	}
 	return nil
 }
 
 // CheckBootc verifies that bootc is available on the remote host
 func (d *SSHDriver) CheckBootc(ctx context.Context) error {
-	sshArgs := []string{
-		"-o", "BatchMode=yes",
-		d.host,
-		"which bootc || command -v bootc",
+	client, err := d.sshClient()
+	if err != nil {
+		return err
 	}
-
-	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
-	if err := cmd.Run(); err != nil {
+	if err := client.Run(ctx, "which bootc || command -v bootc", nil, nil); err != nil {
 		return fmt.Errorf("bootc not found on remote host %s", d.host)
 	}
 	return nil
 }
 
+// bootcCommand builds the remote "sudo bootc ..." command line
+func bootcCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = sshclient.ShellQuote(arg)
+	}
+	return "sudo bootc " + strings.Join(quoted, " ")
+}
+
 // Upgrade upgrades the remote system
 func (d *SSHDriver) Upgrade(ctx context.Context, opts UpgradeOptions) error {
 	args := []string{"upgrade"}
@@ -135,16 +158,12 @@ func (d *SSHDriver) Upgrade(ctx context.Context, opts UpgradeOptions) error {
 		args = append(args, "--quiet")
 	}
 
-	output, err := d.run(ctx, args...)
-	if err != nil {
+	// Quiet upgrades only report errors
+	if opts.Quiet {
+		_, err := d.run(ctx, args...)
 		return err
 	}
-
-	// Print output if not quiet
-	if !opts.Quiet && len(output) > 0 {
-		fmt.Print(string(output))
-	}
-	return nil
+	return d.stream(ctx, args...)
 }
 
 // Switch switches to a different image on the remote system
@@ -163,15 +182,7 @@ func (d *SSHDriver) Switch(ctx context.Context, image string, opts SwitchOptions
 
 	args = append(args, image)
 
-	output, err := d.run(ctx, args...)
-	if err != nil {
-		return err
-	}
-
-	if len(output) > 0 {
-		fmt.Print(string(output))
-	}
-	return nil
+	return d.stream(ctx, args...)
 }
 
 // Rollback performs a rollback on the remote system
@@ -181,15 +192,7 @@ func (d *SSHDriver) Rollback(ctx context.Context, opts RollbackOptions) error {
 		args = append(args, "--apply")
 	}
 
-	output, err := d.run(ctx, args...)
-	if err != nil {
-		return err
-	}
-
-	if len(output) > 0 {
-		fmt.Print(string(output))
-	}
-	return nil
+	return d.stream(ctx, args...)
 }
 
 // Status returns the current status of the remote system
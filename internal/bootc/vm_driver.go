@@ -5,14 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
-	"strings"
+	"os"
+	"time"
 
-	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/sshclient"
+	"github.com/tnk4on/bootc-man/internal/vm"
 )
 
 // VMDriver implements Driver for bootc operations on VMs managed by bootc-man
-// It connects via SSH using the VM's stored connection information
+// It connects via SSH using the VM's stored connection information; the
+// host key is checked against bootc-man's known_hosts file
 type VMDriver struct {
 	vmName     string // VM name (as registered with bootc-man vm)
 	sshHost    string // SSH host (usually localhost)
@@ -21,6 +23,7 @@ type VMDriver struct {
 	sshKeyPath string // Path to SSH private key
 	verbose    bool   // Show commands being executed
 	dryRun     bool   // Show commands without executing
+	client     *sshclient.Client
 }
 
 // VMDriverOptions contains options for creating a VM driver
@@ -44,6 +47,12 @@ func NewVMDriver(opts VMDriverOptions) *VMDriver {
 		sshKeyPath: opts.SSHKeyPath,
 		verbose:    opts.Verbose,
 		dryRun:     opts.DryRun,
+		client: vm.NewSSHClient(opts.VMName, vm.SSHConfig{
+			Host:    opts.SSHHost,
+			Port:    opts.SSHPort,
+			User:    opts.SSHUser,
+			KeyPath: opts.SSHKeyPath,
+		}),
 	}
 }
 
@@ -57,50 +66,44 @@ func (d *VMDriver) Host() string {
 	return fmt.Sprintf("vm:%s", d.vmName)
 }
 
-// run executes a command on the VM via SSH
-func (d *VMDriver) run(ctx context.Context, args ...string) ([]byte, error) {
-	// Build the remote command
-	remoteCmd := "sudo bootc " + strings.Join(args, " ")
-
-	// Build equivalent command for display
-	equivalentCmd := fmt.Sprintf("ssh -i %s -p %d %s@%s %s",
-		d.sshKeyPath, d.sshPort, d.sshUser, d.sshHost, remoteCmd)
-
+// prepare shows the equivalent command and reports whether to execute it
+func (d *VMDriver) prepare(remoteCmd string) bool {
 	// Show command in verbose mode or dry-run
 	if d.verbose || d.dryRun {
-		fmt.Printf("📋 Equivalent command:\n   %s\n\n", equivalentCmd)
+		fmt.Printf("📋 Equivalent command:\n   ssh -i %s -p %d %s@%s %s\n\n",
+			d.sshKeyPath, d.sshPort, d.sshUser, d.sshHost, remoteCmd)
 	}
+	return !d.dryRun
+}
 
-	// In dry-run mode, don't execute
-	if d.dryRun {
+// run executes a bootc command on the VM and returns its output
+func (d *VMDriver) run(ctx context.Context, args ...string) ([]byte, error) {
+	remoteCmd := bootcCommand(args)
+	if !d.prepare(remoteCmd) {
 		return []byte{}, nil
 	}
 
-	// Build SSH args for VM connection
-	sshArgs := []string{
-		"-i", d.sshKeyPath,
-		"-p", fmt.Sprintf("%d", d.sshPort),
-		"-o", "BatchMode=yes",
-		"-o", config.SSHOptionStrictHostKeyCheckingNo,
-		"-o", config.SSHOptionUserKnownHostsFileDevNull,
-		"-o", "LogLevel=ERROR",
-		fmt.Sprintf("%s@%s", d.sshUser, d.sshHost),
-		remoteCmd,
-	}
-
-	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	if err != nil {
+	if err := d.client.Run(ctx, remoteCmd, &stdout, &stderr); err != nil {
 		return nil, fmt.Errorf("ssh to VM %s failed: %w\nstderr: %s",
 			d.vmName, err, stderr.String())
 	}
 	return stdout.Bytes(), nil
 }
 
+// stream executes a bootc command on the VM, printing its output live
+func (d *VMDriver) stream(ctx context.Context, args ...string) error {
+	remoteCmd := bootcCommand(args)
+	if !d.prepare(remoteCmd) {
+		return nil
+	}
+
+	if err := d.client.Run(ctx, remoteCmd, os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("ssh to VM %s failed: %w", d.vmName, err)
+	}
+	return nil
+}
+
 // IsDryRun returns whether the driver is in dry-run mode
 func (d *VMDriver) IsDryRun() bool {
 	return d.dryRun
@@ -108,44 +111,18 @@ func (d *VMDriver) IsDryRun() bool {
 
 // CheckConnection verifies SSH connectivity to the VM
 func (d *VMDriver) CheckConnection(ctx context.Context) error {
-	sshArgs := []string{
-		"-i", d.sshKeyPath,
-		"-p", fmt.Sprintf("%d", d.sshPort),
-		"-o", "BatchMode=yes",
-		"-o", config.SSHOptionStrictHostKeyCheckingNo,
-		"-o", config.SSHOptionUserKnownHostsFileDevNull,
-		"-o", "LogLevel=ERROR",
-		"-o", config.SSHOptionConnectTimeout10,
-		fmt.Sprintf("%s@%s", d.sshUser, d.sshHost),
-		"echo ok",
-	}
-
-	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("SSH connection to VM %s failed: %w\nstderr: %s\n\nMake sure the VM is running:\n  bootc-man vm status %s",
-			d.vmName, err, stderr.String(), d.vmName)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := d.client.Run(ctx, "echo ok", nil, nil); err != nil {
+		return fmt.Errorf("SSH connection to VM %s failed: %w\n\nMake sure the VM is running:\n  bootc-man vm status %s",
+			d.vmName, err, d.vmName)
 	}
 	return nil
 }
 
 // CheckBootc verifies that bootc is available on the VM
 func (d *VMDriver) CheckBootc(ctx context.Context) error {
-	sshArgs := []string{
-		"-i", d.sshKeyPath,
-		"-p", fmt.Sprintf("%d", d.sshPort),
-		"-o", "BatchMode=yes",
-		"-o", config.SSHOptionStrictHostKeyCheckingNo,
-		"-o", config.SSHOptionUserKnownHostsFileDevNull,
-		"-o", "LogLevel=ERROR",
-		fmt.Sprintf("%s@%s", d.sshUser, d.sshHost),
-		"which bootc || command -v bootc",
-	}
-
-	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
-	if err := cmd.Run(); err != nil {
+	if err := d.client.Run(ctx, "which bootc || command -v bootc", nil, nil); err != nil {
 		return fmt.Errorf("bootc not found on VM %s", d.vmName)
 	}
 	return nil
@@ -165,16 +142,12 @@ func (d *VMDriver) Upgrade(ctx context.Context, opts UpgradeOptions) error {
 		args = append(args, "--quiet")
 	}
 
-	output, err := d.run(ctx, args...)
-	if err != nil {
+	// Quiet upgrades only report errors
+	if opts.Quiet {
+		_, err := d.run(ctx, args...)
 		return err
 	}
-
-	// Print output if not quiet
-	if !opts.Quiet && len(output) > 0 {
-		fmt.Print(string(output))
-	}
-	return nil
+	return d.stream(ctx, args...)
 }
 
 // Switch switches to a different image on the VM
@@ -193,15 +166,7 @@ func (d *VMDriver) Switch(ctx context.Context, image string, opts SwitchOptions)
 
 	args = append(args, image)
 
-	output, err := d.run(ctx, args...)
-	if err != nil {
-		return err
-	}
-
-	if len(output) > 0 {
-		fmt.Print(string(output))
-	}
-	return nil
+	return d.stream(ctx, args...)
 }
 
 // Rollback performs a rollback on the VM
@@ -211,15 +176,7 @@ func (d *VMDriver) Rollback(ctx context.Context, opts RollbackOptions) error {
 		args = append(args, "--apply")
 	}
 
-	output, err := d.run(ctx, args...)
-	if err != nil {
-		return err
-	}
-
-	if len(output) > 0 {
-		fmt.Print(string(output))
-	}
-	return nil
+	return d.stream(ctx, args...)
 }
 
 // Status returns the current status of the VM system
@@ -10,6 +10,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/tnk4on/bootc-man/internal/cleanup"
 	"github.com/tnk4on/bootc-man/internal/config"
 	"github.com/tnk4on/bootc-man/internal/podman"
 )
@@ -132,8 +133,13 @@ func (c *ConvertStage) convertToFormat(ctx context.Context, format ConvertFormat
 	if err := os.MkdirAll(tempOutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create temp output directory: %w", err)
 	}
-	// Clean up temp directory on completion
-	defer os.RemoveAll(tempOutputDir)
+	// Clean up temp directory on completion, also when interrupted
+	// bootc-image-builder runs as root under sudo, so the directory may not
+	// be removable; it is then recorded for 'bootc-man system prune'
+	tempCleanup := cleanup.Register("remove convert output", func(context.Context) error {
+		return os.RemoveAll(tempOutputDir)
+	}, cleanup.Resource{Path: tempOutputDir})
+	defer func() { _ = tempCleanup.Run(context.Background()) }()
 
 	// Prepare bootc-image-builder command arguments
 	args := []string{"run", "--rm"}
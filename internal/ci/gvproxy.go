@@ -78,6 +78,16 @@ func NewGvproxyClient(vmName string, verbose bool) (*GvproxyClient, error) {
 	}, nil
 }
 
+// NewGvproxyServiceClient creates a client for the HTTP API of an already
+// running gvproxy, e.g. from VMInfo.GvproxyServiceSocket
+// Only the API methods (forwarders, leases) can be used.
+func NewGvproxyServiceClient(serviceSocketPath string, verbose bool) *GvproxyClient {
+	return &GvproxyClient{
+		serviceSocketPath: serviceSocketPath,
+		verbose:           verbose,
+	}
+}
+
 // getAvailableSSHPort finds an available TCP port for SSH forwarding
 // Uses podman machine's port allocation system to avoid conflicts
 func getAvailableSSHPort() (int, error) {
@@ -452,68 +462,90 @@ func (g *GvproxyClient) GetForwarders(ctx context.Context) ([]ForwarderInfo, err
 
 // UnexposePort removes port forwarding for a given local port using gvproxy's HTTP API
 func (g *GvproxyClient) UnexposePort(ctx context.Context) error {
-	if g.serviceSocketPath == "" {
-		return fmt.Errorf("service socket path not set")
-	}
+	return g.UnexposeForward(ctx, fmt.Sprintf(":%d", g.sshPort), "tcp")
+}
 
-	url := "http://unix/services/forwarder/unexpose"
+// ExposePort exposes a port on the host to a VM IP address using gvproxy's HTTP API
+// This allows dynamic port forwarding when the VM's IP address is not 192.168.127.2
+// If a port forwarding already exists, it will be removed first
+func (g *GvproxyClient) ExposePort(ctx context.Context, vmIP string, vmPort int) error {
+	// First, try to remove any existing port forwarding to avoid "proxy already running" error
+	_ = g.UnexposePort(ctx) // Ignore errors - port may not exist
 
-	// Create HTTP client that uses Unix socket
-	client := &http.Client{
-		Transport: &http.Transport{
-			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
-				return net.Dial("unix", g.serviceSocketPath)
-			},
-		},
+	remote := fmt.Sprintf("%s:%d", vmIP, vmPort)
+	if err := g.ExposeForward(ctx, fmt.Sprintf(":%d", g.sshPort), remote, "tcp"); err != nil {
+		return err
 	}
 
+	if g.verbose {
+		fmt.Printf("✅ Exposed port %d on host to %s via gvproxy\n", g.sshPort, remote)
+	}
+
+	return nil
+}
+
+// ExposeForward forwards local (host address, e.g. "127.0.0.1:8080") to
+// remote (VM address, e.g. "192.168.127.2:80") for protocol tcp or udp
+// A stale forward on the same local address is replaced.
+func (g *GvproxyClient) ExposeForward(ctx context.Context, local, remote, protocol string) error {
 	payload := map[string]string{
-		"local":    fmt.Sprintf(":%d", g.sshPort),
-		"protocol": "tcp",
+		"local":    local,
+		"remote":   remote,
+		"protocol": protocol,
 	}
 
-	jsonData, err := json.Marshal(payload)
+	// Body: {"local":":2222","remote":"192.168.127.3:22","protocol":"tcp"}
+	status, body, err := g.forwarderRequest(ctx, "expose", payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return fmt.Errorf("failed to expose port: %w", err)
+	}
+	if status == http.StatusOK {
+		return nil
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	// "proxy already running" means the local address is still forwarded;
+	// remove the old forward and retry once
+	if strings.Contains(body, "proxy already running") {
+		if err := g.UnexposeForward(ctx, local, protocol); err == nil {
+			status, body, err = g.forwarderRequest(ctx, "expose", payload)
+			if err != nil {
+				return fmt.Errorf("failed to expose port: %w", err)
+			}
+			if status == http.StatusOK {
+				return nil
+			}
+		}
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return fmt.Errorf("failed to expose port: status %d, body: %s", status, body)
+}
 
-	resp, err := client.Do(req)
+// UnexposeForward removes the forward of local (host address) for protocol
+// Removing a forward that does not exist is not an error.
+func (g *GvproxyClient) UnexposeForward(ctx context.Context, local, protocol string) error {
+	payload := map[string]string{
+		"local":    local,
+		"protocol": protocol,
+	}
+
+	status, body, err := g.forwarderRequest(ctx, "unexpose", payload)
 	if err != nil {
 		return fmt.Errorf("failed to unexpose port: %w", err)
 	}
-	defer resp.Body.Close()
 
 	// Ignore errors if port is not already exposed (404 or proxy not found)
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to unexpose port: status %d, body: %s", resp.StatusCode, string(body))
+	if status != http.StatusOK && status != http.StatusNotFound {
+		return fmt.Errorf("failed to unexpose port: status %d, body: %s", status, body)
 	}
-
 	return nil
 }
 
-// ExposePort exposes a port on the host to a VM IP address using gvproxy's HTTP API
-// This allows dynamic port forwarding when the VM's IP address is not 192.168.127.2
-// If a port forwarding already exists, it will be removed first
-func (g *GvproxyClient) ExposePort(ctx context.Context, vmIP string, vmPort int) error {
+// forwarderRequest posts payload to /services/forwarder/<action> and returns
+// the response status and body
+func (g *GvproxyClient) forwarderRequest(ctx context.Context, action string, payload map[string]string) (int, string, error) {
 	if g.serviceSocketPath == "" {
-		return fmt.Errorf("service socket path not set")
+		return 0, "", fmt.Errorf("service socket path not set")
 	}
 
-	// First, try to remove any existing port forwarding to avoid "proxy already running" error
-	_ = g.UnexposePort(ctx) // Ignore errors - port may not exist
-
-	// Use gvproxy's HTTP API to expose the port
-	// POST to http://unix/services/forwarder/expose
-	// Body: {"local":":2222","remote":"192.168.127.3:22","protocol":"tcp"}
-	url := "http://unix/services/forwarder/expose"
-
 	// Create HTTP client that uses Unix socket
 	client := &http.Client{
 		Transport: &http.Transport{
@@ -521,59 +553,26 @@ func (g *GvproxyClient) ExposePort(ctx context.Context, vmIP string, vmPort int)
 				return net.Dial("unix", g.serviceSocketPath)
 			},
 		},
-	}
-
-	payload := map[string]string{
-		"local":    fmt.Sprintf(":%d", g.sshPort),
-		"remote":   fmt.Sprintf("%s:%d", vmIP, vmPort),
-		"protocol": "tcp",
+		Timeout: config.DefaultHTTPClientTimeout,
 	}
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return 0, "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://unix/services/forwarder/"+action, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to expose port: %w", err)
+		return 0, "", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		// Check if error is "proxy already running" - this can happen if unexpose didn't work
-		bodyStr := string(body)
-		if strings.Contains(bodyStr, "proxy already running") {
-			// Try to unexpose and retry once more
-			if err := g.UnexposePort(ctx); err == nil {
-				// Retry the expose
-				req2, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-				req2.Header.Set("Content-Type", "application/json")
-				resp2, err2 := client.Do(req2)
-				if err2 == nil {
-					resp2.Body.Close()
-					if resp2.StatusCode == http.StatusOK {
-						if g.verbose {
-							fmt.Printf("✅ Exposed port %d on host to %s:%d via gvproxy (after retry)\n", g.sshPort, vmIP, vmPort)
-						}
-						return nil
-					}
-				}
-			}
-		}
-		return fmt.Errorf("failed to expose port: status %d, body: %s", resp.StatusCode, bodyStr)
-	}
-
-	if g.verbose {
-		fmt.Printf("✅ Exposed port %d on host to %s:%d via gvproxy\n", g.sshPort, vmIP, vmPort)
-	}
-
-	return nil
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), nil
 }
@@ -0,0 +1,104 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeGvproxyAPI serves the forwarder endpoints of gvproxy's service socket
+type fakeGvproxyAPI struct {
+	mu         sync.Mutex
+	forwarders map[string]ForwarderInfo // keyed by local/protocol
+}
+
+func newFakeGvproxyAPI(t *testing.T) (*fakeGvproxyAPI, string) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "service.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+
+	api := &fakeGvproxyAPI{forwarders: map[string]ForwarderInfo{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services/forwarder/expose", func(w http.ResponseWriter, r *http.Request) {
+		var f ForwarderInfo
+		_ = json.NewDecoder(r.Body).Decode(&f)
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		if _, ok := api.forwarders[f.Local+"/"+f.Protocol]; ok {
+			http.Error(w, "proxy already running", http.StatusInternalServerError)
+			return
+		}
+		api.forwarders[f.Local+"/"+f.Protocol] = f
+	})
+	mux.HandleFunc("/services/forwarder/unexpose", func(w http.ResponseWriter, r *http.Request) {
+		var f ForwarderInfo
+		_ = json.NewDecoder(r.Body).Decode(&f)
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		if _, ok := api.forwarders[f.Local+"/"+f.Protocol]; !ok {
+			http.Error(w, "proxy not found", http.StatusNotFound)
+			return
+		}
+		delete(api.forwarders, f.Local+"/"+f.Protocol)
+	})
+	mux.HandleFunc("/services/forwarder/all", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		all := []ForwarderInfo{}
+		for _, f := range api.forwarders {
+			all = append(all, f)
+		}
+		_ = json.NewEncoder(w).Encode(all)
+	})
+
+	server := &http.Server{Handler: mux}
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(func() { server.Close() })
+	return api, socket
+}
+
+func TestGvproxyForwarders(t *testing.T) {
+	api, socket := newFakeGvproxyAPI(t)
+	g := NewGvproxyServiceClient(socket, false)
+	ctx := context.Background()
+
+	if err := g.ExposeForward(ctx, "127.0.0.1:8080", "192.168.127.2:80", "tcp"); err != nil {
+		t.Fatalf("ExposeForward() error = %v", err)
+	}
+	// Exposing the same local address again replaces the stale forward
+	if err := g.ExposeForward(ctx, "127.0.0.1:8080", "192.168.127.3:80", "tcp"); err != nil {
+		t.Fatalf("ExposeForward() again error = %v", err)
+	}
+	if err := g.ExposeForward(ctx, "127.0.0.1:5353", "192.168.127.2:53", "udp"); err != nil {
+		t.Fatalf("ExposeForward(udp) error = %v", err)
+	}
+
+	forwarders, err := g.GetForwarders(ctx)
+	if err != nil {
+		t.Fatalf("GetForwarders() error = %v", err)
+	}
+	if len(forwarders) != 2 {
+		t.Errorf("GetForwarders() = %v, want 2 forwarders", forwarders)
+	}
+	if got := api.forwarders["127.0.0.1:8080/tcp"].Remote; got != "192.168.127.3:80" {
+		t.Errorf("remote of 127.0.0.1:8080 = %q, want 192.168.127.3:80", got)
+	}
+
+	if err := g.UnexposeForward(ctx, "127.0.0.1:8080", "tcp"); err != nil {
+		t.Fatalf("UnexposeForward() error = %v", err)
+	}
+	// Removing a forward that does not exist is not an error
+	if err := g.UnexposeForward(ctx, "127.0.0.1:8080", "tcp"); err != nil {
+		t.Errorf("UnexposeForward(missing) error = %v", err)
+	}
+	if _, ok := api.forwarders["127.0.0.1:8080/tcp"]; ok {
+		t.Error("forward 127.0.0.1:8080/tcp still present after UnexposeForward")
+	}
+}
@@ -3,6 +3,7 @@ package ci
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -27,6 +28,7 @@ type IgnitionUser struct {
 	Name              string   `json:"name"`
 	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
 	UID               *int     `json:"uid,omitempty"`
+	Groups            []string `json:"groups,omitempty"`
 }
 
 // IgnitionFile represents a file in Ignition config
@@ -48,46 +50,64 @@ type IgnitionFile struct {
 	} `json:"fileEmbedded1"`
 }
 
+// ignitionFileJSON is the on-disk form of IgnitionFile
+// Ignition flattens the node and file attributes into a single object.
+type ignitionFileJSON struct {
+	Path     string               `json:"path"`
+	User     *ignitionFileOwner   `json:"user,omitempty"`
+	Group    *ignitionFileOwner   `json:"group,omitempty"`
+	Mode     *int                 `json:"mode,omitempty"`
+	Contents ignitionFileContents `json:"contents"`
+}
+
+// ignitionFileOwner is the user or group owning a file
+type ignitionFileOwner struct {
+	Name string `json:"name,omitempty"`
+}
+
+// ignitionFileContents is the source of a file's contents
+type ignitionFileContents struct {
+	Source string `json:"source"`
+}
+
+// MarshalJSON encodes the file in the flat layout required by the Ignition spec
+func (f IgnitionFile) MarshalJSON() ([]byte, error) {
+	out := ignitionFileJSON{Path: f.Node.Path, Mode: f.Node.Mode}
+	if f.Node.User.Name != "" {
+		out.User = &ignitionFileOwner{Name: f.Node.User.Name}
+	}
+	if f.Node.Group.Name != "" {
+		out.Group = &ignitionFileOwner{Name: f.Node.Group.Name}
+	}
+	out.Contents.Source = f.FileEmbedded1.Contents.Source
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes the flat Ignition file layout
+func (f *IgnitionFile) UnmarshalJSON(data []byte) error {
+	var in ignitionFileJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*f = IgnitionFile{}
+	f.Node.Path = in.Path
+	f.Node.Mode = in.Mode
+	if in.User != nil {
+		f.Node.User.Name = in.User.Name
+	}
+	if in.Group != nil {
+		f.Node.Group.Name = in.Group.Name
+	}
+	f.FileEmbedded1.Contents.Source = in.Contents.Source
+	return nil
+}
+
 // GenerateIgnitionConfig generates an Ignition config file with SSH keys
+// Non-root users are added to the wheel group and get passwordless sudo.
 func GenerateIgnitionConfig(sshPublicKey string, username string) (*IgnitionConfig, error) {
-	// Read SSH public key from file or use provided string
-	var sshKey string
-	if sshPublicKey != "" {
-		// Check if it's a file path
-		if _, err := os.Stat(sshPublicKey); err == nil {
-			data, err := os.ReadFile(sshPublicKey)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read SSH key file: %w", err)
-			}
-			sshKey = strings.TrimSpace(string(data))
-		} else {
-			// Assume it's the key content itself
-			sshKey = strings.TrimSpace(sshPublicKey)
-		}
-	} else {
-		// Try to get SSH key from default location
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
-		}
-		
-		// Try common SSH key locations
-		sshKeyPaths := []string{
-			filepath.Join(homeDir, ".ssh", "id_ed25519.pub"),
-			filepath.Join(homeDir, ".ssh", "id_rsa.pub"),
-			filepath.Join(homeDir, ".ssh", "id_ecdsa.pub"),
-		}
-		
-		for _, keyPath := range sshKeyPaths {
-			if data, err := os.ReadFile(keyPath); err == nil {
-				sshKey = strings.TrimSpace(string(data))
-				break
-			}
-		}
-		
-		if sshKey == "" {
-			return nil, fmt.Errorf("no SSH public key found. Please specify one or ensure ~/.ssh/id_ed25519.pub exists")
-		}
+	sshKey, err := loadSSHPublicKey(sshPublicKey)
+	if err != nil {
+		return nil, err
 	}
 
 	config := &IgnitionConfig{}
@@ -102,17 +122,49 @@ func GenerateIgnitionConfig(sshPublicKey string, username string) (*IgnitionConf
 		uid = 1000
 	}
 
-	config.Passwd.Users = []IgnitionUser{
-		{
-			Name:              username,
-			SSHAuthorizedKeys: []string{sshKey},
-			UID:               &uid,
-		},
+	user := IgnitionUser{
+		Name:              username,
+		SSHAuthorizedKeys: []string{sshKey},
+		UID:               &uid,
+	}
+	if username != "root" {
+		user.Groups = []string{"wheel"}
+
+		mode := 0440
+		sudoers := IgnitionFile{}
+		sudoers.Node.Path = "/etc/sudoers.d/" + username
+		sudoers.Node.Mode = &mode
+		sudoers.FileEmbedded1.Contents.Source = "data:," + url.PathEscape(sudoersEntry(username))
+		config.Storage.Files = append(config.Storage.Files, sudoers)
 	}
+	config.Passwd.Users = []IgnitionUser{user}
 
 	return config, nil
 }
 
+// sudoersEntry returns the sudoers line granting passwordless sudo to username
+func sudoersEntry(username string) string {
+	return fmt.Sprintf("%s ALL=(ALL) NOPASSWD: ALL\n", username)
+}
+
+// loadSSHPublicKey returns the public key from a file path, the key content
+// itself, or the first key found in ~/.ssh when sshPublicKey is empty
+func loadSSHPublicKey(sshPublicKey string) (string, error) {
+	if sshPublicKey == "" {
+		return GetSSHPublicKey()
+	}
+	// Check if it's a file path
+	if _, err := os.Stat(sshPublicKey); err == nil {
+		data, err := os.ReadFile(sshPublicKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to read SSH key file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	// Assume it's the key content itself
+	return strings.TrimSpace(sshPublicKey), nil
+}
+
 // WriteIgnitionConfig writes an Ignition config to a file
 func WriteIgnitionConfig(config *IgnitionConfig, path string) error {
 	data, err := json.MarshalIndent(config, "", "  ")
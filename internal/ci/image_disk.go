@@ -0,0 +1,178 @@
+package ci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tnk4on/bootc-man/internal/cleanup"
+	"github.com/tnk4on/bootc-man/internal/lock"
+	"github.com/tnk4on/bootc-man/internal/podman"
+	"github.com/tnk4on/bootc-man/internal/vm"
+)
+
+// ImageDiskOptions configures ImageDisk
+type ImageDiskOptions struct {
+	BootcImageBuilder string
+	// SSHUser and SSHPublicKey add a user that logs in with the key; both are
+	// empty when the key is provisioned at first boot instead
+	SSHUser      string
+	SSHPublicKey string
+	Verbose      bool
+}
+
+// ImageDiskResult describes a disk converted from a container image
+type ImageDiskResult struct {
+	Path    string // raw disk image in the cache
+	ImageID string // ID (config digest) of the converted image
+	Arch    string // image architecture
+	Cached  bool   // true if an earlier conversion was reused
+}
+
+// ImageDisk returns a raw disk of a bootc container image
+// Images from a registry are pulled, local images are used as they are. The
+// image is converted with bootc-image-builder once per image ID and SSH
+// login, and the disk is cached under the bootc-man data directory.
+func ImageDisk(ctx context.Context, podmanClient *podman.Client, imageRef string, opts ImageDiskOptions) (*ImageDiskResult, error) {
+	if !strings.HasPrefix(imageRef, "localhost/") {
+		fmt.Printf("📥 Pulling %s...\n", imageRef)
+		if err := podmanClient.Pull(ctx, imageRef); err != nil {
+			// Offline, or an image that only exists locally
+			if _, inspectErr := podmanClient.ImageInspect(ctx, imageRef); inspectErr != nil {
+				return nil, fmt.Errorf("failed to pull %s: %w", imageRef, err)
+			}
+			fmt.Printf("⚠️  Warning: pull failed, using the local image: %v\n", err)
+		}
+	}
+	info, err := podmanClient.ImageInspect(ctx, imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("image %s not found: %w", imageRef, err)
+	}
+	if !info.IsBootc() {
+		return nil, fmt.Errorf("%s is not a bootc image (label %s=1 missing)", imageRef, podman.BootcLabel)
+	}
+	arch, err := vm.NormalizeArch(info.Architecture)
+	if err != nil {
+		return nil, err
+	}
+
+	var userConfig string
+	if opts.SSHPublicKey != "" {
+		key, err := loadSSHPublicKey(opts.SSHPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		userConfig = imageUserConfig(opts.SSHUser, key)
+	}
+
+	cacheDir, err := vm.GetImageCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	imageDir := filepath.Join(cacheDir, strings.TrimPrefix(info.ID, "sha256:"))
+	if err := os.MkdirAll(imageDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create image cache directory: %w", err)
+	}
+	result := &ImageDiskResult{
+		Path:    filepath.Join(imageDir, imageDiskName(userConfig)),
+		ImageID: info.ID,
+		Arch:    arch,
+	}
+
+	// Concurrent starts of the same image wait for one conversion
+	l, err := lock.Acquire(result.Path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer l.Release()
+	if _, err := os.Stat(result.Path); err == nil {
+		result.Cached = true
+		return result, nil
+	}
+
+	fmt.Printf("🔄 Converting %s to a disk image (first use of this image)...\n", imageRef)
+	if err := convertImageDisk(ctx, podmanClient, imageRef, userConfig, result.Path, opts); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// convertImageDisk converts imageRef to a raw disk at dest with the convert
+// stage machinery
+func convertImageDisk(ctx context.Context, podmanClient *podman.Client, imageRef, userConfig, dest string, opts ImageDiskOptions) error {
+	workDir := dest + ".tmp"
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	// bootc-image-builder runs as root under sudo, so the directory may not be
+	// removable; it is then recorded for 'bootc-man system prune'
+	workCleanup := cleanup.Register("remove image conversion", func(context.Context) error {
+		return os.RemoveAll(workDir)
+	}, cleanup.Resource{Path: workDir})
+	defer func() { _ = workCleanup.Run(context.Background()) }()
+
+	format := ConvertFormat{Type: "raw"}
+	if userConfig != "" {
+		format.Config = filepath.Join(workDir, "config.toml")
+		if err := os.WriteFile(format.Config, []byte(userConfig), 0644); err != nil {
+			return fmt.Errorf("failed to write config.toml: %w", err)
+		}
+	}
+	pipeline := &Pipeline{
+		Metadata: PipelineMetadata{Name: "disk"},
+		Spec: PipelineSpec{
+			Convert: &ConvertConfig{Enabled: true, Formats: []ConvertFormat{format}},
+		},
+		baseDir: workDir,
+	}
+	stage := NewConvertStageWithImage(pipeline, podmanClient, imageRef, opts.Verbose, opts.BootcImageBuilder)
+	if err := stage.Execute(ctx); err != nil {
+		return err
+	}
+
+	// Publish the disk atomically so an interrupted conversion is never reused
+	output := filepath.Join(workDir, "output", "images", "disk.raw")
+	if err := os.Rename(output, dest); err != nil {
+		// The work directory may be on another filesystem; copy next to dest
+		// first so that dest only ever holds a complete disk
+		partial := dest + ".partial"
+		if err := copyFile(output, partial); err != nil {
+			_ = os.Remove(partial)
+			return fmt.Errorf("failed to move converted disk: %w", err)
+		}
+		if err := os.Rename(partial, dest); err != nil {
+			_ = os.Remove(partial)
+			return fmt.Errorf("failed to move converted disk: %w", err)
+		}
+	}
+	return nil
+}
+
+// imageUserConfig returns the bootc-image-builder config.toml that adds an
+// SSH login to a converted disk
+// Non-root users are added to the wheel group.
+func imageUserConfig(user, sshKey string) string {
+	var b strings.Builder
+	b.WriteString("[[customizations.user]]\n")
+	fmt.Fprintf(&b, "name = %s\n", strconv.Quote(user))
+	fmt.Fprintf(&b, "key = %s\n", strconv.Quote(sshKey))
+	if user != "root" {
+		b.WriteString("groups = [\"wheel\"]\n")
+	}
+	return b.String()
+}
+
+// imageDiskName returns the cache file name of a disk converted with
+// userConfig, so disks with different SSH logins do not collide
+func imageDiskName(userConfig string) string {
+	if userConfig == "" {
+		return "disk.raw"
+	}
+	sum := sha256.Sum256([]byte(userConfig))
+	return "disk-" + hex.EncodeToString(sum[:])[:12] + ".raw"
+}
@@ -0,0 +1,51 @@
+package ci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImageUserConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		wantGroups bool
+	}{
+		{"regular user joins wheel", "user", true},
+		{"root has no groups", "root", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := imageUserConfig(tt.user, "ssh-ed25519 AAAA test@host")
+			for _, want := range []string{
+				"[[customizations.user]]\n",
+				`name = "` + tt.user + `"`,
+				`key = "ssh-ed25519 AAAA test@host"`,
+			} {
+				if !strings.Contains(got, want) {
+					t.Errorf("imageUserConfig() = %q, missing %q", got, want)
+				}
+			}
+			if hasGroups := strings.Contains(got, `groups = ["wheel"]`); hasGroups != tt.wantGroups {
+				t.Errorf("imageUserConfig() wheel group = %v, want %v", hasGroups, tt.wantGroups)
+			}
+		})
+	}
+}
+
+func TestImageDiskName(t *testing.T) {
+	if got := imageDiskName(""); got != "disk.raw" {
+		t.Errorf("imageDiskName(\"\") = %q, want disk.raw", got)
+	}
+	a := imageDiskName(imageUserConfig("user", "ssh-ed25519 AAAA a"))
+	b := imageDiskName(imageUserConfig("user", "ssh-ed25519 AAAA b"))
+	if a == b {
+		t.Errorf("disks with different SSH keys share the name %q", a)
+	}
+	if !strings.HasPrefix(a, "disk-") || !strings.HasSuffix(a, ".raw") || len(a) != len("disk-")+12+len(".raw") {
+		t.Errorf("imageDiskName() = %q, want disk-<12 hex>.raw", a)
+	}
+	if again := imageDiskName(imageUserConfig("user", "ssh-ed25519 AAAA a")); again != a {
+		t.Errorf("imageDiskName() not stable: %q != %q", again, a)
+	}
+}
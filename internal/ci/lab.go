@@ -0,0 +1,141 @@
+package ci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/vm"
+	"gopkg.in/yaml.v3"
+)
+
+// LabDefinition describes VMs that are started and stopped as a unit on a
+// shared lab network
+type LabDefinition struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Kind       string           `yaml:"kind"`
+	Metadata   PipelineMetadata `yaml:"metadata"`
+	Spec       LabSpec          `yaml:"spec"`
+	baseDir    string           // Directory of the lab file (for resolving relative paths)
+}
+
+// LabSpec contains the lab specification
+type LabSpec struct {
+	// Subnet of the lab network (default: 10.89.0.0/24)
+	Subnet string  `yaml:"subnet,omitempty"`
+	VMs    []LabVM `yaml:"vms"`
+}
+
+// LabVM is a VM of a lab, booted from the disk image of a pipeline
+// VMs are started in the order they are listed.
+type LabVM struct {
+	Name     string `yaml:"name"`
+	Pipeline string `yaml:"pipeline"`     // Pipeline file, relative to the lab file
+	IP       string `yaml:"ip,omitempty"` // Static lab IP (default: next free address from .10)
+	CPUs     int    `yaml:"cpus,omitempty"`
+	Memory   int    `yaml:"memory,omitempty"` // MB
+}
+
+// LoadLabDefinition loads a lab definition from a YAML file
+func LoadLabDefinition(path string) (*LabDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lab file %s: %w", path, err)
+	}
+
+	var lab LabDefinition
+	if err := yaml.Unmarshal(data, &lab); err != nil {
+		return nil, fmt.Errorf("failed to parse lab file %s: %w", path, err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve lab file path: %w", err)
+	}
+	lab.baseDir = filepath.Dir(absPath)
+
+	if err := lab.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid lab definition: %w", err)
+	}
+	return &lab, nil
+}
+
+// Validate validates the lab definition
+func (l *LabDefinition) Validate() error {
+	if l.APIVersion != config.PipelineAPIVersion {
+		return fmt.Errorf("unsupported apiVersion: %q (expected %s)", l.APIVersion, config.PipelineAPIVersion)
+	}
+	if l.Kind != config.LabKind {
+		return fmt.Errorf("unsupported kind: %q (expected %s)", l.Kind, config.LabKind)
+	}
+	if l.Metadata.Name == "" {
+		return fmt.Errorf("metadata.name is required")
+	}
+	if len(l.Spec.VMs) == 0 {
+		return fmt.Errorf("spec.vms: at least one VM is required")
+	}
+
+	// Assigning the addresses checks names, subnet and IPs in one go
+	if _, err := l.NewLab(); err != nil {
+		return err
+	}
+	for i, v := range l.Spec.VMs {
+		if v.Pipeline == "" {
+			return fmt.Errorf("spec.vms[%d].pipeline is required", i)
+		}
+		if l.baseDir != "" {
+			if _, err := os.Stat(l.PipelinePath(v)); err != nil {
+				return fmt.Errorf("spec.vms[%d].pipeline: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// NewLab creates the lab network state with addresses for all VMs
+// VMs with a static IP are added first so automatic addresses avoid them.
+func (l *LabDefinition) NewLab() (*vm.Lab, error) {
+	lab, err := vm.NewLab(l.Metadata.Name, l.Spec.Subnet)
+	if err != nil {
+		return nil, fmt.Errorf("metadata.name: %w", err)
+	}
+	if err := l.AssignAddresses(lab); err != nil {
+		return nil, err
+	}
+	return lab, nil
+}
+
+// AssignAddresses adds the lab's VMs to a lab network, keeping the addresses
+// of VMs that are already members
+func (l *LabDefinition) AssignAddresses(lab *vm.Lab) error {
+	seen := map[string]bool{}
+	for i, v := range l.Spec.VMs {
+		if v.Name == "" || vm.SanitizeVMName(v.Name) != v.Name {
+			return fmt.Errorf("spec.vms[%d].name: invalid VM name %q (use letters, digits and hyphens)", i, v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("spec.vms[%d].name: duplicate VM name %q", i, v.Name)
+		}
+		seen[v.Name] = true
+	}
+	for _, static := range []bool{true, false} {
+		for i, v := range l.Spec.VMs {
+			if (v.IP != "") != static {
+				continue
+			}
+			if _, err := lab.AddMember(v.Name, v.IP); err != nil {
+				return fmt.Errorf("spec.vms[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PipelinePath returns the absolute path of a VM's pipeline file
+func (l *LabDefinition) PipelinePath(v LabVM) string {
+	if filepath.IsAbs(v.Pipeline) {
+		return v.Pipeline
+	}
+	return filepath.Join(l.baseDir, v.Pipeline)
+}
@@ -0,0 +1,89 @@
+package ci
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tnk4on/bootc-man/internal/testutil"
+)
+
+func TestLoadLabDefinition(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFile(t, filepath.Join(dir, "registry"), "bootc-ci.yaml", testutil.SamplePipelineYAML())
+	testutil.WriteFile(t, filepath.Join(dir, "node"), "bootc-ci.yaml", testutil.SamplePipelineYAML())
+
+	header := "apiVersion: bootc-man/v1\nkind: Lab\nmetadata:\n  name: cluster\n"
+	tests := []struct {
+		name        string
+		yaml        string
+		errContains string
+	}{
+		{
+			name: "valid",
+			yaml: header + `spec:
+  vms:
+    - name: node1
+      pipeline: node/bootc-ci.yaml
+    - name: registry
+      pipeline: registry/bootc-ci.yaml
+      ip: 10.89.0.10
+`,
+		},
+		{
+			name:        "wrong kind",
+			yaml:        strings.Replace(header, "kind: Lab", "kind: Pipeline", 1) + "spec:\n  vms:\n    - name: a\n      pipeline: node/bootc-ci.yaml\n",
+			errContains: "unsupported kind",
+		},
+		{
+			name:        "no vms",
+			yaml:        header + "spec:\n  vms: []\n",
+			errContains: "spec.vms",
+		},
+		{
+			name:        "duplicate name",
+			yaml:        header + "spec:\n  vms:\n    - name: a\n      pipeline: node/bootc-ci.yaml\n    - name: a\n      pipeline: node/bootc-ci.yaml\n",
+			errContains: "duplicate VM name",
+		},
+		{
+			name:        "missing pipeline",
+			yaml:        header + "spec:\n  vms:\n    - name: a\n      pipeline: missing/bootc-ci.yaml\n",
+			errContains: "spec.vms[0].pipeline",
+		},
+		{
+			name:        "ip outside subnet",
+			yaml:        header + "spec:\n  subnet: 172.30.0.0/24\n  vms:\n    - name: a\n      pipeline: node/bootc-ci.yaml\n      ip: 10.89.0.10\n",
+			errContains: "not in lab subnet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := testutil.WriteFile(t, dir, "bootc-lab.yaml", tt.yaml)
+			def, err := LoadLabDefinition(path)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("LoadLabDefinition() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadLabDefinition() error = %v", err)
+			}
+
+			// Static addresses are reserved before automatic ones
+			lab, err := def.NewLab()
+			if err != nil {
+				t.Fatalf("NewLab() error = %v", err)
+			}
+			if ip := lab.Member("registry").IP; ip != "10.89.0.10" {
+				t.Errorf("registry IP = %s, want 10.89.0.10", ip)
+			}
+			if ip := lab.Member("node1").IP; ip != "10.89.0.11" {
+				t.Errorf("node1 IP = %s, want 10.89.0.11", ip)
+			}
+			if got := def.PipelinePath(def.Spec.VMs[0]); got != filepath.Join(dir, "node", "bootc-ci.yaml") {
+				t.Errorf("PipelinePath() = %q", got)
+			}
+		})
+	}
+}
@@ -2,12 +2,15 @@
 package ci
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/lock"
+	"github.com/tnk4on/bootc-man/internal/vm"
 	"gopkg.in/yaml.v3"
 )
 
@@ -139,6 +142,24 @@ type BootTestConfig struct {
 	Timeout int      `yaml:"timeout,omitempty"`
 	Checks  []string `yaml:"checks,omitempty"`
 	GUI     bool     `yaml:"gui,omitempty"` // Display VM console in GUI window (macOS only)
+	// Provisioning delivers the SSH key at first boot instead of baking it
+	// into the image: "ignition", "cloud-init" or "none" (default)
+	Provisioning string `yaml:"provisioning,omitempty"`
+	// Arch is the architecture of the image under test (amd64 or arm64);
+	// other architectures than the host's are emulated with QEMU TCG
+	Arch string `yaml:"arch,omitempty"`
+	// SecureBoot boots with UEFI secure boot enforced and asserts it in the guest
+	SecureBoot bool `yaml:"secureBoot,omitempty"`
+	// TPM attaches an emulated TPM 2.0 and asserts that the boot was measured
+	TPM bool `yaml:"tpm,omitempty"`
+	// PCRs are expected SHA-256 PCR values by index (requires tpm)
+	PCRs map[int]string `yaml:"pcrs,omitempty"`
+	// Firmware boots with "uefi" (default) or "bios" (SeaBIOS, x86_64 only)
+	// and asserts the boot mode in the guest when set
+	Firmware string `yaml:"firmware,omitempty"`
+	// Profile is a VM profile from the bootc-man config (vm.profiles) that
+	// sets CPUs, memory, disk size, firmware and NIC model of the test VM
+	Profile string `yaml:"profile,omitempty"`
 }
 
 // UpgradeTestConfig defines upgrade test settings
@@ -227,6 +248,26 @@ func (p *Pipeline) Validate() error {
 		return fmt.Errorf("spec.source.containerfile is required")
 	}
 
+	if p.Spec.Test != nil && p.Spec.Test.Boot != nil {
+		if err := vm.ValidateProvisioning(p.Spec.Test.Boot.Provisioning); err != nil {
+			return fmt.Errorf("spec.test.boot.provisioning: %w", err)
+		}
+		if arch := p.Spec.Test.Boot.Arch; arch != "" {
+			if _, err := vm.NormalizeArch(arch); err != nil {
+				return fmt.Errorf("spec.test.boot.arch: %w", err)
+			}
+		}
+		if err := vm.ValidatePCRs(p.Spec.Test.Boot.PCRs); err != nil {
+			return fmt.Errorf("spec.test.boot.pcrs: %w", err)
+		}
+		if len(p.Spec.Test.Boot.PCRs) > 0 && !p.Spec.Test.Boot.TPM {
+			return fmt.Errorf("spec.test.boot.pcrs requires spec.test.boot.tpm")
+		}
+		if err := vm.ValidateFirmware(p.Spec.Test.Boot.Firmware, p.ImageArch(), p.Spec.Test.Boot.SecureBoot); err != nil {
+			return fmt.Errorf("spec.test.boot.firmware: %w", err)
+		}
+	}
+
 	// Validate file paths exist
 	if err := p.validatePaths(); err != nil {
 		return err
@@ -338,3 +379,31 @@ func (p *Pipeline) ResolveContextPath() (string, error) {
 func (p *Pipeline) BaseDir() string {
 	return p.baseDir
 }
+
+// Lock takes the lock on the pipeline's output directory without waiting
+// A pipeline run holds it from start to end, so two runs of the same
+// pipeline checkout never write the same build artifacts.
+func (p *Pipeline) Lock() (*lock.Lock, error) {
+	l, err := lock.TryAcquire(filepath.Join(p.baseDir, "output", ".bootc-man.lock"))
+	var held *lock.HeldError
+	if errors.As(err, &held) {
+		if held.PID > 0 {
+			return nil, fmt.Errorf("pipeline '%s' already running (pid %d)", p.Metadata.Name, held.PID)
+		}
+		return nil, fmt.Errorf("pipeline '%s' already running", p.Metadata.Name)
+	}
+	return l, err
+}
+
+// ImageArch returns the architecture of the image the pipeline tests
+// test.boot.arch takes precedence; a build for a single platform implies its
+// architecture. An empty string means the host architecture.
+func (p *Pipeline) ImageArch() string {
+	if p.Spec.Test != nil && p.Spec.Test.Boot != nil && p.Spec.Test.Boot.Arch != "" {
+		return p.Spec.Test.Boot.Arch
+	}
+	if p.Spec.Build != nil && len(p.Spec.Build.Platforms) == 1 {
+		return p.Spec.Build.Platforms[0]
+	}
+	return ""
+}
@@ -323,6 +323,68 @@ func TestPipelineValidate(t *testing.T) {
 			wantErr:     true,
 			errContains: "spec.source.containerfile is required",
 		},
+		{
+			name: "unsupported provisioning",
+			pipeline: Pipeline{
+				APIVersion: "bootc-man/v1",
+				Kind:       "Pipeline",
+				Metadata:   PipelineMetadata{Name: "test"},
+				Spec: PipelineSpec{
+					Source: SourceConfig{Containerfile: "Containerfile"},
+					Test:   &TestConfig{Boot: &BootTestConfig{Provisioning: "kickstart"}},
+				},
+			},
+			wantErr:     true,
+			errContains: "spec.test.boot.provisioning",
+		},
+		{
+			name: "unsupported arch",
+			pipeline: Pipeline{
+				APIVersion: "bootc-man/v1",
+				Kind:       "Pipeline",
+				Metadata:   PipelineMetadata{Name: "test"},
+				Spec: PipelineSpec{
+					Source: SourceConfig{Containerfile: "Containerfile"},
+					Test:   &TestConfig{Boot: &BootTestConfig{Arch: "riscv64"}},
+				},
+			},
+			wantErr:     true,
+			errContains: "spec.test.boot.arch",
+		},
+		{
+			name: "pcrs without tpm",
+			pipeline: Pipeline{
+				APIVersion: "bootc-man/v1",
+				Kind:       "Pipeline",
+				Metadata:   PipelineMetadata{Name: "test"},
+				Spec: PipelineSpec{
+					Source: SourceConfig{Containerfile: "Containerfile"},
+					Test: &TestConfig{Boot: &BootTestConfig{
+						PCRs: map[int]string{7: "3d458cfe55cc03ea1f443f1562beec8df51c75e14a9fcf9a7234a13f198e7969"},
+					}},
+				},
+			},
+			wantErr:     true,
+			errContains: "spec.test.boot.pcrs",
+		},
+		{
+			name: "bios with secure boot",
+			pipeline: Pipeline{
+				APIVersion: "bootc-man/v1",
+				Kind:       "Pipeline",
+				Metadata:   PipelineMetadata{Name: "test"},
+				Spec: PipelineSpec{
+					Source: SourceConfig{Containerfile: "Containerfile"},
+					Test: &TestConfig{Boot: &BootTestConfig{
+						Arch:       "amd64",
+						Firmware:   "bios",
+						SecureBoot: true,
+					}},
+				},
+			},
+			wantErr:     true,
+			errContains: "spec.test.boot.firmware",
+		},
 	}
 
 	for _, tt := range tests {
@@ -495,6 +557,34 @@ func TestPipelineBaseDir(t *testing.T) {
 	}
 }
 
+func TestPipelineImageArch(t *testing.T) {
+	tests := []struct {
+		name string
+		spec PipelineSpec
+		want string
+	}{
+		{"default", PipelineSpec{}, ""},
+		{"single platform", PipelineSpec{Build: &BuildConfig{Platforms: []string{"linux/arm64"}}}, "linux/arm64"},
+		{"multiple platforms", PipelineSpec{Build: &BuildConfig{Platforms: []string{"linux/amd64", "linux/arm64"}}}, ""},
+		{
+			"boot arch wins",
+			PipelineSpec{
+				Build: &BuildConfig{Platforms: []string{"linux/amd64", "linux/arm64"}},
+				Test:  &TestConfig{Boot: &BootTestConfig{Arch: "arm64"}},
+			},
+			"arm64",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipeline{Spec: tt.spec}
+			if got := p.ImageArch(); got != tt.want {
+				t.Errorf("ImageArch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // containsString is a helper to check if a string contains a substring
 func containsString(s, substr string) bool {
 	return len(substr) == 0 || (len(s) >= len(substr) && findSubstring(s, substr))
@@ -0,0 +1,95 @@
+package ci
+
+import (
+	"fmt"
+
+	"github.com/tnk4on/bootc-man/internal/vm"
+	"gopkg.in/yaml.v3"
+)
+
+// cloudConfig is the subset of cloud-config used for provisioning test VMs
+type cloudConfig struct {
+	Users             []cloudConfigUser `yaml:"users,omitempty"`
+	DisableRoot       *bool             `yaml:"disable_root,omitempty"`
+	SSHAuthorizedKeys []string          `yaml:"ssh_authorized_keys,omitempty"`
+}
+
+// cloudConfigUser is a user entry in cloud-config
+type cloudConfigUser struct {
+	Name              string   `yaml:"name"`
+	Groups            string   `yaml:"groups,omitempty"`
+	Sudo              string   `yaml:"sudo,omitempty"`
+	SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys"`
+}
+
+// GenerateCloudInitUserData generates cloud-config user-data with SSH keys
+// Non-root users are added to the wheel group and get passwordless sudo.
+func GenerateCloudInitUserData(sshPublicKey string, username string) ([]byte, error) {
+	sshKey, err := loadSSHPublicKey(sshPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg cloudConfig
+	if username == "root" {
+		enabled := false
+		cfg.DisableRoot = &enabled
+		cfg.SSHAuthorizedKeys = []string{sshKey}
+	} else {
+		cfg.Users = []cloudConfigUser{
+			{
+				Name:              username,
+				Groups:            "wheel",
+				Sudo:              "ALL=(ALL) NOPASSWD:ALL",
+				SSHAuthorizedKeys: []string{sshKey},
+			},
+		}
+	}
+
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cloud-config: %w", err)
+	}
+	return append([]byte("#cloud-config\n"), data...), nil
+}
+
+// GenerateCloudInitMetaData generates NoCloud meta-data for a VM
+func GenerateCloudInitMetaData(instanceID, hostname string) []byte {
+	return []byte(fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", instanceID, hostname))
+}
+
+// PrepareProvisioning writes first-boot provisioning data for a VM
+// destBase is the path prefix for the generated files; ".ign" or
+// "-seed.iso" is appended. Returns nil when method is "none" or empty.
+func PrepareProvisioning(method, destBase, vmName, sshPublicKey, username string) (*vm.Provisioning, error) {
+	if err := vm.ValidateProvisioning(method); err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case vm.ProvisioningIgnition:
+		cfg, err := GenerateIgnitionConfig(sshPublicKey, username)
+		if err != nil {
+			return nil, err
+		}
+		path := destBase + ".ign"
+		if err := WriteIgnitionConfig(cfg, path); err != nil {
+			return nil, err
+		}
+		return &vm.Provisioning{Method: method, IgnitionFile: path}, nil
+
+	case vm.ProvisioningCloudInit:
+		userData, err := GenerateCloudInitUserData(sshPublicKey, username)
+		if err != nil {
+			return nil, err
+		}
+		path := destBase + "-seed.iso"
+		if err := vm.WriteCloudInitSeed(path, userData, GenerateCloudInitMetaData(vmName, vmName), nil); err != nil {
+			return nil, err
+		}
+		return &vm.Provisioning{Method: method, CloudInitSeed: path}, nil
+
+	default:
+		return nil, nil
+	}
+}
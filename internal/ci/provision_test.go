@@ -0,0 +1,157 @@
+package ci
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tnk4on/bootc-man/internal/vm"
+	"gopkg.in/yaml.v3"
+)
+
+const testProvisionKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB test@example.com"
+
+func TestGenerateCloudInitUserData(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "regular user",
+			username: "user",
+			contains: []string{"name: user", "groups: wheel", "NOPASSWD:ALL", testProvisionKey},
+			excludes: []string{"disable_root"},
+		},
+		{
+			name:     "root user",
+			username: "root",
+			contains: []string{"disable_root: false", testProvisionKey},
+			excludes: []string{"users:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := GenerateCloudInitUserData(testProvisionKey, tt.username)
+			if err != nil {
+				t.Fatalf("GenerateCloudInitUserData() error = %v", err)
+			}
+			got := string(data)
+			if !strings.HasPrefix(got, "#cloud-config\n") {
+				t.Errorf("user-data does not start with #cloud-config: %q", got)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("user-data missing %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(got, s) {
+					t.Errorf("user-data unexpectedly contains %q:\n%s", s, got)
+				}
+			}
+
+			var parsed map[string]interface{}
+			if err := yaml.Unmarshal(data, &parsed); err != nil {
+				t.Errorf("user-data is not valid YAML: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateIgnitionConfigSudoers(t *testing.T) {
+	cfg, err := GenerateIgnitionConfig(testProvisionKey, "user")
+	if err != nil {
+		t.Fatalf("GenerateIgnitionConfig() error = %v", err)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+
+	// Ignition expects path/mode/contents at the top level of each file entry
+	var raw struct {
+		Passwd struct {
+			Users []struct {
+				Groups []string `json:"groups"`
+			} `json:"users"`
+		} `json:"passwd"`
+		Storage struct {
+			Files []map[string]json.RawMessage `json:"files"`
+		} `json:"storage"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("failed to unmarshal config: %v", err)
+	}
+
+	if len(raw.Passwd.Users) != 1 || len(raw.Passwd.Users[0].Groups) != 1 || raw.Passwd.Users[0].Groups[0] != "wheel" {
+		t.Errorf("user groups = %+v, want [wheel]", raw.Passwd.Users)
+	}
+	if len(raw.Storage.Files) != 1 {
+		t.Fatalf("len(storage.files) = %d, want 1", len(raw.Storage.Files))
+	}
+	for _, key := range []string{"path", "mode", "contents"} {
+		if _, ok := raw.Storage.Files[0][key]; !ok {
+			t.Errorf("storage.files[0] missing %q: %s", key, data)
+		}
+	}
+	if !strings.Contains(string(raw.Storage.Files[0]["path"]), "/etc/sudoers.d/user") {
+		t.Errorf("storage.files[0].path = %s, want /etc/sudoers.d/user", raw.Storage.Files[0]["path"])
+	}
+}
+
+func TestPrepareProvisioning(t *testing.T) {
+	tests := []struct {
+		method   string
+		wantNil  bool
+		wantFile string
+	}{
+		{method: "", wantNil: true},
+		{method: vm.ProvisioningNone, wantNil: true},
+		{method: vm.ProvisioningIgnition, wantFile: "test-vm.ign"},
+		{method: vm.ProvisioningCloudInit, wantFile: "test-vm-seed.iso"},
+	}
+
+	for _, tt := range tests {
+		t.Run("method="+tt.method, func(t *testing.T) {
+			dir := t.TempDir()
+			prov, err := PrepareProvisioning(tt.method, filepath.Join(dir, "test-vm"), "test-vm", testProvisionKey, "user")
+			if err != nil {
+				t.Fatalf("PrepareProvisioning() error = %v", err)
+			}
+			if tt.wantNil {
+				if prov != nil {
+					t.Errorf("PrepareProvisioning() = %+v, want nil", prov)
+				}
+				return
+			}
+			if prov == nil || prov.Method != tt.method {
+				t.Fatalf("PrepareProvisioning() = %+v, want method %q", prov, tt.method)
+			}
+
+			files := prov.Files()
+			if len(files) != 1 || files[0] != filepath.Join(dir, tt.wantFile) {
+				t.Fatalf("Files() = %v, want [%s]", files, tt.wantFile)
+			}
+			if _, err := os.Stat(files[0]); err != nil {
+				t.Errorf("provisioning file not written: %v", err)
+			}
+
+			prov.Remove()
+			if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+				t.Errorf("Remove() did not delete %s", files[0])
+			}
+		})
+	}
+}
+
+func TestPrepareProvisioningInvalidMethod(t *testing.T) {
+	if _, err := PrepareProvisioning("kickstart", filepath.Join(t.TempDir(), "vm"), "vm", testProvisionKey, "user"); err == nil {
+		t.Error("PrepareProvisioning() expected error for unsupported method")
+	}
+}
@@ -1,7 +1,11 @@
 package ci
 
 import (
+	"cmp"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,7 +14,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/tnk4on/bootc-man/internal/cleanup"
 	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/sshclient"
 	"github.com/tnk4on/bootc-man/internal/vm"
 )
 
@@ -30,6 +36,23 @@ func NewTestStage(pipeline *Pipeline, imageTag string, verbose bool) *TestStage
 	}
 }
 
+// testVMProfile returns the shape of the test VM: the VM defaults from the
+// bootc-man config, or the named profile
+func testVMProfile(name string) (config.VMProfile, error) {
+	cfg, err := config.Load("")
+	if err != nil {
+		return config.VMProfile{}, fmt.Errorf("failed to load config: %w", err)
+	}
+	if name == "" {
+		return config.VMProfile{CPUs: cfg.VM.CPUs, Memory: cfg.VM.Memory}, nil
+	}
+	profile, err := cfg.VM.Profile(name)
+	if err != nil {
+		return config.VMProfile{}, fmt.Errorf("spec.test.boot.profile: %w", err)
+	}
+	return profile, nil
+}
+
 // Execute runs the test stage
 func (t *TestStage) Execute(ctx context.Context) error {
 	if t.pipeline.Spec.Test == nil {
@@ -41,9 +64,22 @@ func (t *TestStage) Execute(ctx context.Context) error {
 		return fmt.Errorf("boot test is not enabled")
 	}
 
-	// Find raw disk image file from convert stage
-	// bootc-man uses raw format exclusively for cross-platform compatibility
-	diskImagePath, err := t.findDiskImageFile()
+	// VM shape: the VM defaults from the config, or test.boot.profile
+	profile, err := testVMProfile(cfg.Boot.Profile)
+	if err != nil {
+		return err
+	}
+
+	// VM driver selected in the bootc-man config (qemu, libvirt or a plugin)
+	// It decides which image formats the test VM boots and how its disk is made.
+	var driverName, libvirtURI string
+	if bmCfg, err := config.Load(""); err == nil {
+		driverName, libvirtURI = bmCfg.VM.Driver, bmCfg.VM.LibvirtURI
+	}
+	vmType := vm.DriverVMType(driverName)
+
+	// Find disk image file from convert stage
+	diskImagePath, err := t.findDiskImageFile(vmType)
 	if err != nil {
 		return fmt.Errorf("failed to find disk image file: %w\n   Make sure to run the convert stage first: bootc-man ci run --stage convert", err)
 	}
@@ -52,51 +88,124 @@ func (t *TestStage) Execute(ctx context.Context) error {
 		fmt.Printf("Found disk image file: %s\n", diskImagePath)
 	}
 
-	// Generate VM name from pipeline name with ci-test prefix to avoid conflicts with vm start
+	// The test VM and its disk are named after the pipeline and a per-run
+	// ID, so concurrent runs of the same pipeline (e.g. two CI jobs on one
+	// runner) never share them
 	pipelineName := t.pipeline.Metadata.Name
 	pipelineName = strings.ReplaceAll(pipelineName, "/", "-")
 	pipelineName = strings.ReplaceAll(pipelineName, " ", "-")
 	pipelineName = strings.ToLower(pipelineName)
-	vmName := sanitizeVMName("ci-test-" + pipelineName)
+	runID := testRunID()
+	vmName := testVMName(pipelineName, runID)
+	if t.verbose {
+		fmt.Printf("Test VM: %s\n", vmName)
+	}
 
-	// Copy disk image to temporary location for test execution
-	testDiskPath := filepath.Join(config.TempDataDir(), fmt.Sprintf("bootc-man-test-%s.raw", pipelineName))
+	// Hold the VM lock for the whole test so prune leaves the VM alone
+	vmLock, err := vm.LockVM(vmName)
+	if err != nil {
+		return err
+	}
+	defer vmLock.Release()
 
-	// Clean up any existing temporary test disk from previous failed run
-	if _, err := os.Stat(testDiskPath); err == nil {
-		if t.verbose {
-			fmt.Printf("Removing stale test disk from previous run: %s\n", testDiskPath)
+	// Derive a private test disk from the converted image
+	// On QEMU this is a qcow2 overlay backed by the converted image, so no
+	// copy is made and several test VMs can share the image read-only.
+	// ISO installers are attached read-only and install onto a blank disk.
+	testDiskBase := filepath.Join(config.TempDataDir(), fmt.Sprintf("%s%s-%s", config.TestDiskPrefix, pipelineName, runID))
+	testDiskPath, _, err := vm.PlanVMDisk(diskImagePath, testDiskBase, vmType)
+	if err != nil {
+		return err
+	}
+	installDiskPath := ""
+	if testDiskPath == diskImagePath {
+		installDiskPath = testDiskBase + "-install.qcow2"
+	}
+
+	// Remove the test disk after the test, or when interrupted
+	// Test disks of crashed runs are left to 'bootc-man system prune'
+	var diskResources []cleanup.Resource
+	for _, path := range []string{testDiskPath, installDiskPath} {
+		if path != "" && path != diskImagePath {
+			diskResources = append(diskResources, cleanup.Resource{Path: path})
 		}
-		os.Remove(testDiskPath)
 	}
+	diskCleanup := cleanup.Register("remove test disk", func(context.Context) error {
+		var errs []error
+		for _, r := range diskResources {
+			if t.verbose {
+				fmt.Printf("🧹 Cleaning up test disk: %s\n", r.Path)
+			}
+			if err := os.Remove(r.Path); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("⚠️  Warning: Failed to remove test disk: %v\n", err)
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}, diskResources...)
+	defer func() { _ = diskCleanup.Run(context.Background()) }()
 
-	// Copy disk image for test execution
 	if t.verbose {
-		fmt.Printf("Copying disk image for test execution...\n")
-		fmt.Printf("  Source: %s\n", diskImagePath)
-		fmt.Printf("  Dest:   %s\n", testDiskPath)
+		fmt.Printf("Preparing test disk...\n")
+		fmt.Printf("  Base: %s\n", diskImagePath)
+		fmt.Printf("  Dest: %s\n", testDiskPath)
 	}
-	if err := copyFile(diskImagePath, testDiskPath); err != nil {
-		return fmt.Errorf("failed to copy disk image: %w", err)
+	testDiskPath, _, err = vm.CreateVMDisk(ctx, diskImagePath, testDiskBase, vmType, t.verbose)
+	if err != nil {
+		return fmt.Errorf("failed to prepare test disk: %w", err)
+	}
+	if testDiskPath != diskImagePath {
+		if err := vm.GrowDisk(ctx, testDiskPath, profile.DiskSize, t.verbose); err != nil {
+			return fmt.Errorf("failed to prepare test disk: %w", err)
+		}
 	}
 	if t.verbose {
-		fmt.Println("✅ Disk image copied")
+		fmt.Println("✅ Test disk ready")
 	}
 
-	// Schedule cleanup of test disk after test completion
-	defer func() {
-		if t.verbose {
-			fmt.Printf("🧹 Cleaning up test disk: %s\n", testDiskPath)
+	// Get SSH key path
+	// With first-boot provisioning a dedicated keypair is generated for the
+	// test VM, so the runner needs no personal SSH key
+	var sshKeyPath string
+	if cfg.Boot.Provisioning != "" && cfg.Boot.Provisioning != vm.ProvisioningNone {
+		sshKeyPath, err = vm.EnsureVMSSHKey(vmName)
+		if err != nil {
+			return err
 		}
-		if err := os.Remove(testDiskPath); err != nil {
-			fmt.Printf("⚠️  Warning: Failed to remove test disk: %v\n", err)
+		keyCleanup := cleanup.Register("remove test SSH key", func(context.Context) error {
+			return vm.RemoveVMSSHKey(vmName)
+		}, cleanup.Resource{Path: filepath.Dir(sshKeyPath)})
+		defer func() { _ = keyCleanup.Run(context.Background()) }()
+	} else {
+		sshKeyPath, err = t.findSSHKeyPath()
+		if err != nil {
+			return err
 		}
-	}()
+	}
 
-	// Get SSH key path
-	sshKeyPath, err := t.findSSHKeyPath()
+	// The test disk is fresh, so the VM comes up with new host keys
+	_ = vm.ForgetHostKey(vmName)
+	defer func() { _ = vm.ForgetHostKey(vmName) }()
+
+	// Generate first-boot provisioning data so the image does not need a
+	// baked-in SSH key
+	provisioning, err := PrepareProvisioning(cfg.Boot.Provisioning, testDiskBase, vmName, sshKeyPath+".pub", "user")
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to prepare %s provisioning: %w", cfg.Boot.Provisioning, err)
+	}
+	if provisioning != nil {
+		var files []cleanup.Resource
+		for _, f := range provisioning.Files() {
+			files = append(files, cleanup.Resource{Path: f})
+		}
+		provisioningCleanup := cleanup.Register("remove provisioning files", func(context.Context) error {
+			provisioning.Remove()
+			return nil
+		}, files...)
+		defer func() { _ = provisioningCleanup.Run(context.Background()) }()
+		if t.verbose {
+			fmt.Printf("Provisioning via %s: %s\n", provisioning.Method, strings.Join(provisioning.Files(), ", "))
+		}
 	}
 
 	// Determine if GUI should be enabled
@@ -110,15 +219,25 @@ func (t *TestStage) Execute(ctx context.Context) error {
 	// Create VM driver for current platform
 	// SSHPort is set to 0 for dynamic allocation via port-alloc.dat
 	vmOpts := vm.VMOptions{
-		Name:       vmName,
-		DiskImage:  testDiskPath,
-		CPUs:       2,
-		Memory:     4096,
-		SSHKeyPath: sshKeyPath,
-		SSHUser:    "user",
-		SSHPort:    0, // Dynamic allocation
-		GUI:        guiEnabled,
-	}
+		Name:         vmName,
+		DiskImage:    testDiskPath,
+		InstallDisk:  installDiskPath,
+		CPUs:         profile.CPUs,
+		Memory:       profile.Memory,
+		SSHKeyPath:   sshKeyPath,
+		SSHUser:      "user",
+		SSHPort:      0, // Dynamic allocation
+		GUI:          guiEnabled,
+		Provisioning: provisioning,
+		Arch:         t.pipeline.ImageArch(),
+		SecureBoot:   cfg.Boot.SecureBoot,
+		TPM:          cfg.Boot.TPM,
+		Firmware:     cmp.Or(cfg.Boot.Firmware, profile.Firmware),
+		NICModel:     profile.NICModel,
+	}
+	// ISO installers install onto a blank disk of the profile's size
+	vmOpts.InstallDiskSize = profile.DiskSize
+	vmOpts.Driver, vmOpts.LibvirtURI = driverName, libvirtURI
 
 	driver, err := vm.NewDriver(vmOpts, t.verbose)
 	if err != nil {
@@ -131,31 +250,43 @@ func (t *TestStage) Execute(ctx context.Context) error {
 	}
 
 	// Display platform info
-	vmType := driver.Type()
+	vmType = driver.Type()
 	fmt.Printf("🖥️  Platform: %s (%s)\n", runtime.GOOS, vmType.String())
-	fmt.Printf("   Host gateway IP: %s\n", vmType.HostGatewayIP())
+	if gateway := vmType.HostGatewayIP(); gateway != "" {
+		fmt.Printf("   Host gateway IP: %s\n", gateway)
+	}
+	if cfg.Boot.Profile != "" {
+		fmt.Printf("   Profile: %s (%d CPUs, %d MB)\n", cfg.Boot.Profile, profile.CPUs, profile.Memory)
+	}
+	if e, ok := driver.(vm.Emulator); ok && e.Accelerator() == vm.AccelTCG {
+		fmt.Printf("   Accelerator: TCG (software emulation, timeouts scaled x%d)\n", vm.EmulationTimeoutScale)
+	}
 
 	// Start VM
 	if t.verbose {
 		fmt.Println("🚀 Starting VM...")
 	}
-	if err := driver.Start(ctx, vmOpts); err != nil {
-		return fmt.Errorf("failed to start VM: %w", err)
-	}
-
-	// Ensure VM is cleaned up on exit
-	defer func() {
+	// Ensure VM is cleaned up on exit, also when interrupted while starting
+	vmCleanup := cleanup.Register("stop test VM "+vmName, func(context.Context) error {
 		if t.verbose {
 			fmt.Println("🧹 Cleaning up VM...")
 		}
-		_ = driver.Cleanup()
-	}()
+		return driver.Cleanup()
+	})
+	defer func() { _ = vmCleanup.Run(context.Background()) }()
+	if err := driver.Start(ctx, vmOpts); err != nil {
+		return fmt.Errorf("failed to start VM: %w", err)
+	}
+	if pid := driver.GetProcessID(); pid > 0 {
+		vmCleanup.AddResource(vm.ProcessResource(pid))
+	}
 
 	// Wait for VM to be ready
 	timeout := time.Duration(cfg.Boot.Timeout) * time.Second
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
+	timeout = vm.ScaleTimeout(driver, timeout)
 
 	fmt.Printf("⏳ Waiting for VM to boot (timeout: %v)...\n", timeout)
 	vmReadyStart := time.Now()
@@ -172,65 +303,130 @@ func (t *TestStage) Execute(ctx context.Context) error {
 	fmt.Printf("✅ VM is running (took %v)\n", vmReadyDuration.Round(time.Millisecond))
 
 	// Perform boot checks if configured
-	if len(cfg.Boot.Checks) > 0 {
-		// Wait for SSH to be available
-		fmt.Println("⏳ Waiting for SSH to be available...")
-		sshStart := time.Now()
-		if err := driver.WaitForSSH(ctx); err != nil {
-			// Show diagnostics
-			t.showSSHDiagnostics(driver)
-			return fmt.Errorf("SSH not available: %w", err)
-		}
-		sshDuration := time.Since(sshStart)
-		fmt.Printf("✅ SSH connection established (took %v)\n", sshDuration.Round(time.Millisecond))
-
-		// Execute boot checks
-		fmt.Println("🔍 Running boot checks...")
-		for i, check := range cfg.Boot.Checks {
-			if t.verbose {
-				fmt.Printf("   [%d/%d] %s\n", i+1, len(cfg.Boot.Checks), check)
-			}
+	if len(cfg.Boot.Checks) == 0 && !cfg.Boot.SecureBoot && !cfg.Boot.TPM && cfg.Boot.Firmware == "" {
+		if t.verbose {
+			fmt.Println("ℹ️  No boot checks configured")
+		}
+		return nil
+	}
+
+	// Wait for SSH to be available
+	fmt.Println("⏳ Waiting for SSH to be available...")
+	sshStart := time.Now()
+	if err := driver.WaitForSSH(ctx); err != nil {
+		// Show diagnostics
+		t.showSSHDiagnostics(driver)
+		return fmt.Errorf("SSH not available: %w", err)
+	}
+	sshDuration := time.Since(sshStart)
+	fmt.Printf("✅ SSH connection established (took %v)\n", sshDuration.Round(time.Millisecond))
+
+	// Verify the boot mode, secure boot state and measured boot before any
+	// check can reboot
+	if err := t.verifyFirmware(ctx, driver, cfg.Boot); err != nil {
+		return err
+	}
+
+	if len(cfg.Boot.Checks) == 0 {
+		return nil
+	}
+
+	// Execute boot checks
+	fmt.Println("🔍 Running boot checks...")
+	for i, check := range cfg.Boot.Checks {
+		if t.verbose {
+			fmt.Printf("   [%d/%d] %s\n", i+1, len(cfg.Boot.Checks), check)
+		}
 
-			output, err := driver.SSH(ctx, check)
-			if err != nil {
-				// Check if this is a reboot command
-				if t.isRebootCommand(check) && t.isExpectedRebootError(err) {
-					if output != "" {
-						fmt.Printf("   Output: %s\n", strings.TrimSpace(output))
-					}
-					fmt.Printf("   ✅ %s\n", check)
-
-					// Wait for VM to restart after reboot
-					if err := t.waitForReboot(ctx, driver, check); err != nil {
-						return err
-					}
-					continue
-				}
-				return fmt.Errorf("boot check failed: %s\nError: %w\nOutput: %s", check, err, output)
+		output, err := driver.SSH(ctx, check)
+		// A reboot command may drop the connection before it reports
+		// an exit status; that is success, unlike a non-zero exit code
+		rebooting := t.isRebootCommand(check) && (err == nil || sshclient.IsConnectionLost(err))
+		if err != nil && !rebooting {
+			if code := sshclient.ExitCode(err); code > 0 {
+				return fmt.Errorf("boot check failed: %s\nExit code: %d\nOutput: %s", check, code, output)
 			}
+			return fmt.Errorf("boot check failed: %s\nError: %w\nOutput: %s", check, err, output)
+		}
+
+		if output != "" {
+			fmt.Printf("   Output: %s\n", strings.TrimSpace(output))
+		}
+		fmt.Printf("   ✅ %s\n", check)
 
-			if output != "" {
-				fmt.Printf("   Output: %s\n", strings.TrimSpace(output))
+		// Wait for VM to restart after reboot
+		if rebooting {
+			if err := t.waitForReboot(ctx, driver, check); err != nil {
+				return err
 			}
-			fmt.Printf("   ✅ %s\n", check)
 		}
+	}
 
-		fmt.Println("✅ All boot checks passed")
-	} else {
-		if t.verbose {
-			fmt.Println("ℹ️  No boot checks configured")
+	fmt.Println("✅ All boot checks passed")
+	return nil
+}
+
+// verifyFirmware asserts inside the guest that it was booted with the
+// configured firmware, that secure boot is enforced and that the boot was
+// measured into the TPM with the expected PCR values
+func (t *TestStage) verifyFirmware(ctx context.Context, driver vm.Driver, boot *BootTestConfig) error {
+	if boot.Firmware != "" {
+		output, err := driver.SSH(ctx, vm.BootModeCheckCommand)
+		if err != nil {
+			return fmt.Errorf("failed to detect the boot mode: %w\nOutput: %s", err, output)
 		}
+		if mode := strings.TrimSpace(output); mode != boot.Firmware {
+			return fmt.Errorf("guest booted with %s, want %s", mode, boot.Firmware)
+		}
+		fmt.Printf("✅ Booted with %s firmware\n", strings.ToUpper(boot.Firmware))
 	}
 
+	if boot.SecureBoot {
+		output, err := driver.SSH(ctx, vm.SecureBootCheckCommand)
+		if err != nil {
+			return fmt.Errorf("failed to read the SecureBoot EFI variable: %w\nOutput: %s", err, output)
+		}
+		enabled, err := vm.ParseSecureBootState(output)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return fmt.Errorf("secure boot is not enabled in the guest")
+		}
+		fmt.Println("✅ Secure boot is enabled")
+	}
+
+	if boot.TPM {
+		output, err := driver.SSH(ctx, vm.PCRReadCommand)
+		if err != nil {
+			return fmt.Errorf("failed to read TPM PCRs: %w\nOutput: %s", err, output)
+		}
+		pcrs, err := vm.ParsePCRs(output)
+		if err != nil {
+			return err
+		}
+		if t.verbose {
+			for _, index := range vm.MeasuredBootPCRs {
+				fmt.Printf("   PCR %d: %s\n", index, pcrs[index])
+			}
+		}
+		if err := vm.CheckPCRs(pcrs, boot.PCRs); err != nil {
+			return fmt.Errorf("measured boot check failed: %w", err)
+		}
+		if len(boot.PCRs) > 0 {
+			fmt.Printf("✅ Measured boot PCRs match (%d expected values)\n", len(boot.PCRs))
+		} else {
+			fmt.Println("✅ Boot was measured into the TPM")
+		}
+	}
 	return nil
 }
 
-// findDiskImageFile finds the raw disk image file from convert stage artifacts
-// bootc-man uses raw format exclusively for cross-platform compatibility:
-// - vfkit (macOS) only supports raw
-// - QEMU (Linux) supports raw natively
-// - Simpler workflow without format conversion
-func (t *TestStage) findDiskImageFile() (string, error) {
+// findDiskImageFile finds the disk image file from convert stage artifacts
+// Only formats the VM type can boot are considered:
+// - vfkit (macOS) and plugins only support raw
+// - QEMU and libvirt detect raw, qcow2, vmdk and iso from the file header
+func (t *TestStage) findDiskImageFile(vmType vm.VMType) (string, error) {
 	artifactsDir := filepath.Join(t.pipeline.baseDir, "output", "images")
 
 	// Generate expected filename from pipeline name
@@ -239,29 +435,12 @@ func (t *TestStage) findDiskImageFile() (string, error) {
 	pipelineName = strings.ReplaceAll(pipelineName, " ", "-")
 	pipelineName = strings.ToLower(pipelineName)
 
-	// Try to find raw file with expected name
-	rawFile := filepath.Join(artifactsDir, fmt.Sprintf("%s.raw", pipelineName))
-	if _, err := os.Stat(rawFile); err == nil {
-		return rawFile, nil
+	formats := vmType.SupportedImageFormats()
+	if path := vm.FindDiskImageInDir(artifactsDir, pipelineName, formats); path != "" {
+		return path, nil
 	}
 
-	// Search for any raw file in the directory
-	var foundRawFile string
-	_ = filepath.Walk(artifactsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-		if strings.HasSuffix(info.Name(), ".raw") {
-			foundRawFile = path
-			return filepath.SkipAll
-		}
-		return nil
-	})
-	if foundRawFile != "" {
-		return foundRawFile, nil
-	}
-
-	return "", fmt.Errorf("no raw disk image file found in %s\n   bootc-man requires raw format. Make sure convert stage outputs raw format", artifactsDir)
+	return "", fmt.Errorf("no disk image file (%s) found in %s\n   Make sure convert stage outputs one of these formats", strings.Join(formats, ", "), artifactsDir)
 }
 
 // findSSHKeyPath finds the SSH private key path
@@ -283,7 +462,7 @@ func (t *TestStage) findSSHKeyPath() (string, error) {
 		}
 	}
 
-	return "", fmt.Errorf("no SSH private key found. Please ensure ~/.ssh/id_ed25519 or ~/.ssh/id_rsa exists")
+	return "", fmt.Errorf("no SSH private key found. Please ensure ~/.ssh/id_ed25519 or ~/.ssh/id_rsa exists\n   Or set test.boot.provisioning to generate a dedicated key for the test VM")
 }
 
 // isRebootCommand checks if the command triggers a reboot
@@ -295,14 +474,6 @@ func (t *TestStage) isRebootCommand(cmd string) bool {
 				strings.Contains(cmd, "bootc rollback")))
 }
 
-// isExpectedRebootError checks if the error is expected during reboot
-func (t *TestStage) isExpectedRebootError(err error) bool {
-	errStr := err.Error()
-	return strings.Contains(errStr, "exit status 255") ||
-		strings.Contains(errStr, "Connection closed") ||
-		strings.Contains(errStr, "Connection reset")
-}
-
 // waitForReboot waits for the VM to restart after a reboot command
 func (t *TestStage) waitForReboot(ctx context.Context, driver vm.Driver, cmd string) error {
 	isSoftReboot := strings.Contains(cmd, "soft-reboot") || strings.Contains(cmd, "--soft-reboot")
@@ -314,11 +485,20 @@ func (t *TestStage) waitForReboot(ctx context.Context, driver vm.Driver, cmd str
 	fmt.Printf("   ⚠️  Detected %s, waiting for VM to restart...\n", rebootType)
 
 	// Wait for VM to stop (skip for soft-reboot)
-	if !isSoftReboot {
+	// Drivers that observe guest resets directly are used when available;
+	// polling the state misses reboots where the hypervisor process stays up
+	if watcher, ok := driver.(vm.RebootWatcher); ok && !isSoftReboot {
+		if t.verbose {
+			fmt.Println("   ⏳ Waiting for guest reset...")
+		}
+		if err := watcher.WaitForReboot(ctx, vm.ScaleTimeout(driver, 30*time.Second)); err != nil && t.verbose {
+			fmt.Printf("   ⚠️  %v\n", err)
+		}
+	} else if !isSoftReboot {
 		if t.verbose {
 			fmt.Println("   ⏳ Waiting for VM to stop...")
 		}
-		stopDeadline := time.Now().Add(30 * time.Second)
+		stopDeadline := time.Now().Add(vm.ScaleTimeout(driver, 30*time.Second))
 		for time.Now().Before(stopDeadline) {
 			state, _ := driver.GetState(ctx)
 			if state == vm.VMStateStopped {
@@ -332,7 +512,7 @@ func (t *TestStage) waitForReboot(ctx context.Context, driver vm.Driver, cmd str
 	if t.verbose {
 		fmt.Println("   ⏳ Waiting for VM to restart...")
 	}
-	restartDeadline := time.Now().Add(60 * time.Second)
+	restartDeadline := time.Now().Add(vm.ScaleTimeout(driver, 60*time.Second))
 	for time.Now().Before(restartDeadline) {
 		state, _ := driver.GetState(ctx)
 		if state == vm.VMStateRunning {
@@ -430,6 +610,23 @@ func extractDiagnosticsFromLog(logContent string) []string {
 	return diagnostics
 }
 
+// testRunID returns a short random ID that makes test VM names unique
+func testRunID() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// testVMName returns the name of a test VM: the pipeline name with a ci-test
+// prefix to avoid conflicts with vm start, followed by the run ID
+func testVMName(pipelineName, runID string) string {
+	base := sanitizeVMName("ci-test-" + pipelineName)
+	if maxLen := 30 - len(runID) - 1; len(base) > maxLen {
+		base = base[:maxLen]
+	}
+	return base + "-" + runID
+}
+
 // sanitizeVMName sanitizes a VM name
 func sanitizeVMName(name string) string {
 	maxLen := 30
@@ -0,0 +1,55 @@
+package ci
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTestVMName(t *testing.T) {
+	tests := []struct {
+		pipeline string
+		want     string
+	}{
+		{"app", "ci-test-app-a1b2c3"},
+		{"my.app", "ci-test-my-app-a1b2c3"},
+		{"a-very-long-pipeline-name-for-testing", "ci-test-a-very-long-pip-a1b2c3"},
+	}
+	for _, tt := range tests {
+		got := testVMName(tt.pipeline, "a1b2c3")
+		if got != tt.want {
+			t.Errorf("testVMName(%q) = %q, want %q", tt.pipeline, got, tt.want)
+		}
+		if len(got) > 30 {
+			t.Errorf("testVMName(%q) = %q is longer than 30 characters", tt.pipeline, got)
+		}
+	}
+
+	id := testRunID()
+	if !regexp.MustCompile(`^[0-9a-f]{6}$`).MatchString(id) {
+		t.Errorf("testRunID() = %q, want 6 hex digits", id)
+	}
+	if testVMName("app", id) == testVMName("app", testRunID()) {
+		t.Error("two runs got the same test VM name")
+	}
+}
+
+func TestPipelineLock(t *testing.T) {
+	p := &Pipeline{Metadata: PipelineMetadata{Name: "app"}, baseDir: t.TempDir()}
+
+	l, err := p.Lock()
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	_, err = p.Lock()
+	if err == nil || !strings.Contains(err.Error(), "pipeline 'app' already running (pid ") {
+		t.Errorf("second Lock() error = %v, want already running", err)
+	}
+	_ = l.Release()
+
+	l, err = p.Lock()
+	if err != nil {
+		t.Fatalf("Lock() after Release() error = %v", err)
+	}
+	_ = l.Release()
+}
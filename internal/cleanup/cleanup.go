@@ -0,0 +1,320 @@
+// Package cleanup runs teardown actions that must happen even when bootc-man
+// is interrupted
+// Stages and drivers register an action for every resource that outlives a
+// single function call (daemonized VMs, helper processes, temporary disks).
+// On SIGINT/SIGTERM the actions run in reverse order with a deadline, and
+// whatever could not be cleaned up is recorded for 'bootc-man system prune'.
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/lock"
+)
+
+// DefaultTimeout is how long the signal handler waits for cleanup actions
+const DefaultTimeout = 30 * time.Second
+
+// Resource is a file or process that a cleanup action removes
+type Resource struct {
+	Path string   `json:"path,omitempty"` // 削除対象のファイルまたはディレクトリ
+	PID  int      `json:"pid,omitempty"`  // 停止対象のプロセス
+	Args []string `json:"args,omitempty"` // PID 再利用検出用のコマンドライン
+}
+
+// Leftover is a resource whose cleanup action failed or never ran
+type Leftover struct {
+	Resource
+	Action     string    `json:"action"`          // 失敗したクリーンアップ処理の名前
+	Error      string    `json:"error,omitempty"` // 失敗理由
+	RecordedAt time.Time `json:"recordedAt"`      // 記録日時
+}
+
+// Handle is a registered cleanup action
+type Handle struct {
+	name      string
+	fn        func(context.Context) error
+	resources []Resource
+
+	once sync.Once
+	err  error
+}
+
+var (
+	mu      sync.Mutex
+	handles []*Handle
+)
+
+// Register registers fn as the teardown action for resources
+// The action runs when the returned handle's Run is called or, if bootc-man
+// is interrupted first, from the signal handler. Resources are recorded as
+// leftovers if the action fails.
+func Register(name string, fn func(context.Context) error, resources ...Resource) *Handle {
+	h := &Handle{name: name, fn: fn, resources: resources}
+	mu.Lock()
+	handles = append(handles, h)
+	mu.Unlock()
+	return h
+}
+
+// AddResource adds a resource that becomes known after registration, such as
+// the PID of a process started by the registered code
+func (h *Handle) AddResource(r Resource) {
+	mu.Lock()
+	h.resources = append(h.resources, r)
+	mu.Unlock()
+}
+
+// Run runs the action and unregisters it
+// The action runs at most once; concurrent and later calls wait for the
+// first one and return its error. It stays registered while it runs, so an
+// interrupt during a deferred Run waits for it instead of skipping it.
+func (h *Handle) Run(ctx context.Context) error {
+	h.once.Do(func() {
+		h.err = h.fn(ctx)
+		unregister(h)
+		if h.err != nil {
+			record(h, h.err)
+		}
+	})
+	return h.err
+}
+
+// Release unregisters the action without running it
+// Used once ownership of the resources passes elsewhere, e.g. when a VM that
+// was being started is up and is now managed by 'vm stop'.
+func (h *Handle) Release() {
+	h.once.Do(func() { unregister(h) })
+}
+
+func unregister(h *Handle) {
+	mu.Lock()
+	defer mu.Unlock()
+	if i := slices.Index(handles, h); i >= 0 {
+		handles = slices.Delete(handles, i, i+1)
+	}
+}
+
+// Pending returns the number of registered actions
+func Pending() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return len(handles)
+}
+
+// RunAll runs all registered actions in reverse registration order
+// Actions still running or not yet started when ctx expires are recorded as
+// leftovers.
+func RunAll(ctx context.Context) error {
+	var errs []error
+	for {
+		mu.Lock()
+		if len(handles) == 0 {
+			mu.Unlock()
+			break
+		}
+		h := handles[len(handles)-1]
+		mu.Unlock()
+
+		if ctx.Err() != nil {
+			unregister(h)
+			record(h, fmt.Errorf("not run: %w", ctx.Err()))
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, ctx.Err()))
+			continue
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- h.Run(ctx) }()
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			}
+		case <-ctx.Done():
+			// The action keeps running in the background; if the process
+			// exits first, prune finds what it did not get to
+			unregister(h)
+			record(h, fmt.Errorf("timed out: %w", ctx.Err()))
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, ctx.Err()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Kill sends SIGKILL to every process of a registered action and records
+// all registered resources as leftovers
+// Used when the user insists on quitting while cleanup is still running.
+func Kill() {
+	mu.Lock()
+	pending := slices.Clone(handles)
+	handles = nil
+	mu.Unlock()
+
+	for _, h := range slices.Backward(pending) {
+		for _, r := range h.resources {
+			if r.PID > 0 {
+				_ = syscall.Kill(r.PID, syscall.SIGKILL)
+			}
+		}
+		record(h, errors.New("killed"))
+	}
+}
+
+// HandleSignals cancels the command context on SIGINT or SIGTERM and runs
+// the registered actions with a deadline of timeout
+// A second signal kills the registered processes and exits immediately. The
+// returned channel is closed once cleanup has finished.
+func HandleSignals(cancel context.CancelFunc, timeout time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sigCh
+		pending := Pending() > 0
+		if pending {
+			fmt.Println()
+			fmt.Println("⚠️  Interrupted, cleaning up (press Ctrl-C again to force quit)...")
+		}
+		cancel()
+
+		go func() {
+			<-sigCh
+			if Pending() > 0 {
+				fmt.Println("⚠️  Forced quit, killing remaining processes")
+				Kill()
+				fmt.Println("Run 'bootc-man system prune' to remove what was left behind")
+			}
+			os.Exit(130)
+		}()
+
+		ctx, cancelCleanup := context.WithTimeout(context.Background(), timeout)
+		defer cancelCleanup()
+		if err := RunAll(ctx); err != nil {
+			fmt.Printf("⚠️  Warning: cleanup incomplete:\n%v\n", err)
+			fmt.Println("Run 'bootc-man system prune' to remove what was left behind")
+		} else if pending {
+			fmt.Println("🧹 Cleanup complete")
+		}
+		close(done)
+	}()
+	return done
+}
+
+// recordPath returns the file leftovers are recorded in
+func recordPath() (string, error) {
+	if runtime.GOOS == "windows" {
+		appData := os.Getenv("APPDATA")
+		if appData == "" {
+			return "", fmt.Errorf("APPDATA environment variable not set")
+		}
+		return filepath.Join(appData, "bootc-man", "leftovers.json"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "bootc-man", "leftovers.json"), nil
+}
+
+// record appends the resources of h to the leftovers file
+// Failures are only reported; the resources are still found by prune's
+// regular scan.
+func record(h *Handle, cause error) {
+	mu.Lock()
+	resources := slices.Clone(h.resources)
+	mu.Unlock()
+	if len(resources) == 0 {
+		return
+	}
+	now := time.Now()
+	err := updateLeftovers(func(leftovers []Leftover) []Leftover {
+		for _, r := range resources {
+			leftovers = append(leftovers, Leftover{Resource: r, Action: h.name, Error: cause.Error(), RecordedAt: now})
+		}
+		return leftovers
+	})
+	if err != nil {
+		fmt.Printf("⚠️  Warning: failed to record leftover resources: %v\n", err)
+	}
+}
+
+// Leftovers returns the recorded leftover resources
+func Leftovers() ([]Leftover, error) {
+	path, err := recordPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadLeftovers(path)
+}
+
+// Forget removes the recorded leftovers for which gone returns true
+func Forget(gone func(Leftover) bool) error {
+	return updateLeftovers(func(leftovers []Leftover) []Leftover {
+		return slices.DeleteFunc(leftovers, gone)
+	})
+}
+
+func loadLeftovers(path string) ([]Leftover, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read leftovers: %w", err)
+	}
+	var leftovers []Leftover
+	if err := json.Unmarshal(data, &leftovers); err != nil {
+		return nil, fmt.Errorf("failed to parse leftovers: %w", err)
+	}
+	return leftovers, nil
+}
+
+// updateLeftovers rewrites the leftovers file under its lock
+func updateLeftovers(update func([]Leftover) []Leftover) error {
+	path, err := recordPath()
+	if err != nil {
+		return err
+	}
+	l, err := lock.Acquire(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	leftovers, err := loadLeftovers(path)
+	if err != nil {
+		return err
+	}
+	leftovers = update(leftovers)
+	if len(leftovers) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove leftovers: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(leftovers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal leftovers: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write leftovers: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write leftovers: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunAll(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var order []string
+	action := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	Register("first", action("first", nil), Resource{Path: "/tmp/first"})
+	done := Register("done", action("done", nil))
+	Register("failing", action("failing", errors.New("busy")), Resource{Path: "/tmp/failing"})
+	released := Register("released", action("released", nil))
+	Register("last", action("last", nil))
+
+	if err := done.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	released.Release()
+	if err := done.Run(context.Background()); err != nil || len(order) != 1 {
+		t.Fatalf("second Run() ran the action again: %v", order)
+	}
+
+	if err := RunAll(context.Background()); err == nil {
+		t.Error("RunAll() error = nil, want the failing action's error")
+	}
+	if want := []string{"done", "last", "failing", "first"}; !slices.Equal(order, want) {
+		t.Errorf("actions ran in order %v, want %v", order, want)
+	}
+	if Pending() != 0 {
+		t.Errorf("Pending() = %d after RunAll(), want 0", Pending())
+	}
+
+	leftovers, err := Leftovers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leftovers) != 1 || leftovers[0].Path != "/tmp/failing" || leftovers[0].Action != "failing" {
+		t.Fatalf("Leftovers() = %+v, want /tmp/failing", leftovers)
+	}
+
+	if err := Forget(func(l Leftover) bool { return l.Path == "/tmp/failing" }); err != nil {
+		t.Fatal(err)
+	}
+	if leftovers, _ := Leftovers(); len(leftovers) != 0 {
+		t.Errorf("Leftovers() = %+v after Forget(), want none", leftovers)
+	}
+}
+
+func TestRunAllDeadline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	block := make(chan struct{})
+	defer close(block)
+	Register("skipped", func(context.Context) error { return nil }, Resource{Path: "/tmp/skipped"})
+	Register("hung", func(context.Context) error { <-block; return nil }, Resource{PID: 4242})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := RunAll(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunAll() error = %v, want deadline exceeded", err)
+	}
+
+	leftovers, err := Leftovers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range leftovers {
+		got = append(got, l.Action)
+	}
+	if want := []string{"hung", "skipped"}; !slices.Equal(got, want) {
+		t.Errorf("leftover actions = %v, want %v", got, want)
+	}
+}
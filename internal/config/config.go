@@ -5,8 +5,11 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -24,8 +27,13 @@ const (
 	EnvPodmanPath        = "BOOTCMAN_PODMAN"
 	EnvBootcImageBuilder = "BOOTCMAN_BOOTC_IMAGE_BUILDER"
 	EnvExperimental      = "BOOTCMAN_EXPERIMENTAL"
+	EnvVMDriver          = "BOOTCMAN_VM_DRIVER"
 )
 
+// vmDriverNamePattern matches VM driver names; names other than the
+// built-in drivers select a bootc-man-driver-<name> plugin
+var vmDriverNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 // Config represents the bootc-man configuration
 type Config struct {
 	Runtime      RuntimeConfig    `yaml:"runtime"`
@@ -86,6 +94,54 @@ type VMConfig struct {
 	CPUs int `yaml:"cpus"`
 	// Default memory size in MB for VMs
 	Memory int `yaml:"memory"`
+	// Named VM shapes, selected with vm start --profile or test.boot.profile
+	Profiles map[string]VMProfile `yaml:"profiles,omitempty"`
+	// VM driver: qemu (default), libvirt (Linux) or the name of an external
+	// bootc-man-driver-<name> plugin
+	Driver string `yaml:"driver,omitempty"`
+	// libvirt connection URI used by the libvirt driver
+	LibvirtURI string `yaml:"libvirt_uri,omitempty"`
+}
+
+// VMProfile is a named VM shape, e.g. matching the target hardware
+// Unset CPUs and memory fall back to the VM defaults.
+type VMProfile struct {
+	// Number of CPUs
+	CPUs int `yaml:"cpus,omitempty"`
+	// Memory size in MB
+	Memory int `yaml:"memory,omitempty"`
+	// Disk size in GB; VM disks are grown to this size, never shrunk
+	DiskSize int `yaml:"disk_size,omitempty"`
+	// Boot firmware: uefi or bios
+	Firmware string `yaml:"firmware,omitempty"`
+	// QEMU NIC model, e.g. virtio-net-pci or e1000e
+	NICModel string `yaml:"nic_model,omitempty"`
+}
+
+// DefaultVMProfiles returns the built-in VM profiles
+func DefaultVMProfiles() map[string]VMProfile {
+	return map[string]VMProfile{
+		"small":       {CPUs: 1, Memory: 2048},
+		"large":       {CPUs: 8, Memory: 16384, DiskSize: 100},
+		"edge-device": {CPUs: 2, Memory: 2048, DiskSize: 32, NICModel: "e1000e"},
+	}
+}
+
+// Profile returns a named VM profile with unset CPUs and memory taken from
+// the VM defaults
+func (c VMConfig) Profile(name string) (VMProfile, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		names := slices.Sorted(maps.Keys(c.Profiles))
+		return VMProfile{}, fmt.Errorf("unknown VM profile %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	if p.CPUs == 0 {
+		p.CPUs = c.CPUs
+	}
+	if p.Memory == 0 {
+		p.Memory = c.Memory
+	}
+	return p, nil
 }
 
 // ContainersConfig contains container naming settings
@@ -182,9 +238,12 @@ func DefaultConfig() *Config {
 			Port: DefaultGUIPort,
 		},
 		VM: VMConfig{
-			SSHUser: DefaultSSHUser,
-			CPUs:    DefaultVMCPUs,
-			Memory:  DefaultVMMemoryMB,
+			SSHUser:    DefaultSSHUser,
+			CPUs:       DefaultVMCPUs,
+			Memory:     DefaultVMMemoryMB,
+			Profiles:   DefaultVMProfiles(),
+			Driver:     VMDriverQemu,
+			LibvirtURI: DefaultLibvirtURI,
 		},
 		Containers: ContainersConfig{
 			RegistryName:       ContainerNameRegistry,
@@ -358,6 +417,19 @@ func mergeConfig(dst, src *Config) {
 	if src.VM.Memory != 0 {
 		dst.VM.Memory = src.VM.Memory
 	}
+	if src.VM.Driver != "" {
+		dst.VM.Driver = src.VM.Driver
+	}
+	if src.VM.LibvirtURI != "" {
+		dst.VM.LibvirtURI = src.VM.LibvirtURI
+	}
+	// Profiles are merged by name; a profile replaces the one it redefines
+	for name, profile := range src.VM.Profiles {
+		if dst.VM.Profiles == nil {
+			dst.VM.Profiles = map[string]VMProfile{}
+		}
+		dst.VM.Profiles[name] = profile
+	}
 
 	// Containers
 	if src.Containers.RegistryName != "" {
@@ -486,6 +558,11 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.CI.BootcImageBuilder = v
 	}
 
+	// VM driver
+	if v := os.Getenv(EnvVMDriver); v != "" {
+		cfg.VM.Driver = v
+	}
+
 	// Experimental mode
 	if v := os.Getenv(EnvExperimental); v == "1" || v == "true" {
 		cfg.Experimental = true
@@ -542,6 +619,10 @@ func (c *Config) Validate() error {
 		errs = append(errs, fmt.Sprintf("invalid GUI port: %d", c.GUI.Port))
 	}
 
+	if c.VM.Driver != "" && !vmDriverNamePattern.MatchString(c.VM.Driver) {
+		errs = append(errs, fmt.Sprintf("invalid VM driver: %s (must be %s, %s or the name of a %s<name> plugin)", c.VM.Driver, VMDriverQemu, VMDriverLibvirt, VMDriverPluginPrefix))
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("configuration errors: %s", strings.Join(errs, "; "))
 	}
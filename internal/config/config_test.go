@@ -76,6 +76,21 @@ func TestConfigValidate(t *testing.T) {
 			modify:  func(c *Config) { c.Registry.Port = 5001; c.CI.Port = 9000; c.GUI.Port = 8080 },
 			wantErr: false,
 		},
+		{
+			name:    "libvirt VM driver",
+			modify:  func(c *Config) { c.VM.Driver = VMDriverLibvirt },
+			wantErr: false,
+		},
+		{
+			name:    "external VM driver",
+			modify:  func(c *Config) { c.VM.Driver = "cloud-hypervisor" },
+			wantErr: false,
+		},
+		{
+			name:    "invalid VM driver name",
+			modify:  func(c *Config) { c.VM.Driver = "../xen" },
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -424,6 +439,54 @@ func TestMergeConfig(t *testing.T) {
 	}
 }
 
+// TestMergeConfigProfiles tests that profiles are merged by name
+func TestMergeConfigProfiles(t *testing.T) {
+	dst := DefaultConfig()
+	src := &Config{
+		VM: VMConfig{
+			Profiles: map[string]VMProfile{
+				"small": {CPUs: 2},
+				"rpi":   {CPUs: 4, Memory: 8192, NICModel: "e1000e"},
+			},
+		},
+	}
+
+	mergeConfig(dst, src)
+
+	if got := dst.VM.Profiles["small"]; got.CPUs != 2 || got.Memory != 0 {
+		t.Errorf("Profiles[small] = %+v, want the redefined profile", got)
+	}
+	if _, ok := dst.VM.Profiles["rpi"]; !ok {
+		t.Error("Profiles[rpi] not merged")
+	}
+	if _, ok := dst.VM.Profiles["edge-device"]; !ok {
+		t.Error("built-in profile edge-device lost")
+	}
+}
+
+func TestVMConfigProfile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.VM.Profiles["small"] = VMProfile{CPUs: 1}
+
+	small, err := cfg.VM.Profile("small")
+	if err != nil {
+		t.Fatalf("Profile(small) error = %v", err)
+	}
+	if small.CPUs != 1 || small.Memory != DefaultVMMemoryMB {
+		t.Errorf("Profile(small) = %+v, want 1 CPU and the default memory", small)
+	}
+
+	edge, _ := cfg.VM.Profile("edge-device")
+	if edge.NICModel != "e1000e" || edge.DiskSize == 0 {
+		t.Errorf("Profile(edge-device) = %+v", edge)
+	}
+
+	_, err = cfg.VM.Profile("huge")
+	if err == nil || !strings.Contains(err.Error(), "edge-device, large, small") {
+		t.Errorf("Profile(huge) error = %v, want the available profiles", err)
+	}
+}
+
 // TestMergeConfigAllSections tests merging all config sections
 func TestMergeConfigAllSections(t *testing.T) {
 	dst := DefaultConfig()
@@ -840,6 +903,12 @@ func TestDefaultConfigVM(t *testing.T) {
 	if cfg.VM.Memory != DefaultVMMemoryMB {
 		t.Errorf("VM.Memory = %d, want %d", cfg.VM.Memory, DefaultVMMemoryMB)
 	}
+	if cfg.VM.Driver != VMDriverQemu {
+		t.Errorf("VM.Driver = %q, want %q", cfg.VM.Driver, VMDriverQemu)
+	}
+	if cfg.VM.LibvirtURI != DefaultLibvirtURI {
+		t.Errorf("VM.LibvirtURI = %q, want %q", cfg.VM.LibvirtURI, DefaultLibvirtURI)
+	}
 }
 
 // TestValidateMultipleErrors tests that Validate returns all errors
@@ -167,6 +167,8 @@ const (
 	EFIStorePattern = "bootc-man-%s-efi-store"
 	// TestDiskImagePattern is the pattern for test disk image filename
 	TestDiskImagePattern = "bootc-man-test-%s.raw"
+	// TestDiskPrefix is the filename prefix of test disks and their provisioning files
+	TestDiskPrefix = "bootc-man-test-"
 	// ScanArchiveTempPattern is the pattern for scan archive temp filename
 	ScanArchiveTempPattern = "bootc-man-scan-*.tar"
 	// DigestFileTempPattern is the pattern for digest file temp filename
@@ -182,6 +184,23 @@ const (
 	DefaultVMCPUs = 2
 	// DefaultVMMemoryMB is the default memory size in MB for VMs
 	DefaultVMMemoryMB = 4096
+	// DefaultVMInstallDiskSizeGB is the default size of the blank target disk
+	// created when booting an ISO installer
+	DefaultVMInstallDiskSizeGB = 20
+	// DefaultVMStopTimeout is the default number of seconds to wait for the
+	// guest to power off before the VM is stopped forcibly
+	DefaultVMStopTimeout = 60
+	// VMDriverQemu runs VMs as bootc-man managed QEMU processes (Linux default)
+	VMDriverQemu = "qemu"
+	// VMDriverLibvirt runs VMs as transient libvirt domains
+	VMDriverLibvirt = "libvirt"
+	// DefaultLibvirtURI is the libvirt connection used by the libvirt driver
+	DefaultLibvirtURI = "qemu:///session"
+	// VMDriverPluginPrefix is the executable name prefix of external VM
+	// drivers; vm.driver: <name> runs bootc-man-driver-<name>
+	VMDriverPluginPrefix = "bootc-man-driver-"
+	// VMDriverPluginDir is searched for external VM drivers after PATH
+	VMDriverPluginDir = "/usr/libexec/bootc-man"
 )
 
 // =============================================================================
@@ -197,6 +216,10 @@ const (
 	PipelineKind = "Pipeline"
 	// DefaultPipelineFileName is the default pipeline definition filename
 	DefaultPipelineFileName = "bootc-ci.yaml"
+	// LabKind is the expected kind for lab definitions
+	LabKind = "Lab"
+	// DefaultLabFileName is the default lab definition filename
+	DefaultLabFileName = "bootc-lab.yaml"
 	// DefaultContainerfileName is the default Containerfile name
 	DefaultContainerfileName = "Containerfile"
 )
@@ -210,12 +233,22 @@ const (
 	BinaryGvproxy = "gvproxy"
 	// BinaryVfkit is the name of the vfkit binary
 	BinaryVfkit = "vfkit"
+	// BinaryVirtiofsd is the name of the virtiofsd binary
+	BinaryVirtiofsd = "virtiofsd"
+	// BinarySwtpm is the name of the swtpm binary
+	BinarySwtpm = "swtpm"
 	// BinaryPodman is the name of the podman binary
 	BinaryPodman = "podman"
 	// BinarySSH is the name of the ssh binary
 	BinarySSH = "ssh"
 	// BinarySSHKeygen is the name of the ssh-keygen binary
 	BinarySSHKeygen = "ssh-keygen"
+	// BinaryQemuImg is the name of the qemu-img binary
+	BinaryQemuImg = "qemu-img"
+	// BinaryVirsh is the name of the libvirt command line client
+	BinaryVirsh = "virsh"
+	// BinaryPasst is the name of the passt binary (libvirt user networking)
+	BinaryPasst = "passt"
 )
 
 // =============================================================================
@@ -81,6 +81,32 @@ func FindVfkitBinary() string {
 	return BinaryVfkit // fallback
 }
 
+// FindVirtiofsdBinary searches for the virtiofsd binary in priority order:
+// 1. PATH: virtiofsd
+// 2. System locations (Linux): /usr/libexec/virtiofsd, /usr/lib/qemu/virtiofsd
+// Returns the absolute path to the binary, or the bare name as fallback.
+func FindVirtiofsdBinary() string {
+	// 1. Check PATH
+	if path, err := exec.LookPath(BinaryVirtiofsd); err == nil {
+		return path
+	}
+
+	// 2. Check system locations (virtiofsd is usually not in PATH)
+	systemLocations := []string{
+		"/usr/libexec/virtiofsd",      // Fedora/RHEL
+		"/usr/lib/qemu/virtiofsd",     // Debian/Ubuntu
+		"/usr/lib/virtiofsd",          // Arch
+		"/usr/libexec/qemu/virtiofsd", // Older QEMU bundled builds
+	}
+	for _, loc := range systemLocations {
+		if _, err := os.Stat(loc); err == nil {
+			return loc
+		}
+	}
+
+	return BinaryVirtiofsd // fallback to bare name (will fail with helpful error)
+}
+
 // GetGvproxyVersion returns the installed gvproxy version string (e.g. "v0.8.7").
 // Returns empty string if gvproxy is not found or version cannot be determined.
 func GetGvproxyVersion() string {
@@ -0,0 +1,104 @@
+// Package lock provides advisory file locks shared between bootc-man processes
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// HeldError is returned by TryAcquire when another process holds the lock
+type HeldError struct {
+	Path string
+	PID  int // holder recorded in the lock file, 0 if unknown
+}
+
+func (e *HeldError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s is locked by another process (pid %d)", e.Path, e.PID)
+	}
+	return fmt.Sprintf("%s is locked by another process", e.Path)
+}
+
+// Lock is an exclusive advisory lock on a file
+// The lock is released when Release is called or the process exits, so a
+// crashed process never leaves a stale lock behind. The lock file itself is
+// kept; removing it would let two processes lock different files.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire takes the lock on path, waiting until other holders release it
+func Acquire(path string) (*Lock, error) {
+	return acquire(path, false)
+}
+
+// TryAcquire takes the lock on path without waiting
+// If another process holds the lock, a *HeldError is returned.
+func TryAcquire(path string) (*Lock, error) {
+	return acquire(path, true)
+}
+
+func acquire(path string, nonBlocking bool) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	how := syscall.LOCK_EX
+	if nonBlocking {
+		how |= syscall.LOCK_NB
+	}
+	if err := syscall.Flock(int(file.Fd()), how); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, &HeldError{Path: path, PID: Holder(path)}
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// Record the holder for "already running (pid N)" messages
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Release releases the lock
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	_ = l.file.Truncate(0)
+	err := l.file.Close() // closing the descriptor drops the flock
+	l.file = nil
+	return err
+}
+
+// Holder returns the PID recorded in a lock file, or 0
+func Holder(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+// Held reports whether another process holds the lock on path
+func Held(path string) bool {
+	l, err := TryAcquire(path)
+	if err != nil {
+		var held *HeldError
+		return errors.As(err, &held)
+	}
+	_ = l.Release()
+	return false
+}
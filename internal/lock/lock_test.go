@@ -0,0 +1,50 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "test.lock")
+
+	l, err := TryAcquire(path)
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	if got := Holder(path); got != os.Getpid() {
+		t.Errorf("Holder() = %d, want %d", got, os.Getpid())
+	}
+
+	// flock locks belong to the open file, so a second open conflicts even
+	// within this process
+	_, err = TryAcquire(path)
+	var held *HeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("TryAcquire() on held lock error = %v, want *HeldError", err)
+	}
+	if held.PID != os.Getpid() {
+		t.Errorf("HeldError.PID = %d, want %d", held.PID, os.Getpid())
+	}
+	if !Held(path) {
+		t.Error("Held() = false while locked")
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if Held(path) {
+		t.Error("Held() = true after Release()")
+	}
+	if got := Holder(path); got != 0 {
+		t.Errorf("Holder() after Release() = %d, want 0", got)
+	}
+
+	l, err = Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire() after Release() error = %v", err)
+	}
+	_ = l.Release()
+}
@@ -0,0 +1,411 @@
+// Package sshclient is a native SSH client for running commands on VMs and
+// remote bootc hosts
+// A Client keeps its connection open across commands, streams output, and
+// reports the remote exit code separately from connection failures.
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Default timeouts
+const (
+	DefaultConnectTimeout    = 10 * time.Second
+	DefaultKeepAliveInterval = 15 * time.Second
+)
+
+// ErrConnectionLost is returned when the connection drops before the remote
+// command reports an exit status, e.g. because the host rebooted
+var ErrConnectionLost = errors.New("ssh connection lost")
+
+// ExitError is returned when a remote command exits with a non-zero status
+type ExitError struct {
+	Command string
+	Code    int
+	Signal  string // set when the command was killed by a signal
+}
+
+func (e *ExitError) Error() string {
+	if e.Signal != "" {
+		return fmt.Sprintf("command %q killed by signal %s", e.Command, e.Signal)
+	}
+	return fmt.Sprintf("command %q exited with status %d", e.Command, e.Code)
+}
+
+// ExitCode returns the remote exit code of err
+// 0 is returned for nil and -1 for errors that carry no exit status.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return -1
+}
+
+// IsConnectionLost reports whether err means the connection dropped
+// (as opposed to the command failing)
+func IsConnectionLost(err error) bool {
+	return errors.Is(err, ErrConnectionLost)
+}
+
+// Client runs commands over a single, lazily established SSH connection
+// The connection is re-established on the next command after it drops.
+// A Client is safe for concurrent use.
+type Client struct {
+	cfg Config
+
+	mu   sync.Mutex
+	conn *ssh.Client
+	done chan struct{} // closed when conn is closed
+}
+
+// New creates a client for cfg without connecting
+func New(cfg Config) *Client {
+	return &Client{cfg: cfg}
+}
+
+// Config returns the client configuration
+func (c *Client) Config() Config {
+	return c.cfg
+}
+
+// Connect establishes the connection if it is not open yet
+func (c *Client) Connect(ctx context.Context) error {
+	_, err := c.connection(ctx)
+	return err
+}
+
+// connection returns the open connection, dialing a new one if needed
+func (c *Client) connection(ctx context.Context) (*ssh.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil {
+		select {
+		case <-c.done:
+			c.conn = nil
+		default:
+			return c.conn, nil
+		}
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = conn.Wait()
+		close(done)
+	}()
+	go keepAlive(conn, done, c.cfg.keepAliveInterval())
+
+	c.conn = conn
+	c.done = done
+	return conn, nil
+}
+
+// dial opens and authenticates a new connection
+func (c *Client) dial(ctx context.Context) (*ssh.Client, error) {
+	clientConfig, err := c.cfg.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := c.cfg.connectTimeout()
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := c.cfg.Address()
+	netConn, err := c.cfg.dialTransport(dialCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The handshake does not observe ctx; bound it with a deadline instead
+	deadline := time.Now().Add(timeout)
+	if d, ok := dialCtx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	// Proxied connections have no deadlines and are closed on time instead
+	if err := netConn.SetDeadline(deadline); err != nil {
+		timer := time.AfterFunc(time.Until(deadline), func() { netConn.Close() })
+		defer timer.Stop()
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, clientConfig)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", addr, err)
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// keepAlive sends periodic keepalive requests and closes conn when the
+// server stops answering
+func keepAlive(conn *ssh.Client, done <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			errc := make(chan error, 1)
+			go func() {
+				_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+				errc <- err
+			}()
+			select {
+			case err := <-errc:
+				if err != nil {
+					conn.Close()
+					return
+				}
+			case <-time.After(interval):
+				conn.Close()
+				return
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// RunOptions configures a single command execution
+type RunOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Env    map[string]string // sent as "env" requests; the server may reject them
+}
+
+// Run runs command and streams its output to stdout and stderr
+// A non-zero exit status is returned as *ExitError; a dropped connection
+// wraps ErrConnectionLost. Cancelling ctx closes the session.
+func (c *Client) Run(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	return c.RunWithOptions(ctx, command, RunOptions{Stdout: stdout, Stderr: stderr})
+}
+
+// RunWithOptions runs command with the given stdio and environment
+func (c *Client) RunWithOptions(ctx context.Context, command string, opts RunOptions) error {
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+
+	session, err := c.newSession(ctx, conn)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// The connection went away between commands; retry once on a new one
+		if conn, err = c.connection(ctx); err != nil {
+			return err
+		}
+		if session, err = c.newSession(ctx, conn); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("%w: %v", ErrConnectionLost, err)
+		}
+	}
+	defer session.Close()
+
+	for k, v := range opts.Env {
+		_ = session.Setenv(k, v)
+	}
+	// Stdin is copied outside the session: Wait would otherwise block until
+	// Stdin reaches EOF, even after the command has exited
+	var stdin io.WriteCloser
+	if opts.Stdin != nil {
+		if stdin, err = session.StdinPipe(); err != nil {
+			return fmt.Errorf("failed to open stdin: %w", err)
+		}
+	}
+	session.Stdout = opts.Stdout
+	session.Stderr = opts.Stderr
+	if session.Stdout == nil {
+		session.Stdout = io.Discard
+	}
+	if session.Stderr == nil {
+		session.Stderr = io.Discard
+	}
+
+	if err := session.Start(command); err != nil {
+		return fmt.Errorf("failed to start %q: %w", command, err)
+	}
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(stdin, opts.Stdin)
+			stdin.Close()
+		}()
+	}
+
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- session.Wait() }()
+
+	select {
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGKILL)
+		session.Close()
+		// Let the output copies finish, but do not hang on an unresponsive host
+		select {
+		case <-waitErr:
+		case <-time.After(time.Second):
+		}
+		return ctx.Err()
+	case err := <-waitErr:
+		return c.commandError(conn, command, err)
+	}
+}
+
+// newSession opens a session on conn, giving up when ctx is done
+// A connection whose peer vanished without closing it (e.g. a VM reset) does
+// not answer; it is dropped so that the next command reconnects.
+func (c *Client) newSession(ctx context.Context, conn *ssh.Client) (*ssh.Session, error) {
+	type result struct {
+		session *ssh.Session
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		session, err := conn.NewSession()
+		done <- result{session, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			c.dropConnection(conn)
+		}
+		return r.session, r.err
+	case <-ctx.Done():
+		c.dropConnection(conn)
+		return nil, ctx.Err()
+	}
+}
+
+// commandError converts a session error into ExitError or ErrConnectionLost
+func (c *Client) commandError(conn *ssh.Client, command string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Command: command, Code: exitErr.ExitStatus(), Signal: exitErr.Signal()}
+	}
+	var missing *ssh.ExitMissingError
+	if errors.As(err, &missing) || errors.Is(err, io.EOF) {
+		c.dropConnection(conn)
+		return fmt.Errorf("%w while running %q", ErrConnectionLost, command)
+	}
+	return fmt.Errorf("failed to run %q: %w", command, err)
+}
+
+// dropConnection forgets conn so that the next command reconnects
+func (c *Client) dropConnection(conn *ssh.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// Output runs command and returns its stdout and stderr
+func (c *Client) Output(ctx context.Context, command string) ([]byte, []byte, error) {
+	var stdout, stderr strings.Builder
+	err := c.Run(ctx, command, &stdout, &stderr)
+	return []byte(stdout.String()), []byte(stderr.String()), err
+}
+
+// CombinedOutput runs command and returns stdout and stderr interleaved
+func (c *Client) CombinedOutput(ctx context.Context, command string) ([]byte, error) {
+	var out lockedBuffer
+	err := c.Run(ctx, command, &out, &out)
+	return out.Bytes(), err
+}
+
+// Upload writes the content of r to remotePath with the given mode
+// The parent directory must exist. The file is written to a temporary name
+// and renamed, so a partial upload never replaces an existing file.
+func (c *Client) Upload(ctx context.Context, r io.Reader, remotePath string, mode os.FileMode) error {
+	tmp := path.Join(path.Dir(remotePath), fmt.Sprintf(".%s.bootc-man-upload", path.Base(remotePath)))
+	command := fmt.Sprintf("cat > %s && chmod %s %s && mv -f %s %s",
+		ShellQuote(tmp), strconv.FormatUint(uint64(mode.Perm()), 8), ShellQuote(tmp), ShellQuote(tmp), ShellQuote(remotePath))
+
+	var stderr strings.Builder
+	if err := c.RunWithOptions(ctx, command, RunOptions{Stdin: r, Stderr: &stderr}); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("failed to upload %s: %w\n%s", remotePath, err, msg)
+		}
+		return fmt.Errorf("failed to upload %s: %w", remotePath, err)
+	}
+	return nil
+}
+
+// ShellQuote quotes s for use as a single word in a POSIX shell command
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,@%+", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// lockedBuffer is a buffer that can be written from the stdout and stderr
+// copy goroutines at the same time
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf
+}
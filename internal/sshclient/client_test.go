@@ -0,0 +1,468 @@
+package sshclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kevinburke/ssh_config"
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is a minimal SSH server understanding a few commands:
+// "echo <text>", "fail <code>", "drop" (closes the connection) and
+// "cat > <path> ..." (stores stdin). Other commands are run with sh in home.
+// direct-tcpip channels are forwarded, so it can serve as a jump host.
+type testServer struct {
+	addr string
+	home string
+
+	mu          sync.Mutex
+	connections int
+	uploads     map[string]string
+}
+
+func newTestServer(t *testing.T, clientKey ssh.PublicKey) *testServer {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, io.EOF
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &testServer{addr: ln.Addr().String(), home: t.TempDir(), uploads: map[string]string{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.mu.Lock()
+	s.connections++
+	s.mu.Unlock()
+
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		if newChan.ChannelType() == "direct-tcpip" {
+			go forwardChannel(newChan)
+			continue
+		}
+		ch, requests, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range requests {
+				if req.Type != "exec" {
+					_ = req.Reply(req.Type == "env", nil)
+					continue
+				}
+				_ = req.Reply(true, nil)
+				command := string(req.Payload[4:])
+				if command == "drop" {
+					sshConn.Close()
+					return
+				}
+				code := s.exec(ch, command)
+				status := make([]byte, 4)
+				binary.BigEndian.PutUint32(status, uint32(code))
+				_, _ = ch.SendRequest("exit-status", false, status)
+				ch.Close()
+				return
+			}
+		}()
+	}
+}
+
+// forwardChannel connects a direct-tcpip channel to its destination
+func forwardChannel(newChan ssh.NewChannel) {
+	var dest struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChan.ExtraData(), &dest); err != nil {
+		_ = newChan.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(dest.Host, strconv.Itoa(int(dest.Port))))
+	if err != nil {
+		_ = newChan.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	ch, requests, err := newChan.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		_, _ = io.Copy(conn, ch)
+		conn.Close()
+	}()
+	_, _ = io.Copy(ch, conn)
+	ch.Close()
+}
+
+func (s *testServer) exec(ch ssh.Channel, command string) int {
+	switch {
+	case strings.HasPrefix(command, "echo "):
+		_, _ = io.WriteString(ch, strings.TrimPrefix(command, "echo ")+"\n")
+		return 0
+	case strings.HasPrefix(command, "fail "):
+		_, _ = io.WriteString(ch.Stderr(), "failed\n")
+		code, _ := strconv.Atoi(strings.TrimPrefix(command, "fail "))
+		return code
+	case strings.HasPrefix(command, "cat > "):
+		data, _ := io.ReadAll(ch)
+		s.mu.Lock()
+		s.uploads[command] = string(data)
+		s.mu.Unlock()
+		return 0
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = s.home
+	cmd.Stdin = ch
+	cmd.Stdout = ch
+	cmd.Stderr = ch.Stderr()
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 127
+	}
+	return 0
+}
+
+func (s *testServer) connectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// newTestClient writes a client key and returns a client for s
+func newTestClient(t *testing.T) (*Client, ssh.PublicKey, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	cfg := Config{
+		User:              "user",
+		IdentityFiles:     []string{keyPath},
+		KnownHostsFile:    filepath.Join(dir, "known_hosts"),
+		HostKeyAlias:      "bootc-man-test",
+		AcceptNewHostKeys: true,
+	}
+	return New(cfg), sshPub, dir
+}
+
+func pointAt(c *Client, addr string) *Client {
+	host, port, _ := net.SplitHostPort(addr)
+	cfg := c.Config()
+	cfg.Host = host
+	cfg.Port, _ = strconv.Atoi(port)
+	return New(cfg)
+}
+
+func TestClientRun(t *testing.T) {
+	base, pub, dir := newTestClient(t)
+	server := newTestServer(t, pub)
+	client := pointAt(base, server.addr)
+	defer client.Close()
+	ctx := context.Background()
+
+	stdout, _, err := client.Output(ctx, "echo hello")
+	if err != nil {
+		t.Fatalf("Output() error = %v", err)
+	}
+	if string(stdout) != "hello\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "hello\n")
+	}
+
+	// Non-zero exit codes are reported, not treated as connection errors
+	_, stderr, err := client.Output(ctx, "fail 3")
+	if ExitCode(err) != 3 || IsConnectionLost(err) {
+		t.Errorf("Output(fail 3) error = %v, want exit code 3", err)
+	}
+	if string(stderr) != "failed\n" {
+		t.Errorf("stderr = %q, want %q", stderr, "failed\n")
+	}
+
+	if got := server.connectionCount(); got != 1 {
+		t.Errorf("connections = %d, want 1 (connection reused)", got)
+	}
+
+	// A dropped connection is distinguishable and the next command reconnects
+	err = client.Run(ctx, "drop", io.Discard, io.Discard)
+	if !IsConnectionLost(err) {
+		t.Errorf("Run(drop) error = %v, want ErrConnectionLost", err)
+	}
+	if _, _, err := client.Output(ctx, "echo again"); err != nil {
+		t.Fatalf("Output() after drop error = %v", err)
+	}
+	if got := server.connectionCount(); got != 2 {
+		t.Errorf("connections = %d, want 2 (reconnected)", got)
+	}
+
+	// The host key was recorded under the alias
+	known, err := os.ReadFile(filepath.Join(dir, "known_hosts"))
+	if err != nil {
+		t.Fatalf("known_hosts not written: %v", err)
+	}
+	if !strings.HasPrefix(string(known), "bootc-man-test ssh-ed25519 ") {
+		t.Errorf("known_hosts = %q, want entry for bootc-man-test", known)
+	}
+}
+
+func TestClientRejectsChangedHostKey(t *testing.T) {
+	base, pub, _ := newTestClient(t)
+
+	first := pointAt(base, newTestServer(t, pub).addr)
+	if err := first.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	first.Close()
+
+	// Same alias, different host key
+	second := pointAt(base, newTestServer(t, pub).addr)
+	err := second.Connect(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "host key verification failed") {
+		t.Errorf("Connect() error = %v, want host key verification failure", err)
+	}
+}
+
+func TestClientUpload(t *testing.T) {
+	base, pub, _ := newTestClient(t)
+	server := newTestServer(t, pub)
+	client := pointAt(base, server.addr)
+	defer client.Close()
+
+	if err := client.Upload(context.Background(), strings.NewReader("data"), "/tmp/it's.txt", 0640); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	want := `cat > '/tmp/.it'\''s.txt.bootc-man-upload' && chmod 640 '/tmp/.it'\''s.txt.bootc-man-upload' && mv -f '/tmp/.it'\''s.txt.bootc-man-upload' '/tmp/it'\''s.txt'`
+	if got, ok := server.uploads[want]; !ok || got != "data" {
+		t.Errorf("uploads = %v, want %q -> data", server.uploads, want)
+	}
+}
+
+func TestResolveHost(t *testing.T) {
+	sshConfig, err := ssh_config.DecodeBytes([]byte(`
+Host build
+  HostName build.example.com
+  User core
+  Port 2222
+  IdentityFile ~/.ssh/build_key
+
+Host *
+  StrictHostKeyChecking yes
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("USER", "me")
+
+	tests := []struct {
+		alias    string
+		wantAddr string
+		wantUser string
+		wantKey  string
+	}{
+		{"build", "build.example.com:2222", "core", "/home/me/.ssh/build_key"},
+		{"admin@build", "build.example.com:2222", "admin", "/home/me/.ssh/build_key"},
+		{"other", "other:22", "me", "/home/me/.ssh/id_ed25519"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			cfg, err := resolveHost(sshConfig, tt.alias, "/home/me")
+			if err != nil {
+				t.Fatalf("resolveHost() error = %v", err)
+			}
+			if cfg.Address() != tt.wantAddr || cfg.User != tt.wantUser || cfg.IdentityFiles[0] != tt.wantKey {
+				t.Errorf("resolveHost() = %s %s %v, want %s %s %s", cfg.Address(), cfg.User, cfg.IdentityFiles, tt.wantAddr, tt.wantUser, tt.wantKey)
+			}
+			if cfg.AcceptNewHostKeys {
+				t.Error("AcceptNewHostKeys = true, want false (StrictHostKeyChecking yes)")
+			}
+		})
+	}
+}
+
+func TestResolveHostProxy(t *testing.T) {
+	sshConfig, err := ssh_config.DecodeBytes([]byte(`
+Host bastion
+  HostName bastion.example.com
+  User jump
+
+Host internal
+  HostName 10.0.0.5
+  ProxyJump bastion
+
+Host chain
+  ProxyJump admin@edge:2200,bastion
+
+Host piped
+  HostName piped.example.com
+  User core
+  ProxyCommand ssh -W %h:%p %r@gateway
+
+Host loop
+  ProxyJump loop
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("USER", "me")
+
+	cfg, err := resolveHost(sshConfig, "internal", "/home/me")
+	if err != nil {
+		t.Fatalf("resolveHost(internal) error = %v", err)
+	}
+	if cfg.ProxyJump == nil || cfg.ProxyJump.String() != "jump@bastion.example.com:22" {
+		t.Errorf("internal ProxyJump = %+v, want jump@bastion.example.com:22", cfg.ProxyJump)
+	}
+
+	// bastion is reached through edge, the first hop
+	cfg, err = resolveHost(sshConfig, "chain", "/home/me")
+	if err != nil {
+		t.Fatalf("resolveHost(chain) error = %v", err)
+	}
+	if cfg.ProxyJump == nil || cfg.ProxyJump.Address() != "bastion.example.com:22" ||
+		cfg.ProxyJump.ProxyJump == nil || cfg.ProxyJump.ProxyJump.String() != "admin@edge:2200" {
+		t.Errorf("chain ProxyJump = %+v, want bastion through admin@edge:2200", cfg.ProxyJump)
+	}
+
+	cfg, err = resolveHost(sshConfig, "piped", "/home/me")
+	if err != nil {
+		t.Fatalf("resolveHost(piped) error = %v", err)
+	}
+	if want := "ssh -W piped.example.com:22 core@gateway"; cfg.ProxyCommand != want {
+		t.Errorf("ProxyCommand = %q, want %q", cfg.ProxyCommand, want)
+	}
+
+	if _, err := resolveHost(sshConfig, "loop", "/home/me"); err == nil {
+		t.Error("resolveHost(loop) error = nil, want error for a ProxyJump loop")
+	}
+}
+
+func TestClientProxyJump(t *testing.T) {
+	base, pub, _ := newTestClient(t)
+	bastion := newTestServer(t, pub)
+	server := newTestServer(t, pub)
+
+	jump := pointAt(base, bastion.addr).Config()
+	jump.HostKeyAlias = "bootc-man-jump"
+	cfg := pointAt(base, server.addr).Config()
+	cfg.ProxyJump = &jump
+	client := New(cfg)
+	defer client.Close()
+
+	var stdout bytes.Buffer
+	if err := client.Run(context.Background(), "echo through", &stdout, nil); err != nil {
+		t.Fatalf("Run() through jump host error = %v", err)
+	}
+	if stdout.String() != "through\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "through\n")
+	}
+	if bastion.connectionCount() != 1 || server.connectionCount() != 1 {
+		t.Errorf("connections = %d (bastion), %d (server), want 1 each", bastion.connectionCount(), server.connectionCount())
+	}
+}
+
+func TestDialCommand(t *testing.T) {
+	conn, err := dialCommand("cat")
+	if err != nil {
+		t.Fatalf("dialCommand() error = %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("read %q, %v, want ping", buf, err)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "''"},
+		{"/var/tmp/file.txt", "/var/tmp/file.txt"},
+		{"with space", "'with space'"},
+		{"it's", `'it'\''s'`},
+		{"$(reboot)", "'$(reboot)'"},
+	}
+	for _, tt := range tests {
+		if got := ShellQuote(tt.in); got != tt.want {
+			t.Errorf("ShellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevinburke/ssh_config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Config describes how to reach and authenticate to an SSH server
+type Config struct {
+	Host          string
+	Port          int
+	User          string
+	IdentityFiles []string // private keys tried in order; ssh-agent is used as well
+
+	// KnownHostsFile is where host keys are verified and recorded
+	// Defaults to ~/.ssh/known_hosts.
+	KnownHostsFile string
+	// HostKeyAlias is the name the host key is stored under instead of host:port
+	HostKeyAlias string
+	// AcceptNewHostKeys records unknown host keys on first connect
+	// (StrictHostKeyChecking=accept-new); changed keys are always rejected
+	AcceptNewHostKeys bool
+
+	// ProxyCommand is run with /bin/sh and its stdin and stdout carry the
+	// connection instead of a TCP connection to Host
+	ProxyCommand string
+	// ProxyJump is the host the connection is tunnelled through; it can
+	// have a jump host of its own
+	ProxyJump *Config
+
+	ConnectTimeout    time.Duration // default DefaultConnectTimeout
+	KeepAliveInterval time.Duration // default DefaultKeepAliveInterval
+}
+
+// Address returns host:port
+func (c Config) Address() string {
+	port := c.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+// String returns user@host:port for messages
+func (c Config) String() string {
+	return fmt.Sprintf("%s@%s", c.User, c.Address())
+}
+
+func (c Config) connectTimeout() time.Duration {
+	if c.ConnectTimeout > 0 {
+		return c.ConnectTimeout
+	}
+	return DefaultConnectTimeout
+}
+
+func (c Config) keepAliveInterval() time.Duration {
+	if c.KeepAliveInterval > 0 {
+		return c.KeepAliveInterval
+	}
+	return DefaultKeepAliveInterval
+}
+
+// clientConfig builds the x/crypto/ssh client configuration
+func (c Config) clientConfig() (*ssh.ClientConfig, error) {
+	auth, err := c.authMethods()
+	if err != nil {
+		return nil, err
+	}
+
+	knownHosts := c.KnownHostsFile
+	if knownHosts == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		knownHosts = filepath.Join(homeDir, ".ssh", "known_hosts")
+	}
+
+	return &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback(knownHosts, c.HostKeyAlias, c.AcceptNewHostKeys),
+		Timeout:         c.connectTimeout(),
+	}, nil
+}
+
+// authMethods returns public key authentication using the identity files
+// and, if SSH_AUTH_SOCK is set, the ssh-agent
+func (c Config) authMethods() ([]ssh.AuthMethod, error) {
+	var signers []ssh.Signer
+	var loadErrs []string
+	for _, file := range c.IdentityFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				loadErrs = append(loadErrs, err.Error())
+			}
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			var passErr *ssh.PassphraseMissingError
+			if !errors.As(err, &passErr) {
+				loadErrs = append(loadErrs, fmt.Sprintf("%s: %v", file, err))
+			}
+			// Passphrase-protected keys are expected to be in the agent
+			continue
+		}
+		signers = append(signers, signer)
+	}
+
+	var methods []ssh.AuthMethod
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	if len(methods) == 0 {
+		if len(loadErrs) > 0 {
+			return nil, fmt.Errorf("no usable SSH key: %s", strings.Join(loadErrs, "; "))
+		}
+		return nil, fmt.Errorf("no SSH key found (tried %s) and no ssh-agent available", strings.Join(c.IdentityFiles, ", "))
+	}
+	return methods, nil
+}
+
+// ResolveHost builds a Config for a host alias from ~/.ssh/config
+// HostName, User, Port, IdentityFile, UserKnownHostsFile, HostKeyAlias,
+// StrictHostKeyChecking, ProxyCommand and ProxyJump are honoured, including
+// from Include files and Match host blocks; unknown aliases are used as the
+// host name.
+func ResolveHost(alias string) (Config, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	var sshConfig *ssh_config.Config
+	f, err := os.Open(filepath.Join(homeDir, ".ssh", "config"))
+	if err == nil {
+		sshConfig, err = ssh_config.Decode(f)
+		f.Close()
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse ~/.ssh/config: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return Config{}, fmt.Errorf("failed to read ~/.ssh/config: %w", err)
+	}
+
+	return resolveHost(sshConfig, alias, homeDir)
+}
+
+// maxProxyJumpDepth bounds ProxyJump chains, which can loop in a config
+const maxProxyJumpDepth = 8
+
+// resolveHost applies the ssh_config settings for alias
+func resolveHost(sshConfig *ssh_config.Config, alias, homeDir string) (Config, error) {
+	return resolveHostDepth(sshConfig, alias, homeDir, 0)
+}
+
+// resolveHostDepth is resolveHost for a host reached through depth jump hosts
+func resolveHostDepth(sshConfig *ssh_config.Config, alias, homeDir string, depth int) (Config, error) {
+	get := func(key string) string {
+		if sshConfig == nil {
+			return ""
+		}
+		v, _ := sshConfig.Get(alias, key)
+		return v
+	}
+
+	// user@host is accepted like on the ssh command line
+	user := ""
+	host := alias
+	original := alias
+	if i := strings.LastIndex(alias, "@"); i >= 0 {
+		user, host = alias[:i], alias[i+1:]
+		alias = host
+	}
+
+	cfg := Config{
+		Host:              host,
+		Port:              22,
+		User:              user,
+		AcceptNewHostKeys: true,
+	}
+
+	if v := get("HostName"); v != "" {
+		cfg.Host = strings.ReplaceAll(v, "%h", host)
+	}
+	if v := get("Port"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid Port %q for host %s in ~/.ssh/config", v, alias)
+		}
+		cfg.Port = port
+	}
+	if cfg.User == "" {
+		cfg.User = get("User")
+	}
+	if cfg.User == "" {
+		cfg.User = os.Getenv("USER")
+	}
+	if v := get("UserKnownHostsFile"); v != "" {
+		cfg.KnownHostsFile = expandHome(strings.Fields(v)[0], homeDir)
+	}
+	if v := get("HostKeyAlias"); v != "" {
+		cfg.HostKeyAlias = v
+	}
+	if strings.EqualFold(get("StrictHostKeyChecking"), "yes") {
+		cfg.AcceptNewHostKeys = false
+	}
+
+	if sshConfig != nil {
+		files, _ := sshConfig.GetAll(alias, "IdentityFile")
+		for _, f := range files {
+			cfg.IdentityFiles = append(cfg.IdentityFiles, expandHome(f, homeDir))
+		}
+	}
+	if len(cfg.IdentityFiles) == 0 {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			cfg.IdentityFiles = append(cfg.IdentityFiles, filepath.Join(homeDir, ".ssh", name))
+		}
+	}
+
+	// Like ssh, a ProxyCommand takes precedence over a ProxyJump
+	if v := get("ProxyCommand"); v != "" && !strings.EqualFold(v, "none") {
+		cfg.ProxyCommand = strings.NewReplacer(
+			"%%", "%",
+			"%h", cfg.Host,
+			"%p", strconv.Itoa(cfg.Port),
+			"%r", cfg.User,
+			"%n", original,
+		).Replace(v)
+	} else if v := get("ProxyJump"); v != "" && !strings.EqualFold(v, "none") {
+		jump, err := resolveProxyJump(sshConfig, v, homeDir, depth)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ProxyJump %q for host %s in ~/.ssh/config: %w", v, alias, err)
+		}
+		cfg.ProxyJump = jump
+	}
+	return cfg, nil
+}
+
+// resolveProxyJump resolves a ProxyJump list of [user@]host[:port] hops
+// The first hop is connected to directly (or through its own ProxyJump) and
+// each further hop is reached through the one before it.
+func resolveProxyJump(sshConfig *ssh_config.Config, spec, homeDir string, depth int) (*Config, error) {
+	if depth >= maxProxyJumpDepth {
+		return nil, fmt.Errorf("more than %d jump hosts", maxProxyJumpDepth)
+	}
+	var jump *Config
+	for _, hop := range strings.Split(spec, ",") {
+		hop = strings.TrimPrefix(strings.TrimSpace(hop), "ssh://")
+		alias, port := hop, 0
+		if h, p, err := net.SplitHostPort(hop); err == nil {
+			if port, err = strconv.Atoi(p); err != nil {
+				return nil, fmt.Errorf("invalid port in %q", hop)
+			}
+			alias = h
+		}
+		cfg, err := resolveHostDepth(sshConfig, alias, homeDir, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if port != 0 {
+			cfg.Port = port
+		}
+		if jump != nil {
+			cfg.ProxyCommand, cfg.ProxyJump = "", jump
+		}
+		jump = &cfg
+	}
+	return jump, nil
+}
+
+// expandHome expands a leading ~ to homeDir
+func expandHome(p, homeDir string) string {
+	if p == "~" {
+		return homeDir
+	}
+	if strings.HasPrefix(p, "~/") {
+		return filepath.Join(homeDir, p[2:])
+	}
+	return p
+}
@@ -0,0 +1,268 @@
+package sshclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// CopyTo copies a local file or directory to remotePath
+// Like cp -r, the source is copied into remotePath when that is an existing
+// directory and copied as remotePath otherwise. The transfer is a tar stream
+// unpacked by tar on the server.
+func (c *Client) CopyTo(ctx context.Context, localPath, remotePath string) error {
+	if _, err := os.Lstat(localPath); err != nil {
+		return err
+	}
+
+	destDir, name := path.Dir(remotePath), path.Base(remotePath)
+	if c.Run(ctx, "test -d "+ShellQuote(remotePath), nil, nil) == nil {
+		destDir, name = remotePath, filepath.Base(localPath)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, localPath, name))
+	}()
+
+	var stderr bytes.Buffer
+	command := fmt.Sprintf("tar -x -f - -C %s", ShellQuote(destDir))
+	err := c.RunWithOptions(ctx, command, RunOptions{Stdin: pr, Stderr: &stderr})
+	// Unblock the tar writer if the remote side stopped reading
+	pr.Close()
+	if err != nil {
+		return copyError(fmt.Sprintf("copy %s to %s", localPath, remotePath), err, stderr.String())
+	}
+	return nil
+}
+
+// CopyFrom copies a remote file or directory to localPath
+// Like cp -r, the source is copied into localPath when that is an existing
+// directory and copied as localPath otherwise.
+func (c *Client) CopyFrom(ctx context.Context, remotePath, localPath string) error {
+	remotePath = path.Clean(remotePath)
+	srcDir, srcName := path.Dir(remotePath), path.Base(remotePath)
+	if srcName == "." || srcName == ".." || srcName == "/" {
+		// A directory without a name of its own (such as the home directory)
+		// is archived as "." from inside it
+		srcDir, srcName = remotePath, "."
+	}
+	target := localPath
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		target = filepath.Join(localPath, srcName)
+	}
+
+	pr, pw := io.Pipe()
+	extracted := make(chan error, 1)
+	go func() {
+		err := readTar(pr, srcName, target)
+		// Keep draining so that the session can finish
+		_, _ = io.Copy(io.Discard, pr)
+		extracted <- err
+	}()
+
+	var stderr bytes.Buffer
+	command := fmt.Sprintf("tar -c -f - -C %s %s", ShellQuote(srcDir), ShellQuote(srcName))
+	err := c.RunWithOptions(ctx, command, RunOptions{Stdout: pw, Stderr: &stderr})
+	pw.Close()
+	extractErr := <-extracted
+
+	if err != nil {
+		return copyError(fmt.Sprintf("copy %s to %s", remotePath, localPath), err, stderr.String())
+	}
+	if extractErr != nil {
+		return fmt.Errorf("failed to extract %s: %w", remotePath, extractErr)
+	}
+	return nil
+}
+
+// copyError formats a failed copy, including the remote tar's message
+func copyError(what string, err error, stderr string) error {
+	if msg := strings.TrimSpace(stderr); msg != "" {
+		return fmt.Errorf("failed to %s: %w\n%s", what, err, msg)
+	}
+	return fmt.Errorf("failed to %s: %w", what, err)
+}
+
+// writeTar writes localPath to w as a tar archive whose top-level entry is name
+// Owners are not preserved; the files belong to the extracting user.
+func writeTar(w io.Writer, localPath, name string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(localPath, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		rel, err := filepath.Rel(localPath, file)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(name, filepath.ToSlash(rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		hdr.Format = tar.FormatPAX
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readTar extracts the tar archive r, whose top-level entry is srcName, to target
+// The archive comes from the guest, so entries outside srcName, entries
+// written through a symlink and symlinks pointing outside srcName are
+// rejected.
+func readTar(r io.Reader, srcName, target string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(path.Clean(hdr.Name), "/")
+		rel, ok := archiveRel(name, srcName)
+		if !ok || (rel != "" && !strings.HasPrefix(rel, "/")) || strings.Contains(rel, "/../") || strings.HasSuffix(rel, "/..") {
+			return fmt.Errorf("unexpected archive entry %q", hdr.Name)
+		}
+		dest := target + filepath.FromSlash(rel)
+		mode := os.FileMode(hdr.Mode).Perm()
+		if rel != "" {
+			if err := checkNoSymlinkParents(target, rel); err != nil {
+				return err
+			}
+			// Replace a symlink extracted earlier instead of writing where it points
+			if info, err := os.Lstat(dest); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				if err := os.Remove(dest); err != nil {
+					return err
+				}
+			}
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			_ = os.Chmod(dest, mode)
+			_ = os.Chtimes(dest, hdr.ModTime, hdr.ModTime)
+		case tar.TypeSymlink:
+			if !symlinkInTree(name, hdr.Linkname, srcName) {
+				return fmt.Errorf("refusing symlink %q -> %q: it points outside %s", hdr.Name, hdr.Linkname, srcName)
+			}
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			os.Remove(dest)
+			if err := os.Symlink(hdr.Linkname, dest); err != nil {
+				return err
+			}
+		default:
+			// Devices, FIFOs and hard links are skipped
+		}
+	}
+}
+
+// archiveRel returns the path of the archive entry name below srcName,
+// either empty or starting with a slash
+// An archive of "." holds entries such as "./file", which path.Clean turns
+// into "file".
+func archiveRel(name, srcName string) (string, bool) {
+	if srcName != "." {
+		return strings.CutPrefix(name, srcName)
+	}
+	switch {
+	case name == ".":
+		return "", true
+	case path.IsAbs(name), name == "..", strings.HasPrefix(name, "../"):
+		return "", false
+	}
+	return "/" + name, true
+}
+
+// checkNoSymlinkParents fails if target or a directory on the way to rel
+// below it is a symlink, so that no archive entry is written outside target
+func checkNoSymlinkParents(target, rel string) error {
+	dir := target
+	parts := strings.Split(strings.TrimPrefix(rel, "/"), "/")
+	for i := 0; i < len(parts); i++ {
+		if i > 0 {
+			dir = filepath.Join(dir, parts[i-1])
+		}
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to extract %s through symlink %s", rel, dir)
+		}
+	}
+	return nil
+}
+
+// symlinkInTree reports whether the symlink name -> link (archive paths)
+// resolves inside srcName
+func symlinkInTree(name, link, srcName string) bool {
+	if path.IsAbs(link) {
+		return false
+	}
+	resolved := path.Join(path.Dir(name), link)
+	if srcName == "." {
+		return resolved != ".." && !strings.HasPrefix(resolved, "../")
+	}
+	return resolved == srcName || strings.HasPrefix(resolved, srcName+"/")
+}
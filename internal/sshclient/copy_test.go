@@ -0,0 +1,215 @@
+package sshclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates a small directory tree with a file, a subdirectory and a symlink
+func writeTree(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "data.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/data.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkTree verifies a copy of the tree created by writeTree
+func checkTree(t *testing.T, dir string) {
+	t.Helper()
+	if data, err := os.ReadFile(filepath.Join(dir, "sub", "data.txt")); err != nil || string(data) != "data" {
+		t.Errorf("sub/data.txt = %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "run.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("run.sh not copied with mode 0755: %v %v", info, err)
+	}
+	if link, err := os.Readlink(filepath.Join(dir, "link")); err != nil || link != "sub/data.txt" {
+		t.Errorf("link = %q, %v", link, err)
+	}
+}
+
+func TestTarRoundTrip(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "suite")
+	writeTree(t, src)
+
+	var buf bytes.Buffer
+	if err := writeTar(&buf, src, "tests"); err != nil {
+		t.Fatalf("writeTar() error = %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := readTar(&buf, "tests", dst); err != nil {
+		t.Fatalf("readTar() error = %v", err)
+	}
+	checkTree(t, dst)
+}
+
+func TestReadTarRejectsEscapes(t *testing.T) {
+	for _, name := range []string{"../evil", "other/file", "tests/../../evil", "testsuffix"} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 1, Typeflag: tar.TypeReg})
+			_, _ = tw.Write([]byte("x"))
+			_ = tw.Close()
+
+			dir := t.TempDir()
+			if err := readTar(&buf, "tests", filepath.Join(dir, "tests")); err == nil {
+				t.Error("readTar() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestReadTarDot(t *testing.T) {
+	// tar -C <dir> . archives the directory itself as "./"
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	_ = tw.WriteHeader(&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755})
+	_ = tw.WriteHeader(&tar.Header{Name: "./sub/", Typeflag: tar.TypeDir, Mode: 0755})
+	_ = tw.WriteHeader(&tar.Header{Name: "./sub/data.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 4})
+	_, _ = tw.Write([]byte("data"))
+	_ = tw.WriteHeader(&tar.Header{Name: "./link", Typeflag: tar.TypeSymlink, Linkname: "sub/data.txt"})
+	_ = tw.Close()
+
+	dst := filepath.Join(t.TempDir(), "home")
+	if err := readTar(&buf, ".", dst); err != nil {
+		t.Fatalf("readTar() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "link")); err != nil || string(data) != "data" {
+		t.Errorf("link = %q, %v", data, err)
+	}
+
+	for _, hdr := range []tar.Header{
+		{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "/etc/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "./link", Typeflag: tar.TypeSymlink, Linkname: "../outside"},
+	} {
+		t.Run(hdr.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			_ = tw.WriteHeader(&hdr)
+			_ = tw.Close()
+			if err := readTar(&buf, ".", filepath.Join(t.TempDir(), "home")); err == nil {
+				t.Error("readTar() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestReadTarRejectsSymlinkEscapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tar.Header
+	}{
+		{
+			name: "file through symlink",
+			entries: []tar.Header{
+				{Name: "tests/sub/", Typeflag: tar.TypeDir, Mode: 0755},
+				{Name: "tests/link", Typeflag: tar.TypeSymlink, Linkname: "sub"},
+				{Name: "tests/link/.bashrc", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+			},
+		},
+		{
+			name:    "absolute link target",
+			entries: []tar.Header{{Name: "tests/link", Typeflag: tar.TypeSymlink, Linkname: "/home/user"}},
+		},
+		{
+			name:    "link target leaving the tree",
+			entries: []tar.Header{{Name: "tests/sub/link", Typeflag: tar.TypeSymlink, Linkname: "../../outside"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for _, hdr := range tt.entries {
+				_ = tw.WriteHeader(&hdr)
+				if hdr.Size > 0 {
+					_, _ = tw.Write([]byte("x"))
+				}
+			}
+			_ = tw.Close()
+
+			dir := t.TempDir()
+			if err := readTar(&buf, "tests", filepath.Join(dir, "tests")); err == nil {
+				t.Error("readTar() succeeded, want error")
+			}
+			if _, err := os.Stat(filepath.Join(dir, "tests", "sub", ".bashrc")); err == nil {
+				t.Error("file was written through the symlink")
+			}
+		})
+	}
+}
+
+func TestClientCopy(t *testing.T) {
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar not available")
+	}
+	base, pub, _ := newTestClient(t)
+	server := newTestServer(t, pub)
+	client := pointAt(base, server.addr)
+	defer client.Close()
+	ctx := context.Background()
+
+	src := filepath.Join(t.TempDir(), "suite")
+	writeTree(t, src)
+
+	// Copied as the destination name when it does not exist
+	if err := client.CopyTo(ctx, src, "tests"); err != nil {
+		t.Fatalf("CopyTo() error = %v", err)
+	}
+	checkTree(t, filepath.Join(server.home, "tests"))
+
+	// Copied into an existing directory
+	if err := os.Mkdir(filepath.Join(server.home, "dest"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CopyTo(ctx, filepath.Join(src, "sub", "data.txt"), server.home+"/dest"); err != nil {
+		t.Fatalf("CopyTo() into directory error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(server.home, "dest", "data.txt")); err != nil || string(data) != "data" {
+		t.Errorf("dest/data.txt = %q, %v", data, err)
+	}
+
+	// Download into an existing local directory and as a new name
+	local := t.TempDir()
+	if err := client.CopyFrom(ctx, "tests", local); err != nil {
+		t.Fatalf("CopyFrom() error = %v", err)
+	}
+	checkTree(t, filepath.Join(local, "tests"))
+
+	// "<vm>:" downloads the home directory itself
+	home := filepath.Join(t.TempDir(), "home")
+	if err := client.CopyFrom(ctx, ".", home); err != nil {
+		t.Fatalf("CopyFrom(.) error = %v", err)
+	}
+	checkTree(t, filepath.Join(home, "tests"))
+
+	file := filepath.Join(local, "renamed.txt")
+	if err := client.CopyFrom(ctx, "tests/sub/data.txt", file); err != nil {
+		t.Fatalf("CopyFrom() file error = %v", err)
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "data" {
+		t.Errorf("renamed.txt = %q, %v", data, err)
+	}
+
+	// Missing remote files report tar's message
+	err := client.CopyFrom(ctx, "missing", local)
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("CopyFrom(missing) error = %v, want error mentioning the path", err)
+	}
+}
@@ -0,0 +1,72 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// knownHostsMu serialises updates of known_hosts files within the process
+var knownHostsMu sync.Mutex
+
+// hostKeyCallback verifies host keys against knownHostsFile
+// When alias is set, the key is looked up and stored under alias instead of
+// host:port. Unknown keys are recorded if acceptNew is set; a key that
+// differs from the recorded one is always rejected.
+func hostKeyCallback(knownHostsFile, alias string, acceptNew bool) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsMu.Lock()
+		defer knownHostsMu.Unlock()
+
+		// Aliases are stored without port, like OpenSSH's HostKeyAlias
+		name := hostname
+		if alias != "" {
+			name = net.JoinHostPort(alias, "22")
+		}
+
+		if _, err := os.Stat(knownHostsFile); err == nil {
+			check, err := knownhosts.New(knownHostsFile)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", knownHostsFile, err)
+			}
+			err = check(name, remote, key)
+			if err == nil {
+				return nil
+			}
+			var keyErr *knownhosts.KeyError
+			if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+				return fmt.Errorf("host key verification failed for %s (recorded in %s): %w", knownhosts.Normalize(name), knownHostsFile, err)
+			}
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", knownHostsFile, err)
+		}
+
+		if !acceptNew {
+			return fmt.Errorf("host key for %s is not known (add it to %s)", knownhosts.Normalize(name), knownHostsFile)
+		}
+		return appendKnownHost(knownHostsFile, name, key)
+	}
+}
+
+// appendKnownHost records key for name in knownHostsFile
+func appendKnownHost(knownHostsFile, name string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(knownHostsFile), 0700); err != nil {
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(knownHostsFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", knownHostsFile, err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(name)}, key)); err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+	return nil
+}
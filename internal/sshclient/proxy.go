@@ -0,0 +1,112 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dialTransport opens the connection the SSH session runs over: a TCP
+// connection, the stdio of the ProxyCommand or a channel through the
+// ProxyJump host
+func (c Config) dialTransport(ctx context.Context) (net.Conn, error) {
+	addr := c.Address()
+	switch {
+	case c.ProxyCommand != "":
+		conn, err := dialCommand(c.ProxyCommand)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run ProxyCommand for %s: %w", addr, err)
+		}
+		return conn, nil
+
+	case c.ProxyJump != nil:
+		jump, err := New(*c.ProxyJump).dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to jump host %s: %w", c.ProxyJump.Address(), err)
+		}
+		conn, err := jump.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			jump.Close()
+			return nil, fmt.Errorf("failed to connect to %s through %s: %w", addr, c.ProxyJump.Address(), err)
+		}
+		return &jumpConn{Conn: conn, jump: jump}, nil
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// jumpConn is a connection tunnelled through a jump host, which is
+// disconnected together with it
+type jumpConn struct {
+	net.Conn
+	jump *ssh.Client
+}
+
+func (c *jumpConn) Close() error {
+	err := c.Conn.Close()
+	c.jump.Close()
+	return err
+}
+
+// errNoDeadline is returned when deadlines are set on a commandConn
+var errNoDeadline = errors.New("deadlines are not supported on a ProxyCommand connection")
+
+// commandConn is a connection over the stdin and stdout of a ProxyCommand
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+}
+
+// dialCommand starts command with /bin/sh; its stderr goes to ours like
+// with ssh
+func dialCommand(command string) (*commandConn, error) {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &commandConn{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+func (c *commandConn) Read(b []byte) (int, error)  { return c.stdout.Read(b) }
+func (c *commandConn) Write(b []byte) (int, error) { return c.stdin.Write(b) }
+
+func (c *commandConn) Close() error {
+	c.stdin.Close()
+	_ = c.cmd.Process.Kill()
+	_ = c.cmd.Wait()
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr                { return proxyCommandAddr{} }
+func (c *commandConn) RemoteAddr() net.Addr               { return proxyCommandAddr{} }
+func (c *commandConn) SetDeadline(t time.Time) error      { return errNoDeadline }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return errNoDeadline }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return errNoDeadline }
+
+// proxyCommandAddr is the address of both ends of a commandConn
+type proxyCommandAddr struct{}
+
+func (proxyCommandAddr) Network() string { return "proxycommand" }
+func (proxyCommandAddr) String() string  { return "proxycommand" }
@@ -0,0 +1,76 @@
+package vm
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Guest architectures (Go naming, as used in container image platforms)
+const (
+	ArchAMD64 = "amd64"
+	ArchARM64 = "arm64"
+)
+
+// Accelerators used by the QEMU driver
+const (
+	AccelKVM = "kvm"
+	AccelTCG = "tcg"
+)
+
+// EmulationTimeoutScale is the factor boot and SSH timeouts are multiplied by
+// when the guest CPU is emulated in software
+const EmulationTimeoutScale = 5
+
+// HostArch returns the architecture of the host
+func HostArch() string {
+	return runtime.GOARCH
+}
+
+// NormalizeArch converts an architecture or platform name to Go naming
+// "x86_64", "aarch64" and platforms like "linux/arm64" are accepted.
+// An empty string means the host architecture.
+func NormalizeArch(arch string) (string, error) {
+	if arch == "" {
+		return HostArch(), nil
+	}
+	// linux/arm64 or linux/arm64/v8
+	if parts := strings.Split(arch, "/"); len(parts) > 1 {
+		arch = parts[1]
+	}
+	switch strings.ToLower(arch) {
+	case "amd64", "x86_64", "x86-64":
+		return ArchAMD64, nil
+	case "arm64", "aarch64":
+		return ArchARM64, nil
+	}
+	return "", fmt.Errorf("unsupported architecture %q (supported: amd64, arm64)", arch)
+}
+
+// QemuArch returns the QEMU name of an architecture (x86_64, aarch64)
+func QemuArch(arch string) string {
+	switch arch {
+	case ArchARM64:
+		return "aarch64"
+	case ArchAMD64:
+		return "x86_64"
+	}
+	return arch
+}
+
+// Emulator is implemented by drivers that may run the guest without hardware
+// acceleration (e.g. QEMU falling back to TCG)
+type Emulator interface {
+	// Accelerator returns the accelerator in use, e.g. "kvm" or "tcg"
+	Accelerator() string
+}
+
+// ScaleTimeout stretches a boot-related timeout when the driver emulates the
+// guest CPU in software
+func ScaleTimeout(driver Driver, timeout time.Duration) time.Duration {
+	if e, ok := driver.(Emulator); ok && e.Accelerator() == AccelTCG {
+		return timeout * EmulationTimeoutScale
+	}
+	return timeout
+}
@@ -0,0 +1,51 @@
+package vm
+
+import (
+	"testing"
+)
+
+func TestNormalizeArch(t *testing.T) {
+	tests := []struct {
+		arch    string
+		want    string
+		wantErr bool
+	}{
+		{"", HostArch(), false},
+		{"amd64", ArchAMD64, false},
+		{"x86_64", ArchAMD64, false},
+		{"arm64", ArchARM64, false},
+		{"AArch64", ArchARM64, false},
+		{"linux/arm64", ArchARM64, false},
+		{"linux/arm64/v8", ArchARM64, false},
+		{"linux/amd64", ArchAMD64, false},
+		{"riscv64", "", true},
+		{"linux/s390x", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arch, func(t *testing.T) {
+			got, err := NormalizeArch(tt.arch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeArch(%q) error = %v, wantErr %v", tt.arch, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeArch(%q) = %q, want %q", tt.arch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQemuArch(t *testing.T) {
+	tests := []struct {
+		arch string
+		want string
+	}{
+		{ArchAMD64, "x86_64"},
+		{ArchARM64, "aarch64"},
+		{"riscv64", "riscv64"},
+	}
+	for _, tt := range tests {
+		if got := QemuArch(tt.arch); got != tt.want {
+			t.Errorf("QemuArch(%q) = %q, want %q", tt.arch, got, tt.want)
+		}
+	}
+}
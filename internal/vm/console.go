@@ -0,0 +1,83 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ConsoleEscapeByte detaches from the serial console (Ctrl-])
+const ConsoleEscapeByte = 0x1d
+
+// ErrConsoleDetached is returned when the user detached with the escape sequence
+var ErrConsoleDetached = errors.New("detached from console")
+
+// AttachConsole connects the terminal to a VM's serial console socket
+// The terminal is put in raw mode so that control keys reach the guest.
+// It returns ErrConsoleDetached when the user presses Ctrl-], or nil when
+// the VM closes the console (e.g. on shutdown).
+func AttachConsole(ctx context.Context, socketPath string, in *os.File, out io.Writer) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to serial console: %w", err)
+	}
+	defer conn.Close()
+
+	fd := int(in.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal to raw mode: %w", err)
+		}
+		defer func() { _ = term.Restore(fd, state) }()
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(out, conn)
+		errCh <- err
+	}()
+	go func() {
+		errCh <- copyUntilEscape(conn, in)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// copyUntilEscape copies terminal input to the console until the escape
+// byte is read or the input ends
+func copyUntilEscape(dst io.Writer, src io.Reader) error {
+	buf := make([]byte, 1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if i := bytes.IndexByte(buf[:n], ConsoleEscapeByte); i >= 0 {
+				if _, werr := dst.Write(buf[:i]); werr != nil {
+					return werr
+				}
+				return ErrConsoleDetached
+			}
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return ErrConsoleDetached
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCopyUntilEscape(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"escape ends input", "root\r\x1dignored", "root\r"},
+		{"escape first", "\x1dls", ""},
+		{"end of input", "uname -a\r", "uname -a\r"},
+		{"control keys pass through", "\x03\x04\x1b[A", "\x03\x04\x1b[A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := copyUntilEscape(&out, strings.NewReader(tt.input))
+			if !errors.Is(err, ErrConsoleDetached) {
+				t.Errorf("copyUntilEscape() error = %v, want ErrConsoleDetached", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("copyUntilEscape() wrote %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package vm
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/tnk4on/bootc-man/internal/config"
+)
+
+// Methods used to derive a VM disk from a base image
+const (
+	// DiskMethodOverlay creates a qcow2 overlay backed by the base image (QEMU)
+	DiskMethodOverlay = "overlay"
+	// DiskMethodReflink clones the base image with a copy-on-write reflink
+	DiskMethodReflink = "reflink"
+	// DiskMethodCopy makes a full copy of the base image
+	DiskMethodCopy = "copy"
+	// DiskMethodInPlace uses the base image directly (read-only ISO installers)
+	DiskMethodInPlace = "in-place"
+)
+
+// PlanVMDisk determines how a VM-private disk is derived from a base image
+// destBase is the destination path without extension; the returned path has
+// the extension of the resulting disk format.
+// QEMU VMs get a qcow2 overlay whose backing file is the base image, so the
+// base is shared read-only between VMs. Other hypervisors get a clone of the
+// base in its own format (reflink when the filesystem supports it).
+func PlanVMDisk(basePath, destBase string, vmType VMType) (string, string, error) {
+	format, err := DetectImageFormat(basePath)
+	if err != nil {
+		return "", "", err
+	}
+	if !vmType.SupportsImageFormat(format) {
+		return "", "", fmt.Errorf("%s does not support %s disk images (%s)", vmType.String(), format, basePath)
+	}
+
+	if format == ImageFormatISO {
+		return basePath, DiskMethodInPlace, nil
+	}
+
+	if vmType == QemuVM {
+		if _, err := exec.LookPath(config.BinaryQemuImg); err == nil {
+			return destBase + "." + ImageFormatQcow2, DiskMethodOverlay, nil
+		}
+	}
+
+	return destBase + "." + format, DiskMethodReflink, nil
+}
+
+// CreateVMDisk creates a VM-private disk derived from a base image
+// See PlanVMDisk for how the disk is derived. Reflink clones fall back to a
+// full copy when the filesystem does not support them.
+// Returns the path to the created disk and the method it was created with.
+func CreateVMDisk(ctx context.Context, basePath, destBase string, vmType VMType, verbose bool) (string, string, error) {
+	destPath, method, err := PlanVMDisk(basePath, destBase, vmType)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch method {
+	case DiskMethodInPlace:
+		if verbose {
+			fmt.Printf("Using installer ISO in place: %s\n", basePath)
+		}
+		return destPath, method, nil
+
+	case DiskMethodOverlay:
+		if err := createOverlay(ctx, basePath, destPath, verbose); err != nil {
+			return "", "", err
+		}
+		return destPath, method, nil
+	}
+
+	if err := reflinkFile(basePath, destPath); err == nil {
+		if verbose {
+			fmt.Printf("Cloned disk image (reflink): %s -> %s\n", basePath, destPath)
+		}
+		return destPath, DiskMethodReflink, nil
+	} else if verbose {
+		fmt.Printf("Reflink not available (%v), copying disk image\n", err)
+	}
+
+	if err := copyDiskImage(basePath, destPath); err != nil {
+		return "", "", err
+	}
+	return destPath, DiskMethodCopy, nil
+}
+
+// GrowDisk grows a VM disk to sizeGB; larger disks are left alone
+// qcow2 disks are resized with qemu-img and raw disks are extended sparsely.
+// bootc images grow their root filesystem into the new space at boot.
+func GrowDisk(ctx context.Context, path string, sizeGB int, verbose bool) error {
+	if sizeGB <= 0 {
+		return nil
+	}
+	format, err := DetectImageFormat(path)
+	if err != nil {
+		return err
+	}
+	want := int64(sizeGB) << 30
+
+	switch format {
+	case ImageFormatRaw:
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat disk image: %w", err)
+		}
+		if info.Size() >= want {
+			return nil
+		}
+		if verbose {
+			fmt.Printf("Growing disk image to %d GB: %s\n", sizeGB, path)
+		}
+		if err := os.Truncate(path, want); err != nil {
+			return fmt.Errorf("failed to grow disk image: %w", err)
+		}
+		return nil
+
+	case ImageFormatQcow2:
+		size, err := qcow2VirtualSize(path)
+		if err != nil {
+			return err
+		}
+		if size >= want {
+			return nil
+		}
+		args := []string{"resize", "-q", path, fmt.Sprintf("%dG", sizeGB)}
+		if verbose {
+			fmt.Printf("Running: %s %s\n", config.BinaryQemuImg, strings.Join(args, " "))
+		}
+		output, err := exec.CommandContext(ctx, config.BinaryQemuImg, args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to grow disk image: %w\n%s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot resize %s disk images (%s)", format, path)
+}
+
+// qcow2VirtualSize reads the virtual disk size from a qcow2 header
+func qcow2VirtualSize(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open disk image: %w", err)
+	}
+	defer f.Close()
+
+	// The size is a big-endian uint64 at offset 24 of the header
+	var size [8]byte
+	if _, err := f.ReadAt(size[:], 24); err != nil {
+		return 0, fmt.Errorf("failed to read qcow2 header: %w", err)
+	}
+	return int64(binary.BigEndian.Uint64(size[:])), nil
+}
+
+// createOverlay creates a qcow2 overlay with basePath as its backing file
+func createOverlay(ctx context.Context, basePath, destPath string, verbose bool) error {
+	absBase, err := filepath.Abs(basePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve base image path: %w", err)
+	}
+	baseFormat, err := DetectImageFormat(absBase)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"create", "-q", "-f", ImageFormatQcow2, "-b", absBase, "-F", baseFormat, destPath}
+	if verbose {
+		fmt.Printf("Running: %s %s\n", config.BinaryQemuImg, strings.Join(args, " "))
+	}
+
+	output, err := exec.CommandContext(ctx, config.BinaryQemuImg, args...).CombinedOutput()
+	if err != nil {
+		os.Remove(destPath)
+		return fmt.Errorf("failed to create overlay disk: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// copyDiskImage makes a full copy of a disk image
+func copyDiskImage(srcPath, destPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer dst.Close()
+
+	// Copy with progress indication for large files
+	if info, err := src.Stat(); err == nil && info.Size() > 1024*1024*100 { // > 100MB
+		fmt.Printf("Copying %.1f GB disk image (this may take a while)...\n", float64(info.Size())/(1024*1024*1024))
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		os.Remove(destPath) // Clean up partial file
+		return fmt.Errorf("failed to copy disk image: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package vm
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanVMDisk(t *testing.T) {
+	dir := t.TempDir()
+
+	rawPath := filepath.Join(dir, "base.raw")
+	_ = os.WriteFile(rawPath, []byte("raw disk"), 0644)
+
+	qcow2Path := filepath.Join(dir, "base.qcow2")
+	_ = os.WriteFile(qcow2Path, []byte{'Q', 'F', 'I', 0xfb, 0, 0, 0, 3}, 0644)
+
+	isoPath := filepath.Join(dir, "install.iso")
+	iso := make([]byte, isoMagicOffset+len(isoMagic))
+	copy(iso[isoMagicOffset:], isoMagic)
+	_ = os.WriteFile(isoPath, iso, 0644)
+
+	destBase := filepath.Join(dir, "vm1")
+	_, qemuImgErr := exec.LookPath("qemu-img")
+
+	tests := []struct {
+		name       string
+		base       string
+		vmType     VMType
+		wantPath   string
+		wantMethod string
+		wantErr    bool
+		needsQemu  bool
+	}{
+		{
+			name:       "vfkit raw is cloned",
+			base:       rawPath,
+			vmType:     VfkitVM,
+			wantPath:   destBase + ".raw",
+			wantMethod: DiskMethodReflink,
+		},
+		{
+			name:    "vfkit rejects qcow2",
+			base:    qcow2Path,
+			vmType:  VfkitVM,
+			wantErr: true,
+		},
+		{
+			name:       "plugin raw is cloned, never overlaid",
+			base:       rawPath,
+			vmType:     ExternalVM,
+			wantPath:   destBase + ".raw",
+			wantMethod: DiskMethodReflink,
+		},
+		{
+			name:    "plugin rejects qcow2",
+			base:    qcow2Path,
+			vmType:  ExternalVM,
+			wantErr: true,
+		},
+		{
+			name:       "qemu iso used in place",
+			base:       isoPath,
+			vmType:     QemuVM,
+			wantPath:   isoPath,
+			wantMethod: DiskMethodInPlace,
+		},
+		{
+			name:       "qemu raw gets qcow2 overlay",
+			base:       rawPath,
+			vmType:     QemuVM,
+			wantPath:   destBase + ".qcow2",
+			wantMethod: DiskMethodOverlay,
+			needsQemu:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsQemu && qemuImgErr != nil {
+				t.Skip("qemu-img not installed")
+			}
+			path, method, err := PlanVMDisk(tt.base, destBase, tt.vmType)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("PlanVMDisk() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlanVMDisk() error = %v", err)
+			}
+			if path != tt.wantPath {
+				t.Errorf("PlanVMDisk() path = %q, want %q", path, tt.wantPath)
+			}
+			if method != tt.wantMethod {
+				t.Errorf("PlanVMDisk() method = %q, want %q", method, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestCreateVMDiskClone(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "base.raw")
+	if err := os.WriteFile(basePath, []byte("raw disk contents"), 0644); err != nil {
+		t.Fatalf("failed to write base image: %v", err)
+	}
+
+	path, method, err := CreateVMDisk(context.Background(), basePath, filepath.Join(dir, "vm1"), VfkitVM, false)
+	if err != nil {
+		t.Fatalf("CreateVMDisk() error = %v", err)
+	}
+	if method != DiskMethodReflink && method != DiskMethodCopy {
+		t.Errorf("CreateVMDisk() method = %q, want reflink or copy", method)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read VM disk: %v", err)
+	}
+	if string(data) != "raw disk contents" {
+		t.Errorf("VM disk contents = %q, want base image contents", string(data))
+	}
+}
+
+func TestGrowDiskRaw(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.raw")
+	if err := os.WriteFile(path, []byte("raw disk contents"), 0644); err != nil {
+		t.Fatalf("failed to write disk image: %v", err)
+	}
+
+	if err := GrowDisk(context.Background(), path, 1, false); err != nil {
+		t.Fatalf("GrowDisk() error = %v", err)
+	}
+	info, _ := os.Stat(path)
+	if info.Size() != 1<<30 {
+		t.Errorf("size = %d, want %d", info.Size(), 1<<30)
+	}
+
+	// Disks are never shrunk
+	if err := os.Truncate(path, 2<<30); err != nil {
+		t.Fatal(err)
+	}
+	if err := GrowDisk(context.Background(), path, 1, false); err != nil {
+		t.Fatalf("GrowDisk(smaller) error = %v", err)
+	}
+	info, _ = os.Stat(path)
+	if info.Size() != 2<<30 {
+		t.Errorf("size = %d, want %d", info.Size(), 2<<30)
+	}
+}
+
+func TestQcow2VirtualSize(t *testing.T) {
+	header := make([]byte, 32)
+	copy(header, qcow2Magic)
+	header[27] = 0x05 // 0x0000000500000000 = 20 GiB
+	path := filepath.Join(t.TempDir(), "disk.qcow2")
+	if err := os.WriteFile(path, header, 0644); err != nil {
+		t.Fatalf("failed to write disk image: %v", err)
+	}
+
+	size, err := qcow2VirtualSize(path)
+	if err != nil {
+		t.Fatalf("qcow2VirtualSize() error = %v", err)
+	}
+	if size != 20<<30 {
+		t.Errorf("qcow2VirtualSize() = %d, want %d", size, int64(20)<<30)
+	}
+}
@@ -2,7 +2,12 @@ package vm
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"runtime"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/tnk4on/bootc-man/internal/config"
 )
@@ -17,6 +22,10 @@ const (
 	QemuVM
 	// HyperVVM is the Hyper-V hypervisor for Windows (future)
 	HyperVVM
+	// LibvirtVM is QEMU/KVM managed by libvirt (Linux, opt-in)
+	LibvirtVM
+	// ExternalVM is run by a bootc-man-driver-<name> plugin
+	ExternalVM
 	// UnknownVM is an unknown hypervisor type
 	UnknownVM
 )
@@ -29,6 +38,10 @@ func (v VMType) String() string {
 		return "qemu"
 	case HyperVVM:
 		return "hyperv"
+	case LibvirtVM:
+		return config.VMDriverLibvirt
+	case ExternalVM:
+		return "external"
 	default:
 		return "unknown"
 	}
@@ -44,9 +57,39 @@ func (v VMType) ImageFormat() string {
 	return "raw"
 }
 
+// SupportedImageFormats returns the disk image formats this VM type can boot
+// QEMU detects the format from the image header, so it can boot any
+// artifact bootc-image-builder produces, including ISO installers
+func (v VMType) SupportedImageFormats() []string {
+	switch v {
+	case QemuVM, LibvirtVM:
+		return []string{ImageFormatRaw, ImageFormatQcow2, ImageFormatVMDK, ImageFormatISO}
+	default:
+		return []string{ImageFormatRaw}
+	}
+}
+
+// SupportsImageFormat reports whether this VM type can boot the given format
+func (v VMType) SupportsImageFormat(format string) bool {
+	for _, f := range v.SupportedImageFormats() {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
 // HostGatewayIP returns the IP address for accessing the host from within the VM
-// All platforms use gvproxy which provides 192.168.127.1 as the gateway
+// gvproxy provides 192.168.127.1 as the gateway; passt (libvirt) gives the
+// guest the host's own default gateway and maps it to the host.
 func (v VMType) HostGatewayIP() string {
+	switch v {
+	case LibvirtVM:
+		return hostDefaultGateway()
+	case ExternalVM:
+		// Only the plugin knows; it reports it with ssh-config
+		return ""
+	}
 	// gvproxy provides a unified gateway IP across all platforms
 	return "192.168.127.1"
 }
@@ -58,16 +101,24 @@ const (
 	VMStateRunning  VMState = "Running"
 	VMStateStopped  VMState = "Stopped"
 	VMStateStarting VMState = "Starting"
+	VMStatePaused   VMState = "Paused"
 	VMStateError    VMState = "Error"
 	VMStateUnknown  VMState = "Unknown"
+	// VMStateCreated is a VM defined with vm create that has not been started
+	VMStateCreated VMState = "Created"
 )
 
 // VMOptions contains options for starting a VM
 type VMOptions struct {
 	// Name is the VM name
 	Name string
-	// DiskImage is the path to the disk image file
+	// DiskImage is the path to the disk image file (raw, qcow2, vmdk or iso)
 	DiskImage string
+	// InstallDisk is the blank target disk attached when DiskImage is an ISO
+	// installer; it is created as qcow2 if it does not exist
+	InstallDisk string
+	// InstallDiskSize is the size of InstallDisk in GB
+	InstallDiskSize int
 	// CPUs is the number of virtual CPUs
 	CPUs int
 	// Memory is the amount of memory in MB
@@ -84,6 +135,44 @@ type VMOptions struct {
 	SerialLogPath string
 	// EFIVariableStore is the path for EFI variable store (for UEFI boot)
 	EFIVariableStore string
+	// Provisioning is the first-boot data (Ignition or cloud-init) to attach
+	Provisioning *Provisioning
+	// Mounts are host directories shared into the VM via virtio-fs
+	Mounts []SharedDir
+	// Arch is the guest architecture (amd64 or arm64); empty means the host's
+	Arch string
+	// SecureBoot boots with Secure Boot enforced and the default keys enrolled
+	SecureBoot bool
+	// TPM attaches an emulated TPM 2.0 (swtpm) for measured boot
+	TPM bool
+	// Firmware is the boot firmware: "uefi" (default) or "bios" (SeaBIOS, x86_64 only)
+	Firmware string
+	// Lab attaches a second interface to the lab network (the VM must be a member)
+	Lab *Lab
+	// NICModel is the QEMU model of the primary NIC (default: virtio-net-pci)
+	NICModel string
+	// Driver selects the VM driver on Linux: qemu (default) or libvirt
+	Driver string
+	// LibvirtURI is the libvirt connection of the libvirt driver
+	LibvirtURI string
+	// Ports are forwarded when the VM starts; only the libvirt driver uses
+	// them, the others forward ports through gvproxy once the VM is up
+	Ports []PortForward
+}
+
+// NICModelVirtio is the default NIC model of QEMU VMs
+const NICModelVirtio = "virtio-net-pci"
+
+// NICModels are the NIC models a QEMU VM can be given, so that profiles can
+// mirror the network hardware of the target device
+var NICModels = []string{NICModelVirtio, "e1000e", "e1000", "rtl8139"}
+
+// ValidateNICModel checks that a NIC model is supported (empty means virtio)
+func ValidateNICModel(model string) error {
+	if model != "" && !slices.Contains(NICModels, model) {
+		return fmt.Errorf("unsupported NIC model %q (supported: %s)", model, strings.Join(NICModels, ", "))
+	}
+	return nil
 }
 
 // Driver is the interface for VM hypervisor drivers
@@ -102,9 +191,19 @@ type Driver interface {
 	// Returns the process or control handle for the VM
 	Start(ctx context.Context, opts VMOptions) error
 
-	// Stop stops the VM
+	// Stop stops the VM immediately
 	Stop(ctx context.Context) error
 
+	// Shutdown asks the guest OS to power off and waits up to timeout,
+	// stopping the VM forcibly if it does not power off in time
+	Shutdown(ctx context.Context, timeout time.Duration) error
+
+	// Pause suspends VM execution
+	Pause(ctx context.Context) error
+
+	// Resume continues a paused VM
+	Resume(ctx context.Context) error
+
 	// GetState returns the current VM state
 	GetState(ctx context.Context) (VMState, error)
 
@@ -114,9 +213,14 @@ type Driver interface {
 	// WaitForSSH waits for SSH to be available
 	WaitForSSH(ctx context.Context) error
 
-	// SSH executes a command via SSH and returns the output
+	// SSH executes a command via SSH and returns the combined output
+	// A non-zero exit status is returned as *sshclient.ExitError; a dropped
+	// connection wraps sshclient.ErrConnectionLost.
 	SSH(ctx context.Context, command string) (string, error)
 
+	// RunSSH executes a command via SSH, streaming its output
+	RunSSH(ctx context.Context, command string, stdout, stderr io.Writer) error
+
 	// GetSSHConfig returns the SSH connection configuration
 	GetSSHConfig() SSHConfig
 
@@ -136,6 +240,13 @@ type Driver interface {
 	ToVMInfo(name, pipelineName, pipelineFile, imageTag string) *VMInfo
 }
 
+// RebootWatcher is implemented by drivers that can observe guest reboots
+// directly (e.g. QEMU RESET events via QMP) instead of polling the VM state
+type RebootWatcher interface {
+	// WaitForReboot waits until the guest has reset or shut down
+	WaitForReboot(ctx context.Context, timeout time.Duration) error
+}
+
 // SSHConfig contains SSH connection configuration
 type SSHConfig struct {
 	Host        string
@@ -150,6 +261,24 @@ type SSHConfig struct {
 // - driver_linux.go (QEMU)
 // - driver_windows.go (Hyper-V, future)
 
+// DriverVMType returns the VM type a configured VM driver runs VMs with
+func DriverVMType(driver string) VMType {
+	switch driver {
+	case "", config.VMDriverQemu:
+		return GetDefaultVMType()
+	case config.VMDriverLibvirt:
+		return LibvirtVM
+	default:
+		return ExternalVM
+	}
+}
+
+// UsesGvproxy reports whether VMs of this type are networked through gvproxy
+// libvirt and external driver VMs forward their ports when they start.
+func (v VMType) UsesGvproxy() bool {
+	return v != LibvirtVM && v != ExternalVM
+}
+
 // GetDefaultVMType returns the default VM type for the current platform
 func GetDefaultVMType() VMType {
 	switch runtime.GOOS {
@@ -2,7 +2,21 @@
 
 package vm
 
-// NewDriver creates a new VM driver for macOS (vfkit)
+import (
+	"fmt"
+
+	"github.com/tnk4on/bootc-man/internal/config"
+)
+
+// NewDriver creates a new VM driver for macOS
+// vfkit is used unless an external driver is selected.
 func NewDriver(opts VMOptions, verbose bool) (Driver, error) {
-	return NewVfkitDriver(opts, verbose)
+	switch opts.Driver {
+	case "", config.VMDriverQemu:
+		return NewVfkitDriver(opts, verbose)
+	case config.VMDriverLibvirt:
+		return nil, fmt.Errorf("the %s VM driver is only supported on Linux", opts.Driver)
+	default:
+		return NewPluginDriver(opts.Driver, opts, verbose)
+	}
 }
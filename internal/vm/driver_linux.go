@@ -2,7 +2,17 @@
 
 package vm
 
-// NewDriver creates a new VM driver for Linux (QEMU/KVM)
+import "github.com/tnk4on/bootc-man/internal/config"
+
+// NewDriver creates a new VM driver for Linux
+// QEMU/KVM is used unless libvirt or an external driver is selected.
 func NewDriver(opts VMOptions, verbose bool) (Driver, error) {
-	return NewQemuDriver(opts, verbose)
+	switch opts.Driver {
+	case "", config.VMDriverQemu:
+		return NewQemuDriver(opts, verbose)
+	case config.VMDriverLibvirt:
+		return NewLibvirtDriver(opts, verbose)
+	default:
+		return NewPluginDriver(opts.Driver, opts, verbose)
+	}
 }
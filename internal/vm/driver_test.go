@@ -41,6 +41,31 @@ func TestVMTypeImageFormat(t *testing.T) {
 	}
 }
 
+func TestVMTypeSupportsImageFormat(t *testing.T) {
+	tests := []struct {
+		vmType VMType
+		format string
+		want   bool
+	}{
+		{VfkitVM, ImageFormatRaw, true},
+		{VfkitVM, ImageFormatQcow2, false},
+		{VfkitVM, ImageFormatISO, false},
+		{QemuVM, ImageFormatRaw, true},
+		{QemuVM, ImageFormatQcow2, true},
+		{QemuVM, ImageFormatVMDK, true},
+		{QemuVM, ImageFormatISO, true},
+		{HyperVVM, ImageFormatQcow2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vmType.String()+"/"+tt.format, func(t *testing.T) {
+			if got := tt.vmType.SupportsImageFormat(tt.format); got != tt.want {
+				t.Errorf("VMType(%s).SupportsImageFormat(%q) = %v, want %v", tt.vmType, tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestVMTypeHostGatewayIP(t *testing.T) {
 	// All VM types should use gvproxy gateway IP
 	vmTypes := []VMType{VfkitVM, QemuVM, HyperVVM, UnknownVM}
@@ -88,6 +113,7 @@ func TestVMStateConstants(t *testing.T) {
 		{VMStateRunning, "Running"},
 		{VMStateStopped, "Stopped"},
 		{VMStateStarting, "Starting"},
+		{VMStatePaused, "Paused"},
 		{VMStateError, "Error"},
 		{VMStateUnknown, "Unknown"},
 	}
@@ -0,0 +1,462 @@
+package vm
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/cleanup"
+	"github.com/tnk4on/bootc-man/internal/config"
+)
+
+// ExportFormatOVA is an OVF appliance: descriptor and streamOptimized VMDK
+// in a tar archive, for VMware
+const ExportFormatOVA = "ova"
+
+// ExportFormats lists the formats vm export can produce
+var ExportFormats = []string{ImageFormatRaw, ImageFormatQcow2, ImageFormatVMDK, ExportFormatOVA}
+
+// ExportOptions configures ExportVM
+type ExportOptions struct {
+	Format   string
+	Output   string
+	Compress bool
+	// Live reads the disk while it is open by a paused QEMU (qemu-img -U)
+	Live bool
+	// BootedImage and BootedDigest come from bootc status of the running VM
+	BootedImage  string
+	BootedDigest string
+	Verbose      bool
+}
+
+// ExportManifest describes an exported VM disk
+// It is written next to the artifact as <output>.manifest.json.
+type ExportManifest struct {
+	VM           string    `json:"vm"`                     // エクスポート元のVM名
+	Format       string    `json:"format"`                 // 出力形式（raw, qcow2, vmdk, ova）
+	File         string    `json:"file"`                   // 出力ファイル名
+	Compression  string    `json:"compression,omitempty"`  // 圧縮方式（gzip, qcow2, streamOptimized）
+	SHA256       string    `json:"sha256"`                 // 出力ファイルのSHA-256
+	Size         int64     `json:"size"`                   // 出力ファイルサイズ（バイト）
+	VirtualSize  int64     `json:"virtualSize"`            // ディスクの仮想サイズ（バイト）
+	Arch         string    `json:"arch,omitempty"`         // ゲストのアーキテクチャ
+	Firmware     string    `json:"firmware,omitempty"`     // ブートファームウェア（uefi, bios）
+	SourceImage  string    `json:"sourceImage,omitempty"`  // VM作成元のコンテナイメージタグ
+	BootedImage  string    `json:"bootedImage,omitempty"`  // エクスポート時に起動していたbootcイメージ
+	BootedDigest string    `json:"bootedDigest,omitempty"` // エクスポート時に起動していたbootcイメージのダイジェスト
+	Created      time.Time `json:"created"`                // エクスポート日時
+}
+
+// ValidateExportFormat checks that vm export can produce format
+func ValidateExportFormat(format string) error {
+	if !slices.Contains(ExportFormats, format) {
+		return fmt.Errorf("unsupported export format %q (supported: %s)", format, strings.Join(ExportFormats, ", "))
+	}
+	return nil
+}
+
+// ExportFileName returns the default file name of an exported VM disk
+// Compressed raw disks are gzipped; the other formats compress internally.
+func ExportFileName(name, format string, compress bool) string {
+	if format == ImageFormatRaw && compress {
+		return name + ".raw.gz"
+	}
+	return name + "." + format
+}
+
+// ExportManifestPath returns the path of the manifest written for output
+func ExportManifestPath(output string) string {
+	return output + ".manifest.json"
+}
+
+// ExportVM converts the disk of a stopped or paused VM to opts.Format at
+// opts.Output and writes the manifest next to it
+// The export is built under a temporary name and renamed when complete, so
+// an interrupted export never leaves a truncated artifact behind.
+func ExportVM(ctx context.Context, info *VMInfo, opts ExportOptions) (*ExportManifest, error) {
+	if err := ValidateExportFormat(opts.Format); err != nil {
+		return nil, err
+	}
+	if _, err := exec.LookPath(config.BinaryQemuImg); err != nil {
+		return nil, fmt.Errorf("qemu-img is not installed. Install it: sudo dnf install qemu-img")
+	}
+	disk := info.SnapshotDisk()
+	if format, err := DetectImageFormat(disk); err != nil {
+		return nil, err
+	} else if format == ImageFormatISO {
+		return nil, fmt.Errorf("VM '%s' boots an installer ISO without an install disk; nothing to export", info.Name)
+	}
+	virtualSize, err := diskVirtualSize(ctx, disk, opts.Live)
+	if err != nil {
+		return nil, err
+	}
+
+	// Intermediate files live next to the output so the final rename is atomic
+	workDir, err := os.MkdirTemp(filepath.Dir(opts.Output), "."+filepath.Base(opts.Output)+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	workCleanup := cleanup.Register("remove export of VM "+info.Name, func(context.Context) error {
+		return os.RemoveAll(workDir)
+	}, cleanup.Resource{Path: workDir})
+	defer func() { _ = workCleanup.Run(context.Background()) }()
+
+	manifest := &ExportManifest{
+		VM:           info.Name,
+		Format:       opts.Format,
+		File:         filepath.Base(opts.Output),
+		VirtualSize:  virtualSize,
+		Arch:         info.Arch,
+		Firmware:     cmp.Or(info.Firmware, FirmwareUEFI),
+		SourceImage:  info.ImageTag,
+		BootedImage:  opts.BootedImage,
+		BootedDigest: opts.BootedDigest,
+	}
+
+	built := filepath.Join(workDir, manifest.File)
+	switch opts.Format {
+	case ImageFormatRaw:
+		raw := filepath.Join(workDir, "disk.raw")
+		if err := convertDisk(ctx, disk, raw, opts, "-O", ImageFormatRaw); err != nil {
+			return nil, err
+		}
+		if opts.Compress {
+			manifest.Compression = "gzip"
+			if err := gzipFile(raw, built); err != nil {
+				return nil, err
+			}
+		} else if err := os.Rename(raw, built); err != nil {
+			return nil, fmt.Errorf("failed to move exported disk: %w", err)
+		}
+	case ImageFormatQcow2:
+		args := []string{"-O", ImageFormatQcow2}
+		if opts.Compress {
+			manifest.Compression = "qcow2"
+			args = append(args, "-c")
+		}
+		if err := convertDisk(ctx, disk, built, opts, args...); err != nil {
+			return nil, err
+		}
+	case ImageFormatVMDK:
+		args := []string{"-O", ImageFormatVMDK}
+		if opts.Compress {
+			manifest.Compression = "streamOptimized"
+			args = append(args, "-o", "subformat=streamOptimized")
+		}
+		if err := convertDisk(ctx, disk, built, opts, args...); err != nil {
+			return nil, err
+		}
+	case ExportFormatOVA:
+		// VMware only imports streamOptimized disks from an OVA
+		manifest.Compression = "streamOptimized"
+		if err := buildOVA(ctx, info, disk, virtualSize, workDir, built, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest.SHA256, manifest.Size, err = hashFile(built); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(built, opts.Output); err != nil {
+		return nil, fmt.Errorf("failed to move exported disk: %w", err)
+	}
+
+	manifest.Created = time.Now()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export manifest: %w", err)
+	}
+	if err := os.WriteFile(ExportManifestPath(opts.Output), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write export manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// convertDisk runs "qemu-img convert" from src to dest
+// Backing files are flattened, so the result does not depend on the base
+// image of the VM's overlay disk.
+func convertDisk(ctx context.Context, src, dest string, opts ExportOptions, formatArgs ...string) error {
+	args := []string{"convert"}
+	if opts.Live {
+		args = append(args, "-U")
+	}
+	args = append(args, formatArgs...)
+	args = append(args, src, dest)
+	if opts.Verbose {
+		fmt.Printf("Running: %s %s\n", config.BinaryQemuImg, strings.Join(args, " "))
+	}
+	output, err := exec.CommandContext(ctx, config.BinaryQemuImg, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to convert disk: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// diskVirtualSize returns the size of a disk as seen by the guest
+func diskVirtualSize(ctx context.Context, path string, live bool) (int64, error) {
+	args := []string{"info", "--output=json"}
+	if live {
+		args = append(args, "-U")
+	}
+	args = append(args, path)
+	output, err := exec.CommandContext(ctx, config.BinaryQemuImg, args...).Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect disk %s: %w", path, err)
+	}
+	var info struct {
+		VirtualSize int64 `json:"virtual-size"`
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return 0, fmt.Errorf("failed to parse qemu-img info: %w", err)
+	}
+	return info.VirtualSize, nil
+}
+
+// gzipFile compresses src to dest
+func gzipFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open exported disk: %w", err)
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	defer out.Close()
+
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(strings.TrimSuffix(dest, ".gz"))
+	if _, err := io.Copy(zw, in); err != nil {
+		return fmt.Errorf("failed to compress exported disk: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress exported disk: %w", err)
+	}
+	return out.Close()
+}
+
+// hashFile returns the SHA-256 and size of a file
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// buildOVA writes an OVA of the VM's disk to dest
+// The archive holds the OVF descriptor, the .mf checksum manifest and the
+// streamOptimized disk, in the order the OVF specification requires.
+func buildOVA(ctx context.Context, info *VMInfo, disk string, virtualSize int64, workDir, dest string, opts ExportOptions) error {
+	base := strings.TrimSuffix(filepath.Base(dest), ".ova")
+	vmdkName := base + "-disk1.vmdk"
+	vmdk := filepath.Join(workDir, vmdkName)
+	if err := convertDisk(ctx, disk, vmdk, opts, "-O", ImageFormatVMDK, "-o", "subformat=streamOptimized"); err != nil {
+		return err
+	}
+	vmdkInfo, err := os.Stat(vmdk)
+	if err != nil {
+		return fmt.Errorf("failed to stat exported disk: %w", err)
+	}
+
+	ovf, err := ovfDescriptor(ovfData{
+		Name:       info.Name,
+		DiskFile:   vmdkName,
+		DiskSize:   vmdkInfo.Size(),
+		Capacity:   virtualSize,
+		CPUs:       cmp.Or(info.CPUs, 2),
+		Memory:     cmp.Or(info.Memory, 4096),
+		EFI:        cmp.Or(info.Firmware, FirmwareUEFI) == FirmwareUEFI,
+		SecureBoot: info.SecureBoot,
+	})
+	if err != nil {
+		return err
+	}
+	vmdkSum, _, err := hashFile(vmdk)
+	if err != nil {
+		return err
+	}
+	ovfSum := sha256.Sum256(ovf)
+	mf := fmt.Sprintf("SHA256(%s.ovf)= %s\nSHA256(%s)= %s\n", base, hex.EncodeToString(ovfSum[:]), vmdkName, vmdkSum)
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	defer out.Close()
+	tw := tar.NewWriter(out)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{base + ".ovf", ovf},
+		{base + ".mf", []byte(mf)},
+	} {
+		if err := writeTarFile(tw, entry.name, int64(len(entry.data)), bytes.NewReader(entry.data)); err != nil {
+			return err
+		}
+	}
+	f, err := os.Open(vmdk)
+	if err != nil {
+		return fmt.Errorf("failed to open exported disk: %w", err)
+	}
+	defer f.Close()
+	if err := writeTarFile(tw, vmdkName, vmdkInfo.Size(), f); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write OVA: %w", err)
+	}
+	return out.Close()
+}
+
+// writeTarFile adds a regular file to an OVA (ustar, as OVF requires)
+func writeTarFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+		Format:  tar.FormatUSTAR,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write OVA: %w", err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return fmt.Errorf("failed to write OVA: %w", err)
+	}
+	return nil
+}
+
+// ovfData is the input of ovfTemplate
+type ovfData struct {
+	Name       string
+	DiskFile   string
+	DiskSize   int64
+	Capacity   int64
+	CPUs       int
+	Memory     int
+	EFI        bool
+	SecureBoot bool
+}
+
+// ovfTemplate is an OVF 1.0 descriptor of a VMware VM with a paravirtual
+// SCSI disk and a vmxnet3 NIC
+var ovfTemplate = template.Must(template.New("ovf").Funcs(template.FuncMap{"x": xmlEscape}).Parse(
+	`<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:vmw="http://www.vmware.com/schema/ovf">
+  <References>
+    <File ovf:id="file1" ovf:href="{{x .DiskFile}}" ovf:size="{{.DiskSize}}"/>
+  </References>
+  <DiskSection>
+    <Info>Virtual disk information</Info>
+    <Disk ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:capacity="{{.Capacity}}" ovf:capacityAllocationUnits="byte" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+  </DiskSection>
+  <NetworkSection>
+    <Info>The list of logical networks</Info>
+    <Network ovf:name="VM Network">
+      <Description>The VM Network network</Description>
+    </Network>
+  </NetworkSection>
+  <VirtualSystem ovf:id="{{x .Name}}">
+    <Info>A bootc virtual machine exported by bootc-man</Info>
+    <Name>{{x .Name}}</Name>
+    <OperatingSystemSection ovf:id="101" vmw:osType="otherLinux64Guest">
+      <Info>The kind of installed guest operating system</Info>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements</Info>
+      <System>
+        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+        <vssd:InstanceID>0</vssd:InstanceID>
+        <vssd:VirtualSystemIdentifier>{{x .Name}}</vssd:VirtualSystemIdentifier>
+        <vssd:VirtualSystemType>vmx-14</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:AllocationUnits>hertz * 10^6</rasd:AllocationUnits>
+        <rasd:Description>Number of Virtual CPUs</rasd:Description>
+        <rasd:ElementName>{{.CPUs}} virtual CPU(s)</rasd:ElementName>
+        <rasd:InstanceID>1</rasd:InstanceID>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>{{.CPUs}}</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^20</rasd:AllocationUnits>
+        <rasd:Description>Memory Size</rasd:Description>
+        <rasd:ElementName>{{.Memory}}MB of memory</rasd:ElementName>
+        <rasd:InstanceID>2</rasd:InstanceID>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>{{.Memory}}</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:Address>0</rasd:Address>
+        <rasd:Description>SCSI Controller</rasd:Description>
+        <rasd:ElementName>SCSI Controller 0</rasd:ElementName>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:ResourceSubType>VirtualSCSI</rasd:ResourceSubType>
+        <rasd:ResourceType>6</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:ElementName>Hard Disk 1</rasd:ElementName>
+        <rasd:HostResource>ovf:/disk/vmdisk1</rasd:HostResource>
+        <rasd:InstanceID>4</rasd:InstanceID>
+        <rasd:Parent>3</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Connection>VM Network</rasd:Connection>
+        <rasd:ElementName>Network adapter 1</rasd:ElementName>
+        <rasd:InstanceID>5</rasd:InstanceID>
+        <rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+{{- if .EFI}}
+      <vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>
+{{- end}}
+{{- if .SecureBoot}}
+      <vmw:Config ovf:required="false" vmw:key="bootOptions.efiSecureBootEnabled" vmw:value="true"/>
+{{- end}}
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`))
+
+// ovfDescriptor renders the OVF descriptor of an OVA
+func ovfDescriptor(data ovfData) ([]byte, error) {
+	var b bytes.Buffer
+	if err := ovfTemplate.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("failed to render OVF descriptor: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// xmlEscape escapes a string for use in XML text and attributes
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,184 @@
+package vm
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportFileName(t *testing.T) {
+	tests := []struct {
+		format   string
+		compress bool
+		want     string
+	}{
+		{ImageFormatRaw, true, "my-vm.raw.gz"},
+		{ImageFormatRaw, false, "my-vm.raw"},
+		{ImageFormatQcow2, true, "my-vm.qcow2"},
+		{ImageFormatVMDK, true, "my-vm.vmdk"},
+		{ExportFormatOVA, false, "my-vm.ova"},
+	}
+	for _, tt := range tests {
+		if got := ExportFileName("my-vm", tt.format, tt.compress); got != tt.want {
+			t.Errorf("ExportFileName(%q, %v) = %q, want %q", tt.format, tt.compress, got, tt.want)
+		}
+	}
+}
+
+func TestValidateExportFormat(t *testing.T) {
+	for _, format := range ExportFormats {
+		if err := ValidateExportFormat(format); err != nil {
+			t.Errorf("ValidateExportFormat(%q) error = %v", format, err)
+		}
+	}
+	for _, format := range []string{"", "iso", "vhdx"} {
+		if err := ValidateExportFormat(format); err == nil {
+			t.Errorf("ValidateExportFormat(%q) expected error, got nil", format)
+		}
+	}
+}
+
+func TestOVFDescriptor(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        ovfData
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "uefi with secure boot",
+			data: ovfData{Name: "my-vm", DiskFile: "my-vm-disk1.vmdk", DiskSize: 1234, Capacity: 10 << 30, CPUs: 4, Memory: 8192, EFI: true, SecureBoot: true},
+			contains: []string{
+				`ovf:href="my-vm-disk1.vmdk" ovf:size="1234"`,
+				`ovf:capacity="10737418240"`,
+				`<rasd:VirtualQuantity>4</rasd:VirtualQuantity>`,
+				`<rasd:VirtualQuantity>8192</rasd:VirtualQuantity>`,
+				`vmw:key="firmware" vmw:value="efi"`,
+				`vmw:key="bootOptions.efiSecureBootEnabled"`,
+			},
+		},
+		{
+			name:        "bios",
+			data:        ovfData{Name: "my-vm", DiskFile: "my-vm-disk1.vmdk", CPUs: 2, Memory: 4096},
+			notContains: []string{`vmw:key="firmware"`, "efiSecureBootEnabled"},
+		},
+		{
+			name:     "escaped name",
+			data:     ovfData{Name: "a<b>&c", DiskFile: "d.vmdk", CPUs: 2, Memory: 4096},
+			contains: []string{"<Name>a&lt;b&gt;&amp;c</Name>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ovfDescriptor(tt.data)
+			if err != nil {
+				t.Fatalf("ovfDescriptor() error = %v", err)
+			}
+			ovf := string(data)
+			for _, want := range tt.contains {
+				if !strings.Contains(ovf, want) {
+					t.Errorf("OVF descriptor missing %q", want)
+				}
+			}
+			for _, unwanted := range tt.notContains {
+				if strings.Contains(ovf, unwanted) {
+					t.Errorf("OVF descriptor contains %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestExportVM(t *testing.T) {
+	if _, err := exec.LookPath("qemu-img"); err != nil {
+		t.Skip("qemu-img not installed")
+	}
+
+	tmpDir := t.TempDir()
+	base := filepath.Join(tmpDir, "base.raw")
+	if err := os.WriteFile(base, []byte("bootc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(base, 16<<20); err != nil {
+		t.Fatal(err)
+	}
+	disk := filepath.Join(tmpDir, "vm.qcow2")
+	if output, err := exec.Command("qemu-img", "create", "-q", "-f", "qcow2", "-b", base, "-F", "raw", disk).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create overlay disk: %v: %s", err, output)
+	}
+	info := &VMInfo{Name: "export-vm", DiskImage: disk, ImageTag: "localhost/export-vm:latest", CPUs: 2, Memory: 2048}
+
+	for _, format := range ExportFormats {
+		t.Run(format, func(t *testing.T) {
+			output := filepath.Join(tmpDir, ExportFileName(info.Name, format, true))
+			manifest, err := ExportVM(context.Background(), info, ExportOptions{
+				Format:       format,
+				Output:       output,
+				Compress:     true,
+				BootedDigest: "sha256:abc123",
+			})
+			if err != nil {
+				t.Fatalf("ExportVM() error = %v", err)
+			}
+			if manifest.VirtualSize != 16<<20 {
+				t.Errorf("VirtualSize = %d, want %d", manifest.VirtualSize, 16<<20)
+			}
+			if manifest.SourceImage != info.ImageTag || manifest.BootedDigest != "sha256:abc123" {
+				t.Errorf("manifest images = %q, %q", manifest.SourceImage, manifest.BootedDigest)
+			}
+
+			data, err := os.ReadFile(ExportManifestPath(output))
+			if err != nil {
+				t.Fatalf("manifest not written: %v", err)
+			}
+			var saved ExportManifest
+			if err := json.Unmarshal(data, &saved); err != nil {
+				t.Fatalf("invalid manifest: %v", err)
+			}
+			if saved.SHA256 != manifest.SHA256 || saved.File != filepath.Base(output) {
+				t.Errorf("saved manifest = %+v, want %+v", saved, manifest)
+			}
+
+			if format == ExportFormatOVA {
+				f, err := os.Open(output)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				var names []string
+				tr := tar.NewReader(f)
+				for {
+					hdr, err := tr.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatalf("invalid OVA: %v", err)
+					}
+					names = append(names, hdr.Name)
+				}
+				want := []string{"export-vm.ovf", "export-vm.mf", "export-vm-disk1.vmdk"}
+				if strings.Join(names, ",") != strings.Join(want, ",") {
+					t.Errorf("OVA entries = %v, want %v", names, want)
+				}
+			}
+		})
+	}
+
+	// Only the artifacts and manifests are left, no work directories
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			t.Errorf("leftover work directory %s", e.Name())
+		}
+	}
+}
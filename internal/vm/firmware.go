@@ -0,0 +1,145 @@
+package vm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Boot firmware types
+const (
+	FirmwareUEFI = "uefi"
+	FirmwareBIOS = "bios"
+)
+
+// FirmwareTypes lists the supported boot firmware types
+var FirmwareTypes = []string{FirmwareUEFI, FirmwareBIOS}
+
+// BootModeCheckCommand prints the firmware the guest was booted with
+const BootModeCheckCommand = "if [ -d /sys/firmware/efi ]; then echo uefi; else echo bios; fi"
+
+// SecureBootCheckCommand prints the SecureBoot EFI variable of the guest
+const SecureBootCheckCommand = "od -An -t u1 /sys/firmware/efi/efivars/SecureBoot-8be4df61-93ca-11d2-aa0d-00e098032b8c"
+
+// PCRReadCommand prints the SHA-256 PCR values of the guest TPM, one
+// "<index> <hex>" line per PCR
+const PCRReadCommand = `for i in $(seq 0 23); do printf '%s %s\n' "$i" "$(cat /sys/class/tpm/tpm0/pcr-sha256/$i)"; done`
+
+// MeasuredBootPCRs are the PCRs firmware and boot loader extend during a
+// measured boot (firmware, option ROMs, boot loader, secure boot policy)
+var MeasuredBootPCRs = []int{0, 1, 2, 3, 4, 5, 6, 7}
+
+// ValidateFirmware checks the firmware type against the guest architecture
+// and secure boot setting. An empty firmware means UEFI and an empty arch
+// the host architecture.
+func ValidateFirmware(firmware, arch string, secureBoot bool) error {
+	switch firmware {
+	case "", FirmwareUEFI:
+		return nil
+	case FirmwareBIOS:
+	default:
+		return fmt.Errorf("unsupported firmware %q (supported: uefi, bios)", firmware)
+	}
+	arch, err := NormalizeArch(arch)
+	if err != nil {
+		return err
+	}
+	if arch != ArchAMD64 {
+		return fmt.Errorf("BIOS boot is only available for x86_64 guests, not %s", arch)
+	}
+	if secureBoot {
+		return fmt.Errorf("secure boot requires UEFI firmware")
+	}
+	return nil
+}
+
+// VMTPMStateDir returns the directory that holds the swtpm state of a VM
+// Like the NVRAM of a physical TPM, the state persists across VM restarts.
+func VMTPMStateDir(name string) (string, error) {
+	vmsDir, err := GetVMsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(vmsDir, name+"-tpm"), nil
+}
+
+// swtpmArgs returns the arguments for a swtpm TPM 2.0 emulator serving QEMU
+// on a control socket; --terminate makes swtpm exit when QEMU disconnects
+func swtpmArgs(stateDir, socket, logFile string) []string {
+	return []string{
+		"socket", "--tpm2",
+		"--tpmstate", "dir=" + stateDir,
+		"--ctrl", "type=unixio,path=" + socket,
+		"--log", "file=" + logFile,
+		"--terminate",
+	}
+}
+
+// ParseSecureBootState parses the output of SecureBootCheckCommand
+// The variable holds 4 attribute bytes followed by the value (1 = enabled).
+func ParseSecureBootState(output string) (bool, error) {
+	fields := strings.Fields(output)
+	if len(fields) != 5 {
+		return false, fmt.Errorf("unexpected SecureBoot variable: %q", strings.TrimSpace(output))
+	}
+	for _, f := range fields {
+		if _, err := strconv.ParseUint(f, 10, 8); err != nil {
+			return false, fmt.Errorf("unexpected SecureBoot variable: %q", strings.TrimSpace(output))
+		}
+	}
+	return fields[4] == "1", nil
+}
+
+// ParsePCRs parses the output of PCRReadCommand into lower-case hex values
+func ParsePCRs(output string) (map[int]string, error) {
+	pcrs := map[int]string{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue // PCR not readable
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected PCR line: %q", line)
+		}
+		pcrs[index] = strings.ToLower(fields[1])
+	}
+	if len(pcrs) == 0 {
+		return nil, fmt.Errorf("no PCR values found (is the TPM exposed at /sys/class/tpm/tpm0?)")
+	}
+	return pcrs, nil
+}
+
+// CheckPCRs verifies that a measured boot extended the boot PCRs and that
+// PCRs match the expected values
+func CheckPCRs(pcrs map[int]string, expected map[int]string) error {
+	for _, index := range MeasuredBootPCRs {
+		value, ok := pcrs[index]
+		if !ok {
+			return fmt.Errorf("PCR %d could not be read", index)
+		}
+		if strings.Trim(value, "0") == "" {
+			return fmt.Errorf("PCR %d was never extended; the boot was not measured", index)
+		}
+	}
+	for index, want := range expected {
+		if got := pcrs[index]; !strings.EqualFold(got, want) {
+			return fmt.Errorf("PCR %d = %s, want %s", index, got, want)
+		}
+	}
+	return nil
+}
+
+// ValidatePCRs checks expected PCR values: SHA-256 hex for PCRs 0-23
+func ValidatePCRs(expected map[int]string) error {
+	for index, value := range expected {
+		if index < 0 || index > 23 {
+			return fmt.Errorf("invalid PCR index %d (0-23)", index)
+		}
+		if len(value) != 64 || strings.Trim(strings.ToLower(value), "0123456789abcdef") != "" {
+			return fmt.Errorf("invalid value for PCR %d: expected a SHA-256 hex digest", index)
+		}
+	}
+	return nil
+}
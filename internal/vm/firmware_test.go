@@ -0,0 +1,130 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseSecureBootState(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    bool
+		wantErr bool
+	}{
+		{"   6   0   0   0   1\n", true, false},
+		{"   6   0   0   0   0\n", false, false},
+		{"", false, true},
+		{"od: /sys/firmware/efi/efivars/SecureBoot: No such file", false, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSecureBootState(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseSecureBootState(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseSecureBootState(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+// pcrOutput builds PCRReadCommand output with every PCR set to value
+func pcrOutput(value string) string {
+	var b strings.Builder
+	for i := 0; i < 24; i++ {
+		fmt.Fprintf(&b, "%d %s\n", i, value)
+	}
+	return b.String()
+}
+
+func TestParsePCRs(t *testing.T) {
+	measured := strings.Repeat("AB", 32)
+	pcrs, err := ParsePCRs(pcrOutput(measured))
+	if err != nil {
+		t.Fatalf("ParsePCRs() error = %v", err)
+	}
+	if len(pcrs) != 24 || pcrs[7] != strings.ToLower(measured) {
+		t.Errorf("ParsePCRs() = %v, want 24 lower-case PCRs", pcrs)
+	}
+
+	// PCRs that cannot be read print an empty value
+	if _, err := ParsePCRs("0 \n1 \n"); err == nil {
+		t.Error("ParsePCRs(no values) error = nil, want error")
+	}
+	if _, err := ParsePCRs("x abc\n"); err == nil {
+		t.Error("ParsePCRs(bad index) error = nil, want error")
+	}
+}
+
+func TestCheckPCRs(t *testing.T) {
+	measured := strings.Repeat("ab", 32)
+	pcrs, _ := ParsePCRs(pcrOutput(measured))
+	unmeasured, _ := ParsePCRs(pcrOutput(strings.Repeat("0", 64)))
+
+	tests := []struct {
+		name     string
+		pcrs     map[int]string
+		expected map[int]string
+		wantErr  bool
+	}{
+		{"measured", pcrs, nil, false},
+		{"expected match", pcrs, map[int]string{7: strings.ToUpper(measured)}, false},
+		{"expected mismatch", pcrs, map[int]string{7: strings.Repeat("cd", 32)}, true},
+		{"unmeasured", unmeasured, nil, true},
+		{"missing", map[int]string{0: measured}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckPCRs(tt.pcrs, tt.expected); (err != nil) != tt.wantErr {
+				t.Errorf("CheckPCRs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePCRs(t *testing.T) {
+	valid := strings.Repeat("ab", 32)
+	tests := []struct {
+		name     string
+		expected map[int]string
+		wantErr  bool
+	}{
+		{"none", nil, false},
+		{"valid", map[int]string{4: valid, 7: strings.ToUpper(valid)}, false},
+		{"index out of range", map[int]string{24: valid}, true},
+		{"short", map[int]string{7: "abcd"}, true},
+		{"not hex", map[int]string{7: strings.Repeat("zz", 32)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePCRs(tt.expected); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePCRs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFirmware(t *testing.T) {
+	tests := []struct {
+		name       string
+		firmware   string
+		arch       string
+		secureBoot bool
+		wantErr    bool
+	}{
+		{"default", "", ArchARM64, true, false},
+		{"uefi", FirmwareUEFI, ArchARM64, true, false},
+		{"bios", FirmwareBIOS, ArchAMD64, false, false},
+		{"bios x86_64", FirmwareBIOS, "x86_64", false, false},
+		{"bios arm64", FirmwareBIOS, ArchARM64, false, true},
+		{"bios secure boot", FirmwareBIOS, ArchAMD64, true, true},
+		{"unknown", "coreboot", ArchAMD64, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateFirmware(tt.firmware, tt.arch, tt.secureBoot); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFirmware(%q, %q, %v) error = %v, wantErr %v", tt.firmware, tt.arch, tt.secureBoot, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ package vm
 import (
 	"context"
 	"fmt"
+	"io"
+	"time"
 )
 
 // HypervDriver implements the Driver interface for Hyper-V on Windows
@@ -40,6 +42,21 @@ func (d *HypervDriver) Stop(ctx context.Context) error {
 	return fmt.Errorf("Hyper-V driver is not yet implemented")
 }
 
+// Shutdown asks the guest to power off
+func (d *HypervDriver) Shutdown(ctx context.Context, timeout time.Duration) error {
+	return fmt.Errorf("Hyper-V driver is not yet implemented")
+}
+
+// Pause suspends VM execution
+func (d *HypervDriver) Pause(ctx context.Context) error {
+	return fmt.Errorf("Hyper-V driver is not yet implemented")
+}
+
+// Resume continues a paused VM
+func (d *HypervDriver) Resume(ctx context.Context) error {
+	return fmt.Errorf("Hyper-V driver is not yet implemented")
+}
+
 // GetState returns the current VM state
 func (d *HypervDriver) GetState(ctx context.Context) (VMState, error) {
 	return VMStateUnknown, fmt.Errorf("Hyper-V driver is not yet implemented")
@@ -60,6 +77,11 @@ func (d *HypervDriver) SSH(ctx context.Context, command string) (string, error)
 	return "", fmt.Errorf("Hyper-V driver is not yet implemented")
 }
 
+// RunSSH executes a command via SSH, streaming its output
+func (d *HypervDriver) RunSSH(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	return fmt.Errorf("Hyper-V driver is not yet implemented")
+}
+
 // GetSSHConfig returns the SSH configuration
 func (d *HypervDriver) GetSSHConfig() SSHConfig {
 	return d.sshConfig
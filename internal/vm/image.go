@@ -0,0 +1,113 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Disk image formats understood by bootc-man
+const (
+	ImageFormatRaw   = "raw"
+	ImageFormatQcow2 = "qcow2"
+	ImageFormatVMDK  = "vmdk"
+	ImageFormatISO   = "iso"
+)
+
+// DiskImageFormats lists the artifact formats in search preference order
+// raw comes first because it works with every hypervisor
+var DiskImageFormats = []string{ImageFormatRaw, ImageFormatQcow2, ImageFormatVMDK, ImageFormatISO}
+
+// bootcImageBuilderPaths maps a format to the path bootc-image-builder uses
+// inside its output directory
+var bootcImageBuilderPaths = map[string]string{
+	ImageFormatRaw:   filepath.Join("image", "disk.raw"),
+	ImageFormatQcow2: filepath.Join("qcow2", "disk.qcow2"),
+	ImageFormatVMDK:  filepath.Join("vmdk", "disk.vmdk"),
+	ImageFormatISO:   filepath.Join("bootiso", "install.iso"),
+}
+
+const (
+	// isoMagicOffset is where the ISO 9660 primary volume descriptor
+	// identifier ("CD001") lives: sector 16 (2048 bytes each) plus one byte
+	isoMagicOffset = 0x8001
+)
+
+var (
+	qcow2Magic          = []byte{'Q', 'F', 'I', 0xfb}
+	vmdkSparseMagic     = []byte("KDMV")
+	vmdkDescriptorMagic = []byte("# Disk DescriptorFile")
+	isoMagic            = []byte("CD001")
+)
+
+// DetectImageFormat detects the disk image format from the file header
+// Files without a recognised header are treated as raw
+func DetectImageFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open disk image: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, len(vmdkDescriptorMagic))
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read disk image header: %w", err)
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, qcow2Magic):
+		return ImageFormatQcow2, nil
+	case bytes.HasPrefix(header, vmdkSparseMagic), bytes.HasPrefix(header, vmdkDescriptorMagic):
+		return ImageFormatVMDK, nil
+	}
+
+	magic := make([]byte, len(isoMagic))
+	if _, err := f.ReadAt(magic, isoMagicOffset); err == nil && bytes.Equal(magic, isoMagic) {
+		return ImageFormatISO, nil
+	}
+
+	return ImageFormatRaw, nil
+}
+
+// FindDiskImageInDir searches an artifacts directory for a disk image
+// Formats are tried in the given order; for each format the base name, the
+// bootc-image-builder layout and finally any file with a matching extension
+// are considered. Returns an empty string if none is found.
+func FindDiskImageInDir(artifactsDir, baseName string, formats []string) string {
+	for _, format := range formats {
+		ext := "." + format
+
+		if baseName != "" {
+			candidate := filepath.Join(artifactsDir, baseName+ext)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		}
+
+		candidate := filepath.Join(artifactsDir, bootcImageBuilderPaths[format])
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+
+		var found string
+		_ = filepath.Walk(artifactsDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if !info.IsDir() && strings.HasSuffix(info.Name(), ext) {
+				found = path
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if found != "" {
+			return found
+		}
+	}
+	return ""
+}
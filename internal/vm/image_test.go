@@ -0,0 +1,105 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectImageFormat(t *testing.T) {
+	isoHeader := make([]byte, isoMagicOffset+len(isoMagic))
+	copy(isoHeader[isoMagicOffset:], isoMagic)
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"qcow2", append([]byte{'Q', 'F', 'I', 0xfb}, 0, 0, 0, 3), ImageFormatQcow2},
+		{"vmdk sparse", []byte("KDMV\x01\x00\x00\x00"), ImageFormatVMDK},
+		{"vmdk descriptor", []byte("# Disk DescriptorFile\nversion=1\n"), ImageFormatVMDK},
+		{"iso", isoHeader, ImageFormatISO},
+		{"raw", []byte{0xeb, 0x63, 0x90, 0x00, 0x00}, ImageFormatRaw},
+		{"empty", []byte{}, ImageFormatRaw},
+	}
+
+	tmpDir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tt.name+".img")
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatalf("failed to write test image: %v", err)
+			}
+
+			got, err := DetectImageFormat(path)
+			if err != nil {
+				t.Fatalf("DetectImageFormat() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectImageFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectImageFormatMissingFile(t *testing.T) {
+	if _, err := DetectImageFormat(filepath.Join(t.TempDir(), "missing.raw")); err == nil {
+		t.Error("DetectImageFormat() expected error for missing file, got nil")
+	}
+}
+
+func TestFindDiskImageInDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		baseName string
+		formats  []string
+		want     string
+	}{
+		{
+			name:     "raw preferred over qcow2",
+			files:    []string{"test.qcow2", "test.raw"},
+			baseName: "test",
+			formats:  DiskImageFormats,
+			want:     "test.raw",
+		},
+		{
+			name:    "bootc-image-builder vmdk layout",
+			files:   []string{"vmdk/disk.vmdk"},
+			formats: DiskImageFormats,
+			want:    "vmdk/disk.vmdk",
+		},
+		{
+			name:    "iso installer",
+			files:   []string{"bootiso/install.iso"},
+			formats: DiskImageFormats,
+			want:    "bootiso/install.iso",
+		},
+		{
+			name:    "unsupported format skipped",
+			files:   []string{"test.qcow2"},
+			formats: []string{ImageFormatRaw},
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tt.files {
+				path := filepath.Join(dir, f)
+				_ = os.MkdirAll(filepath.Dir(path), 0755)
+				_ = os.WriteFile(path, []byte("test"), 0644)
+			}
+
+			got := FindDiskImageInDir(dir, tt.baseName, tt.formats)
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(dir, tt.want)
+			}
+			if got != want {
+				t.Errorf("FindDiskImageInDir() = %q, want %q", got, want)
+			}
+		})
+	}
+}
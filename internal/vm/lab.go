@@ -0,0 +1,327 @@
+package vm
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/sshclient"
+)
+
+// DefaultLabSubnet is the subnet of a lab network unless one is configured
+// It must not overlap gvproxy's 192.168.127.0/24, which every VM uses for NAT.
+const DefaultLabSubnet = "10.89.0.0/24"
+
+// LabDomain is the DNS domain of lab VMs: <vm>.<lab>.internal
+const LabDomain = "internal"
+
+// labConnection is the NetworkManager connection of the lab interface
+const labConnection = "bootc-man-lab"
+
+// labFirstHost is the host number of the first automatically assigned IP
+const labFirstHost = 10
+
+// Lab is a group of VMs sharing a virtual network segment
+// Each member keeps its own gvproxy for NAT, SSH and port forwards; the lab
+// network is a second interface on an L2 segment that QEMU carries over a
+// host-local multicast group, so members reach each other directly.
+type Lab struct {
+	Name      string      `json:"name"`      // ラボ名
+	Subnet    string      `json:"subnet"`    // ラボネットワークのサブネット（CIDR）
+	Multicast string      `json:"multicast"` // L2セグメントのマルチキャストアドレス（group:port）
+	Members   []LabMember `json:"members"`   // メンバーVM
+	Created   time.Time   `json:"created"`   // 作成日時
+}
+
+// LabMember is a VM attached to a lab network
+type LabMember struct {
+	Name string `json:"name"` // VM名
+	IP   string `json:"ip"`   // ラボネットワーク内の固定IPアドレス
+	MAC  string `json:"mac"`  // ラボ用NICのMACアドレス
+}
+
+// NewLab creates a lab with an empty member list
+// An empty subnet means DefaultLabSubnet.
+func NewLab(name, subnet string) (*Lab, error) {
+	if name == "" || SanitizeVMName(name) != name {
+		return nil, fmt.Errorf("invalid lab name %q (use letters, digits and hyphens)", name)
+	}
+	if subnet == "" {
+		subnet = DefaultLabSubnet
+	}
+	prefix, err := parseLabSubnet(subnet)
+	if err != nil {
+		return nil, err
+	}
+	return &Lab{
+		Name:      name,
+		Subnet:    prefix.String(),
+		Multicast: labMulticast(name),
+		Created:   time.Now(),
+	}, nil
+}
+
+// parseLabSubnet parses an IPv4 subnet with room for the lab's hosts
+func parseLabSubnet(subnet string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil || !prefix.Addr().Is4() {
+		return netip.Prefix{}, fmt.Errorf("invalid lab subnet %q (expected IPv4 CIDR, e.g. %s)", subnet, DefaultLabSubnet)
+	}
+	if prefix.Bits() > 26 {
+		return netip.Prefix{}, fmt.Errorf("lab subnet %s is too small (use /26 or larger)", subnet)
+	}
+	gvproxy := netip.MustParsePrefix("192.168.127.0/24")
+	if prefix.Overlaps(gvproxy) {
+		return netip.Prefix{}, fmt.Errorf("lab subnet %s overlaps the gvproxy network %s", subnet, gvproxy)
+	}
+	return prefix.Masked(), nil
+}
+
+// labMulticast derives the multicast group and port of a lab from its name
+// Groups are in the administratively scoped 239.255.0.0/16 range.
+func labMulticast(name string) string {
+	hash := sha256.Sum256([]byte("bootc-man-lab/" + name))
+	port := 20000 + int(binary.BigEndian.Uint16(hash[2:4]))%10000
+	return fmt.Sprintf("239.255.%d.%d:%d", hash[0], hash[1]|1, port)
+}
+
+// labMAC derives the MAC address of a member's lab interface
+func labMAC(lab, vmName string) string {
+	hash := sha256.Sum256([]byte(lab + "/" + vmName))
+	// Use 52:54:00 prefix (QEMU's locally administered MAC prefix)
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", hash[0], hash[1], hash[2])
+}
+
+// labName returns the name of a lab, or "" for no lab
+func labName(lab *Lab) string {
+	if lab == nil {
+		return ""
+	}
+	return lab.Name
+}
+
+// Member returns the member with the given VM name, or nil
+func (l *Lab) Member(vmName string) *LabMember {
+	for i := range l.Members {
+		if l.Members[i].Name == vmName {
+			return &l.Members[i]
+		}
+	}
+	return nil
+}
+
+// AddMember attaches a VM to the lab and returns its address
+// Existing members keep their address unless a different ip is requested.
+// An empty ip assigns the first free address from .10 on.
+func (l *Lab) AddMember(vmName, ip string) (LabMember, error) {
+	prefix, err := parseLabSubnet(l.Subnet)
+	if err != nil {
+		return LabMember{}, err
+	}
+	existing := l.Member(vmName)
+	if existing != nil && (ip == "" || ip == existing.IP) {
+		return *existing, nil
+	}
+
+	used := map[string]string{}
+	for _, m := range l.Members {
+		if m.Name != vmName {
+			used[m.IP] = m.Name
+		}
+	}
+
+	var addr netip.Addr
+	if ip != "" {
+		addr, err = netip.ParseAddr(ip)
+		if err != nil || !prefix.Contains(addr) {
+			return LabMember{}, fmt.Errorf("invalid IP %q for %s: not in lab subnet %s", ip, vmName, l.Subnet)
+		}
+		if addr == prefix.Addr() || !prefix.Contains(addr.Next()) {
+			return LabMember{}, fmt.Errorf("invalid IP %s for %s: network or broadcast address", ip, vmName)
+		}
+		if owner, ok := used[addr.String()]; ok {
+			return LabMember{}, fmt.Errorf("IP %s is already used by %s", ip, owner)
+		}
+	} else {
+		addr = prefix.Addr()
+		for i := 0; i < labFirstHost; i++ {
+			addr = addr.Next()
+		}
+		for ; ; addr = addr.Next() {
+			if !prefix.Contains(addr.Next()) {
+				return LabMember{}, fmt.Errorf("lab %s has no free addresses in %s", l.Name, l.Subnet)
+			}
+			if _, ok := used[addr.String()]; !ok {
+				break
+			}
+		}
+	}
+
+	member := LabMember{Name: vmName, IP: addr.String(), MAC: labMAC(l.Name, vmName)}
+	if existing != nil {
+		*existing = member
+	} else {
+		l.Members = append(l.Members, member)
+	}
+	return member, nil
+}
+
+// RemoveMember detaches a VM from the lab and reports whether it was a member
+func (l *Lab) RemoveMember(vmName string) bool {
+	for i, m := range l.Members {
+		if m.Name == vmName {
+			l.Members = append(l.Members[:i], l.Members[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Hostname returns the fully qualified name of a member
+func (l *Lab) Hostname(vmName string) string {
+	return fmt.Sprintf("%s.%s.%s", vmName, l.Name, LabDomain)
+}
+
+// HostsEntries returns the /etc/hosts lines for all members
+func (l *Lab) HostsEntries() []string {
+	var entries []string
+	for _, m := range l.Members {
+		entries = append(entries, fmt.Sprintf("%s %s %s", m.IP, l.Hostname(m.Name), m.Name))
+	}
+	return entries
+}
+
+// HostsCommand returns a command that writes the lab's members into the
+// guest's /etc/hosts, replacing the entries of earlier runs
+func (l *Lab) HostsCommand() string {
+	begin := fmt.Sprintf("# BEGIN bootc-man lab %s", l.Name)
+	end := fmt.Sprintf("# END bootc-man lab %s", l.Name)
+	lines := append([]string{begin}, l.HostsEntries()...)
+	lines = append(lines, end)
+	for i, line := range lines {
+		lines[i] = sshclient.ShellQuote(line)
+	}
+	script := fmt.Sprintf("sed -i %s /etc/hosts && printf '%%s\\n' %s >> /etc/hosts",
+		sshclient.ShellQuote(fmt.Sprintf("\\|^%s$|,\\|^%s$|d", begin, end)), strings.Join(lines, " "))
+	return "sudo -n sh -c " + sshclient.ShellQuote(script)
+}
+
+// ConfigureCommand returns a command that assigns the member's static IP to
+// the lab interface (matched by MAC address) with NetworkManager and writes
+// the lab's hosts entries. Both live in /etc, so they survive reboots and
+// bootc upgrades.
+func (l *Lab) ConfigureCommand(m LabMember) string {
+	prefix, _ := parseLabSubnet(l.Subnet)
+	script := strings.Join([]string{
+		"ifname=''",
+		fmt.Sprintf("for d in /sys/class/net/*; do [ \"$(cat $d/address)\" = %s ] && ifname=${d##*/}; done", m.MAC),
+		fmt.Sprintf("[ -n \"$ifname\" ] || { echo 'no interface with MAC %s' >&2; exit 1; }", m.MAC),
+		fmt.Sprintf("nmcli connection delete %s >/dev/null 2>&1 || true", labConnection),
+		fmt.Sprintf("nmcli connection add type ethernet con-name %s ifname \"$ifname\" ipv4.method manual ipv4.addresses %s/%d ipv6.method disabled >/dev/null", labConnection, m.IP, prefix.Bits()),
+		fmt.Sprintf("nmcli connection up %s >/dev/null", labConnection),
+		fmt.Sprintf("hostnamectl set-hostname %s", l.Hostname(m.Name)),
+	}, "\n")
+	return "sudo -n sh -c " + sshclient.ShellQuote(script) + " && " + l.HostsCommand()
+}
+
+// GetLabsDir returns the directory that holds lab state files
+func GetLabsDir() (string, error) {
+	baseDir, err := getDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, "labs"), nil
+}
+
+// SaveLab saves a lab to a JSON file in the global labs directory
+func SaveLab(lab *Lab) error {
+	labsDir, err := GetLabsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(labsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create labs directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(lab, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lab: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(labsDir, lab.Name+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write lab file: %w", err)
+	}
+	return nil
+}
+
+// LoadLab loads a lab from the global labs directory
+func LoadLab(name string) (*Lab, error) {
+	labsDir, err := GetLabsDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(labsDir, name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("lab '%s' not found: %w", name, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("failed to read lab file: %w", err)
+	}
+
+	var lab Lab
+	if err := json.Unmarshal(data, &lab); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal lab: %w", err)
+	}
+	return &lab, nil
+}
+
+// ListLabs lists all labs sorted by name
+func ListLabs() ([]*Lab, error) {
+	labsDir, err := GetLabsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(labsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Lab{}, nil
+		}
+		return nil, fmt.Errorf("failed to read labs directory: %w", err)
+	}
+
+	var labs []*Lab
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		lab, err := LoadLab(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			// Skip corrupted files
+			continue
+		}
+		labs = append(labs, lab)
+	}
+	sort.Slice(labs, func(i, j int) bool { return labs[i].Name < labs[j].Name })
+	return labs, nil
+}
+
+// DeleteLab deletes a lab state file
+func DeleteLab(name string) error {
+	labsDir, err := GetLabsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(labsDir, name+".json")); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("lab '%s' not found", name)
+		}
+		return fmt.Errorf("failed to delete lab file: %w", err)
+	}
+	return nil
+}
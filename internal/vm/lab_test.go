@@ -0,0 +1,153 @@
+package vm
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewLab(t *testing.T) {
+	tests := []struct {
+		name       string
+		lab        string
+		subnet     string
+		wantSubnet string
+		wantErr    bool
+	}{
+		{"default subnet", "cluster", "", DefaultLabSubnet, false},
+		{"custom subnet", "cluster", "172.30.5.0/24", "172.30.5.0/24", false},
+		{"unmasked subnet", "cluster", "172.30.5.7/24", "172.30.5.0/24", false},
+		{"too small", "cluster", "172.30.5.0/28", "", true},
+		{"gvproxy overlap", "cluster", "192.168.0.0/16", "", true},
+		{"ipv6", "cluster", "fd00::/64", "", true},
+		{"invalid name", "my lab", "", "", true},
+		{"empty name", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lab, err := NewLab(tt.lab, tt.subnet)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLab(%q, %q) error = %v, wantErr %v", tt.lab, tt.subnet, err, tt.wantErr)
+			}
+			if err == nil && lab.Subnet != tt.wantSubnet {
+				t.Errorf("Subnet = %q, want %q", lab.Subnet, tt.wantSubnet)
+			}
+		})
+	}
+}
+
+func TestLabMulticast(t *testing.T) {
+	addr := labMulticast("cluster")
+	if !regexp.MustCompile(`^239\.255\.\d+\.\d+:2\d{4}$`).MatchString(addr) {
+		t.Errorf("labMulticast() = %q, want 239.255.x.y:2xxxx", addr)
+	}
+	if labMulticast("cluster") != addr {
+		t.Error("labMulticast() is not stable")
+	}
+	if labMulticast("other") == addr {
+		t.Error("labMulticast() is the same for different labs")
+	}
+}
+
+func TestLabAddMember(t *testing.T) {
+	lab, err := NewLab("cluster", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := lab.AddMember("registry", "10.89.0.5")
+	if err != nil {
+		t.Fatalf("AddMember(static) error = %v", err)
+	}
+	node1, _ := lab.AddMember("node1", "")
+	node2, _ := lab.AddMember("node2", "")
+	if registry.IP != "10.89.0.5" || node1.IP != "10.89.0.10" || node2.IP != "10.89.0.11" {
+		t.Errorf("IPs = %s, %s, %s, want 10.89.0.5, 10.89.0.10, 10.89.0.11", registry.IP, node1.IP, node2.IP)
+	}
+	if node1.MAC == node2.MAC {
+		t.Errorf("node1 and node2 share MAC %s", node1.MAC)
+	}
+
+	// Members keep their address
+	again, _ := lab.AddMember("node1", "")
+	if again != node1 || len(lab.Members) != 3 {
+		t.Errorf("AddMember(existing) = %+v with %d members, want %+v with 3", again, len(lab.Members), node1)
+	}
+
+	for _, ip := range []string{"10.89.0.10", "10.89.1.20", "10.89.0.0", "10.89.0.255", "bad"} {
+		if _, err := lab.AddMember("node3", ip); err == nil {
+			t.Errorf("AddMember(node3, %q) error = nil, want error", ip)
+		}
+	}
+
+	// Freed addresses are reused
+	if !lab.RemoveMember("node1") {
+		t.Fatal("RemoveMember(node1) = false")
+	}
+	node3, _ := lab.AddMember("node3", "")
+	if node3.IP != "10.89.0.10" {
+		t.Errorf("node3 IP = %s, want 10.89.0.10", node3.IP)
+	}
+}
+
+func TestLabHostsAndConfigureCommand(t *testing.T) {
+	lab, _ := NewLab("cluster", "")
+	registry, _ := lab.AddMember("registry", "")
+	_, _ = lab.AddMember("node1", "")
+
+	entries := lab.HostsEntries()
+	want := []string{
+		"10.89.0.10 registry.cluster.internal registry",
+		"10.89.0.11 node1.cluster.internal node1",
+	}
+	if strings.Join(entries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("HostsEntries() = %q, want %q", entries, want)
+	}
+
+	cmd := lab.ConfigureCommand(registry)
+	for _, s := range []string{
+		"sudo -n sh -c",
+		registry.MAC,
+		"ipv4.addresses 10.89.0.10/24",
+		"hostnamectl set-hostname registry.cluster.internal",
+		"# BEGIN bootc-man lab cluster",
+		"10.89.0.11 node1.cluster.internal node1",
+	} {
+		if !strings.Contains(cmd, s) {
+			t.Errorf("ConfigureCommand() = %q, missing %q", cmd, s)
+		}
+	}
+}
+
+func TestLabPersistence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+
+	lab, _ := NewLab("cluster", "")
+	_, _ = lab.AddMember("registry", "")
+	if err := SaveLab(lab); err != nil {
+		t.Fatalf("SaveLab() error = %v", err)
+	}
+
+	loaded, err := LoadLab("cluster")
+	if err != nil {
+		t.Fatalf("LoadLab() error = %v", err)
+	}
+	if loaded.Multicast != lab.Multicast || loaded.Member("registry") == nil {
+		t.Errorf("LoadLab() = %+v, want %+v", loaded, lab)
+	}
+
+	labs, err := ListLabs()
+	if err != nil || len(labs) != 1 {
+		t.Errorf("ListLabs() = %d labs, %v, want 1", len(labs), err)
+	}
+
+	if err := DeleteLab("cluster"); err != nil {
+		t.Fatalf("DeleteLab() error = %v", err)
+	}
+	if _, err := LoadLab("cluster"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadLab() after DeleteLab error = %v, want os.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,133 @@
+package vm
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/config"
+)
+
+// LibvirtDomainName returns the libvirt domain name of a VM
+// The prefix keeps bootc-man's transient domains apart from the user's own
+// domains in virsh list and virt-manager.
+func LibvirtDomainName(vmName string) string {
+	return "bootc-man-" + vmName
+}
+
+// virsh runs a virsh command against a libvirt connection and returns its
+// trimmed output
+func virsh(ctx context.Context, uri string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, config.BinaryVirsh, append([]string{"-c", uri}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("virsh %s failed: %w\n%s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// libvirtDomainState returns the state of a libvirt domain
+// Transient domains disappear when they stop, so an unknown domain is
+// reported as stopped.
+func libvirtDomainState(ctx context.Context, uri, domain string) (VMState, error) {
+	out, err := virsh(ctx, uri, "domstate", domain)
+	if err != nil {
+		if strings.Contains(err.Error(), "failed to get domain") || strings.Contains(err.Error(), "Domain not found") {
+			return VMStateStopped, nil
+		}
+		return VMStateUnknown, err
+	}
+	return libvirtStateToVMState(out), nil
+}
+
+// libvirtStateToVMState maps a virsh domstate string to a VMState
+func libvirtStateToVMState(state string) VMState {
+	switch state {
+	case "running", "in shutdown", "blocked":
+		return VMStateRunning
+	case "paused", "pmsuspended":
+		return VMStatePaused
+	case "shut off", "crashed":
+		return VMStateStopped
+	default:
+		return VMStateUnknown
+	}
+}
+
+// shutdownLibvirt asks the guest of a libvirt domain to power off via ACPI
+// and destroys the domain if it does not power off within timeout
+func shutdownLibvirt(ctx context.Context, uri, domain string, timeout time.Duration) error {
+	if state, err := libvirtDomainState(ctx, uri, domain); err == nil && state == VMStateStopped {
+		return nil
+	}
+	if _, err := virsh(ctx, uri, "shutdown", domain); err != nil {
+		_, _ = virsh(ctx, uri, "destroy", domain)
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if state, err := libvirtDomainState(ctx, uri, domain); err == nil && state == VMStateStopped {
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+	if _, err := virsh(ctx, uri, "destroy", domain); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w (%v), VM was stopped forcibly", ErrShutdownTimeout, timeout)
+}
+
+// libvirtQemuPID returns the PID of the QEMU process libvirt runs a domain
+// in, or 0
+// libvirt passes the domain name as "-name guest=<domain>,...".
+func libvirtQemuPID(domain string) int {
+	procs, err := listProcesses()
+	if err != nil {
+		return 0
+	}
+	for _, p := range procs {
+		if slices.ContainsFunc(p.Args, func(arg string) bool {
+			return arg == "guest="+domain || strings.HasPrefix(arg, "guest="+domain+",")
+		}) {
+			return p.PID
+		}
+	}
+	return 0
+}
+
+// hostDefaultGateway returns the IPv4 default gateway of the host from
+// /proc/net/route, or "" if there is none
+func hostDefaultGateway() string {
+	data, err := os.ReadFile("/proc/net/route")
+	if err != nil {
+		return ""
+	}
+	return parseDefaultGateway(string(data))
+}
+
+// parseDefaultGateway parses the default route out of /proc/net/route
+// Addresses are hex encoded in host byte order (little-endian).
+func parseDefaultGateway(routes string) string {
+	for line := range strings.Lines(routes) {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		return ip.String()
+	}
+	return ""
+}
@@ -0,0 +1,605 @@
+//go:build linux
+
+package vm
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/cleanup"
+	"github.com/tnk4on/bootc-man/internal/config"
+)
+
+// LibvirtDriver runs VMs as transient libvirt domains
+// The domain is created with virsh from generated XML, so it shows up in
+// virsh and virt-manager and disappears when it is shut down. Networking
+// uses libvirt's passt backend with the SSH port forwarded on localhost, so
+// no gvproxy is needed.
+type LibvirtDriver struct {
+	opts      VMOptions
+	verbose   bool
+	uri       string
+	domain    string
+	ssh       driverSSH
+	sshConfig SSHConfig
+	logFile   string
+	efiStore  string
+	accel     string // kvm or tcg, detected on first use
+}
+
+// NewLibvirtDriver creates a new libvirt driver
+func NewLibvirtDriver(opts VMOptions, verbose bool) (*LibvirtDriver, error) {
+	// Set defaults
+	if opts.CPUs == 0 {
+		opts.CPUs = 2
+	}
+	if opts.Memory == 0 {
+		opts.Memory = 4096
+	}
+	if opts.SSHUser == "" {
+		opts.SSHUser = "user"
+	}
+	arch, err := NormalizeArch(opts.Arch)
+	if err != nil {
+		return nil, err
+	}
+	opts.Arch = arch
+	if opts.SSHPort == 0 {
+		// Allocate a port using podman machine's port allocation system
+		port, err := AllocateMachinePort()
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate SSH port: %w", err)
+		}
+		opts.SSHPort = port
+	}
+
+	tmpDir := config.RuntimeDir()
+	logFile := opts.SerialLogPath
+	if logFile == "" {
+		logFile = filepath.Join(tmpDir, fmt.Sprintf("bootc-man-libvirt-%s.log", opts.Name))
+	}
+	efiStore := opts.EFIVariableStore
+	if efiStore == "" {
+		efiVars := "efi-vars"
+		if opts.SecureBoot {
+			efiVars = "efi-vars-secboot"
+		}
+		efiStore = filepath.Join(tmpDir, fmt.Sprintf("bootc-man-libvirt-%s-%s.fd", opts.Name, efiVars))
+	}
+
+	return &LibvirtDriver{
+		opts:     opts,
+		verbose:  verbose,
+		uri:      cmp.Or(opts.LibvirtURI, config.DefaultLibvirtURI),
+		domain:   LibvirtDomainName(opts.Name),
+		logFile:  logFile,
+		efiStore: efiStore,
+		sshConfig: SSHConfig{
+			Host:        "localhost",
+			Port:        opts.SSHPort,
+			User:        opts.SSHUser,
+			KeyPath:     opts.SSHKeyPath,
+			HostGateway: hostDefaultGateway(),
+		},
+	}, nil
+}
+
+// Type returns the VM type
+func (d *LibvirtDriver) Type() VMType {
+	return LibvirtVM
+}
+
+// Available checks that virsh and passt are installed and that the libvirt
+// connection can be opened
+func (d *LibvirtDriver) Available() error {
+	if _, err := exec.LookPath(config.BinaryVirsh); err != nil {
+		return fmt.Errorf("virsh is not installed. Install it: sudo dnf install libvirt-client libvirt-daemon-kvm")
+	}
+	if _, err := exec.LookPath(config.BinaryPasst); err != nil {
+		return fmt.Errorf("passt is not installed (required for libvirt user networking). Install it: sudo dnf install passt")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := virsh(ctx, d.uri, "uri"); err != nil {
+		return fmt.Errorf("cannot connect to libvirt at %s: %w", d.uri, err)
+	}
+	return nil
+}
+
+// Accelerator returns the accelerator used for the guest
+// libvirt runs the domain with KVM when the guest architecture matches the
+// host and /dev/kvm is usable, and with TCG otherwise.
+func (d *LibvirtDriver) Accelerator() string {
+	if d.accel == "" {
+		d.accel = AccelTCG
+		if d.opts.Arch == HostArch() && kvmUsable() {
+			d.accel = AccelKVM
+		}
+	}
+	return d.accel
+}
+
+// Start creates the transient domain
+func (d *LibvirtDriver) Start(ctx context.Context, opts VMOptions) (err error) {
+	// Update options if provided, but preserve the allocated SSH port
+	if opts.Name != "" {
+		arch, err := NormalizeArch(opts.Arch)
+		if err != nil {
+			return err
+		}
+		opts.Arch = arch
+		if opts.SSHPort == 0 {
+			opts.SSHPort = d.opts.SSHPort
+		}
+		d.opts = opts
+		d.accel = ""
+	}
+
+	if d.opts.Lab != nil {
+		return fmt.Errorf("lab networks are not supported by the libvirt driver (use the QEMU driver)")
+	}
+	if err := ValidateFirmware(d.opts.Firmware, d.opts.Arch, d.opts.SecureBoot); err != nil {
+		return err
+	}
+	if err := ValidateNICModel(d.opts.NICModel); err != nil {
+		return err
+	}
+	if err := d.Available(); err != nil {
+		return err
+	}
+	if state, err := d.GetState(ctx); err == nil && state != VMStateStopped {
+		return fmt.Errorf("libvirt domain %s already exists (%s)", d.domain, state)
+	}
+	if d.Accelerator() == AccelTCG {
+		fmt.Println("⚠️  KVM is not available, libvirt will emulate the guest with TCG (boot takes considerably longer)")
+	}
+
+	domainXML, err := d.domainXML(ctx)
+	if err != nil {
+		return err
+	}
+	xmlFile, err := os.CreateTemp(config.RuntimeDir(), fmt.Sprintf("bootc-man-libvirt-%s-*.xml", d.opts.Name))
+	if err != nil {
+		return fmt.Errorf("failed to write domain XML: %w", err)
+	}
+	defer os.Remove(xmlFile.Name())
+	if _, err := xmlFile.WriteString(domainXML); err != nil {
+		xmlFile.Close()
+		return fmt.Errorf("failed to write domain XML: %w", err)
+	}
+	xmlFile.Close()
+	if d.verbose {
+		fmt.Printf("Domain XML:\n%s\n", domainXML)
+	}
+
+	// The domain outlives bootc-man, so destroy it if bootc-man is
+	// interrupted before Start returns; afterwards the caller owns the VM
+	starting := cleanup.Register("stop libvirt VM "+d.opts.Name, d.Stop)
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			_ = starting.Run(context.Background())
+		} else {
+			starting.Release()
+		}
+	}()
+
+	os.Remove(d.logFile)
+	if d.verbose {
+		fmt.Printf("Running: %s -c %s create %s\n", config.BinaryVirsh, d.uri, xmlFile.Name())
+	}
+	if _, err := virsh(ctx, d.uri, "create", xmlFile.Name()); err != nil {
+		return fmt.Errorf("failed to create libvirt domain: %w", err)
+	}
+
+	if pid := d.GetProcessID(); pid > 0 {
+		starting.AddResource(ProcessResource(pid))
+		if d.verbose {
+			fmt.Printf("libvirt domain %s started (QEMU PID %d)\n", d.domain, pid)
+		}
+	}
+	return nil
+}
+
+// libvirtDomainData is the input of libvirtDomainTemplate
+type libvirtDomainData struct {
+	Type       string // kvm or qemu
+	Name       string
+	Memory     int
+	CPUs       int
+	Arch       string
+	Machine    string
+	CPUMode    string
+	UEFI       bool
+	SecureBoot bool
+	SMM        bool
+	NVRAM      string
+	Shared     bool // shared memory backing, required by virtiofs
+	Ignition   string
+	Disks      []libvirtDisk
+	SCSI       bool
+	Mounts     []SharedDir
+	MAC        string
+	NICModel   string
+	Ports      []PortForward
+	LogFile    string
+	TPMModel   string
+	GUI        bool
+}
+
+// libvirtDisk is a disk or CD-ROM of a domain
+type libvirtDisk struct {
+	Device    string // disk or cdrom
+	Format    string
+	Path      string
+	Target    string
+	Bus       string
+	ReadOnly  bool
+	BootOrder int
+}
+
+var libvirtDomainTemplate = template.Must(template.New("domain").Funcs(template.FuncMap{"x": xmlEscape}).Parse(
+	`<domain type='{{.Type}}'>
+  <name>{{x .Name}}</name>
+  <memory unit='MiB'>{{.Memory}}</memory>
+  <vcpu>{{.CPUs}}</vcpu>
+{{- if .Shared}}
+  <memoryBacking>
+    <source type='memfd'/>
+    <access mode='shared'/>
+  </memoryBacking>
+{{- end}}
+  <os{{if .UEFI}} firmware='efi'{{end}}>
+    <type arch='{{.Arch}}' machine='{{.Machine}}'>hvm</type>
+{{- if .UEFI}}
+    <firmware>
+      <feature enabled='{{if .SecureBoot}}yes{{else}}no{{end}}' name='secure-boot'/>
+{{- if .SecureBoot}}
+      <feature enabled='yes' name='enrolled-keys'/>
+{{- end}}
+    </firmware>
+    <nvram>{{x .NVRAM}}</nvram>
+{{- end}}
+  </os>
+  <features>
+    <acpi/>
+{{- if .SMM}}
+    <smm state='on'/>
+{{- end}}
+  </features>
+  <cpu mode='{{.CPUMode}}'/>
+{{- if .Ignition}}
+  <sysinfo type='fwcfg'>
+    <entry name='opt/com.coreos/config' file='{{x .Ignition}}'/>
+  </sysinfo>
+{{- end}}
+  <devices>
+{{- range .Disks}}
+    <disk type='file' device='{{.Device}}'>
+      <driver name='qemu' type='{{.Format}}'/>
+      <source file='{{x .Path}}'/>
+      <target dev='{{.Target}}' bus='{{.Bus}}'/>
+{{- if .ReadOnly}}
+      <readonly/>
+{{- end}}
+{{- if .BootOrder}}
+      <boot order='{{.BootOrder}}'/>
+{{- end}}
+    </disk>
+{{- end}}
+{{- if .SCSI}}
+    <controller type='scsi' model='virtio-scsi'/>
+{{- end}}
+{{- range .Mounts}}
+    <filesystem type='mount' accessmode='passthrough'>
+      <driver type='virtiofs'/>
+      <source dir='{{x .Source}}'/>
+      <target dir='{{x .Tag}}'/>
+{{- if .ReadOnly}}
+      <readonly/>
+{{- end}}
+    </filesystem>
+{{- end}}
+    <interface type='user'>
+      <backend type='passt'/>
+      <mac address='{{.MAC}}'/>
+      <model type='{{.NICModel}}'/>
+{{- range .Ports}}
+      <portForward proto='{{.Protocol}}' address='{{x .HostIP}}'>
+        <range start='{{.HostPort}}' to='{{.GuestPort}}'/>
+      </portForward>
+{{- end}}
+    </interface>
+    <serial type='pty'>
+      <log file='{{x .LogFile}}' append='off'/>
+      <target port='0'/>
+    </serial>
+    <console type='pty'>
+      <target type='serial' port='0'/>
+    </console>
+    <rng model='virtio'>
+      <backend model='random'>/dev/urandom</backend>
+    </rng>
+{{- if .TPMModel}}
+    <tpm model='{{.TPMModel}}'>
+      <backend type='emulator' version='2.0'/>
+    </tpm>
+{{- end}}
+{{- if .GUI}}
+    <graphics type='spice' autoport='yes'/>
+    <video>
+      <model type='virtio'/>
+    </video>
+{{- end}}
+  </devices>
+</domain>
+`))
+
+// domainXML returns the libvirt XML of the transient domain
+func (d *LibvirtDriver) domainXML(ctx context.Context) (string, error) {
+	data := libvirtDomainData{
+		Type:     "kvm",
+		Name:     d.domain,
+		Memory:   d.opts.Memory,
+		CPUs:     d.opts.CPUs,
+		Arch:     QemuArch(d.opts.Arch),
+		Machine:  "q35",
+		CPUMode:  "host-passthrough",
+		UEFI:     d.opts.Firmware != FirmwareBIOS,
+		NVRAM:    d.efiStore,
+		Shared:   len(d.opts.Mounts) > 0,
+		Mounts:   d.opts.Mounts,
+		MAC:      generateMACAddress(d.opts.Name),
+		NICModel: libvirtNICModel(d.opts.NICModel),
+		LogFile:  d.logFile,
+		GUI:      d.opts.GUI,
+	}
+	if d.Accelerator() == AccelTCG {
+		data.Type = "qemu"
+		data.CPUMode = "maximum"
+	}
+	if d.opts.Arch == ArchARM64 {
+		data.Machine = "virt"
+	}
+	if data.UEFI {
+		data.SecureBoot = d.opts.SecureBoot
+		// Secure boot on x86_64 needs SMM so the guest cannot write the
+		// variable store directly
+		data.SMM = d.opts.SecureBoot && d.opts.Arch == ArchAMD64
+	}
+	if d.opts.TPM {
+		data.TPMModel = "tpm-crb"
+		if d.opts.Arch == ArchARM64 {
+			data.TPMModel = "tpm-tis"
+		}
+	}
+
+	// Disks: the system disk boots first; ISO installers are attached as a
+	// CD-ROM and install onto a blank disk
+	format, err := DetectImageFormat(d.opts.DiskImage)
+	if err != nil {
+		return "", err
+	}
+	if format == ImageFormatISO {
+		installDisk, err := ensureInstallDisk(ctx, &d.opts, d.verbose)
+		if err != nil {
+			return "", err
+		}
+		installFormat, err := DetectImageFormat(installDisk)
+		if err != nil {
+			return "", err
+		}
+		cdrom := libvirtDisk{Device: "cdrom", Format: ImageFormatRaw, Path: d.opts.DiskImage, Target: "sda", Bus: "sata", ReadOnly: true, BootOrder: 2}
+		if d.opts.Arch == ArchARM64 {
+			// The aarch64 virt machine has no SATA controller
+			cdrom.Bus = "scsi"
+			data.SCSI = true
+		}
+		data.Disks = append(data.Disks,
+			libvirtDisk{Device: "disk", Format: installFormat, Path: installDisk, Target: "vda", Bus: "virtio", BootOrder: 1},
+			cdrom)
+	} else {
+		data.Disks = append(data.Disks, libvirtDisk{Device: "disk", Format: format, Path: d.opts.DiskImage, Target: "vda", Bus: "virtio", BootOrder: 1})
+	}
+
+	// First-boot provisioning: Ignition via fw_cfg, cloud-init via a NoCloud seed disk
+	if p := d.opts.Provisioning; p != nil {
+		data.Ignition = p.IgnitionFile
+		if p.CloudInitSeed != "" {
+			data.Disks = append(data.Disks, libvirtDisk{Device: "disk", Format: ImageFormatRaw, Path: p.CloudInitSeed, Target: "vdb", Bus: "virtio", ReadOnly: true})
+		}
+	}
+
+	// SSH and published ports are forwarded by passt
+	data.Ports = append(data.Ports, PortForward{HostIP: DefaultForwardHostIP, HostPort: d.opts.SSHPort, GuestPort: 22, Protocol: ProtocolTCP})
+	for _, p := range d.opts.Ports {
+		p.HostIP = cmp.Or(p.HostIP, DefaultForwardHostIP)
+		data.Ports = append(data.Ports, p)
+	}
+
+	var b strings.Builder
+	if err := libvirtDomainTemplate.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to generate domain XML: %w", err)
+	}
+	return b.String(), nil
+}
+
+// libvirtNICModel returns the libvirt interface model of a QEMU NIC model
+func libvirtNICModel(model string) string {
+	if model == "" || model == NICModelVirtio {
+		return "virtio"
+	}
+	return model
+}
+
+// Stop destroys the domain immediately
+func (d *LibvirtDriver) Stop(ctx context.Context) error {
+	d.ssh.close()
+	if state, err := d.GetState(ctx); err == nil && state == VMStateStopped {
+		return nil
+	}
+	if _, err := virsh(ctx, d.uri, "destroy", d.domain); err != nil {
+		return fmt.Errorf("failed to stop VM: %w", err)
+	}
+	return nil
+}
+
+// Shutdown asks the guest to power off via ACPI and waits up to timeout
+// The domain is destroyed if the guest does not power off in time.
+func (d *LibvirtDriver) Shutdown(ctx context.Context, timeout time.Duration) error {
+	d.ssh.close()
+	return shutdownLibvirt(ctx, d.uri, d.domain, timeout)
+}
+
+// Pause suspends VM execution
+func (d *LibvirtDriver) Pause(ctx context.Context) error {
+	if _, err := virsh(ctx, d.uri, "suspend", d.domain); err != nil {
+		return fmt.Errorf("failed to pause VM: %w", err)
+	}
+	return nil
+}
+
+// Resume continues a paused VM
+func (d *LibvirtDriver) Resume(ctx context.Context) error {
+	if _, err := virsh(ctx, d.uri, "resume", d.domain); err != nil {
+		return fmt.Errorf("failed to resume VM: %w", err)
+	}
+	return nil
+}
+
+// GetState returns the current VM state
+func (d *LibvirtDriver) GetState(ctx context.Context) (VMState, error) {
+	return libvirtDomainState(ctx, d.uri, d.domain)
+}
+
+// WaitForReady waits for the domain to be running
+func (d *LibvirtDriver) WaitForReady(ctx context.Context) error {
+	timeout := ScaleTimeout(d, 30*time.Second)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		state, err := d.GetState(ctx)
+		if err != nil {
+			return err
+		}
+		if state == VMStateRunning {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+	return fmt.Errorf("VM did not become ready within %v", timeout)
+}
+
+// WaitForSSH waits for SSH to be available
+// passt forwards the port from the moment the domain starts, so only the
+// guest's sshd has to come up.
+func (d *LibvirtDriver) WaitForSSH(ctx context.Context) error {
+	timeout := ScaleTimeout(d, 2*time.Minute)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", d.sshConfig.Host, d.sshConfig.Port), 2*time.Second)
+		if err == nil {
+			conn.Close()
+			if err := d.ssh.probe(ctx, d.opts.Name, d.sshConfig); err == nil {
+				return nil
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return fmt.Errorf("SSH not available within %v", timeout)
+}
+
+// SSH executes a command via SSH and returns the combined output
+func (d *LibvirtDriver) SSH(ctx context.Context, command string) (string, error) {
+	output, err := d.ssh.get(d.opts.Name, d.sshConfig).CombinedOutput(ctx, command)
+	return string(output), err
+}
+
+// RunSSH executes a command via SSH, streaming its output
+func (d *LibvirtDriver) RunSSH(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	return d.ssh.get(d.opts.Name, d.sshConfig).Run(ctx, command, stdout, stderr)
+}
+
+// GetSSHConfig returns the SSH configuration
+func (d *LibvirtDriver) GetSSHConfig() SSHConfig {
+	return d.sshConfig
+}
+
+// ReadSerialLog reads the serial console log written by libvirt
+func (d *LibvirtDriver) ReadSerialLog() (string, error) {
+	data, err := os.ReadFile(d.logFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Cleanup destroys the domain and removes its log and EFI variable store
+// libvirt removes the TPM state of a transient domain itself.
+func (d *LibvirtDriver) Cleanup() error {
+	if err := d.Stop(context.Background()); err != nil {
+		return err
+	}
+	os.Remove(d.logFile)
+	os.Remove(d.efiStore)
+	return nil
+}
+
+// GetProcessID returns the PID of the QEMU process running the domain
+func (d *LibvirtDriver) GetProcessID() int {
+	return libvirtQemuPID(d.domain)
+}
+
+// GetLogFilePath returns the path to the serial console log file
+func (d *LibvirtDriver) GetLogFilePath() string {
+	return d.logFile
+}
+
+// ToVMInfo creates a VMInfo struct from the driver state
+func (d *LibvirtDriver) ToVMInfo(name, pipelineName, pipelineFile, imageTag string) *VMInfo {
+	return &VMInfo{
+		Name:          name,
+		PipelineName:  pipelineName,
+		PipelineFile:  pipelineFile,
+		ImageTag:      imageTag,
+		DiskImage:     d.opts.DiskImage,
+		InstallDisk:   d.opts.InstallDisk,
+		CPUs:          d.opts.CPUs,
+		Memory:        d.opts.Memory,
+		NICModel:      d.opts.NICModel,
+		Provisioning:  d.opts.Provisioning,
+		Mounts:        d.opts.Mounts,
+		Arch:          d.opts.Arch,
+		Accelerator:   d.Accelerator(),
+		SecureBoot:    d.opts.SecureBoot,
+		TPM:           d.opts.TPM,
+		Firmware:      d.opts.Firmware,
+		Created:       time.Now(),
+		SSHHost:       d.sshConfig.Host,
+		SSHPort:       d.sshConfig.Port,
+		SSHUser:       d.sshConfig.User,
+		SSHKeyPath:    d.sshConfig.KeyPath,
+		LogFile:       d.logFile,
+		State:         string(VMStateRunning),
+		VMType:        LibvirtVM.String(),
+		ProcessID:     d.GetProcessID(),
+		LibvirtURI:    d.uri,
+		LibvirtDomain: d.domain,
+	}
+}
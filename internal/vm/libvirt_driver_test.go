@@ -0,0 +1,111 @@
+//go:build linux
+
+package vm
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLibvirtDomainXML(t *testing.T) {
+	disk := filepath.Join(t.TempDir(), "disk.raw")
+	if err := os.WriteFile(disk, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    VMOptions
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "defaults",
+			opts: VMOptions{Name: "dev", Arch: ArchAMD64, CPUs: 2, Memory: 2048, SSHPort: 2222},
+			want: []string{
+				"<name>bootc-man-dev</name>",
+				"<memory unit='MiB'>2048</memory>",
+				"<os firmware='efi'>",
+				"<feature enabled='no' name='secure-boot'/>",
+				"machine='q35'",
+				"<source file='" + disk + "'/>",
+				"<backend type='passt'/>",
+				"<model type='virtio'/>",
+				"<portForward proto='tcp' address='127.0.0.1'>",
+				"<range start='2222' to='22'/>",
+			},
+			notWant: []string{"<smm", "<tpm", "<filesystem", "<graphics", "<sysinfo"},
+		},
+		{
+			name: "secure boot with TPM",
+			opts: VMOptions{Name: "sb", Arch: ArchAMD64, SecureBoot: true, TPM: true, SSHPort: 2222},
+			want: []string{
+				"<feature enabled='yes' name='secure-boot'/>",
+				"<feature enabled='yes' name='enrolled-keys'/>",
+				"<smm state='on'/>",
+				"<tpm model='tpm-crb'>",
+			},
+		},
+		{
+			name:    "BIOS",
+			opts:    VMOptions{Name: "bios", Arch: ArchAMD64, Firmware: FirmwareBIOS, SSHPort: 2222},
+			want:    []string{"<os>"},
+			notWant: []string{"firmware='efi'", "<nvram>"},
+		},
+		{
+			name: "aarch64",
+			opts: VMOptions{Name: "arm", Arch: ArchARM64, TPM: true, SSHPort: 2222},
+			want: []string{"arch='aarch64' machine='virt'", "<tpm model='tpm-tis'>"},
+		},
+		{
+			name: "mounts, ports and provisioning",
+			opts: VMOptions{
+				Name:         "full",
+				Arch:         ArchAMD64,
+				SSHPort:      2222,
+				NICModel:     "e1000e",
+				Mounts:       []SharedDir{{Source: "/src", Tag: "src", ReadOnly: true}},
+				Ports:        []PortForward{{HostPort: 8080, GuestPort: 80, Protocol: ProtocolTCP}, {HostIP: "0.0.0.0", HostPort: 5353, GuestPort: 53, Protocol: ProtocolUDP}},
+				Provisioning: &Provisioning{IgnitionFile: "/run/full.ign", CloudInitSeed: "/run/full-seed.iso"},
+			},
+			want: []string{
+				"<access mode='shared'/>",
+				"<source dir='/src'/>",
+				"<target dir='src'/>",
+				"<model type='e1000e'/>",
+				"<range start='8080' to='80'/>",
+				"<portForward proto='udp' address='0.0.0.0'>",
+				"<entry name='opt/com.coreos/config' file='/run/full.ign'/>",
+				"<source file='/run/full-seed.iso'/>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.DiskImage = disk
+			d := &LibvirtDriver{opts: tt.opts, domain: LibvirtDomainName(tt.opts.Name), accel: AccelKVM, logFile: "/run/serial.log", efiStore: "/run/efi.fd"}
+			got, err := d.domainXML(context.Background())
+			if err != nil {
+				t.Fatalf("domainXML() error = %v", err)
+			}
+			if err := xml.Unmarshal([]byte(got), new(struct{})); err != nil {
+				t.Fatalf("domainXML() is not well-formed: %v\n%s", err, got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("domainXML() missing %q\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("domainXML() contains %q\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
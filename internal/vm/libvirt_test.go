@@ -0,0 +1,43 @@
+package vm
+
+import "testing"
+
+func TestLibvirtStateToVMState(t *testing.T) {
+	tests := []struct {
+		state string
+		want  VMState
+	}{
+		{"running", VMStateRunning},
+		{"in shutdown", VMStateRunning},
+		{"paused", VMStatePaused},
+		{"pmsuspended", VMStatePaused},
+		{"shut off", VMStateStopped},
+		{"crashed", VMStateStopped},
+		{"dying", VMStateUnknown},
+	}
+	for _, tt := range tests {
+		if got := libvirtStateToVMState(tt.state); got != tt.want {
+			t.Errorf("libvirtStateToVMState(%q) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestParseDefaultGateway(t *testing.T) {
+	const header = "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n"
+	tests := []struct {
+		name   string
+		routes string
+		want   string
+	}{
+		{"default route", header + "eth0\t0000A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\neth0\t00000000\t0100A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n", "192.168.0.1"},
+		{"no default route", header + "eth0\t0000A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseDefaultGateway(tt.routes); got != tt.want {
+				t.Errorf("parseDefaultGateway() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
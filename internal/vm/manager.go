@@ -1,8 +1,13 @@
 package vm
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"os"
+	"strings"
 	"syscall"
+	"time"
 )
 
 // IsProcessRunning checks if a process with the given PID is running
@@ -28,6 +33,17 @@ func IsVMRunning(info *VMInfo) bool {
 	if pid == 0 {
 		pid = info.VfkitPID
 	}
+	if pid == 0 && info.DriverPlugin != "" {
+		// External drivers without a local process are asked for the state
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := callVMPlugin(ctx, info, "state", 0)
+		if err != nil {
+			return false
+		}
+		state := pluginStateToVMState(resp.State)
+		return state == VMStateRunning || state == VMStatePaused || state == VMStateStarting
+	}
 	return IsProcessRunning(pid)
 }
 
@@ -51,3 +67,197 @@ func StopProcess(pid int) error {
 	_, _ = process.Wait()
 	return nil
 }
+
+// ShutdownVM shuts down a VM described by VMInfo and stops its gvproxy
+// QEMU VMs are powered off via QMP, vfkit VMs via the RESTful API, libvirt
+// domains via virsh and external driver VMs by their plugin, so the guest
+// can flush its filesystems. If the
+// guest does not power off within timeout the VM is stopped forcibly and an
+// error wrapping ErrShutdownTimeout is returned.
+func ShutdownVM(ctx context.Context, info *VMInfo, timeout time.Duration) error {
+	var err error
+	if IsVMRunning(info) {
+		switch {
+		case info.LibvirtDomain != "":
+			err = shutdownLibvirt(ctx, info.LibvirtURI, info.LibvirtDomain, timeout)
+		case info.DriverPlugin != "":
+			err = shutdownPlugin(ctx, info, timeout)
+		case info.QMPSocket != "":
+			err = shutdownQMP(ctx, info, timeout)
+		case info.VfkitEndpoint != "":
+			err = shutdownVfkit(ctx, info, timeout)
+		default:
+			stopProcessWithTimeout(vmPID(info), timeout)
+		}
+	}
+	stopProcessWithTimeout(info.GvproxyPID, 3*time.Second)
+	return err
+}
+
+// shutdownQMP powers off a QEMU VM via its QMP socket
+func shutdownQMP(ctx context.Context, info *VMInfo, timeout time.Duration) error {
+	client, err := DialQMP(ctx, info.QMPSocket)
+	if err != nil {
+		// QMP not reachable - fall back to signalling the process
+		stopProcessWithTimeout(vmPID(info), timeout)
+		return nil
+	}
+	defer client.Close()
+	return client.Powerdown(ctx, timeout)
+}
+
+// shutdownVfkit powers off a vfkit VM via its RESTful API
+func shutdownVfkit(ctx context.Context, info *VMInfo, timeout time.Duration) error {
+	pid := vmPID(info)
+	if err := requestVfkitState(ctx, info.VfkitEndpoint, "Stop"); err != nil {
+		stopProcessWithTimeout(pid, timeout)
+		return nil
+	}
+	if waitForProcessExit(pid, timeout) {
+		return nil
+	}
+	_ = requestVfkitState(ctx, info.VfkitEndpoint, "HardStop")
+	stopProcessWithTimeout(pid, 3*time.Second)
+	return fmt.Errorf("%w (%v), VM was stopped forcibly", ErrShutdownTimeout, timeout)
+}
+
+// PauseVM suspends execution of a running VM
+func PauseVM(ctx context.Context, info *VMInfo) error {
+	return changeVMRunState(ctx, info, "stop", "Pause")
+}
+
+// ResumeVM continues a paused VM
+func ResumeVM(ctx context.Context, info *VMInfo) error {
+	return changeVMRunState(ctx, info, "cont", "Resume")
+}
+
+// changeVMRunState sends a pause/resume request using the VM's control channel
+func changeVMRunState(ctx context.Context, info *VMInfo, qmpCmd, vfkitState string) error {
+	if !IsVMRunning(info) {
+		return fmt.Errorf("VM '%s' is not running", info.Name)
+	}
+	switch {
+	case info.LibvirtDomain != "":
+		virshCmd := "suspend"
+		if qmpCmd == "cont" {
+			virshCmd = "resume"
+		}
+		_, err := virsh(ctx, info.LibvirtURI, virshCmd, info.LibvirtDomain)
+		return err
+	case info.DriverPlugin != "":
+		method := "pause"
+		if qmpCmd == "cont" {
+			method = "resume"
+		}
+		_, err := callVMPlugin(ctx, info, method, 0)
+		return err
+	case info.QMPSocket != "":
+		_, err := qmpCommand(ctx, info.QMPSocket, qmpCmd, nil)
+		return err
+	case info.VfkitEndpoint != "":
+		return requestVfkitState(ctx, info.VfkitEndpoint, vfkitState)
+	default:
+		return fmt.Errorf("VM '%s' has no control channel (restart it with this version of bootc-man)", info.Name)
+	}
+}
+
+// GetVMState returns the state of a VM described by VMInfo
+// The process check is refined with the hypervisor's run state when the
+// control channel is reachable, so paused VMs are reported as such.
+func GetVMState(ctx context.Context, info *VMInfo) VMState {
+	if !IsVMRunning(info) {
+		if info.State == string(VMStateCreated) {
+			return VMStateCreated
+		}
+		return VMStateStopped
+	}
+	if info.LibvirtDomain != "" {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if state, err := libvirtDomainState(ctx, info.LibvirtURI, info.LibvirtDomain); err == nil && state != VMStateUnknown {
+			return state
+		}
+		return VMStateRunning
+	}
+	if info.DriverPlugin != "" {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if resp, err := callVMPlugin(ctx, info, "state", 0); err == nil && pluginStateToVMState(resp.State) != VMStateUnknown {
+			return pluginStateToVMState(resp.State)
+		}
+		return VMStateRunning
+	}
+	if info.QMPSocket != "" {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		client, err := DialQMP(ctx, info.QMPSocket)
+		if err != nil {
+			return VMStateRunning
+		}
+		defer client.Close()
+		if status, err := client.QueryStatus(ctx); err == nil {
+			return qmpStateToVMState(status)
+		}
+	}
+	return VMStateRunning
+}
+
+// vmPID returns the main VM process ID, supporting the legacy vfkit field
+func vmPID(info *VMInfo) int {
+	if info.ProcessID != 0 {
+		return info.ProcessID
+	}
+	return info.VfkitPID
+}
+
+// waitForProcessExit polls until the process exits or timeout elapses
+// Returns true if the process exited
+func waitForProcessExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !IsProcessRunning(pid) {
+			return true
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	return !IsProcessRunning(pid)
+}
+
+// stopProcessWithTimeout interrupts a process and kills it if it is still
+// running after timeout
+func stopProcessWithTimeout(pid int, timeout time.Duration) {
+	if !IsProcessRunning(pid) {
+		return
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return
+	}
+	if err := process.Signal(os.Interrupt); err == nil && waitForProcessExit(pid, timeout) {
+		return
+	}
+	_ = process.Kill()
+	waitForProcessExit(pid, 3*time.Second)
+}
+
+// requestVfkitState sends a state change request to a vfkit RESTful endpoint
+func requestVfkitState(ctx context.Context, endpoint, state string) error {
+	body := fmt.Sprintf(`{"state": "%s"}`, state)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint+"/vm/state", strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to change VM state: %s", resp.Status)
+	}
+	return nil
+}
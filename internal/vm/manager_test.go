@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"context"
 	"os"
 	"testing"
 )
@@ -108,6 +109,26 @@ func TestIsVMRunning(t *testing.T) {
 	}
 }
 
+func TestGetVMState(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		info *VMInfo
+		want VMState
+	}{
+		{"created", &VMInfo{State: string(VMStateCreated)}, VMStateCreated},
+		{"stopped", &VMInfo{State: string(VMStateRunning)}, VMStateStopped},
+		{"running", &VMInfo{State: string(VMStateCreated), ProcessID: os.Getpid()}, VMStateRunning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetVMState(ctx, tt.info); got != tt.want {
+				t.Errorf("GetVMState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestStopProcess(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -0,0 +1,92 @@
+package vm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxMountTagLength is the longest tag virtio-fs accepts
+const maxMountTagLength = 36
+
+var mountTagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// SharedDir is a host directory shared into the VM via virtio-fs
+type SharedDir struct {
+	Source   string `json:"source"`             // ホスト側ディレクトリ（絶対パス）
+	Tag      string `json:"tag"`                // VM内でmountに使うタグ
+	ReadOnly bool   `json:"readOnly,omitempty"` // 読み取り専用で共有
+}
+
+// ParseSharedDir parses <hostdir>:<tag>[:ro|:rw]
+// The host directory is made absolute and must exist.
+func ParseSharedDir(spec string) (SharedDir, error) {
+	var m SharedDir
+	rest := spec
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		switch rest[i+1:] {
+		case "ro":
+			m.ReadOnly = true
+			rest = rest[:i]
+		case "rw":
+			rest = rest[:i]
+		}
+	}
+
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 {
+		return SharedDir{}, fmt.Errorf("invalid mount %q: expected <hostdir>:<tag>[:ro]", spec)
+	}
+	source, tag := rest[:i], rest[i+1:]
+
+	if len(tag) > maxMountTagLength || !mountTagPattern.MatchString(tag) {
+		return SharedDir{}, fmt.Errorf("invalid mount %q: tag must be 1-%d letters, digits, '.', '_' or '-'", spec, maxMountTagLength)
+	}
+	m.Tag = tag
+
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return SharedDir{}, fmt.Errorf("invalid mount %q: %w", spec, err)
+	}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return SharedDir{}, fmt.Errorf("invalid mount %q: %w", spec, err)
+	}
+	if !fi.IsDir() {
+		return SharedDir{}, fmt.Errorf("invalid mount %q: %s is not a directory", spec, abs)
+	}
+	m.Source = abs
+	return m, nil
+}
+
+// ValidateSharedDirs checks that mount tags are unique
+func ValidateSharedDirs(mounts []SharedDir) error {
+	seen := map[string]bool{}
+	for _, m := range mounts {
+		if seen[m.Tag] {
+			return fmt.Errorf("mount tag %q is used more than once", m.Tag)
+		}
+		seen[m.Tag] = true
+	}
+	return nil
+}
+
+// String returns the mount in the form accepted by ParseSharedDir
+func (m SharedDir) String() string {
+	s := m.Source + ":" + m.Tag
+	if m.ReadOnly {
+		s += ":ro"
+	}
+	return s
+}
+
+// MountCommand returns the command that mounts the share inside the VM
+func (m SharedDir) MountCommand(target string) string {
+	opts := ""
+	if m.ReadOnly {
+		opts = " -o ro"
+	}
+	return fmt.Sprintf("sudo mount -t virtiofs%s %s %s", opts, m.Tag, target)
+}
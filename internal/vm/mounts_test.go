@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSharedDir(t *testing.T) {
+	dir := t.TempDir()
+	colonDir := filepath.Join(dir, "a:b")
+	if err := os.Mkdir(colonDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec    string
+		want    SharedDir
+		wantErr bool
+	}{
+		{dir + ":src", SharedDir{Source: dir, Tag: "src"}, false},
+		{dir + ":src:ro", SharedDir{Source: dir, Tag: "src", ReadOnly: true}, false},
+		{dir + ":src:rw", SharedDir{Source: dir, Tag: "src"}, false},
+		{colonDir + ":etc-overlay", SharedDir{Source: colonDir, Tag: "etc-overlay"}, false},
+		{dir + "/:tests.v1", SharedDir{Source: dir, Tag: "tests.v1"}, false},
+		{dir, SharedDir{}, true},
+		{dir + ":", SharedDir{}, true},
+		{":src", SharedDir{}, true},
+		{dir + ":bad tag", SharedDir{}, true},
+		{dir + ":-src", SharedDir{}, true},
+		{dir + ":0123456789012345678901234567890123456", SharedDir{}, true},
+		{file + ":src", SharedDir{}, true},
+		{filepath.Join(dir, "missing") + ":src", SharedDir{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSharedDir(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSharedDir(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSharedDir(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSharedDirRelative(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.Mkdir("src", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseSharedDir("src:code")
+	if err != nil {
+		t.Fatalf("ParseSharedDir() error = %v", err)
+	}
+	if !filepath.IsAbs(got.Source) || filepath.Base(got.Source) != "src" {
+		t.Errorf("Source = %q, want absolute path of src", got.Source)
+	}
+}
+
+func TestSharedDirStringAndMountCommand(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		m         SharedDir
+		wantMount string
+	}{
+		{SharedDir{Source: dir, Tag: "src"}, "sudo mount -t virtiofs src /mnt/src"},
+		{SharedDir{Source: dir, Tag: "etc", ReadOnly: true}, "sudo mount -t virtiofs -o ro etc /mnt/etc"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.MountCommand("/mnt/" + tt.m.Tag); got != tt.wantMount {
+			t.Errorf("MountCommand() = %q, want %q", got, tt.wantMount)
+		}
+		// String output parses back to the same mount
+		if again, err := ParseSharedDir(tt.m.String()); err != nil || again != tt.m {
+			t.Errorf("ParseSharedDir(%q) = %+v, %v, want %+v", tt.m.String(), again, err, tt.m)
+		}
+	}
+}
+
+func TestValidateSharedDirs(t *testing.T) {
+	a := SharedDir{Source: "/a", Tag: "a"}
+	b := SharedDir{Source: "/b", Tag: "b"}
+	if err := ValidateSharedDirs([]SharedDir{a, b}); err != nil {
+		t.Errorf("ValidateSharedDirs() error = %v", err)
+	}
+	if err := ValidateSharedDirs([]SharedDir{a, {Source: "/c", Tag: "a"}}); err == nil {
+		t.Error("ValidateSharedDirs(duplicate tag) error = nil, want error")
+	}
+}
@@ -0,0 +1,186 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/config"
+)
+
+// External VM drivers
+//
+// A VM driver other than the built-in ones is an executable named
+// bootc-man-driver-<name>, found in PATH or in /usr/libexec/bootc-man. For
+// every driver call bootc-man runs the executable once, writes a JSON request
+// to its stdin and reads a JSON response from its stdout; stderr is shown to
+// the user when the call fails (and always with --verbose). The plugin keeps
+// whatever state it needs per VM name, since the VM outlives bootc-man.
+//
+// Methods: available, start, stop, shutdown, pause, resume, state,
+// wait-ready, ssh-config, serial-log and cleanup. A plugin answers methods
+// it does not implement with {"unsupported": true}; bootc-man then falls
+// back to stop (for shutdown) or polling state (for wait-ready).
+
+// PluginProtocolVersion is the version of the driver plugin protocol
+const PluginProtocolVersion = 1
+
+// errPluginUnsupported is returned for methods a plugin does not implement
+var errPluginUnsupported = errors.New("not supported by the VM driver")
+
+// pluginRequest is the JSON request written to a driver plugin's stdin
+type pluginRequest struct {
+	Version        int            `json:"version"`                  // プロトコルバージョン
+	Method         string         `json:"method"`                   // 呼び出すメソッド（start, stop, state等）
+	VM             string         `json:"vm"`                       // VM名
+	Options        *pluginOptions `json:"options,omitempty"`        // VMの設定（startのみ）
+	TimeoutSeconds int            `json:"timeoutSeconds,omitempty"` // shutdown/wait-readyの待ち時間
+}
+
+// pluginOptions are the VM options sent with start
+type pluginOptions struct {
+	DiskImage    string        `json:"diskImage"`              // ディスクイメージパス
+	DiskFormat   string        `json:"diskFormat"`             // ディスクイメージ形式（現在はrawのみ）
+	CPUs         int           `json:"cpus"`                   // CPU数
+	Memory       int           `json:"memory"`                 // メモリサイズ（MB）
+	Arch         string        `json:"arch"`                   // ゲストのアーキテクチャ（amd64, arm64）
+	SSHPort      int           `json:"sshPort"`                // ゲストの22番へ転送するホスト側ポート
+	SSHUser      string        `json:"sshUser"`                // SSHユーザー名
+	SSHKeyPath   string        `json:"sshKeyPath"`             // SSH秘密鍵パス
+	GUI          bool          `json:"gui,omitempty"`          // GUIウィンドウを表示
+	SecureBoot   bool          `json:"secureBoot,omitempty"`   // セキュアブートを有効化
+	TPM          bool          `json:"tpm,omitempty"`          // 仮想TPM 2.0を接続
+	Firmware     string        `json:"firmware,omitempty"`     // ブートファームウェア（uefi, bios）
+	NICModel     string        `json:"nicModel,omitempty"`     // NICモデル
+	Provisioning *Provisioning `json:"provisioning,omitempty"` // Ignition/cloud-initによる初回起動時の設定
+	Mounts       []SharedDir   `json:"mounts,omitempty"`       // 共有するホストディレクトリ
+	Ports        []PortForward `json:"ports,omitempty"`        // 追加のポートフォワード
+}
+
+// pluginResponse is the JSON response a driver plugin writes to stdout
+type pluginResponse struct {
+	Error       string     `json:"error,omitempty"`       // 失敗時のエラーメッセージ
+	Unsupported bool       `json:"unsupported,omitempty"` // メソッド未対応
+	State       string     `json:"state,omitempty"`       // VM状態（running, paused, stopped等）
+	PID         int        `json:"pid,omitempty"`         // VMを実行しているローカルプロセスのID
+	Forced      bool       `json:"forced,omitempty"`      // shutdownが時間内に終わらず強制停止した
+	LogFile     string     `json:"logFile,omitempty"`     // シリアルコンソールログファイル
+	SerialLog   string     `json:"serialLog,omitempty"`   // シリアルコンソールログの内容
+	SSH         *pluginSSH `json:"ssh,omitempty"`         // SSH接続先（省略した項目はbootc-manの値を使用）
+}
+
+// pluginSSH is the SSH endpoint reported by ssh-config
+type pluginSSH struct {
+	Host        string `json:"host,omitempty"`        // SSH接続先ホスト
+	Port        int    `json:"port,omitempty"`        // SSH接続先ポート
+	User        string `json:"user,omitempty"`        // SSHユーザー名
+	HostGateway string `json:"hostGateway,omitempty"` // VMから見たホストのIPアドレス
+}
+
+// FindDriverPlugin returns the path of the bootc-man-driver-<name> executable
+func FindDriverPlugin(name string) (string, error) {
+	exe := config.VMDriverPluginPrefix + name
+	if path, err := exec.LookPath(exe); err == nil {
+		return path, nil
+	}
+	path := filepath.Join(config.VMDriverPluginDir, exe)
+	if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+		return path, nil
+	}
+	return "", fmt.Errorf("VM driver %q not found: install %s in PATH or %s", name, exe, config.VMDriverPluginDir)
+}
+
+// callPlugin runs one request against a driver plugin
+func callPlugin(ctx context.Context, path string, req pluginRequest, verbose bool) (*pluginResponse, error) {
+	req.Version = PluginProtocolVersion
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s request: %w", req.Method, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if verbose {
+		cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
+		fmt.Printf("Running: %s (%s %s)\n", path, req.Method, req.VM)
+	}
+	runErr := cmd.Run()
+
+	name := filepath.Base(path)
+	var resp pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("%s %s failed: %w\n%s", name, req.Method, runErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("%s %s returned an invalid response: %w", name, req.Method, err)
+	}
+	switch {
+	case resp.Unsupported:
+		return nil, fmt.Errorf("%s: %w", req.Method, errPluginUnsupported)
+	case resp.Error != "":
+		return nil, fmt.Errorf("%s %s: %s", name, req.Method, resp.Error)
+	case runErr != nil:
+		return nil, fmt.Errorf("%s %s failed: %w\n%s", name, req.Method, runErr, strings.TrimSpace(stderr.String()))
+	}
+	return &resp, nil
+}
+
+// callVMPlugin runs a request against the driver plugin a VM was started with
+func callVMPlugin(ctx context.Context, info *VMInfo, method string, timeout time.Duration) (*pluginResponse, error) {
+	path, err := FindDriverPlugin(info.DriverPlugin)
+	if err != nil {
+		return nil, err
+	}
+	return callPlugin(ctx, path, pluginRequest{Method: method, VM: info.Name, TimeoutSeconds: int(timeout.Seconds())}, false)
+}
+
+// shutdownPlugin powers off a VM of an external driver, falling back to
+// stop if the driver has no graceful shutdown
+func shutdownPlugin(ctx context.Context, info *VMInfo, timeout time.Duration) error {
+	resp, err := callVMPlugin(ctx, info, "shutdown", timeout)
+	if errors.Is(err, errPluginUnsupported) {
+		_, err = callVMPlugin(ctx, info, "stop", 0)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if resp.Forced {
+		return fmt.Errorf("%w (%v), VM was stopped forcibly", ErrShutdownTimeout, timeout)
+	}
+	return nil
+}
+
+// CleanupVMDriver lets the driver plugin a VM was started with remove its
+// state for the VM; VMs of built-in drivers have nothing to clean up
+func CleanupVMDriver(ctx context.Context, info *VMInfo) error {
+	if info.DriverPlugin == "" {
+		return nil
+	}
+	if _, err := callVMPlugin(ctx, info, "cleanup", 0); err != nil && !errors.Is(err, errPluginUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// pluginStateToVMState maps a state reported by a driver plugin to a VMState
+// States are matched case-insensitively; anything else is unknown.
+func pluginStateToVMState(state string) VMState {
+	for _, s := range []VMState{VMStateRunning, VMStateStopped, VMStateStarting, VMStatePaused, VMStateError} {
+		if strings.EqualFold(state, string(s)) {
+			return s
+		}
+	}
+	return VMStateUnknown
+}
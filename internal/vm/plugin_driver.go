@@ -0,0 +1,364 @@
+package vm
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/cleanup"
+)
+
+// PluginDriver drives VMs through an external bootc-man-driver-<name>
+// executable
+// bootc-man keeps SSH, the test stage and VM bookkeeping; the plugin only
+// runs the hypervisor. See plugin.go for the protocol.
+type PluginDriver struct {
+	name      string
+	opts      VMOptions
+	verbose   bool
+	ssh       driverSSH
+	sshConfig SSHConfig
+	pid       int
+	logFile   string
+}
+
+// NewPluginDriver creates a driver for the bootc-man-driver-<name> plugin
+// The plugin is looked up when it is first called, so that Available can
+// report a missing plugin.
+func NewPluginDriver(name string, opts VMOptions, verbose bool) (*PluginDriver, error) {
+	// Set defaults
+	if opts.CPUs == 0 {
+		opts.CPUs = 2
+	}
+	if opts.Memory == 0 {
+		opts.Memory = 4096
+	}
+	if opts.SSHUser == "" {
+		opts.SSHUser = "user"
+	}
+	arch, err := NormalizeArch(opts.Arch)
+	if err != nil {
+		return nil, err
+	}
+	opts.Arch = arch
+	if opts.SSHPort == 0 {
+		// Allocate a port using podman machine's port allocation system
+		port, err := AllocateMachinePort()
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate SSH port: %w", err)
+		}
+		opts.SSHPort = port
+	}
+
+	return &PluginDriver{
+		name:    name,
+		opts:    opts,
+		verbose: verbose,
+		sshConfig: SSHConfig{
+			Host:    "localhost",
+			Port:    opts.SSHPort,
+			User:    opts.SSHUser,
+			KeyPath: opts.SSHKeyPath,
+		},
+	}, nil
+}
+
+// call runs a request against the plugin
+func (d *PluginDriver) call(ctx context.Context, method string, req pluginRequest) (*pluginResponse, error) {
+	path, err := FindDriverPlugin(d.name)
+	if err != nil {
+		return nil, err
+	}
+	req.Method = method
+	req.VM = d.opts.Name
+	return callPlugin(ctx, path, req, d.verbose)
+}
+
+// Type returns the VM type
+func (d *PluginDriver) Type() VMType {
+	return ExternalVM
+}
+
+// Available checks that the plugin is installed and can run VMs
+func (d *PluginDriver) Available() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := d.call(ctx, "available", pluginRequest{}); err != nil && !errors.Is(err, errPluginUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// Start asks the plugin to start the VM
+func (d *PluginDriver) Start(ctx context.Context, opts VMOptions) (err error) {
+	// Update options if provided, but preserve the allocated SSH port
+	if opts.Name != "" {
+		arch, err := NormalizeArch(opts.Arch)
+		if err != nil {
+			return err
+		}
+		opts.Arch = arch
+		if opts.SSHPort == 0 {
+			opts.SSHPort = d.opts.SSHPort
+		}
+		d.opts = opts
+		d.sshConfig.Port = opts.SSHPort
+	}
+	if d.opts.Lab != nil {
+		return fmt.Errorf("lab networks are not supported by the %s VM driver (use the QEMU driver)", d.name)
+	}
+
+	// Plugins get a private clone of a raw image (see PlanVMDisk); a qcow2
+	// overlay made for QEMU would hand them a disk with a backing file
+	format, err := DetectImageFormat(d.opts.DiskImage)
+	if err != nil {
+		return err
+	}
+	if !ExternalVM.SupportsImageFormat(format) {
+		return fmt.Errorf("the %s VM driver cannot boot %s disk images (%s); recreate the VM from a raw image with this driver", d.name, format, d.opts.DiskImage)
+	}
+
+	// The plugin's VM outlives bootc-man, so stop it if bootc-man is
+	// interrupted before Start returns; afterwards the caller owns the VM
+	starting := cleanup.Register("stop "+d.name+" VM "+d.opts.Name, d.Stop)
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			_ = starting.Run(context.Background())
+		} else {
+			starting.Release()
+		}
+	}()
+
+	resp, err := d.call(ctx, "start", pluginRequest{Options: &pluginOptions{
+		DiskImage:    d.opts.DiskImage,
+		DiskFormat:   format,
+		CPUs:         d.opts.CPUs,
+		Memory:       d.opts.Memory,
+		Arch:         d.opts.Arch,
+		SSHPort:      d.opts.SSHPort,
+		SSHUser:      d.opts.SSHUser,
+		SSHKeyPath:   d.opts.SSHKeyPath,
+		GUI:          d.opts.GUI,
+		SecureBoot:   d.opts.SecureBoot,
+		TPM:          d.opts.TPM,
+		Firmware:     d.opts.Firmware,
+		NICModel:     d.opts.NICModel,
+		Provisioning: d.opts.Provisioning,
+		Mounts:       d.opts.Mounts,
+		Ports:        d.opts.Ports,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to start VM: %w", err)
+	}
+	d.pid = resp.PID
+	d.logFile = resp.LogFile
+	if d.pid > 0 {
+		starting.AddResource(ProcessResource(d.pid))
+	}
+
+	// The plugin may put SSH somewhere else, e.g. on the guest's own address
+	if resp, err := d.call(ctx, "ssh-config", pluginRequest{}); err == nil && resp.SSH != nil {
+		d.sshConfig.Host = cmp.Or(resp.SSH.Host, d.sshConfig.Host)
+		d.sshConfig.Port = cmp.Or(resp.SSH.Port, d.sshConfig.Port)
+		d.sshConfig.User = cmp.Or(resp.SSH.User, d.sshConfig.User)
+		d.sshConfig.HostGateway = resp.SSH.HostGateway
+	} else if err != nil && !errors.Is(err, errPluginUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// Stop asks the plugin to stop the VM immediately
+func (d *PluginDriver) Stop(ctx context.Context) error {
+	d.ssh.close()
+	_, err := d.call(ctx, "stop", pluginRequest{})
+	return err
+}
+
+// Shutdown asks the plugin to power off the guest gracefully within timeout
+// Plugins without a graceful shutdown stop the VM instead.
+func (d *PluginDriver) Shutdown(ctx context.Context, timeout time.Duration) error {
+	d.ssh.close()
+	resp, err := d.call(ctx, "shutdown", pluginRequest{TimeoutSeconds: int(timeout.Seconds())})
+	if errors.Is(err, errPluginUnsupported) {
+		return d.Stop(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if resp.Forced {
+		return fmt.Errorf("%w (%v), VM was stopped forcibly", ErrShutdownTimeout, timeout)
+	}
+	return nil
+}
+
+// Pause suspends VM execution
+func (d *PluginDriver) Pause(ctx context.Context) error {
+	_, err := d.call(ctx, "pause", pluginRequest{})
+	return err
+}
+
+// Resume continues a paused VM
+func (d *PluginDriver) Resume(ctx context.Context) error {
+	_, err := d.call(ctx, "resume", pluginRequest{})
+	return err
+}
+
+// GetState returns the current VM state
+func (d *PluginDriver) GetState(ctx context.Context) (VMState, error) {
+	resp, err := d.call(ctx, "state", pluginRequest{})
+	if err != nil {
+		return VMStateUnknown, err
+	}
+	if resp.PID > 0 {
+		d.pid = resp.PID
+	}
+	return pluginStateToVMState(resp.State), nil
+}
+
+// WaitForReady waits for the VM to be running
+// Plugins without wait-ready are polled with state.
+func (d *PluginDriver) WaitForReady(ctx context.Context) error {
+	timeout := 60 * time.Second
+	_, err := d.call(ctx, "wait-ready", pluginRequest{TimeoutSeconds: int(timeout.Seconds())})
+	if !errors.Is(err, errPluginUnsupported) {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		state, err := d.GetState(ctx)
+		if err != nil {
+			return err
+		}
+		if state == VMStateRunning {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+	return fmt.Errorf("VM did not become ready within %v", timeout)
+}
+
+// WaitForSSH waits for SSH to be available
+func (d *PluginDriver) WaitForSSH(ctx context.Context) error {
+	timeout := 2 * time.Minute
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(d.sshConfig.Host, fmt.Sprint(d.sshConfig.Port)), 2*time.Second)
+		if err == nil {
+			conn.Close()
+			if err := d.ssh.probe(ctx, d.opts.Name, d.sshConfig); err == nil {
+				return nil
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return fmt.Errorf("SSH not available within %v", timeout)
+}
+
+// SSH executes a command via SSH and returns the combined output
+func (d *PluginDriver) SSH(ctx context.Context, command string) (string, error) {
+	output, err := d.ssh.get(d.opts.Name, d.sshConfig).CombinedOutput(ctx, command)
+	return string(output), err
+}
+
+// RunSSH executes a command via SSH, streaming its output
+func (d *PluginDriver) RunSSH(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	return d.ssh.get(d.opts.Name, d.sshConfig).Run(ctx, command, stdout, stderr)
+}
+
+// GetSSHConfig returns the SSH configuration
+func (d *PluginDriver) GetSSHConfig() SSHConfig {
+	return d.sshConfig
+}
+
+// ReadSerialLog returns the serial console log
+// The plugin can return the log itself or name a log file in start.
+func (d *PluginDriver) ReadSerialLog() (string, error) {
+	resp, err := d.call(context.Background(), "serial-log", pluginRequest{})
+	if err == nil {
+		return resp.SerialLog, nil
+	}
+	if !errors.Is(err, errPluginUnsupported) {
+		return "", err
+	}
+	if d.logFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(d.logFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Cleanup stops the VM and lets the plugin remove its state
+// A VM that is already stopped is not an error.
+func (d *PluginDriver) Cleanup() error {
+	ctx := context.Background()
+	if err := d.Stop(ctx); err != nil && !errors.Is(err, errPluginUnsupported) {
+		if state, stateErr := d.GetState(ctx); stateErr != nil || state != VMStateStopped {
+			return err
+		}
+	}
+	if _, err := d.call(ctx, "cleanup", pluginRequest{}); err != nil && !errors.Is(err, errPluginUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// GetProcessID returns the PID of the local process running the VM, as
+// reported by the plugin
+func (d *PluginDriver) GetProcessID() int {
+	return d.pid
+}
+
+// GetLogFilePath returns the path to the serial console log file, if the
+// plugin named one
+func (d *PluginDriver) GetLogFilePath() string {
+	return d.logFile
+}
+
+// ToVMInfo creates a VMInfo struct from the driver state
+func (d *PluginDriver) ToVMInfo(name, pipelineName, pipelineFile, imageTag string) *VMInfo {
+	return &VMInfo{
+		Name:         name,
+		PipelineName: pipelineName,
+		PipelineFile: pipelineFile,
+		ImageTag:     imageTag,
+		DiskImage:    d.opts.DiskImage,
+		CPUs:         d.opts.CPUs,
+		Memory:       d.opts.Memory,
+		NICModel:     d.opts.NICModel,
+		Provisioning: d.opts.Provisioning,
+		Mounts:       d.opts.Mounts,
+		Arch:         d.opts.Arch,
+		SecureBoot:   d.opts.SecureBoot,
+		TPM:          d.opts.TPM,
+		Firmware:     d.opts.Firmware,
+		Created:      time.Now(),
+		SSHHost:      d.sshConfig.Host,
+		SSHPort:      d.sshConfig.Port,
+		SSHUser:      d.sshConfig.User,
+		SSHKeyPath:   d.sshConfig.KeyPath,
+		LogFile:      d.logFile,
+		State:        string(VMStateRunning),
+		VMType:       ExternalVM.String(),
+		ProcessID:    d.pid,
+		DriverPlugin: d.name,
+	}
+}
@@ -0,0 +1,151 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakePlugin answers driver requests by method and logs every request
+const fakePlugin = `#!/bin/sh
+req=$(cat)
+echo "$req" >> "$PLUGIN_LOG"
+case "$req" in
+*'"method":"start"'*) echo '{"logFile":"/run/fake.log"}' ;;
+*'"method":"ssh-config"'*) echo '{"ssh":{"host":"10.0.0.5","port":22}}' ;;
+*'"method":"state"'*) echo '{"state":"running"}' ;;
+*'"method":"stop"'*|*'"method":"cleanup"'*) echo '{}' ;;
+*'"method":"pause"'*) echo '{"error":"cannot pause"}' ;;
+*'"method":"shutdown"'*|*'"method":"wait-ready"'*) echo '{"unsupported":true}' ;;
+*) echo "unexpected request" >&2; exit 3 ;;
+esac
+`
+
+func TestPluginDriver(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bootc-man-driver-fake"), []byte(fakePlugin), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("HOME", dir)
+	logFile := filepath.Join(dir, "requests.log")
+	t.Setenv("PLUGIN_LOG", logFile)
+	disk := filepath.Join(dir, "disk.raw")
+	if err := os.WriteFile(disk, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	d, err := NewPluginDriver("fake", VMOptions{Name: "dev", DiskImage: disk, SSHPort: 2222}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(ctx, VMOptions{}); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if cfg := d.GetSSHConfig(); cfg.Host != "10.0.0.5" || cfg.Port != 22 || cfg.User != "user" {
+		t.Errorf("GetSSHConfig() = %+v, want 10.0.0.5:22 as user", cfg)
+	}
+	if d.GetLogFilePath() != "/run/fake.log" {
+		t.Errorf("GetLogFilePath() = %q, want /run/fake.log", d.GetLogFilePath())
+	}
+	if err := d.WaitForReady(ctx); err != nil {
+		t.Errorf("WaitForReady() error = %v, want state polling to succeed", err)
+	}
+	if err := d.Shutdown(ctx, 0); err != nil {
+		t.Errorf("Shutdown() error = %v, want fallback to stop", err)
+	}
+	if err := d.Pause(ctx); err == nil || !strings.Contains(err.Error(), "cannot pause") {
+		t.Errorf("Pause() error = %v, want the plugin's error", err)
+	}
+	if err := d.Resume(ctx); err == nil || !strings.Contains(err.Error(), "unexpected request") {
+		t.Errorf("Resume() error = %v, want the plugin's stderr", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := string(data)
+	for _, want := range []string{`"version":1`, `"diskFormat":"raw"`, `"sshPort":2222`, `"method":"stop"`} {
+		if !strings.Contains(requests, want) {
+			t.Errorf("requests missing %s:\n%s", want, requests)
+		}
+	}
+
+	// Cleanup stops the VM before the plugin removes its state
+	if err := os.Truncate(logFile, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Cleanup(); err != nil {
+		t.Errorf("Cleanup() error = %v", err)
+	}
+	if data, _ := os.ReadFile(logFile); !strings.Contains(string(data), `"method":"stop"`) ||
+		strings.Index(string(data), `"method":"stop"`) > strings.Index(string(data), `"method":"cleanup"`) {
+		t.Errorf("Cleanup() requests = %s, want stop then cleanup", data)
+	}
+
+	info := d.ToVMInfo("dev", "", "", "")
+	if info.DriverPlugin != "fake" || info.UsesGvproxy() {
+		t.Errorf("ToVMInfo() DriverPlugin = %q, want fake without gvproxy", info.DriverPlugin)
+	}
+}
+
+func TestPluginDriverRejectsOverlay(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bootc-man-driver-fake"), []byte(fakePlugin), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("HOME", dir)
+	logFile := filepath.Join(dir, "requests.log")
+	t.Setenv("PLUGIN_LOG", logFile)
+
+	// A qcow2 overlay left over from the QEMU driver
+	disk := filepath.Join(dir, "dev.qcow2")
+	if err := os.WriteFile(disk, []byte{'Q', 'F', 'I', 0xfb, 0, 0, 0, 3}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewPluginDriver("fake", VMOptions{Name: "dev", DiskImage: disk, SSHPort: 2222}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(context.Background(), VMOptions{}); err == nil || !strings.Contains(err.Error(), "cannot boot qcow2") {
+		t.Errorf("Start() error = %v, want qcow2 rejected", err)
+	}
+	if data, _ := os.ReadFile(logFile); strings.Contains(string(data), `"method":"start"`) {
+		t.Errorf("Start() sent a start request for a qcow2 disk:\n%s", data)
+	}
+}
+
+func TestFindDriverPluginMissing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if _, err := FindDriverPlugin("does-not-exist"); err == nil {
+		t.Error("FindDriverPlugin() error = nil for a missing plugin")
+	}
+	if _, err := callVMPlugin(context.Background(), &VMInfo{Name: "dev", DriverPlugin: "does-not-exist"}, "state", 0); err == nil || errors.Is(err, errPluginUnsupported) {
+		t.Errorf("callVMPlugin() error = %v, want not found", err)
+	}
+}
+
+func TestPluginStateToVMState(t *testing.T) {
+	tests := []struct {
+		state string
+		want  VMState
+	}{
+		{"running", VMStateRunning},
+		{"Paused", VMStatePaused},
+		{"STOPPED", VMStateStopped},
+		{"starting", VMStateStarting},
+		{"booting", VMStateUnknown},
+		{"", VMStateUnknown},
+	}
+	for _, tt := range tests {
+		if got := pluginStateToVMState(tt.state); got != tt.want {
+			t.Errorf("pluginStateToVMState(%q) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package vm
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Port forward protocols
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+// DefaultForwardHostIP is the host address forwarded ports listen on unless
+// another one is given, so test VMs are not reachable from the network
+const DefaultForwardHostIP = "127.0.0.1"
+
+// PortForward forwards a host port to a port of the VM via gvproxy
+type PortForward struct {
+	HostIP    string `json:"hostIp,omitempty"` // ホスト側の待ち受けアドレス（省略時は127.0.0.1）
+	HostPort  int    `json:"hostPort"`         // ホスト側ポート
+	GuestPort int    `json:"guestPort"`        // VM側ポート
+	Protocol  string `json:"protocol"`         // プロトコル（tcp または udp）
+}
+
+// ParsePortForward parses [hostIP:]hostPort:guestPort[/tcp|/udp]
+// A single port forwards the same port number on both sides.
+func ParsePortForward(spec string) (PortForward, error) {
+	p := PortForward{Protocol: ProtocolTCP}
+
+	ports := spec
+	if i := strings.LastIndex(spec, "/"); i >= 0 {
+		ports = spec[:i]
+		p.Protocol = strings.ToLower(spec[i+1:])
+		if p.Protocol != ProtocolTCP && p.Protocol != ProtocolUDP {
+			return PortForward{}, fmt.Errorf("invalid port forward %q: protocol must be tcp or udp", spec)
+		}
+	}
+
+	// The host IP may be an IPv6 address in brackets
+	if strings.HasPrefix(ports, "[") {
+		end := strings.Index(ports, "]:")
+		if end < 0 {
+			return PortForward{}, fmt.Errorf("invalid port forward %q", spec)
+		}
+		p.HostIP = ports[1:end]
+		ports = ports[end+2:]
+	}
+
+	parts := strings.Split(ports, ":")
+	switch len(parts) {
+	case 1:
+		parts = []string{parts[0], parts[0]}
+	case 2:
+	case 3:
+		if p.HostIP != "" {
+			return PortForward{}, fmt.Errorf("invalid port forward %q", spec)
+		}
+		p.HostIP, parts = parts[0], parts[1:]
+	default:
+		return PortForward{}, fmt.Errorf("invalid port forward %q: expected [hostIP:]hostPort:guestPort[/udp]", spec)
+	}
+	if p.HostIP != "" && net.ParseIP(p.HostIP) == nil {
+		return PortForward{}, fmt.Errorf("invalid port forward %q: %q is not an IP address", spec, p.HostIP)
+	}
+
+	var err error
+	if p.HostPort, err = parsePort(parts[0]); err != nil {
+		return PortForward{}, fmt.Errorf("invalid port forward %q: %w", spec, err)
+	}
+	if p.GuestPort, err = parsePort(parts[1]); err != nil {
+		return PortForward{}, fmt.Errorf("invalid port forward %q: %w", spec, err)
+	}
+	return p, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// Local returns the host address as gvproxy expects it (ip:port)
+func (p PortForward) Local() string {
+	hostIP := p.HostIP
+	if hostIP == "" {
+		hostIP = DefaultForwardHostIP
+	}
+	return net.JoinHostPort(hostIP, strconv.Itoa(p.HostPort))
+}
+
+// String returns the forward in the form accepted by ParsePortForward
+func (p PortForward) String() string {
+	hostPort := strconv.Itoa(p.HostPort)
+	if p.HostIP != "" {
+		hostPort = net.JoinHostPort(p.HostIP, hostPort)
+	}
+	return fmt.Sprintf("%s:%d/%s", hostPort, p.GuestPort, p.Protocol)
+}
+
+// FindPortForward returns the forward of the given host port and protocol, or nil
+func (info *VMInfo) FindPortForward(hostPort int, protocol string) *PortForward {
+	for i := range info.Ports {
+		if info.Ports[i].HostPort == hostPort && info.Ports[i].Protocol == protocol {
+			return &info.Ports[i]
+		}
+	}
+	return nil
+}
+
+// AddPortForward records a forward
+// Adding an identical forward again is a no-op; a different forward on the
+// same host port is an error.
+func (info *VMInfo) AddPortForward(p PortForward) error {
+	if existing := info.FindPortForward(p.HostPort, p.Protocol); existing != nil {
+		if *existing == p {
+			return nil
+		}
+		return fmt.Errorf("host port %d/%s is already forwarded to port %d of VM '%s'", p.HostPort, p.Protocol, existing.GuestPort, info.Name)
+	}
+	info.Ports = append(info.Ports, p)
+	return nil
+}
+
+// RemovePortForward removes the forward of the given host port and protocol
+func (info *VMInfo) RemovePortForward(hostPort int, protocol string) (PortForward, error) {
+	for i, p := range info.Ports {
+		if p.HostPort == hostPort && p.Protocol == protocol {
+			info.Ports = append(info.Ports[:i], info.Ports[i+1:]...)
+			return p, nil
+		}
+	}
+	return PortForward{}, fmt.Errorf("host port %d/%s is not forwarded for VM '%s'", hostPort, protocol, info.Name)
+}
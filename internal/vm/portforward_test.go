@@ -0,0 +1,101 @@
+package vm
+
+import (
+	"testing"
+)
+
+func TestParsePortForward(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    PortForward
+		wantErr bool
+	}{
+		{"8080:80", PortForward{HostPort: 8080, GuestPort: 80, Protocol: "tcp"}, false},
+		{"9090", PortForward{HostPort: 9090, GuestPort: 9090, Protocol: "tcp"}, false},
+		{"5353:53/udp", PortForward{HostPort: 5353, GuestPort: 53, Protocol: "udp"}, false},
+		{"8443:443/TCP", PortForward{HostPort: 8443, GuestPort: 443, Protocol: "tcp"}, false},
+		{"0.0.0.0:8080:80", PortForward{HostIP: "0.0.0.0", HostPort: 8080, GuestPort: 80, Protocol: "tcp"}, false},
+		{"[::1]:8080:80", PortForward{HostIP: "::1", HostPort: 8080, GuestPort: 80, Protocol: "tcp"}, false},
+		{"8080:80/sctp", PortForward{}, true},
+		{"8080:http", PortForward{}, true},
+		{"70000:80", PortForward{}, true},
+		{"host:8080:80", PortForward{}, true},
+		{"1:2:3:4", PortForward{}, true},
+		{"", PortForward{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParsePortForward(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePortForward(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePortForward(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortForwardLocalAndString(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantLocal  string
+		wantString string
+	}{
+		{"8080:80", "127.0.0.1:8080", "8080:80/tcp"},
+		{"0.0.0.0:5353:53/udp", "0.0.0.0:5353", "0.0.0.0:5353:53/udp"},
+		{"[::1]:8080:80", "[::1]:8080", "[::1]:8080:80/tcp"},
+	}
+	for _, tt := range tests {
+		p, err := ParsePortForward(tt.spec)
+		if err != nil {
+			t.Fatalf("ParsePortForward(%q) error = %v", tt.spec, err)
+		}
+		if got := p.Local(); got != tt.wantLocal {
+			t.Errorf("Local() = %q, want %q", got, tt.wantLocal)
+		}
+		if got := p.String(); got != tt.wantString {
+			t.Errorf("String() = %q, want %q", got, tt.wantString)
+		}
+		// String output parses back to the same forward
+		if again, err := ParsePortForward(p.String()); err != nil || again != p {
+			t.Errorf("ParsePortForward(%q) = %+v, %v, want %+v", p.String(), again, err, p)
+		}
+	}
+}
+
+func TestVMInfoPortForwards(t *testing.T) {
+	info := &VMInfo{Name: "test"}
+	web := PortForward{HostPort: 8080, GuestPort: 80, Protocol: ProtocolTCP}
+
+	if err := info.AddPortForward(web); err != nil {
+		t.Fatalf("AddPortForward() error = %v", err)
+	}
+	// Adding the same forward again is a no-op
+	if err := info.AddPortForward(web); err != nil || len(info.Ports) != 1 {
+		t.Errorf("AddPortForward(same) = %v, ports = %v", err, info.Ports)
+	}
+	// Same host port to another guest port conflicts
+	if err := info.AddPortForward(PortForward{HostPort: 8080, GuestPort: 81, Protocol: ProtocolTCP}); err == nil {
+		t.Error("AddPortForward(conflict) error = nil, want error")
+	}
+	// Same port number over UDP is a different forward
+	if err := info.AddPortForward(PortForward{HostPort: 8080, GuestPort: 80, Protocol: ProtocolUDP}); err != nil {
+		t.Errorf("AddPortForward(udp) error = %v", err)
+	}
+
+	if got := info.FindPortForward(8080, ProtocolUDP); got == nil || got.Protocol != ProtocolUDP {
+		t.Errorf("FindPortForward(8080, udp) = %v", got)
+	}
+
+	removed, err := info.RemovePortForward(8080, ProtocolTCP)
+	if err != nil || removed != web {
+		t.Errorf("RemovePortForward() = %+v, %v, want %+v", removed, err, web)
+	}
+	if len(info.Ports) != 1 || info.Ports[0].Protocol != ProtocolUDP {
+		t.Errorf("ports after remove = %v", info.Ports)
+	}
+	if _, err := info.RemovePortForward(8080, ProtocolTCP); err == nil {
+		t.Error("RemovePortForward(missing) error = nil, want error")
+	}
+}
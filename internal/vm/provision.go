@@ -0,0 +1,61 @@
+package vm
+
+import (
+	"fmt"
+	"os"
+)
+
+// First-boot provisioning methods
+const (
+	ProvisioningNone      = "none"
+	ProvisioningIgnition  = "ignition"
+	ProvisioningCloudInit = "cloud-init"
+)
+
+// ProvisioningMethods lists the supported provisioning methods
+var ProvisioningMethods = []string{ProvisioningNone, ProvisioningIgnition, ProvisioningCloudInit}
+
+// Provisioning describes the first-boot data delivered to a VM
+// Ignition configs are passed via QEMU fw_cfg (opt/com.coreos/config) or
+// vfkit --ignition; cloud-init uses a NoCloud seed disk labelled "cidata".
+type Provisioning struct {
+	Method        string `json:"method"`                  // ignition または cloud-init
+	IgnitionFile  string `json:"ignitionFile,omitempty"`  // Ignition設定ファイルパス
+	CloudInitSeed string `json:"cloudInitSeed,omitempty"` // cloud-init NoCloudシードISOパス
+}
+
+// ValidateProvisioning checks that method is a supported provisioning method
+// An empty method is treated as "none".
+func ValidateProvisioning(method string) error {
+	if method == "" {
+		return nil
+	}
+	for _, m := range ProvisioningMethods {
+		if method == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported provisioning method %q (supported: ignition, cloud-init, none)", method)
+}
+
+// Files returns the generated files that belong to the provisioning data
+func (p *Provisioning) Files() []string {
+	if p == nil {
+		return nil
+	}
+	var files []string
+	if p.IgnitionFile != "" {
+		files = append(files, p.IgnitionFile)
+	}
+	if p.CloudInitSeed != "" {
+		files = append(files, p.CloudInitSeed)
+	}
+	return files
+}
+
+// Remove deletes the generated provisioning files
+func (p *Provisioning) Remove() {
+	for _, f := range p.Files() {
+		os.Remove(f)
+	}
+}
@@ -0,0 +1,728 @@
+package vm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/cleanup"
+	"github.com/tnk4on/bootc-man/internal/config"
+)
+
+// PruneGracePeriod is how old an unreferenced file or helper process must be
+// before it is treated as orphaned, so VMs that are still starting are safe
+const PruneGracePeriod = 10 * time.Minute
+
+// PruneScope selects which resources FindOrphans looks at
+type PruneScope int
+
+const (
+	// PruneScopeVMs covers VM definitions and the files in the VMs and SSH directories
+	PruneScopeVMs PruneScope = iota
+	// PruneScopeSystem additionally covers helper processes, runtime files,
+	// test disks and port allocations
+	PruneScopeSystem
+)
+
+// PruneKind is the type of an orphaned resource
+type PruneKind string
+
+const (
+	PruneKindProcess PruneKind = "process"
+	PruneKindVM      PruneKind = "vm"
+	PruneKindFile    PruneKind = "file"
+	PruneKindPort    PruneKind = "port"
+)
+
+// PruneItem is an orphaned resource found by FindOrphans
+type PruneItem struct {
+	Kind   PruneKind
+	Name   string // VM name or helper binary
+	Path   string // file or directory to remove, or VM info file
+	PID    int
+	Args   []string
+	Port   int
+	Size   int64
+	Reason string
+
+	lab string
+}
+
+// Description returns a one-line description of the item
+func (it PruneItem) Description() string {
+	switch it.Kind {
+	case PruneKindProcess:
+		return fmt.Sprintf("%s (PID %d): %s", it.Name, it.PID, it.Reason)
+	case PruneKindVM:
+		return fmt.Sprintf("%s: %s", it.Name, it.Reason)
+	case PruneKindPort:
+		return fmt.Sprintf("%d: %s", it.Port, it.Reason)
+	default:
+		return it.Path
+	}
+}
+
+// hostProcess is a running process and its command line
+type hostProcess struct {
+	PID     int
+	Args    []string
+	Started time.Time
+}
+
+// orphanFinder holds what is known to be in use while looking for orphans
+type orphanFinder struct {
+	now        time.Time
+	runtimeDir string
+	procs      []hostProcess   // processes referencing bootc-man files
+	stale      map[int]bool    // helper processes whose VM is gone
+	refs       map[string]bool // files referenced by live processes
+	vms        []string        // defined VMs
+	running    []string        // running VMs
+}
+
+// FindOrphans returns the resources left behind by crashed or interrupted
+// VMs and test runs
+// Nothing that is referenced on the command line of a live process, belongs
+// to a defined VM or is younger than PruneGracePeriod is returned.
+func FindOrphans(scope PruneScope) ([]PruneItem, error) {
+	vmsDir, err := GetVMsDir()
+	if err != nil {
+		return nil, err
+	}
+	sshDir, err := GetSSHDir()
+	if err != nil {
+		return nil, err
+	}
+	f := &orphanFinder{now: time.Now(), runtimeDir: config.RuntimeDir()}
+	tempDir := config.TempDataDir()
+
+	procs, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+	f.procs = referencingProcesses(procs, []string{f.runtimeDir, tempDir, vmsDir})
+	f.stale = map[int]bool{}
+	if scope == PruneScopeSystem {
+		for _, p := range staleHelpers(f.procs, f.runtimeDir, f.now) {
+			f.stale[p.PID] = true
+		}
+	}
+	f.refs = map[string]bool{}
+	for _, p := range f.procs {
+		if f.stale[p.PID] {
+			continue
+		}
+		for _, dir := range []string{f.runtimeDir, tempDir, vmsDir} {
+			for _, path := range cmdlinePaths(p.Args, dir) {
+				f.refs[path] = true
+			}
+		}
+	}
+
+	var items []PruneItem
+	if scope == PruneScopeSystem {
+		items = append(items, f.helperItems()...)
+	}
+	vmItems, err := f.vmItems()
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, vmItems...)
+	for _, vmItem := range vmItems {
+		// Stale VM info files are removed with the VM, not as loose files
+		f.refs[vmItem.Path] = true
+	}
+	items = append(items, f.dirItems(vmsDir, "", f.vms, false)...)
+	items = append(items, f.sshItems(sshDir)...)
+	if scope == PruneScopeSystem {
+		items = append(items, f.runtimeItems()...)
+		items = append(items, f.dirItems(tempDir, config.TestDiskPrefix, nil, false)...)
+		portItems, err := f.portItems()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, portItems...)
+		items = append(items, f.leftoverItems(procs, items)...)
+	}
+	return items, nil
+}
+
+// leftoverItems returns the resources that interrupted runs recorded as not
+// cleaned up and that still exist
+// The grace period does not apply since the run that owned them gave up on
+// them, but files in use and processes whose command line changed are kept.
+func (f *orphanFinder) leftoverItems(procs []hostProcess, found []PruneItem) []PruneItem {
+	leftovers, err := cleanup.Leftovers()
+	if err != nil {
+		return nil
+	}
+	var items []PruneItem
+	for _, l := range leftovers {
+		listed := slices.ContainsFunc(slices.Concat(found, items), func(it PruneItem) bool {
+			return (l.PID > 0 && it.PID == l.PID) || (l.Path != "" && it.Path == l.Path)
+		})
+		switch {
+		case listed:
+		case l.PID > 0:
+			if !leftoverRunning(l, procs) {
+				continue
+			}
+			items = append(items, PruneItem{
+				Kind:   PruneKindProcess,
+				Name:   filepath.Base(l.Args[0]),
+				PID:    l.PID,
+				Args:   l.Args,
+				Reason: fmt.Sprintf("left running by interrupted %q", l.Action),
+			})
+		case l.Path != "":
+			if _, err := os.Lstat(l.Path); err != nil || f.refs[l.Path] {
+				continue
+			}
+			items = append(items, PruneItem{Kind: PruneKindFile, Path: l.Path, Size: diskUsage(l.Path)})
+		}
+	}
+	return items
+}
+
+// leftoverRunning reports whether a recorded leftover process still runs
+// with the recorded command line
+func leftoverRunning(l cleanup.Leftover, procs []hostProcess) bool {
+	if len(l.Args) == 0 {
+		return false
+	}
+	i := slices.IndexFunc(procs, func(p hostProcess) bool { return p.PID == l.PID })
+	return i >= 0 && slices.Equal(procs[i].Args, l.Args)
+}
+
+// ProcessResource returns the cleanup resource for a process, including its
+// command line so that prune never signals a process that reused the PID
+func ProcessResource(pid int) cleanup.Resource {
+	r := cleanup.Resource{PID: pid}
+	if procs, err := listProcesses(); err == nil {
+		if i := slices.IndexFunc(procs, func(p hostProcess) bool { return p.PID == pid }); i >= 0 {
+			r.Args = procs[i].Args
+		}
+	}
+	return r
+}
+
+// vmItems returns VM definitions whose disk image is gone
+func (f *orphanFinder) vmItems() ([]PruneItem, error) {
+	infos, err := ListVMInfos()
+	if err != nil {
+		return nil, err
+	}
+	vmsDir, err := GetVMsDir()
+	if err != nil {
+		return nil, err
+	}
+	var items []PruneItem
+	for _, info := range infos {
+		if IsVMRunning(info) || VMLocked(info.Name) {
+			f.running = append(f.running, info.Name)
+			f.vms = append(f.vms, info.Name)
+			continue
+		}
+		if info.DiskImage == "" {
+			f.vms = append(f.vms, info.Name)
+			continue
+		}
+		if _, err := os.Stat(info.DiskImage); !errors.Is(err, fs.ErrNotExist) {
+			f.vms = append(f.vms, info.Name)
+			continue
+		}
+		path := filepath.Join(vmsDir, info.Name+".json")
+		items = append(items, PruneItem{
+			Kind:   PruneKindVM,
+			Name:   info.Name,
+			Path:   path,
+			Size:   diskUsage(path),
+			Reason: fmt.Sprintf("disk image missing (%s)", info.DiskImage),
+			lab:    info.Lab,
+		})
+	}
+	return items, nil
+}
+
+// helperItems returns gvproxy, virtiofsd and swtpm processes whose VM is gone
+func (f *orphanFinder) helperItems() []PruneItem {
+	var items []PruneItem
+	for _, p := range f.procs {
+		if !f.stale[p.PID] {
+			continue
+		}
+		items = append(items, PruneItem{
+			Kind:   PruneKindProcess,
+			Name:   filepath.Base(p.Args[0]),
+			PID:    p.PID,
+			Args:   p.Args,
+			Reason: fmt.Sprintf("no VM uses %s", cmdlinePaths(p.Args, f.runtimeDir)[0]),
+		})
+	}
+	return items
+}
+
+// runtimeItems returns sockets, PID files, logs and EFI stores in RuntimeDir
+// that no VM uses
+// Stopped VMs keep their logs and EFI variable stores.
+func (f *orphanFinder) runtimeItems() []PruneItem {
+	var stopped []string
+	for _, name := range f.vms {
+		if !slices.Contains(f.running, name) {
+			stopped = append(stopped, name)
+		}
+	}
+	var items []PruneItem
+	for _, item := range f.dirItems(f.runtimeDir, "bootc-man-", f.running, true) {
+		base := filepath.Base(item.Path)
+		transient := strings.HasSuffix(base, ".sock") || strings.HasSuffix(base, ".pid")
+		if !transient && ownedBy(runtimeFileStem(base), stopped) {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// sshItems returns generated SSH keypairs of VMs that no longer exist
+// Keypairs of running test VMs are kept; they are recognised by the runtime
+// files their hypervisor has open.
+func (f *orphanFinder) sshItems(sshDir string) []PruneItem {
+	entries, err := os.ReadDir(sshDir)
+	if err != nil {
+		return nil
+	}
+	var live []string
+	for path := range f.refs {
+		if filepath.Dir(path) == f.runtimeDir {
+			live = append(live, runtimeFileStem(filepath.Base(path)))
+		}
+	}
+	var items []PruneItem
+	for _, e := range entries {
+		if !e.IsDir() || slices.Contains(f.vms, e.Name()) || VMLocked(e.Name()) || !f.old(filepath.Join(sshDir, e.Name())) {
+			continue
+		}
+		if slices.ContainsFunc(live, func(stem string) bool { return ownedBy(stem, []string{e.Name()}) }) {
+			continue
+		}
+		path := filepath.Join(sshDir, e.Name())
+		items = append(items, PruneItem{Kind: PruneKindFile, Name: e.Name(), Path: path, Size: diskUsage(path)})
+	}
+	return items
+}
+
+// dirItems returns the entries of dir starting with prefix that are not
+// referenced by a live process, not owned by one of vms and older than the
+// grace period
+func (f *orphanFinder) dirItems(dir, prefix string, vms []string, runtimeNames bool) []PruneItem {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var items []PruneItem
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if !strings.HasPrefix(e.Name(), prefix) || f.refs[path] || !f.old(path) {
+			continue
+		}
+		stem := e.Name()
+		if runtimeNames {
+			stem = runtimeFileStem(stem)
+		}
+		if ownedBy(stem, vms) {
+			continue
+		}
+		items = append(items, PruneItem{Kind: PruneKindFile, Path: path, Size: diskUsage(path)})
+	}
+	return items
+}
+
+// portItems returns port-alloc.dat entries that nothing uses
+// The file is shared with podman machine, so ports of podman machines and
+// ports that are bound on localhost are kept.
+func (f *orphanFinder) portItems() ([]PruneItem, error) {
+	lock, err := acquirePortLock()
+	if err != nil {
+		return nil, err
+	}
+	ports, err := loadPortAllocations()
+	lock.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := podmanMachinePorts()
+	if infos, err := ListVMInfos(); err == nil {
+		for _, info := range infos {
+			if slices.Contains(f.running, info.Name) {
+				inUse[info.SSHPort] = true
+			}
+		}
+	}
+
+	var items []PruneItem
+	for _, port := range slices.Sorted(maps.Keys(ports)) {
+		if inUse[port] || !IsLocalPortAvailable(port) {
+			continue
+		}
+		items = append(items, PruneItem{Kind: PruneKindPort, Port: port, Reason: "allocated in port-alloc.dat but unused"})
+	}
+	return items, nil
+}
+
+// old reports whether path was last modified before the grace period
+func (f *orphanFinder) old(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && f.now.Sub(info.ModTime()) >= PruneGracePeriod
+}
+
+// PruneOrphans removes the items returned by FindOrphans and returns the
+// number of bytes reclaimed
+// Helper processes are only stopped if their command line is unchanged, so
+// a recycled PID is never signalled.
+func PruneOrphans(items []PruneItem) (int64, error) {
+	var procs []hostProcess
+	if slices.ContainsFunc(items, func(it PruneItem) bool { return it.Kind == PruneKindProcess }) {
+		var err error
+		if procs, err = listProcesses(); err != nil {
+			return 0, err
+		}
+	}
+
+	var reclaimed int64
+	var errs []error
+	for _, it := range items {
+		switch it.Kind {
+		case PruneKindProcess:
+			i := slices.IndexFunc(procs, func(p hostProcess) bool { return p.PID == it.PID })
+			if i < 0 || !slices.Equal(procs[i].Args, it.Args) {
+				continue
+			}
+			stopProcessWithTimeout(it.PID, 3*time.Second)
+		case PruneKindVM:
+			if err := DeleteVMInfo(it.Name); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if it.lab != "" {
+				if lab, err := LoadLab(it.lab); err == nil && lab.RemoveMember(it.Name) {
+					if err := SaveLab(lab); err != nil {
+						errs = append(errs, err)
+					}
+				}
+			}
+			_ = ForgetHostKey(it.Name)
+			reclaimed += it.Size
+		case PruneKindFile:
+			if err := os.RemoveAll(it.Path); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove %s: %w", it.Path, err))
+				continue
+			}
+			reclaimed += it.Size
+		case PruneKindPort:
+			if err := ReleaseMachinePort(it.Port); err != nil {
+				errs = append(errs, fmt.Errorf("failed to release port %d: %w", it.Port, err))
+			}
+		}
+	}
+
+	// Forget the recorded leftovers that are gone now
+	if len(items) > 0 {
+		procs, _ = listProcesses()
+		_ = cleanup.Forget(func(l cleanup.Leftover) bool {
+			if l.PID > 0 {
+				return !leftoverRunning(l, procs)
+			}
+			_, err := os.Lstat(l.Path)
+			return errors.Is(err, fs.ErrNotExist)
+		})
+	}
+	return reclaimed, errors.Join(errs...)
+}
+
+// staleHelpers returns the gvproxy, virtiofsd and swtpm processes that share
+// no runtime file with a running hypervisor
+// Each helper is connected to its VM through a socket in the runtime
+// directory that appears on both command lines.
+func staleHelpers(procs []hostProcess, runtimeDir string, now time.Time) []hostProcess {
+	used := map[string]bool{}
+	for _, p := range procs {
+		if isHypervisor(p.Args) {
+			for _, path := range cmdlinePaths(p.Args, runtimeDir) {
+				used[path] = true
+			}
+		}
+	}
+	var stale []hostProcess
+	for _, p := range procs {
+		if !isHelper(p.Args) || now.Sub(p.Started) < PruneGracePeriod {
+			continue
+		}
+		paths := cmdlinePaths(p.Args, runtimeDir)
+		if len(paths) > 0 && !slices.ContainsFunc(paths, func(path string) bool { return used[path] }) {
+			stale = append(stale, p)
+		}
+	}
+	return stale
+}
+
+// isHypervisor reports whether a command line runs QEMU or vfkit
+func isHypervisor(args []string) bool {
+	base := filepath.Base(args[0])
+	return strings.HasPrefix(base, "qemu-system-") || base == config.BinaryVfkit
+}
+
+// isHelper reports whether a command line runs one of the VM helpers
+func isHelper(args []string) bool {
+	switch filepath.Base(args[0]) {
+	case config.BinaryGvproxy, config.BinaryVirtiofsd, config.BinarySwtpm:
+		return true
+	}
+	return false
+}
+
+// referencingProcesses returns the processes other than this one whose
+// command line refers to a file in one of dirs
+func referencingProcesses(procs []hostProcess, dirs []string) []hostProcess {
+	var out []hostProcess
+	for _, p := range procs {
+		if p.PID == os.Getpid() {
+			continue
+		}
+		if slices.ContainsFunc(dirs, func(dir string) bool { return len(cmdlinePaths(p.Args, dir)) > 0 }) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// cmdlinePaths returns the paths below dir that appear in a command line,
+// including paths embedded in options such as file=<path>,format=raw
+func cmdlinePaths(args []string, dir string) []string {
+	var paths []string
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for _, arg := range args {
+		for rest := arg; ; {
+			i := strings.Index(rest, prefix)
+			if i < 0 {
+				break
+			}
+			rest = rest[i:]
+			end := strings.IndexAny(rest, ",;\"' ")
+			if end < 0 {
+				end = len(rest)
+			}
+			if path := rest[:end]; len(path) > len(prefix) && !slices.Contains(paths, path) {
+				paths = append(paths, path)
+			}
+			rest = rest[end:]
+		}
+	}
+	return paths
+}
+
+// runtimeFileStem strips the bootc-man- and helper prefixes from the name of
+// a runtime file, leaving the VM name and a suffix
+// e.g. bootc-man-qemu-dev-qmp.sock -> dev-qmp.sock
+func runtimeFileStem(name string) string {
+	name = strings.TrimPrefix(name, "bootc-man-")
+	for _, helper := range []string{"qemu-", "libvirt-", "gvproxy-", "vfkit-", "virtiofsd-", "swtpm-"} {
+		if after, ok := strings.CutPrefix(name, helper); ok {
+			return after
+		}
+	}
+	return name
+}
+
+// ownedBy reports whether a file stem belongs to one of the VMs
+// VM files are named <vm>.<ext> or <vm>-<suffix>. The check errs on the side
+// of keeping files: dev-2.qcow2 counts as owned by a VM named dev.
+func ownedBy(stem string, vms []string) bool {
+	return slices.ContainsFunc(vms, func(name string) bool {
+		return stem == name || strings.HasPrefix(stem, name+".") || strings.HasPrefix(stem, name+"-")
+	})
+}
+
+// podmanMachinePorts returns the SSH ports of podman machines, which share
+// port-alloc.dat with bootc-man
+func podmanMachinePorts() map[int]bool {
+	ports := map[int]bool{}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ports
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	root := filepath.Join(configHome, "containers", "podman", "machine")
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		// podman 5 keeps the port under SSH, podman 4 at the top level
+		var machine struct {
+			Port int
+			SSH  struct{ Port int }
+		}
+		if json.Unmarshal(data, &machine) == nil {
+			ports[machine.Port] = true
+			ports[machine.SSH.Port] = true
+		}
+		return nil
+	})
+	return ports
+}
+
+// listProcesses returns the running processes with their command lines
+func listProcesses() ([]hostProcess, error) {
+	if runtime.GOOS == "linux" {
+		return listProcProcesses()
+	}
+	return listPsProcesses()
+}
+
+// listProcProcesses reads the process table from /proc
+func listProcProcesses() ([]hostProcess, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	bootTime := procBootTime()
+	var procs []hostProcess
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", e.Name(), "cmdline"))
+		if err != nil || len(data) == 0 {
+			continue // exited, or a kernel thread
+		}
+		args := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+		procs = append(procs, hostProcess{PID: pid, Args: args, Started: procStartTime(pid, bootTime)})
+	}
+	return procs, nil
+}
+
+// procBootTime returns the boot time from /proc/stat
+func procBootTime() time.Time {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}
+	}
+	for line := range strings.Lines(string(data)) {
+		if after, ok := strings.CutPrefix(line, "btime "); ok {
+			if secs, err := strconv.ParseInt(strings.TrimSpace(after), 10, 64); err == nil {
+				return time.Unix(secs, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// procStartTime returns the start time of a process from /proc/<pid>/stat
+// The start time is given in clock ticks after boot (USER_HZ, 100 on Linux).
+// If it cannot be read, the current time is returned so the process is
+// treated as new.
+func procStartTime(pid int, bootTime time.Time) time.Time {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil || bootTime.IsZero() {
+		return time.Now()
+	}
+	// The command name may contain spaces; the fields after it start with
+	// the state (field 3), so starttime (field 22) is at index 19
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 20 {
+		return time.Now()
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Now()
+	}
+	return bootTime.Add(time.Duration(ticks) * time.Second / 100)
+}
+
+// listPsProcesses reads the process table with ps (macOS)
+// ps joins the arguments with spaces, which is enough to find the runtime
+// paths since those never contain spaces.
+func listPsProcesses() ([]hostProcess, error) {
+	out, err := exec.Command("ps", "-axww", "-o", "pid=,etime=,command=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	now := time.Now()
+	var procs []hostProcess
+	for line := range strings.Lines(string(out)) {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		elapsed, err := parseElapsed(fields[1])
+		if err != nil {
+			continue
+		}
+		procs = append(procs, hostProcess{PID: pid, Args: fields[2:], Started: now.Add(-elapsed)})
+	}
+	return procs, nil
+}
+
+// parseElapsed parses the ps etime format [[dd-]hh:]mm:ss
+func parseElapsed(s string) (time.Duration, error) {
+	var days int
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid elapsed time %q", s)
+		}
+		days, s = n, rest
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid elapsed time %q", s)
+	}
+	var secs int
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("invalid elapsed time %q", s)
+		}
+		secs = secs*60 + n
+	}
+	return time.Duration(days)*24*time.Hour + time.Duration(secs)*time.Second, nil
+}
+
+// diskUsage returns the size of a file, or the total size of a directory
+func diskUsage(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
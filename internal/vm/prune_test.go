@@ -0,0 +1,240 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/cleanup"
+)
+
+func TestCmdlinePaths(t *testing.T) {
+	dir := "/var/tmp/bootc-man"
+	args := []string{
+		"/usr/bin/qemu-system-x86_64",
+		"-netdev", "stream,id=net0,addr.type=unix,addr.path=/var/tmp/bootc-man/bootc-man-gvproxy-dev.sock,server=off",
+		"-qmp", "unix:/var/tmp/bootc-man/bootc-man-qemu-dev-qmp.sock,server=on,wait=off",
+		"-pidfile", "/var/tmp/bootc-man/bootc-man-qemu-dev.pid",
+		"-drive", "file=/home/u/.local/share/bootc-man/vms/dev.qcow2,format=qcow2",
+		"-listen-qemu", "unix:///var/tmp/bootc-man/bootc-man-gvproxy-dev.sock",
+		"/var/tmp/bootc-man/",
+	}
+	want := []string{
+		"/var/tmp/bootc-man/bootc-man-gvproxy-dev.sock",
+		"/var/tmp/bootc-man/bootc-man-qemu-dev-qmp.sock",
+		"/var/tmp/bootc-man/bootc-man-qemu-dev.pid",
+	}
+	if got := cmdlinePaths(args, dir); !slices.Equal(got, want) {
+		t.Errorf("cmdlinePaths() = %q, want %q", got, want)
+	}
+	if got := cmdlinePaths([]string{"/usr/bin/sleep", "60"}, dir); len(got) != 0 {
+		t.Errorf("cmdlinePaths(unrelated) = %q, want none", got)
+	}
+}
+
+func TestRuntimeFileStem(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"bootc-man-qemu-dev-qmp.sock", "dev-qmp.sock"},
+		{"bootc-man-gvproxy-dev.pid", "dev.pid"},
+		{"bootc-man-virtiofsd-dev-src.log", "dev-src.log"},
+		{"bootc-man-dev-efi-store", "dev-efi-store"},
+		{"bootc-man-libvirt-dev-efi-vars.fd", "dev-efi-vars.fd"},
+		{"bootc-man-ci-test-app-gvproxy.sock", "ci-test-app-gvproxy.sock"},
+	}
+	for _, tt := range tests {
+		if got := runtimeFileStem(tt.name); got != tt.want {
+			t.Errorf("runtimeFileStem(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOwnedBy(t *testing.T) {
+	vms := []string{"dev", "web"}
+	tests := []struct {
+		stem string
+		want bool
+	}{
+		{"dev", true},
+		{"dev.qcow2", true},
+		{"dev-tpm", true},
+		{"dev-2.qcow2", true},
+		{"dev2.qcow2", false},
+		{"devel.raw", false},
+		{"old.json", false},
+	}
+	for _, tt := range tests {
+		if got := ownedBy(tt.stem, vms); got != tt.want {
+			t.Errorf("ownedBy(%q) = %v, want %v", tt.stem, got, tt.want)
+		}
+	}
+}
+
+func TestStaleHelpers(t *testing.T) {
+	dir := "/var/tmp/bootc-man"
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	procs := []hostProcess{
+		{PID: 10, Started: old, Args: []string{"/usr/bin/qemu-system-x86_64", "-netdev", "stream,addr.path=/var/tmp/bootc-man/bootc-man-gvproxy-dev.sock"}},
+		{PID: 11, Started: old, Args: []string{"/usr/bin/gvproxy", "-listen-qemu", "unix:///var/tmp/bootc-man/bootc-man-gvproxy-dev.sock"}},
+		{PID: 20, Started: old, Args: []string{"/usr/bin/gvproxy", "-listen-qemu", "unix:///var/tmp/bootc-man/bootc-man-gvproxy-gone.sock"}},
+		{PID: 21, Started: old, Args: []string{"/usr/libexec/virtiofsd", "--socket-path", "/var/tmp/bootc-man/bootc-man-qemu-gone-fs-src.sock"}},
+		{PID: 22, Started: now, Args: []string{"/usr/bin/swtpm", "socket", "--ctrl", "type=unixio,path=/var/tmp/bootc-man/bootc-man-qemu-new-tpm.sock"}},
+		{PID: 23, Started: old, Args: []string{"/usr/bin/tail", "-f", "/var/tmp/bootc-man/bootc-man-qemu-gone.log"}},
+	}
+	var pids []int
+	for _, p := range staleHelpers(procs, dir, now) {
+		pids = append(pids, p.PID)
+	}
+	if want := []int{20, 21}; !slices.Equal(pids, want) {
+		t.Errorf("staleHelpers() = %v, want %v", pids, want)
+	}
+}
+
+func TestParseElapsed(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"05:03", 5*time.Minute + 3*time.Second, false},
+		{"02:05:03", 2*time.Hour + 5*time.Minute + 3*time.Second, false},
+		{"3-02:05:03", 74*time.Hour + 5*time.Minute + 3*time.Second, false},
+		{"42", 0, true},
+		{"x:10", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseElapsed(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseElapsed(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseElapsed(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFindDataOrphans(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	vmsDir, _ := GetVMsDir()
+	sshDir, _ := GetSSHDir()
+
+	// kept: a VM with its disk; stale: a VM whose disk was deleted
+	keptDisk := filepath.Join(vmsDir, "kept.qcow2")
+	for _, info := range []*VMInfo{
+		{Name: "kept", DiskImage: keptDisk},
+		{Name: "stale", DiskImage: filepath.Join(vmsDir, "stale.qcow2")},
+	} {
+		if err := SaveVMInfo(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []string{
+		keptDisk,
+		filepath.Join(vmsDir, "kept-seed.iso"),
+		filepath.Join(vmsDir, "stale-seed.iso"),
+		filepath.Join(vmsDir, "gone.raw"),
+		filepath.Join(vmsDir, "new.raw"),
+		filepath.Join(sshDir, "kept", "id_ed25519"),
+		filepath.Join(sshDir, "gone", "id_ed25519"),
+		filepath.Join(sshDir, "known_hosts"),
+	}
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Everything but new.raw is older than the grace period
+	past := time.Now().Add(-time.Hour)
+	for _, path := range append(files, filepath.Join(sshDir, "kept"), filepath.Join(sshDir, "gone"),
+		filepath.Join(vmsDir, "kept.json"), filepath.Join(vmsDir, "stale.json")) {
+		if filepath.Base(path) != "new.raw" {
+			_ = os.Chtimes(path, past, past)
+		}
+	}
+
+	f := &orphanFinder{now: time.Now(), runtimeDir: t.TempDir(), refs: map[string]bool{}}
+	vmItems, err := f.vmItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vmItems) != 1 || vmItems[0].Name != "stale" || vmItems[0].Kind != PruneKindVM {
+		t.Fatalf("vmItems() = %+v, want the stale VM", vmItems)
+	}
+	f.refs[vmItems[0].Path] = true
+
+	var got []string
+	for _, it := range append(f.dirItems(vmsDir, "", f.vms, false), f.sshItems(sshDir)...) {
+		got = append(got, it.Path)
+	}
+	want := []string{
+		filepath.Join(vmsDir, "gone.raw"),
+		filepath.Join(vmsDir, "stale-seed.iso"),
+		filepath.Join(sshDir, "gone"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("orphans = %q, want %q", got, want)
+	}
+
+	items := append(vmItems, PruneItem{Kind: PruneKindFile, Path: want[0], Size: 4})
+	reclaimed, err := PruneOrphans(items)
+	if err != nil {
+		t.Fatalf("PruneOrphans() error = %v", err)
+	}
+	if _, err := LoadVMInfo("stale"); err == nil {
+		t.Error("stale VM info still exists after PruneOrphans()")
+	}
+	if _, err := os.Stat(want[0]); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after PruneOrphans()", want[0])
+	}
+	if reclaimed < 4 {
+		t.Errorf("PruneOrphans() reclaimed %d bytes, want at least 4", reclaimed)
+	}
+}
+
+func TestLeftoverItems(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	left := filepath.Join(dir, ".tmp-app-qcow2")
+	inUse := filepath.Join(dir, "bootc-man-test-app-1a2b3c.qcow2")
+	for _, path := range []string{left, inUse} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range []cleanup.Resource{
+		{Path: left},
+		{Path: inUse},
+		{Path: filepath.Join(dir, "gone")},
+		{PID: 10, Args: []string{"/usr/bin/qemu-system-x86_64", "-name", "ci-test-app"}},
+		{PID: 11, Args: []string{"/usr/bin/gvproxy"}},
+	} {
+		h := cleanup.Register("test", func(context.Context) error { return errors.New("failed") }, r)
+		_ = h.Run(context.Background())
+	}
+
+	procs := []hostProcess{
+		{PID: 10, Args: []string{"/usr/bin/qemu-system-x86_64", "-name", "ci-test-app"}},
+		{PID: 11, Args: []string{"/usr/bin/sleep", "60"}}, // PID reused
+	}
+	f := &orphanFinder{now: time.Now(), refs: map[string]bool{inUse: true}}
+	items := f.leftoverItems(procs, nil)
+	if len(items) != 2 || items[0].Path != left || items[1].PID != 10 {
+		t.Fatalf("leftoverItems() = %+v, want %s and PID 10", items, left)
+	}
+	if items := f.leftoverItems(procs, items[:1]); len(items) != 1 || items[0].PID != 10 {
+		t.Errorf("leftoverItems() lists already found items again: %+v", items)
+	}
+}
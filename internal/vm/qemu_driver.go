@@ -4,6 +4,7 @@ package vm
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/sha256"
 	"encoding/json"
@@ -19,6 +20,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/tnk4on/bootc-man/internal/cleanup"
 	"github.com/tnk4on/bootc-man/internal/config"
 )
 
@@ -26,15 +28,23 @@ import (
 type QemuDriver struct {
 	opts                 VMOptions
 	verbose              bool
+	ssh                  driverSSH
 	sshConfig            SSHConfig
 	logFile              string
 	efiStore             string
 	pidFile              string
+	qmpSocket            string
+	consoleSocket        string
+	qmp                  *QMPClient // kept open so events are not missed
 	gvproxySocket        string
 	gvproxyServiceSocket string // HTTP API socket for dynamic port forwarding
 	gvproxyPidFile       string
 	gvproxyPID           int
 	gvproxyCmd           *exec.Cmd
+	virtiofsdCmds        []*exec.Cmd // one virtiofsd per shared directory
+	accel                string      // kvm or tcg, detected on first use
+	tpmSocket            string
+	swtpmCmd             *exec.Cmd
 	macAddress           string
 }
 
@@ -50,6 +60,11 @@ func NewQemuDriver(opts VMOptions, verbose bool) (*QemuDriver, error) {
 	if opts.SSHUser == "" {
 		opts.SSHUser = "user"
 	}
+	arch, err := NormalizeArch(opts.Arch)
+	if err != nil {
+		return nil, err
+	}
+	opts.Arch = arch
 	if opts.SSHPort == 0 {
 		// Allocate a port using podman machine's port allocation system
 		port, err := AllocateMachinePort()
@@ -67,20 +82,31 @@ func NewQemuDriver(opts VMOptions, verbose bool) (*QemuDriver, error) {
 	}
 	efiStore := opts.EFIVariableStore
 	if efiStore == "" {
-		efiStore = filepath.Join(tmpDir, fmt.Sprintf("bootc-man-qemu-%s-efi-vars.fd", opts.Name))
+		// Secure boot uses a variable store with enrolled keys, kept apart
+		// from the plain one so the mode can be switched between runs
+		efiVars := "efi-vars"
+		if opts.SecureBoot {
+			efiVars = "efi-vars-secboot"
+		}
+		efiStore = filepath.Join(tmpDir, fmt.Sprintf("bootc-man-qemu-%s-%s.fd", opts.Name, efiVars))
 	}
 	pidFile := filepath.Join(tmpDir, fmt.Sprintf("bootc-man-qemu-%s.pid", opts.Name))
+	qmpSocket := filepath.Join(tmpDir, fmt.Sprintf("bootc-man-qemu-%s-qmp.sock", opts.Name))
+	consoleSocket := filepath.Join(tmpDir, fmt.Sprintf("bootc-man-qemu-%s-console.sock", opts.Name))
 
 	// Generate unique MAC address for this VM
 	macAddress := generateMACAddress(opts.Name)
 
 	return &QemuDriver{
-		opts:       opts,
-		verbose:    verbose,
-		logFile:    logFile,
-		efiStore:   efiStore,
-		pidFile:    pidFile,
-		macAddress: macAddress,
+		opts:          opts,
+		verbose:       verbose,
+		logFile:       logFile,
+		efiStore:      efiStore,
+		pidFile:       pidFile,
+		qmpSocket:     qmpSocket,
+		consoleSocket: consoleSocket,
+		tpmSocket:     filepath.Join(tmpDir, fmt.Sprintf("bootc-man-qemu-%s-tpm.sock", opts.Name)),
+		macAddress:    macAddress,
 		sshConfig: SSHConfig{
 			Host:        "localhost",
 			Port:        opts.SSHPort,
@@ -96,19 +122,41 @@ func (d *QemuDriver) Type() VMType {
 	return QemuVM
 }
 
-// Available checks if QEMU, KVM, and gvproxy are available
+// Available checks if QEMU and gvproxy are available
+// KVM is optional: without it the guest is emulated with TCG.
 func (d *QemuDriver) Available() error {
-	// Check for qemu-system-x86_64 (or appropriate architecture)
+	// Check for the QEMU binary of the guest architecture
 	binary := d.getQemuBinary()
 	if _, err := exec.LookPath(binary); err != nil {
+		if d.opts.Arch == ArchARM64 {
+			return fmt.Errorf("%s is not installed. Install it: sudo dnf install qemu-system-aarch64", binary)
+		}
 		return fmt.Errorf("%s is not installed. Install it: sudo dnf install qemu-kvm", binary)
 	}
 
-	// Check for KVM support (required for acceptable performance)
-	if _, err := os.Stat("/dev/kvm"); err != nil {
-		return fmt.Errorf(`KVM is not available. VM execution requires KVM for acceptable performance.
+	// Check for gvproxy (required for networking)
+	if _, err := exec.LookPath(d.getGvproxyBinary()); err != nil {
+		return fmt.Errorf("gvproxy is not installed. Install it: sudo dnf install gvisor-tap-vsock")
+	}
+
+	// virtiofsd is only needed for shared directories
+	if len(d.opts.Mounts) > 0 {
+		if _, err := exec.LookPath(config.FindVirtiofsdBinary()); err != nil {
+			return fmt.Errorf("virtiofsd is not installed (required for --mount). Install it: sudo dnf install virtiofsd")
+		}
+	}
 
-To enable KVM:
+	// swtpm emulates the TPM
+	if d.opts.TPM {
+		if _, err := exec.LookPath(config.BinarySwtpm); err != nil {
+			return fmt.Errorf("swtpm is not installed (required for --tpm). Install it: sudo dnf install swtpm")
+		}
+	}
+	return nil
+}
+
+// kvmSetupHint explains how to enable KVM when QEMU falls back to TCG
+const kvmSetupHint = `To enable KVM:
 
 1. Check if your CPU supports virtualization:
    grep -E '(vmx|svm)' /proc/cpuinfo
@@ -128,14 +176,174 @@ To enable KVM:
    ls -la /dev/kvm
    sudo chmod 666 /dev/kvm  # Temporary fix
    # or add user to kvm group:
-   sudo usermod -aG kvm $USER`)
+   sudo usermod -aG kvm $USER`
+
+// Accelerator returns the accelerator used for the guest
+// KVM is used when the guest architecture matches the host and /dev/kvm is
+// usable; otherwise QEMU falls back to TCG software emulation.
+func (d *QemuDriver) Accelerator() string {
+	if d.accel == "" {
+		d.accel = AccelTCG
+		if d.opts.Arch == HostArch() && kvmUsable() {
+			d.accel = AccelKVM
+		}
 	}
+	return d.accel
+}
 
-	// Check for gvproxy (required for networking)
-	if _, err := exec.LookPath(d.getGvproxyBinary()); err != nil {
-		return fmt.Errorf("gvproxy is not installed. Install it: sudo dnf install gvisor-tap-vsock")
+// kvmUsable checks that /dev/kvm exists and can be opened by this user
+func kvmUsable() bool {
+	f, err := os.OpenFile("/dev/kvm", os.O_RDWR, 0)
+	if err != nil {
+		return false
 	}
-	return nil
+	f.Close()
+	return true
+}
+
+// buildMachineArgs returns the machine type, accelerator and CPU model
+// aarch64 guests use the generic "virt" machine; TCG emulates the most
+// capable CPU model QEMU has since the host CPU cannot be passed through.
+// Secure boot on x86_64 needs SMM, which requires the q35 machine, so that
+// the guest OS cannot write the firmware's variable store directly.
+func (d *QemuDriver) buildMachineArgs() []string {
+	accel := d.Accelerator()
+	machine := "accel=" + accel
+	secureSMM := d.machineType() == qemuMachineQ35
+	switch d.machineType() {
+	case qemuMachineVirt:
+		machine = "virt," + machine
+	case qemuMachineQ35:
+		machine = "q35,smm=on," + machine
+	}
+	cpu := "host"
+	if accel == AccelTCG {
+		cpu = "max"
+	}
+	args := []string{"-M", machine, "-cpu", cpu}
+	if secureSMM {
+		args = append(args, "-global", "driver=cfi.pflash01,property=secure,value=on")
+	}
+	return args
+}
+
+// QEMU machine types selected by machineType
+const (
+	qemuMachinePC   = "pc"
+	qemuMachineQ35  = "q35"
+	qemuMachineVirt = "virt"
+)
+
+// machineType returns the QEMU machine type of the VM
+func (d *QemuDriver) machineType() string {
+	switch {
+	case d.opts.Arch == ArchARM64:
+		return qemuMachineVirt
+	case d.opts.SecureBoot && d.opts.Arch == ArchAMD64:
+		return qemuMachineQ35
+	default:
+		return qemuMachinePC
+	}
+}
+
+// uefiFirmware is a UEFI firmware image and its variable store template
+type uefiFirmware struct {
+	code string
+	vars string
+}
+
+// uefiFirmwarePaths lists known firmware locations per guest architecture
+// Note: Ubuntu/Debian uses *_4M variants (4MB firmware), Fedora/RHEL uses standard names
+var uefiFirmwarePaths = map[string][]uefiFirmware{
+	ArchAMD64: {
+		{"/usr/share/OVMF/OVMF_CODE.fd", "/usr/share/OVMF/OVMF_VARS.fd"},                   // Fedora/RHEL
+		{"/usr/share/OVMF/OVMF_CODE_4M.fd", "/usr/share/OVMF/OVMF_VARS_4M.fd"},             // Ubuntu/Debian (4MB variant)
+		{"/usr/share/edk2/ovmf/OVMF_CODE.fd", "/usr/share/edk2/ovmf/OVMF_VARS.fd"},         // Fedora alternate
+		{"/usr/share/qemu/OVMF_CODE.fd", "/usr/share/qemu/OVMF_VARS.fd"},                   // Generic
+		{"/usr/share/edk2-ovmf/x64/OVMF_CODE.fd", "/usr/share/edk2-ovmf/x64/OVMF_VARS.fd"}, // Debian/Ubuntu alternate
+	},
+	ArchARM64: {
+		{"/usr/share/AAVMF/AAVMF_CODE.fd", "/usr/share/AAVMF/AAVMF_VARS.fd"},                                // Fedora/RHEL, Ubuntu/Debian
+		{"/usr/share/edk2/aarch64/QEMU_EFI-pflash.raw", "/usr/share/edk2/aarch64/vars-template-pflash.raw"}, // Fedora edk2-aarch64
+		{"/usr/share/edk2/aarch64/QEMU_CODE.fd", "/usr/share/edk2/aarch64/QEMU_VARS.fd"},                    // Arch
+	},
+}
+
+// buildFirmwareArgs returns the firmware arguments and creates the EFI
+// variable store on first boot
+// BIOS boot needs no arguments: QEMU boots x86_64 guests with SeaBIOS
+// unless pflash firmware is given, using the BIOS boot partition of the disk.
+func (d *QemuDriver) buildFirmwareArgs() ([]string, error) {
+	if d.opts.Firmware == FirmwareBIOS {
+		return nil, nil
+	}
+
+	// UEFI boot (OVMF on x86_64, AAVMF on aarch64)
+	firmware, err := findUEFIFirmware(d.opts.Arch, d.opts.SecureBoot)
+	if err != nil {
+		return nil, err
+	}
+
+	// EFI with variable store for persistent boot settings
+	args := []string{"-drive", fmt.Sprintf("if=pflash,format=raw,readonly=on,file=%s", firmware.code)}
+
+	// Create EFI variable store if it doesn't exist
+	if _, err := os.Stat(d.efiStore); os.IsNotExist(err) {
+		// Copy template to create writable variable store
+		if _, err := os.Stat(firmware.vars); err == nil {
+			if err := copyFile(firmware.vars, d.efiStore); err != nil {
+				return nil, fmt.Errorf("failed to create EFI variable store: %w", err)
+			}
+		}
+	}
+	if _, err := os.Stat(d.efiStore); err == nil {
+		args = append(args, "-drive", fmt.Sprintf("if=pflash,format=raw,file=%s", d.efiStore))
+	}
+	return args, nil
+}
+
+// secureBootFirmwarePaths lists secure boot capable firmware per guest
+// architecture, paired with variable stores that have the Microsoft and
+// distribution keys enrolled
+var secureBootFirmwarePaths = map[string][]uefiFirmware{
+	ArchAMD64: {
+		{"/usr/share/edk2/ovmf/OVMF_CODE.secboot.fd", "/usr/share/edk2/ovmf/OVMF_VARS.secboot.fd"}, // Fedora
+		{"/usr/share/OVMF/OVMF_CODE.secboot.fd", "/usr/share/OVMF/OVMF_VARS.secboot.fd"},           // RHEL
+		{"/usr/share/OVMF/OVMF_CODE_4M.secboot.fd", "/usr/share/OVMF/OVMF_VARS_4M.ms.fd"},          // Ubuntu/Debian (4MB variant)
+		{"/usr/share/OVMF/OVMF_CODE.secboot.fd", "/usr/share/OVMF/OVMF_VARS.ms.fd"},                // Ubuntu/Debian
+	},
+	ArchARM64: {
+		{"/usr/share/AAVMF/AAVMF_CODE.ms.fd", "/usr/share/AAVMF/AAVMF_VARS.ms.fd"}, // Ubuntu/Debian
+	},
+}
+
+// findUEFIFirmware returns the first installed firmware for the guest architecture
+// With secureBoot, both the firmware and the enrolled variable store must exist.
+func findUEFIFirmware(arch string, secureBoot bool) (uefiFirmware, error) {
+	if secureBoot {
+		for _, fw := range secureBootFirmwarePaths[arch] {
+			if _, err := os.Stat(fw.code); err != nil {
+				continue
+			}
+			if _, err := os.Stat(fw.vars); err == nil {
+				return fw, nil
+			}
+		}
+		if arch == ArchARM64 {
+			return uefiFirmware{}, fmt.Errorf("secure boot AAVMF firmware not found. Install it: sudo apt install qemu-efi-aarch64")
+		}
+		return uefiFirmware{}, fmt.Errorf("secure boot OVMF firmware not found. Install it: sudo dnf install edk2-ovmf")
+	}
+
+	for _, fw := range uefiFirmwarePaths[arch] {
+		if _, err := os.Stat(fw.code); err == nil {
+			return fw, nil
+		}
+	}
+	if arch == ArchARM64 {
+		return uefiFirmware{}, fmt.Errorf("AAVMF firmware not found. Install it: sudo dnf install edk2-aarch64")
+	}
+	return uefiFirmware{}, fmt.Errorf("OVMF firmware not found. Install it: sudo dnf install edk2-ovmf (or boot with --firmware bios)")
 }
 
 // getGvproxyBinary returns the gvproxy binary path
@@ -153,18 +361,21 @@ func generateMACAddress(vmName string) string {
 	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", hash[0], hash[1], hash[2])
 }
 
-// getQemuBinary returns the QEMU binary path for the current architecture
+// getQemuBinary returns the QEMU binary path for the guest architecture
 // Searches common locations where QEMU is installed on different distributions
 func (d *QemuDriver) getQemuBinary() string {
 	// Common QEMU binary locations
-	// - qemu-system-x86_64: Fedora, Ubuntu, standard installations
-	// - /usr/libexec/qemu-kvm: RHEL, CentOS (qemu-kvm package)
+	// - qemu-system-<arch>: Fedora, Ubuntu, standard installations
+	// - /usr/libexec/qemu-kvm: RHEL, CentOS (qemu-kvm package, host architecture only)
 	// - /usr/bin/qemu-kvm: Alternative location on some systems
-	locations := []string{
-		"qemu-system-x86_64",    // Standard (in PATH)
-		"/usr/libexec/qemu-kvm", // RHEL/CentOS
-		"/usr/bin/qemu-kvm",     // Alternative
-		"qemu-kvm",              // In PATH
+	standard := "qemu-system-" + QemuArch(d.opts.Arch)
+	locations := []string{standard}
+	if d.opts.Arch == HostArch() {
+		locations = append(locations,
+			"/usr/libexec/qemu-kvm", // RHEL/CentOS
+			"/usr/bin/qemu-kvm",     // Alternative
+			"qemu-kvm",              // In PATH
+		)
 	}
 	for _, loc := range locations {
 		if path, err := exec.LookPath(loc); err == nil {
@@ -172,24 +383,60 @@ func (d *QemuDriver) getQemuBinary() string {
 		}
 	}
 	// Fallback to standard name (will fail with helpful error message)
-	return "qemu-system-x86_64"
+	return standard
 }
 
 // Start starts the VM
-func (d *QemuDriver) Start(ctx context.Context, opts VMOptions) error {
+func (d *QemuDriver) Start(ctx context.Context, opts VMOptions) (err error) {
 	// Update options if provided
 	if opts.Name != "" {
+		arch, err := NormalizeArch(opts.Arch)
+		if err != nil {
+			return err
+		}
+		opts.Arch = arch
 		d.opts = opts
+		d.accel = ""
 	}
 
 	if err := d.Available(); err != nil {
 		return err
 	}
+	if err := ValidateFirmware(d.opts.Firmware, d.opts.Arch, d.opts.SecureBoot); err != nil {
+		return err
+	}
+	if err := ValidateNICModel(d.opts.NICModel); err != nil {
+		return err
+	}
+
+	// Without KVM the guest runs, much slower, under TCG emulation
+	switch {
+	case d.opts.Arch != HostArch():
+		fmt.Printf("ℹ️  Emulating %s guest on %s host with TCG (boot takes considerably longer)\n", QemuArch(d.opts.Arch), QemuArch(HostArch()))
+	case d.Accelerator() == AccelTCG:
+		fmt.Println("⚠️  KVM is not available, falling back to TCG software emulation (boot takes considerably longer)")
+		if d.verbose {
+			fmt.Println(kvmSetupHint)
+		}
+	}
+
+	// QEMU daemonizes and gvproxy is not tied to ctx, so an interrupt while
+	// starting would leave them running; once Start returns, the caller owns
+	// the VM
+	starting := cleanup.Register("stop QEMU VM "+d.opts.Name, d.Stop)
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			_ = starting.Run(context.Background())
+		} else {
+			starting.Release()
+		}
+	}()
 
 	// Start gvproxy for networking
 	if err := d.startGvproxy(ctx); err != nil {
 		return fmt.Errorf("failed to start gvproxy: %w", err)
 	}
+	starting.AddResource(ProcessResource(d.gvproxyCmd.Process.Pid))
 
 	// Wait for gvproxy socket to be created
 	// Use longer timeout for CI environments where gvproxy may take longer to initialize
@@ -219,66 +466,60 @@ func (d *QemuDriver) Start(ctx context.Context, opts VMOptions) error {
 	// Build QEMU command line
 	args := []string{}
 
-	// Machine type and acceleration (KVM is required, checked in Available())
-	args = append(args, "-M", "accel=kvm")
-	args = append(args, "-cpu", "host")
+	// Machine type and acceleration (KVM if usable, otherwise TCG)
+	args = append(args, d.buildMachineArgs()...)
 
 	// Resources
 	args = append(args, "-smp", fmt.Sprintf("%d", d.opts.CPUs))
 	args = append(args, "-m", fmt.Sprintf("%d", d.opts.Memory))
 
-	// UEFI boot (using OVMF)
-	// Check for OVMF firmware locations
-	// Note: Ubuntu/Debian uses *_4M variants (4MB firmware), Fedora/RHEL uses standard names
-	ovmfPaths := []string{
-		"/usr/share/OVMF/OVMF_CODE.fd",          // Fedora/RHEL
-		"/usr/share/OVMF/OVMF_CODE_4M.fd",       // Ubuntu/Debian (4MB variant)
-		"/usr/share/edk2/ovmf/OVMF_CODE.fd",     // Fedora alternate
-		"/usr/share/qemu/OVMF_CODE.fd",          // Generic
-		"/usr/share/edk2-ovmf/x64/OVMF_CODE.fd", // Debian/Ubuntu alternate
-	}
-	var ovmfCode string
-	for _, p := range ovmfPaths {
-		if _, err := os.Stat(p); err == nil {
-			ovmfCode = p
-			break
-		}
+	// Boot firmware (UEFI or SeaBIOS)
+	firmwareArgs, err := d.buildFirmwareArgs()
+	if err != nil {
+		d.stopGvproxy()
+		return err
 	}
-	if ovmfCode == "" {
-		return fmt.Errorf("OVMF firmware not found. Install it: sudo dnf install edk2-ovmf")
+	args = append(args, firmwareArgs...)
+
+	// Disk image with boot priority
+	// The format is detected from the image header so qcow2/vmdk artifacts
+	// can be booted directly and ISO installers get a blank target disk
+	diskArgs, err := d.buildDiskArgs(ctx)
+	if err != nil {
+		d.stopGvproxy()
+		return err
 	}
+	args = append(args, diskArgs...)
 
-	// EFI with variable store for persistent boot settings
-	args = append(args, "-drive", fmt.Sprintf("if=pflash,format=raw,readonly=on,file=%s", ovmfCode))
+	// First-boot provisioning data
+	args = append(args, d.buildProvisioningArgs()...)
 
-	// Create EFI variable store if it doesn't exist
-	ovmfVarsTemplate := strings.Replace(ovmfCode, "CODE", "VARS", 1)
-	if _, err := os.Stat(d.efiStore); os.IsNotExist(err) {
-		// Copy template to create writable variable store
-		if _, err := os.Stat(ovmfVarsTemplate); err == nil {
-			if err := copyFile(ovmfVarsTemplate, d.efiStore); err != nil {
-				return fmt.Errorf("failed to create EFI variable store: %w", err)
-			}
-		}
-	}
-	if _, err := os.Stat(d.efiStore); err == nil {
-		args = append(args, "-drive", fmt.Sprintf("if=pflash,format=raw,file=%s", d.efiStore))
-	}
+	// Shared host directories (virtio-fs via virtiofsd)
+	args = append(args, d.buildSharedDirArgs()...)
 
-	// Disk image with boot priority (raw format only for cross-platform compatibility)
-	// Use id and bootindex to ensure disk is booted first before network
-	args = append(args, "-drive", fmt.Sprintf("file=%s,format=raw,if=none,id=disk0", d.opts.DiskImage))
-	args = append(args, "-device", "virtio-blk-pci,drive=disk0,bootindex=0")
+	// Emulated TPM 2.0 (swtpm)
+	args = append(args, d.buildTPMArgs()...)
 
 	// Networking via gvproxy (unified across platforms)
 	// Uses stream socket to connect to gvproxy
 	// Unique MAC address per VM allows multiple VMs and avoids conflict with podman machine
-	// bootindex=1 ensures network device is after disk in boot order
+	// The network device always boots last (after disk and installer media)
 	args = append(args, "-netdev", fmt.Sprintf("stream,id=net0,addr.type=unix,addr.path=%s,server=off", d.gvproxySocket))
-	args = append(args, "-device", fmt.Sprintf("virtio-net-pci,netdev=net0,mac=%s,bootindex=1", d.macAddress))
+	args = append(args, "-device", fmt.Sprintf("%s,netdev=net0,mac=%s,bootindex=2", cmp.Or(d.opts.NICModel, NICModelVirtio), d.macAddress))
+
+	// Lab network shared with the other members of the lab
+	labArgs, err := d.buildLabNetArgs()
+	if err != nil {
+		d.stopGvproxy()
+		return err
+	}
+	args = append(args, labArgs...)
 
-	// Serial console output to file
-	args = append(args, "-serial", fmt.Sprintf("file:%s", d.logFile))
+	// Serial console on a unix socket for interactive access (vm console),
+	// with all output also written to the log file
+	os.Remove(d.consoleSocket)
+	args = append(args, "-chardev", fmt.Sprintf("socket,id=serial0,path=%s,server=on,wait=off,logfile=%s", d.consoleSocket, d.logFile))
+	args = append(args, "-serial", "chardev:serial0")
 
 	// Random number generator
 	args = append(args, "-device", "virtio-rng-pci")
@@ -286,12 +527,21 @@ func (d *QemuDriver) Start(ctx context.Context, opts VMOptions) error {
 	// Display
 	if d.opts.GUI {
 		// Enable graphical display
+		// The aarch64 virt machine has no default display or keyboard
+		if d.opts.Arch == ArchARM64 {
+			args = append(args, "-device", "virtio-gpu-pci", "-device", "qemu-xhci", "-device", "usb-kbd")
+		}
 		args = append(args, "-display", "gtk")
 	} else {
 		// No display - use VNC with no listener to avoid GTK/SDL initialization
 		args = append(args, "-vnc", "none")
 	}
 
+	// QMP control socket for state queries, graceful shutdown, pause/resume
+	// and guest events (RESET, SHUTDOWN)
+	os.Remove(d.qmpSocket)
+	args = append(args, "-qmp", fmt.Sprintf("unix:%s,server=on,wait=off", d.qmpSocket))
+
 	// Daemonize (run in background)
 	args = append(args, "-daemonize")
 	args = append(args, "-pidfile", d.pidFile)
@@ -300,27 +550,170 @@ func (d *QemuDriver) Start(ctx context.Context, opts VMOptions) error {
 		fmt.Printf("Running: %s %s\n", d.getQemuBinary(), strings.Join(args, " "))
 	}
 
+	// virtiofsd and swtpm must be listening before QEMU connects to them
+	if err := d.startVirtiofsd(ctx); err != nil {
+		d.stopGvproxy()
+		return err
+	}
+	if err := d.startSwtpm(ctx); err != nil {
+		d.stopVirtiofsd()
+		d.stopGvproxy()
+		return err
+	}
+
 	// Execute QEMU
 	cmd := exec.CommandContext(ctx, d.getQemuBinary(), args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
+		d.stopSwtpm()
+		d.stopVirtiofsd()
 		d.stopGvproxy()
 		return fmt.Errorf("failed to start QEMU: %w", err)
 	}
 
 	// Read PID from file
 	time.Sleep(500 * time.Millisecond) // Wait for PID file to be written
-	if pidData, err := os.ReadFile(d.pidFile); err == nil {
+	if pid := d.GetProcessID(); pid > 0 {
+		starting.AddResource(ProcessResource(pid))
 		if d.verbose {
-			fmt.Printf("QEMU started with PID: %s\n", strings.TrimSpace(string(pidData)))
+			fmt.Printf("QEMU started with PID: %d\n", pid)
 		}
 	}
 
+	// Connect to QMP right away so reboot events during boot are captured
+	if _, err := d.qmpClient(ctx); err != nil && d.verbose {
+		fmt.Printf("⚠️  Failed to connect to QMP: %v\n", err)
+	}
+
 	return nil
 }
 
+// qmpClient returns the QMP connection, connecting on first use
+// A QMP socket serves one client at a time, so the connection is kept open
+// for the lifetime of the driver.
+func (d *QemuDriver) qmpClient(ctx context.Context) (*QMPClient, error) {
+	if d.qmp != nil {
+		select {
+		case <-d.qmp.Closed():
+			d.qmp = nil
+		default:
+			return d.qmp, nil
+		}
+	}
+	client, err := DialQMP(ctx, d.qmpSocket)
+	if err != nil {
+		return nil, err
+	}
+	d.qmp = client
+	return client, nil
+}
+
+// closeQMP closes the QMP connection if open
+func (d *QemuDriver) closeQMP() {
+	if d.qmp != nil {
+		d.qmp.Close()
+		d.qmp = nil
+	}
+}
+
+// buildDiskArgs returns the QEMU arguments for the VM's disks
+// For disk images the detected format is passed explicitly so QEMU does not
+// have to probe it. ISO installers are attached as a CD-ROM together with a
+// blank target disk; the target disk boots first so that the installed
+// system is used once the unattended install has finished.
+func (d *QemuDriver) buildDiskArgs(ctx context.Context) ([]string, error) {
+	format, err := DetectImageFormat(d.opts.DiskImage)
+	if err != nil {
+		return nil, err
+	}
+	if d.verbose {
+		fmt.Printf("Detected disk image format: %s\n", format)
+	}
+
+	if format != ImageFormatISO {
+		return []string{
+			"-drive", fmt.Sprintf("file=%s,format=%s,if=none,id=disk0", d.opts.DiskImage, format),
+			"-device", "virtio-blk-pci,drive=disk0,bootindex=0",
+		}, nil
+	}
+
+	installDisk, err := ensureInstallDisk(ctx, &d.opts, d.verbose)
+	if err != nil {
+		return nil, err
+	}
+	installFormat, err := DetectImageFormat(installDisk)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"-drive", fmt.Sprintf("file=%s,format=%s,if=none,id=disk0", installDisk, installFormat),
+		"-device", "virtio-blk-pci,drive=disk0,bootindex=0",
+		"-drive", fmt.Sprintf("file=%s,format=raw,if=none,id=cdrom0,media=cdrom,readonly=on", d.opts.DiskImage),
+	}
+	// The virt machine has no IDE controller, so the CD-ROM hangs off SCSI
+	if d.machineType() == qemuMachineVirt {
+		return append(args,
+			"-device", "virtio-scsi-pci,id=scsi0",
+			"-device", "scsi-cd,bus=scsi0.0,drive=cdrom0,bootindex=1",
+		), nil
+	}
+	return append(args, "-device", "ide-cd,drive=cdrom0,bootindex=1"), nil
+}
+
+// buildProvisioningArgs returns the QEMU arguments that deliver first-boot
+// provisioning data: Ignition via fw_cfg, cloud-init via a NoCloud seed disk
+func (d *QemuDriver) buildProvisioningArgs() []string {
+	p := d.opts.Provisioning
+	if p == nil {
+		return nil
+	}
+	var args []string
+	if p.IgnitionFile != "" {
+		args = append(args, "-fw_cfg", fmt.Sprintf("name=opt/com.coreos/config,file=%s", p.IgnitionFile))
+	}
+	if p.CloudInitSeed != "" {
+		args = append(args, "-drive", fmt.Sprintf("file=%s,format=raw,if=none,id=seed0,readonly=on", p.CloudInitSeed))
+		args = append(args, "-device", "virtio-blk-pci,drive=seed0")
+	}
+	return args
+}
+
+// ensureInstallDisk creates the blank qcow2 target disk for ISO installs
+// and records it in opts
+func ensureInstallDisk(ctx context.Context, opts *VMOptions, verbose bool) (string, error) {
+	installDisk := opts.InstallDisk
+	if installDisk == "" {
+		installDisk = strings.TrimSuffix(opts.DiskImage, filepath.Ext(opts.DiskImage)) + "-install.qcow2"
+	}
+	if _, err := os.Stat(installDisk); err == nil {
+		return installDisk, nil
+	}
+
+	size := opts.InstallDiskSize
+	if size == 0 {
+		size = config.DefaultVMInstallDiskSizeGB
+	}
+
+	qemuImg, err := exec.LookPath(config.BinaryQemuImg)
+	if err != nil {
+		return "", fmt.Errorf("qemu-img is required to create the install target disk. Install it: sudo dnf install qemu-img")
+	}
+
+	args := []string{"create", "-q", "-f", "qcow2", installDisk, fmt.Sprintf("%dG", size)}
+	if verbose {
+		fmt.Printf("Running: %s %s\n", qemuImg, strings.Join(args, " "))
+	}
+	if output, err := exec.CommandContext(ctx, qemuImg, args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to create install target disk: %w\n%s", err, string(output))
+	}
+
+	opts.InstallDisk = installDisk
+	return installDisk, nil
+}
+
 // startGvproxy starts gvproxy for VM networking
 func (d *QemuDriver) startGvproxy(ctx context.Context) error {
 	// Create socket paths
@@ -398,8 +791,220 @@ func (d *QemuDriver) stopGvproxy() {
 	os.Remove(d.gvproxyServiceSocket)
 }
 
-// Stop stops the VM
+// virtiofsdSocket returns the vhost-user socket path for a shared directory
+func (d *QemuDriver) virtiofsdSocket(m SharedDir) string {
+	return filepath.Join(config.RuntimeDir(), fmt.Sprintf("bootc-man-qemu-%s-fs-%s.sock", d.opts.Name, m.Tag))
+}
+
+// buildSharedDirArgs returns the QEMU arguments for shared directories
+// vhost-user-fs needs guest memory that virtiofsd can map, so the RAM is
+// backed by a shared memfd when any directory is shared.
+func (d *QemuDriver) buildSharedDirArgs() []string {
+	if len(d.opts.Mounts) == 0 {
+		return nil
+	}
+	args := []string{
+		"-object", fmt.Sprintf("memory-backend-memfd,id=mem,size=%dM,share=on", d.opts.Memory),
+		"-numa", "node,memdev=mem",
+	}
+	for i, m := range d.opts.Mounts {
+		args = append(args, "-chardev", fmt.Sprintf("socket,id=fs%d,path=%s", i, d.virtiofsdSocket(m)))
+		args = append(args, "-device", fmt.Sprintf("vhost-user-fs-pci,queue-size=1024,chardev=fs%d,tag=%s", i, m.Tag))
+	}
+	return args
+}
+
+// virtiofsdArgs returns the virtiofsd arguments for a shared directory
+// The sandbox is disabled so virtiofsd runs without root privileges.
+func virtiofsdArgs(m SharedDir, socket string) []string {
+	args := []string{
+		"--socket-path", socket,
+		"--shared-dir", m.Source,
+		"--sandbox", "none",
+		"--cache", "auto",
+	}
+	if m.ReadOnly {
+		args = append(args, "--readonly")
+	}
+	return args
+}
+
+// startVirtiofsd starts one virtiofsd per shared directory and waits for
+// their sockets; virtiofsd exits on its own when QEMU disconnects
+func (d *QemuDriver) startVirtiofsd(ctx context.Context) error {
+	binary := config.FindVirtiofsdBinary()
+	for _, m := range d.opts.Mounts {
+		socket := d.virtiofsdSocket(m)
+		logPath := filepath.Join(config.RuntimeDir(), fmt.Sprintf("bootc-man-virtiofsd-%s-%s.log", d.opts.Name, m.Tag))
+		cmd, err := d.startHelper(ctx, binary, virtiofsdArgs(m, socket), socket, logPath)
+		if cmd != nil {
+			d.virtiofsdCmds = append(d.virtiofsdCmds, cmd)
+		}
+		if err != nil {
+			d.stopVirtiofsd()
+			return fmt.Errorf("failed to start virtiofsd for %s: %w", m.Source, err)
+		}
+	}
+	return nil
+}
+
+// startHelper starts a helper process QEMU connects to over a unix socket
+// and waits until the socket exists. The process is detached from the
+// parent process group so Ctrl+C does not stop it; a started process is
+// returned even on error so that it can be stopped.
+func (d *QemuDriver) startHelper(ctx context.Context, binary string, args []string, socket, logPath string) (*exec.Cmd, error) {
+	os.Remove(socket)
+	if d.verbose {
+		fmt.Printf("Running: %s %s\n", binary, strings.Join(args, " "))
+	}
+
+	cmd := exec.Command(binary, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+	if logFile, err := os.Create(logPath); err == nil {
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		defer logFile.Close()
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	if err := waitForSocket(ctx, socket, exited); err != nil {
+		errMsg := err.Error()
+		if log, _ := os.ReadFile(logPath); len(log) > 0 {
+			errMsg += fmt.Sprintf("\n%s log:\n%s", filepath.Base(binary), string(log))
+		}
+		return cmd, fmt.Errorf("%s", errMsg)
+	}
+	if d.verbose {
+		fmt.Printf("%s ready (PID %d)\n", filepath.Base(binary), cmd.Process.Pid)
+	}
+	return cmd, nil
+}
+
+// waitForSocket waits until a helper process has created its socket
+func waitForSocket(ctx context.Context, socket string, exited <-chan struct{}) error {
+	timeout := time.After(10 * time.Second)
+	for {
+		if _, err := os.Stat(socket); err == nil {
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("process exited before creating %s", socket)
+		case <-timeout:
+			return fmt.Errorf("socket not created: %s", socket)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// stopVirtiofsd stops the virtiofsd processes started by this driver
+func (d *QemuDriver) stopVirtiofsd() {
+	for _, cmd := range d.virtiofsdCmds {
+		if cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+	}
+	d.virtiofsdCmds = nil
+	for _, m := range d.opts.Mounts {
+		os.Remove(d.virtiofsdSocket(m))
+	}
+}
+
+// buildLabNetArgs returns the QEMU arguments for the lab network interface
+// All members join the lab's multicast group on the loopback interface,
+// which forms one L2 segment without root privileges or host bridges. The
+// option ROM is disabled so the firmware never tries to PXE boot from it.
+func (d *QemuDriver) buildLabNetArgs() ([]string, error) {
+	if d.opts.Lab == nil {
+		return nil, nil
+	}
+	member := d.opts.Lab.Member(d.opts.Name)
+	if member == nil {
+		return nil, fmt.Errorf("VM %s is not a member of lab %s", d.opts.Name, d.opts.Lab.Name)
+	}
+	return []string{
+		"-netdev", fmt.Sprintf("socket,id=lab0,mcast=%s,localaddr=127.0.0.1", d.opts.Lab.Multicast),
+		"-device", fmt.Sprintf("virtio-net-pci,netdev=lab0,mac=%s,romfile=", member.MAC),
+	}, nil
+}
+
+// buildTPMArgs returns the QEMU arguments for the emulated TPM
+// x86_64 guests get a CRB interface TPM; the aarch64 virt machine has no
+// ISA bus and uses the TIS sysbus device instead.
+func (d *QemuDriver) buildTPMArgs() []string {
+	if !d.opts.TPM {
+		return nil
+	}
+	device := "tpm-crb"
+	if d.opts.Arch == ArchARM64 {
+		device = "tpm-tis-device"
+	}
+	return []string{
+		"-chardev", fmt.Sprintf("socket,id=chrtpm,path=%s", d.tpmSocket),
+		"-tpmdev", "emulator,id=tpm0,chardev=chrtpm",
+		"-device", fmt.Sprintf("%s,tpmdev=tpm0", device),
+	}
+}
+
+// startSwtpm starts swtpm with the VM's persistent TPM state
+func (d *QemuDriver) startSwtpm(ctx context.Context) error {
+	if !d.opts.TPM {
+		return nil
+	}
+	stateDir, err := VMTPMStateDir(d.opts.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return fmt.Errorf("failed to create TPM state directory: %w", err)
+	}
+	logPath := filepath.Join(config.RuntimeDir(), fmt.Sprintf("bootc-man-swtpm-%s.log", d.opts.Name))
+	cmd, err := d.startHelper(ctx, config.BinarySwtpm, swtpmArgs(stateDir, d.tpmSocket, logPath), d.tpmSocket, logPath)
+	d.swtpmCmd = cmd
+	if err != nil {
+		d.stopSwtpm()
+		return fmt.Errorf("failed to start swtpm: %w", err)
+	}
+	return nil
+}
+
+// stopSwtpm stops swtpm if it was started by this driver
+func (d *QemuDriver) stopSwtpm() {
+	if d.swtpmCmd != nil && d.swtpmCmd.Process != nil {
+		_ = d.swtpmCmd.Process.Kill()
+	}
+	d.swtpmCmd = nil
+	os.Remove(d.tpmSocket)
+}
+
+// Stop stops the VM immediately
 func (d *QemuDriver) Stop(ctx context.Context) error {
+	d.ssh.close()
+	defer d.stopVirtiofsd()
+	defer d.stopSwtpm()
+
+	// Ask QEMU to quit via QMP first; the PID-based kill below is the fallback
+	if client, err := d.qmpClient(ctx); err == nil {
+		_, _ = client.Execute(ctx, "quit", nil)
+		select {
+		case <-client.Closed():
+		case <-time.After(5 * time.Second):
+		}
+	}
+	d.closeQMP()
+
 	// Read PID and send SIGTERM
 	pidData, err := os.ReadFile(d.pidFile)
 	if err != nil {
@@ -445,8 +1050,74 @@ func (d *QemuDriver) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Shutdown asks the guest to power off via ACPI and waits up to timeout
+// QEMU is told to quit if the guest does not power off in time.
+func (d *QemuDriver) Shutdown(ctx context.Context, timeout time.Duration) error {
+	client, err := d.qmpClient(ctx)
+	if err != nil {
+		if state, _ := d.GetState(ctx); state == VMStateStopped {
+			d.stopGvproxy()
+			return nil
+		}
+		return fmt.Errorf("graceful shutdown requires QMP: %w", err)
+	}
+
+	err = client.Powerdown(ctx, timeout)
+	d.closeQMP()
+	d.stopGvproxy()
+	return err
+}
+
+// Pause suspends VM execution
+func (d *QemuDriver) Pause(ctx context.Context) error {
+	client, err := d.qmpClient(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.Execute(ctx, "stop", nil); err != nil {
+		return fmt.Errorf("failed to pause VM: %w", err)
+	}
+	return nil
+}
+
+// Resume continues a paused VM
+func (d *QemuDriver) Resume(ctx context.Context) error {
+	client, err := d.qmpClient(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.Execute(ctx, "cont", nil); err != nil {
+		return fmt.Errorf("failed to resume VM: %w", err)
+	}
+	return nil
+}
+
+// WaitForReboot waits for the guest to reset or shut down
+// Events are buffered from the moment QMP is connected, so a reboot that
+// happened before this call is still observed.
+func (d *QemuDriver) WaitForReboot(ctx context.Context, timeout time.Duration) error {
+	client, err := d.qmpClient(ctx)
+	if err != nil {
+		return err
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if _, err := client.WaitForEvent(waitCtx, QMPEventReset, QMPEventShutdown); err != nil {
+		return fmt.Errorf("no reboot observed within %v: %w", timeout, err)
+	}
+	return nil
+}
+
 // GetState returns the current VM state
+// The run state comes from QMP query-status; the PID file is used when QMP
+// is not reachable.
 func (d *QemuDriver) GetState(ctx context.Context) (VMState, error) {
+	if client, err := d.qmpClient(ctx); err == nil {
+		if status, err := client.QueryStatus(ctx); err == nil {
+			return qmpStateToVMState(status), nil
+		}
+	}
+
 	pidData, err := os.ReadFile(d.pidFile)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -472,7 +1143,7 @@ func (d *QemuDriver) GetState(ctx context.Context) (VMState, error) {
 // WaitForReady waits for the VM to be ready
 func (d *QemuDriver) WaitForReady(ctx context.Context) error {
 	// Wait for VM to start and begin booting
-	timeout := 30 * time.Second
+	timeout := ScaleTimeout(d, 30*time.Second)
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
@@ -498,8 +1169,8 @@ func (d *QemuDriver) WaitForReady(ctx context.Context) error {
 
 // WaitForSSH waits for SSH to be available
 func (d *QemuDriver) WaitForSSH(ctx context.Context) error {
-	// KVM is required, so we can use a reasonable timeout
-	timeout := 2 * time.Minute
+	// Emulated guests (TCG) get a proportionally longer timeout
+	timeout := ScaleTimeout(d, 2*time.Minute)
 	deadline := time.Now().Add(timeout)
 
 	portForwardingSet := false
@@ -527,6 +1198,10 @@ func (d *QemuDriver) WaitForSSH(ctx context.Context) error {
 			conn.Close()
 			// Port is open, now try actual SSH connection
 			if err := d.testSSHConnection(ctx); err == nil {
+				// Events up to here belong to the boot (e.g. installer reboots)
+				if d.qmp != nil {
+					d.qmp.DrainEvents()
+				}
 				return nil
 			}
 		}
@@ -626,32 +1301,18 @@ func (d *QemuDriver) setupPortForwarding(ctx context.Context, vmIP string) error
 
 // testSSHConnection tests if SSH connection works
 func (d *QemuDriver) testSSHConnection(ctx context.Context) error {
-	args := d.buildSSHArgs("echo connected")
-	cmd := exec.CommandContext(ctx, "ssh", args...)
-	return cmd.Run()
+	return d.ssh.probe(ctx, d.opts.Name, d.sshConfig)
 }
 
-// SSH executes a command via SSH
+// SSH executes a command via SSH and returns the combined output
 func (d *QemuDriver) SSH(ctx context.Context, command string) (string, error) {
-	args := d.buildSSHArgs(command)
-	cmd := exec.CommandContext(ctx, "ssh", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := d.ssh.get(d.opts.Name, d.sshConfig).CombinedOutput(ctx, command)
 	return string(output), err
 }
 
-// buildSSHArgs builds SSH command arguments
-func (d *QemuDriver) buildSSHArgs(command string) []string {
-	args := []string{
-		"-i", d.sshConfig.KeyPath,
-		"-p", fmt.Sprintf("%d", d.sshConfig.Port),
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-o", "ConnectTimeout=5",
-		"-o", "BatchMode=yes",
-		fmt.Sprintf("%s@%s", d.sshConfig.User, d.sshConfig.Host),
-		command,
-	}
-	return args
+// RunSSH executes a command via SSH, streaming its output
+func (d *QemuDriver) RunSSH(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	return d.ssh.get(d.opts.Name, d.sshConfig).Run(ctx, command, stdout, stderr)
 }
 
 // GetSSHConfig returns the SSH configuration
@@ -675,17 +1336,28 @@ func (d *QemuDriver) ReadSerialLog() (string, error) {
 func (d *QemuDriver) Cleanup() error {
 	// Stop the VM if running
 	ctx := context.Background()
-	if state, _ := d.GetState(ctx); state == VMStateRunning {
+	if state, _ := d.GetState(ctx); state != VMStateStopped {
 		if err := d.Stop(ctx); err != nil {
 			return err
 		}
 	}
+	d.closeQMP()
+	d.ssh.close()
+	d.stopVirtiofsd()
+	d.stopSwtpm()
 
 	// Remove temporary files
 	os.Remove(d.pidFile)
+	os.Remove(d.qmpSocket)
+	os.Remove(d.consoleSocket)
 	os.Remove(d.logFile)
 	os.Remove(d.efiStore)
 	os.Remove(d.gvproxySocket)
+	if d.opts.TPM {
+		if stateDir, err := VMTPMStateDir(d.opts.Name); err == nil {
+			os.RemoveAll(stateDir)
+		}
+	}
 
 	return nil
 }
@@ -720,6 +1392,18 @@ func (d *QemuDriver) ToVMInfo(name, pipelineName, pipelineFile, imageTag string)
 		PipelineFile:         pipelineFile,
 		ImageTag:             imageTag,
 		DiskImage:            d.opts.DiskImage,
+		InstallDisk:          d.opts.InstallDisk,
+		CPUs:                 d.opts.CPUs,
+		Memory:               d.opts.Memory,
+		NICModel:             d.opts.NICModel,
+		Provisioning:         d.opts.Provisioning,
+		Mounts:               d.opts.Mounts,
+		Arch:                 d.opts.Arch,
+		Accelerator:          d.Accelerator(),
+		SecureBoot:           d.opts.SecureBoot,
+		TPM:                  d.opts.TPM,
+		Firmware:             d.opts.Firmware,
+		Lab:                  labName(d.opts.Lab),
 		Created:              time.Now(),
 		SSHHost:              d.sshConfig.Host,
 		SSHPort:              d.sshConfig.Port,
@@ -730,6 +1414,8 @@ func (d *QemuDriver) ToVMInfo(name, pipelineName, pipelineFile, imageTag string)
 		VMType:               QemuVM.String(),
 		ProcessID:            d.GetProcessID(),
 		PIDFile:              d.pidFile,
+		QMPSocket:            d.qmpSocket,
+		ConsoleSocket:        d.consoleSocket,
 		GvproxySocket:        d.gvproxySocket,
 		GvproxyServiceSocket: d.gvproxyServiceSocket,
 		GvproxyPID:           d.gvproxyPID,
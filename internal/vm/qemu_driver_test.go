@@ -0,0 +1,226 @@
+//go:build linux
+
+package vm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQemuBuildSharedDirArgs(t *testing.T) {
+	d := &QemuDriver{opts: VMOptions{Name: "test", Memory: 2048}}
+	if args := d.buildSharedDirArgs(); args != nil {
+		t.Errorf("buildSharedDirArgs() without mounts = %v, want nil", args)
+	}
+
+	d.opts.Mounts = []SharedDir{
+		{Source: "/src", Tag: "src"},
+		{Source: "/etc-overlay", Tag: "etc", ReadOnly: true},
+	}
+	args := strings.Join(d.buildSharedDirArgs(), " ")
+	for _, want := range []string{
+		"-object memory-backend-memfd,id=mem,size=2048M,share=on",
+		"-numa node,memdev=mem",
+		"-chardev socket,id=fs0,path=" + d.virtiofsdSocket(d.opts.Mounts[0]),
+		"-device vhost-user-fs-pci,queue-size=1024,chardev=fs0,tag=src",
+		"-chardev socket,id=fs1,path=" + d.virtiofsdSocket(d.opts.Mounts[1]),
+		"-device vhost-user-fs-pci,queue-size=1024,chardev=fs1,tag=etc",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("buildSharedDirArgs() = %q, missing %q", args, want)
+		}
+	}
+}
+
+func TestVirtiofsdArgs(t *testing.T) {
+	args := virtiofsdArgs(SharedDir{Source: "/src", Tag: "src"}, "/run/fs.sock")
+	want := []string{"--socket-path", "/run/fs.sock", "--shared-dir", "/src", "--sandbox", "none", "--cache", "auto"}
+	if !slices.Equal(args, want) {
+		t.Errorf("virtiofsdArgs() = %v, want %v", args, want)
+	}
+
+	args = virtiofsdArgs(SharedDir{Source: "/src", Tag: "src", ReadOnly: true}, "/run/fs.sock")
+	if !slices.Contains(args, "--readonly") {
+		t.Errorf("virtiofsdArgs(read-only) = %v, want --readonly", args)
+	}
+}
+
+func TestQemuBuildMachineArgs(t *testing.T) {
+	tests := []struct {
+		arch  string
+		accel string
+		want  []string
+	}{
+		{ArchAMD64, AccelKVM, []string{"-M", "accel=kvm", "-cpu", "host"}},
+		{ArchAMD64, AccelTCG, []string{"-M", "accel=tcg", "-cpu", "max"}},
+		{ArchARM64, AccelKVM, []string{"-M", "virt,accel=kvm", "-cpu", "host"}},
+		{ArchARM64, AccelTCG, []string{"-M", "virt,accel=tcg", "-cpu", "max"}},
+	}
+	for _, tt := range tests {
+		d := &QemuDriver{opts: VMOptions{Arch: tt.arch}, accel: tt.accel}
+		if got := d.buildMachineArgs(); !slices.Equal(got, tt.want) {
+			t.Errorf("buildMachineArgs(%s, %s) = %v, want %v", tt.arch, tt.accel, got, tt.want)
+		}
+	}
+}
+
+func TestQemuBuildDiskArgsISO(t *testing.T) {
+	dir := t.TempDir()
+	iso := filepath.Join(dir, "install.iso")
+	header := make([]byte, isoMagicOffset+len(isoMagic))
+	copy(header[isoMagicOffset:], isoMagic)
+	if err := os.WriteFile(iso, header, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// An existing install target disk is reused, so qemu-img is not needed
+	installDisk := filepath.Join(dir, "install.raw")
+	if err := os.WriteFile(installDisk, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		arch    string
+		want    []string
+		notWant string
+	}{
+		{ArchAMD64, []string{"-device ide-cd,drive=cdrom0,bootindex=1"}, "scsi-cd"},
+		{ArchARM64, []string{"-device virtio-scsi-pci,id=scsi0", "-device scsi-cd,bus=scsi0.0,drive=cdrom0,bootindex=1"}, "ide-cd"},
+	}
+	for _, tt := range tests {
+		d := &QemuDriver{opts: VMOptions{Arch: tt.arch, DiskImage: iso, InstallDisk: installDisk}}
+		got, err := d.buildDiskArgs(context.Background())
+		if err != nil {
+			t.Fatalf("buildDiskArgs(%s) error = %v", tt.arch, err)
+		}
+		args := strings.Join(got, " ")
+		for _, want := range tt.want {
+			if !strings.Contains(args, want) {
+				t.Errorf("buildDiskArgs(%s) = %q, missing %q", tt.arch, args, want)
+			}
+		}
+		if strings.Contains(args, tt.notWant) {
+			t.Errorf("buildDiskArgs(%s) = %q, must not use %s", tt.arch, args, tt.notWant)
+		}
+	}
+}
+
+func TestQemuAcceleratorForeignArch(t *testing.T) {
+	foreign := ArchARM64
+	if HostArch() == ArchARM64 {
+		foreign = ArchAMD64
+	}
+	d := &QemuDriver{opts: VMOptions{Arch: foreign}}
+	if got := d.Accelerator(); got != AccelTCG {
+		t.Errorf("Accelerator() for %s guest = %q, want %q", foreign, got, AccelTCG)
+	}
+	if got := ScaleTimeout(d, time.Minute); got != EmulationTimeoutScale*time.Minute {
+		t.Errorf("ScaleTimeout() = %v, want %v", got, EmulationTimeoutScale*time.Minute)
+	}
+
+	d = &QemuDriver{opts: VMOptions{Arch: HostArch()}, accel: AccelKVM}
+	if got := ScaleTimeout(d, time.Minute); got != time.Minute {
+		t.Errorf("ScaleTimeout() with KVM = %v, want %v", got, time.Minute)
+	}
+}
+
+func TestQemuBinaryForArch(t *testing.T) {
+	d := &QemuDriver{opts: VMOptions{Arch: ArchARM64}}
+	if HostArch() != ArchARM64 {
+		// qemu-kvm only runs host-architecture guests
+		if got := d.getQemuBinary(); !strings.HasSuffix(got, "qemu-system-aarch64") {
+			t.Errorf("getQemuBinary() = %q, want qemu-system-aarch64", got)
+		}
+	}
+}
+
+func TestQemuSecureBootMachineArgs(t *testing.T) {
+	d := &QemuDriver{opts: VMOptions{Arch: ArchAMD64, SecureBoot: true}, accel: AccelKVM}
+	want := []string{"-M", "q35,smm=on,accel=kvm", "-cpu", "host", "-global", "driver=cfi.pflash01,property=secure,value=on"}
+	if got := d.buildMachineArgs(); !slices.Equal(got, want) {
+		t.Errorf("buildMachineArgs(secure boot) = %v, want %v", got, want)
+	}
+
+	// aarch64 firmware enforces secure boot without SMM
+	d = &QemuDriver{opts: VMOptions{Arch: ArchARM64, SecureBoot: true}, accel: AccelTCG}
+	want = []string{"-M", "virt,accel=tcg", "-cpu", "max"}
+	if got := d.buildMachineArgs(); !slices.Equal(got, want) {
+		t.Errorf("buildMachineArgs(arm64 secure boot) = %v, want %v", got, want)
+	}
+}
+
+func TestQemuBuildTPMArgs(t *testing.T) {
+	d := &QemuDriver{opts: VMOptions{Arch: ArchAMD64}, tpmSocket: "/run/tpm.sock"}
+	if args := d.buildTPMArgs(); args != nil {
+		t.Errorf("buildTPMArgs() without TPM = %v, want nil", args)
+	}
+
+	tests := []struct {
+		arch   string
+		device string
+	}{
+		{ArchAMD64, "tpm-crb,tpmdev=tpm0"},
+		{ArchARM64, "tpm-tis-device,tpmdev=tpm0"},
+	}
+	for _, tt := range tests {
+		d := &QemuDriver{opts: VMOptions{Arch: tt.arch, TPM: true}, tpmSocket: "/run/tpm.sock"}
+		want := []string{
+			"-chardev", "socket,id=chrtpm,path=/run/tpm.sock",
+			"-tpmdev", "emulator,id=tpm0,chardev=chrtpm",
+			"-device", tt.device,
+		}
+		if got := d.buildTPMArgs(); !slices.Equal(got, want) {
+			t.Errorf("buildTPMArgs(%s) = %v, want %v", tt.arch, got, want)
+		}
+	}
+}
+
+func TestSwtpmArgs(t *testing.T) {
+	args := strings.Join(swtpmArgs("/vms/test-tpm", "/run/tpm.sock", "/run/swtpm.log"), " ")
+	want := "socket --tpm2 --tpmstate dir=/vms/test-tpm --ctrl type=unixio,path=/run/tpm.sock --log file=/run/swtpm.log --terminate"
+	if args != want {
+		t.Errorf("swtpmArgs() = %q, want %q", args, want)
+	}
+}
+
+func TestQemuBuildFirmwareArgsBIOS(t *testing.T) {
+	// SeaBIOS is QEMU's default firmware, so BIOS boot adds no pflash drives
+	d := &QemuDriver{opts: VMOptions{Arch: ArchAMD64, Firmware: FirmwareBIOS}, efiStore: t.TempDir() + "/efi-vars.fd"}
+	args, err := d.buildFirmwareArgs()
+	if err != nil {
+		t.Fatalf("buildFirmwareArgs() error = %v", err)
+	}
+	if args != nil {
+		t.Errorf("buildFirmwareArgs(bios) = %v, want nil", args)
+	}
+}
+
+func TestQemuBuildLabNetArgs(t *testing.T) {
+	d := &QemuDriver{opts: VMOptions{Name: "node1"}}
+	if args, err := d.buildLabNetArgs(); err != nil || args != nil {
+		t.Errorf("buildLabNetArgs() without lab = %v, %v, want nil", args, err)
+	}
+
+	lab, _ := NewLab("cluster", "")
+	d.opts.Lab = lab
+	if _, err := d.buildLabNetArgs(); err == nil {
+		t.Error("buildLabNetArgs() for non-member error = nil, want error")
+	}
+
+	member, _ := lab.AddMember("node1", "")
+	args, err := d.buildLabNetArgs()
+	if err != nil {
+		t.Fatalf("buildLabNetArgs() error = %v", err)
+	}
+	want := []string{
+		"-netdev", "socket,id=lab0,mcast=" + lab.Multicast + ",localaddr=127.0.0.1",
+		"-device", "virtio-net-pci,netdev=lab0,mac=" + member.MAC + ",romfile=",
+	}
+	if !slices.Equal(args, want) {
+		t.Errorf("buildLabNetArgs() = %v, want %v", args, want)
+	}
+}
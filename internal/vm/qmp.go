@@ -0,0 +1,312 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QMP event names used by bootc-man
+const (
+	QMPEventShutdown  = "SHUTDOWN"
+	QMPEventPowerdown = "POWERDOWN"
+	QMPEventReset     = "RESET"
+	QMPEventStop      = "STOP"
+	QMPEventResume    = "RESUME"
+)
+
+// QMP run states reported by query-status
+const (
+	QMPStatusRunning  = "running"
+	QMPStatusPaused   = "paused"
+	QMPStatusShutdown = "shutdown"
+)
+
+// QMPEvent is an asynchronous event emitted by QEMU
+type QMPEvent struct {
+	Event     string          `json:"event"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Timestamp struct {
+		Seconds      int64 `json:"seconds"`
+		Microseconds int64 `json:"microseconds"`
+	} `json:"timestamp"`
+}
+
+// QMPError is an error returned by a QMP command
+type QMPError struct {
+	Class string `json:"class"`
+	Desc  string `json:"desc"`
+}
+
+func (e *QMPError) Error() string {
+	return fmt.Sprintf("QMP error %s: %s", e.Class, e.Desc)
+}
+
+// qmpMessage is any message received on the QMP socket
+type qmpMessage struct {
+	QMP    json.RawMessage `json:"QMP,omitempty"`
+	ID     json.RawMessage `json:"id,omitempty"`
+	Return json.RawMessage `json:"return,omitempty"`
+	Error  *QMPError       `json:"error,omitempty"`
+	QMPEvent
+}
+
+// ErrShutdownTimeout is returned when the guest did not power off in time
+// and the VM had to be stopped forcibly
+var ErrShutdownTimeout = errors.New("guest did not power off in time")
+
+// qmpEventBuffer is the number of events kept before old ones are dropped
+const qmpEventBuffer = 64
+
+// QMPClient is a minimal client for the QEMU Machine Protocol
+// Commands are serialised and tagged with an id so that a late response to
+// a cancelled command is never taken for the current one; events are
+// buffered and can be consumed with WaitForEvent while commands are being
+// executed.
+type QMPClient struct {
+	conn      net.Conn
+	mu        sync.Mutex    // serialises commands
+	lastID    uint64        // id of the last command sent, guarded by mu
+	pending   atomic.Uint64 // id of the command awaiting a response
+	responses chan qmpMessage
+	events    chan QMPEvent
+	done      chan struct{}
+	readErr   error
+}
+
+// DialQMP connects to a QMP socket and negotiates capabilities
+func DialQMP(ctx context.Context, socketPath string) (*QMPClient, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to QMP socket: %w", err)
+	}
+
+	dec := json.NewDecoder(conn)
+
+	// QEMU sends a greeting before accepting commands
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var greeting qmpMessage
+	if err := dec.Decode(&greeting); err != nil || greeting.QMP == nil {
+		conn.Close()
+		if err == nil {
+			err = errors.New("unexpected greeting")
+		}
+		return nil, fmt.Errorf("failed to read QMP greeting: %w", err)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	c := &QMPClient{
+		conn:      conn,
+		responses: make(chan qmpMessage, 1),
+		events:    make(chan QMPEvent, qmpEventBuffer),
+		done:      make(chan struct{}),
+	}
+	go c.readLoop(dec)
+
+	if _, err := c.Execute(ctx, "qmp_capabilities", nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// readLoop dispatches responses and events until the connection closes
+func (c *QMPClient) readLoop(dec *json.Decoder) {
+	defer close(c.done)
+	for {
+		var msg qmpMessage
+		if err := dec.Decode(&msg); err != nil {
+			c.readErr = err
+			return
+		}
+		if msg.Event != "" {
+			select {
+			case c.events <- msg.QMPEvent:
+			default:
+				// Drop the oldest event to make room
+				select {
+				case <-c.events:
+				default:
+				}
+				c.events <- msg.QMPEvent
+			}
+			continue
+		}
+		// Responses to other (cancelled) commands are dropped
+		if string(msg.ID) != strconv.FormatUint(c.pending.Load(), 10) {
+			continue
+		}
+		select {
+		case c.responses <- msg:
+		default:
+		}
+	}
+}
+
+// Execute runs a QMP command and returns its raw result
+func (c *QMPClient) Execute(ctx context.Context, command string, args interface{}) (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastID++
+	id := c.lastID
+	c.pending.Store(id)
+	// Discard a late response to a previously cancelled command
+	select {
+	case <-c.responses:
+	default:
+	}
+
+	req := map[string]interface{}{"execute": command, "id": id}
+	if args != nil {
+		req["arguments"] = args
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to send QMP command %s: %w", command, err)
+	}
+
+	for {
+		select {
+		case msg := <-c.responses:
+			// A response queued just before id was set belongs to an older command
+			if string(msg.ID) != strconv.FormatUint(id, 10) {
+				continue
+			}
+			if msg.Error != nil {
+				return nil, msg.Error
+			}
+			return msg.Return, nil
+		case <-c.done:
+			return nil, fmt.Errorf("QMP connection closed during %s: %v", command, c.readErr)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// QueryStatus returns the VM run state (running, paused, shutdown, ...)
+func (c *QMPClient) QueryStatus(ctx context.Context) (string, error) {
+	raw, err := c.Execute(ctx, "query-status", nil)
+	if err != nil {
+		return "", err
+	}
+	var status struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return "", fmt.Errorf("failed to parse query-status result: %w", err)
+	}
+	return status.Status, nil
+}
+
+// WaitForEvent waits for one of the named events
+// Events received before the call are considered as well.
+func (c *QMPClient) WaitForEvent(ctx context.Context, names ...string) (QMPEvent, error) {
+	for {
+		select {
+		case ev := <-c.events:
+			for _, name := range names {
+				if ev.Event == name {
+					return ev, nil
+				}
+			}
+		case <-c.done:
+			return QMPEvent{}, fmt.Errorf("QMP connection closed: %v", c.readErr)
+		case <-ctx.Done():
+			return QMPEvent{}, ctx.Err()
+		}
+	}
+}
+
+// DrainEvents discards buffered events
+func (c *QMPClient) DrainEvents() {
+	for {
+		select {
+		case <-c.events:
+		default:
+			return
+		}
+	}
+}
+
+// Closed returns a channel that is closed when the connection ends
+// QEMU closes the connection when it exits
+func (c *QMPClient) Closed() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the QMP connection
+func (c *QMPClient) Close() error {
+	return c.conn.Close()
+}
+
+// qmpStateToVMState maps a QMP run state to a VMState
+func qmpStateToVMState(status string) VMState {
+	switch status {
+	case QMPStatusRunning:
+		return VMStateRunning
+	case QMPStatusPaused, "suspended":
+		return VMStatePaused
+	case QMPStatusShutdown:
+		return VMStateStopped
+	case "prelaunch", "inmigrate", "restore-vm":
+		return VMStateStarting
+	case "guest-panicked", "internal-error", "io-error":
+		return VMStateError
+	default:
+		return VMStateUnknown
+	}
+}
+
+// qmpCommand connects to a QMP socket, runs a single command and disconnects
+func qmpCommand(ctx context.Context, socketPath, command string, args interface{}) (json.RawMessage, error) {
+	client, err := DialQMP(ctx, socketPath)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.Execute(ctx, command, args)
+}
+
+// Powerdown asks the guest to power off via ACPI and waits for QEMU to exit
+// If the guest does not shut down within timeout, QEMU is told to quit and
+// an error wrapping ErrShutdownTimeout is returned.
+func (c *QMPClient) Powerdown(ctx context.Context, timeout time.Duration) error {
+	if _, err := c.Execute(ctx, "system_powerdown", nil); err != nil {
+		return fmt.Errorf("failed to request powerdown: %w", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	select {
+	case <-c.Closed():
+		// QEMU exited after the guest powered off
+		return nil
+	case <-waitCtx.Done():
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Guest did not power off in time - stop QEMU
+	if _, err := c.Execute(ctx, "quit", nil); err != nil {
+		select {
+		case <-c.Closed():
+		default:
+			return fmt.Errorf("%w (%v) and quit failed: %v", ErrShutdownTimeout, timeout, err)
+		}
+	}
+	return fmt.Errorf("%w (%v), VM was stopped forcibly", ErrShutdownTimeout, timeout)
+}
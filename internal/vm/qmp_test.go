@@ -0,0 +1,185 @@
+package vm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeQMPServer emulates the parts of QEMU's QMP protocol used by bootc-man
+// handler returns the response line for a command, which is tagged with the
+// command's id; an empty string closes the connection without replying (like
+// QEMU after "quit").
+func fakeQMPServer(t *testing.T, handler func(conn net.Conn, command string, id json.RawMessage) string) string {
+	t.Helper()
+
+	// Unix socket paths are limited in length, so avoid t.TempDir()
+	dir, err := os.MkdirTemp("", "qmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "qmp.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprintln(conn, `{"QMP": {"version": {"qemu": {"major": 9}}, "capabilities": []}}`)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var req struct {
+				Execute string          `json:"execute"`
+				ID      json.RawMessage `json:"id"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+				return
+			}
+			if req.Execute == "qmp_capabilities" {
+				fmt.Fprintln(conn, withQMPID(`{"return": {}}`, req.ID))
+				continue
+			}
+			reply := handler(conn, req.Execute, req.ID)
+			if reply == "" {
+				return
+			}
+			fmt.Fprintln(conn, withQMPID(reply, req.ID))
+		}
+	}()
+
+	return socketPath
+}
+
+// withQMPID adds the id of a command to a QMP response
+func withQMPID(reply string, id json.RawMessage) string {
+	return fmt.Sprintf(`{"id": %s, %s`, id, reply[1:])
+}
+
+func TestQMPQueryStatus(t *testing.T) {
+	socket := fakeQMPServer(t, func(conn net.Conn, command string, id json.RawMessage) string {
+		if command == "query-status" {
+			// An event arriving before the response must not be mistaken for it
+			fmt.Fprintln(conn, `{"event": "RESUME", "timestamp": {"seconds": 1, "microseconds": 0}}`)
+			// Neither must a late response to an earlier command
+			fmt.Fprintln(conn, `{"id": 999, "return": {"status": "running", "running": true}}`)
+			return `{"return": {"status": "paused", "running": false}}`
+		}
+		return `{"error": {"class": "CommandNotFound", "desc": "unknown"}}`
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := DialQMP(ctx, socket)
+	if err != nil {
+		t.Fatalf("DialQMP() error = %v", err)
+	}
+	defer client.Close()
+
+	status, err := client.QueryStatus(ctx)
+	if err != nil {
+		t.Fatalf("QueryStatus() error = %v", err)
+	}
+	if status != QMPStatusPaused {
+		t.Errorf("QueryStatus() = %q, want %q", status, QMPStatusPaused)
+	}
+
+	ev, err := client.WaitForEvent(ctx, QMPEventResume)
+	if err != nil {
+		t.Fatalf("WaitForEvent() error = %v", err)
+	}
+	if ev.Event != QMPEventResume {
+		t.Errorf("WaitForEvent() = %q, want %q", ev.Event, QMPEventResume)
+	}
+
+	_, err = client.Execute(ctx, "bogus", nil)
+	var qmpErr *QMPError
+	if !errors.As(err, &qmpErr) || qmpErr.Class != "CommandNotFound" {
+		t.Errorf("Execute(bogus) error = %v, want QMPError CommandNotFound", err)
+	}
+}
+
+func TestQMPPowerdown(t *testing.T) {
+	tests := []struct {
+		name        string
+		powerOff    bool
+		wantTimeout bool
+	}{
+		{"guest powers off", true, false},
+		{"guest ignores request", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socket := fakeQMPServer(t, func(conn net.Conn, command string, id json.RawMessage) string {
+				switch command {
+				case "system_powerdown":
+					if tt.powerOff {
+						fmt.Fprintln(conn, withQMPID(`{"return": {}}`, id))
+						fmt.Fprintln(conn, `{"event": "SHUTDOWN", "timestamp": {"seconds": 1, "microseconds": 0}}`)
+						return "" // QEMU exits
+					}
+					return `{"return": {}}`
+				case "quit":
+					return ""
+				}
+				return `{"return": {}}`
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			client, err := DialQMP(ctx, socket)
+			if err != nil {
+				t.Fatalf("DialQMP() error = %v", err)
+			}
+			defer client.Close()
+
+			err = client.Powerdown(ctx, 200*time.Millisecond)
+			if got := errors.Is(err, ErrShutdownTimeout); got != tt.wantTimeout {
+				t.Errorf("Powerdown() error = %v, want timeout %v", err, tt.wantTimeout)
+			}
+			if !tt.wantTimeout && err != nil {
+				t.Errorf("Powerdown() unexpected error = %v", err)
+			}
+		})
+	}
+}
+
+func TestQMPStateToVMState(t *testing.T) {
+	tests := []struct {
+		status string
+		want   VMState
+	}{
+		{QMPStatusRunning, VMStateRunning},
+		{QMPStatusPaused, VMStatePaused},
+		{QMPStatusShutdown, VMStateStopped},
+		{"prelaunch", VMStateStarting},
+		{"guest-panicked", VMStateError},
+		{"something-new", VMStateUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := qmpStateToVMState(tt.status); got != tt.want {
+				t.Errorf("qmpStateToVMState(%q) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
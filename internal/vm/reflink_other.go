@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package vm
+
+import "errors"
+
+// reflinkFile is not supported on this platform
+func reflinkFile(src, dst string) error {
+	return errors.New("reflink is not supported on this platform")
+}
@@ -0,0 +1,295 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// CloudInitSeedLabel is the volume label cloud-init's NoCloud datasource looks for
+const CloudInitSeedLabel = "cidata"
+
+const isoSectorSize = 2048
+
+// isoFile is a file stored in the root directory of an ISO image
+type isoFile struct {
+	name   string
+	data   []byte
+	sector uint32
+}
+
+// WriteCloudInitSeed writes a cloud-init NoCloud seed ISO containing
+// user-data and meta-data (and network-config if given)
+func WriteCloudInitSeed(path string, userData, metaData, networkConfig []byte) error {
+	files := map[string][]byte{
+		"user-data": userData,
+		"meta-data": metaData,
+	}
+	if networkConfig != nil {
+		files["network-config"] = networkConfig
+	}
+
+	image, err := buildISO(CloudInitSeedLabel, files, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, image, 0644); err != nil {
+		return fmt.Errorf("failed to write cloud-init seed: %w", err)
+	}
+	return nil
+}
+
+// buildISO builds a minimal ISO 9660 image with a single root directory
+// A Joliet supplementary volume descriptor is included so that file names
+// such as "user-data" are presented as-is by the guest kernel.
+//
+// Layout (2048-byte sectors):
+//
+//	0-15   system area
+//	16     primary volume descriptor
+//	17     Joliet supplementary volume descriptor
+//	18     volume descriptor set terminator
+//	19-22  path tables (primary L/M, Joliet L/M)
+//	23     primary root directory
+//	24     Joliet root directory
+//	25-    file data
+func buildISO(label string, contents map[string][]byte, now time.Time) ([]byte, error) {
+	const (
+		pvdSector        = 16
+		svdSector        = 17
+		termSector       = 18
+		pathTableSector  = 19
+		rootSector       = 23
+		jolietRootSector = 24
+		dataSector       = 25
+	)
+
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make([]isoFile, 0, len(names))
+	next := uint32(dataSector)
+	for _, name := range names {
+		data := contents[name]
+		files = append(files, isoFile{name: name, data: data, sector: next})
+		next += uint32((len(data) + isoSectorSize - 1) / isoSectorSize)
+	}
+	totalSectors := next
+
+	image := make([]byte, int(totalSectors)*isoSectorSize)
+	sector := func(n int) []byte {
+		return image[n*isoSectorSize : (n+1)*isoSectorSize]
+	}
+
+	rootPrimary := buildISODirectory(rootSector, files, now, false)
+	rootJoliet := buildISODirectory(jolietRootSector, files, now, true)
+	if len(rootPrimary) > isoSectorSize || len(rootJoliet) > isoSectorSize {
+		return nil, fmt.Errorf("too many files for ISO root directory")
+	}
+	copy(sector(rootSector), rootPrimary)
+	copy(sector(jolietRootSector), rootJoliet)
+
+	// Path tables: a single entry for the root directory
+	pathTableLen := 10
+	writePathTable(sector(pathTableSector), rootSector, binary.LittleEndian)
+	writePathTable(sector(pathTableSector+1), rootSector, binary.BigEndian)
+	writePathTable(sector(pathTableSector+2), jolietRootSector, binary.LittleEndian)
+	writePathTable(sector(pathTableSector+3), jolietRootSector, binary.BigEndian)
+
+	writeVolumeDescriptor(sector(pvdSector), volumeDescriptor{
+		kind:           1,
+		label:          label,
+		totalSectors:   totalSectors,
+		pathTableLen:   pathTableLen,
+		lPathTable:     pathTableSector,
+		mPathTable:     pathTableSector + 1,
+		rootDirSector:  rootSector,
+		rootDirSectors: 1,
+		now:            now,
+	})
+	writeVolumeDescriptor(sector(svdSector), volumeDescriptor{
+		kind:           2,
+		joliet:         true,
+		label:          label,
+		totalSectors:   totalSectors,
+		pathTableLen:   pathTableLen,
+		lPathTable:     pathTableSector + 2,
+		mPathTable:     pathTableSector + 3,
+		rootDirSector:  jolietRootSector,
+		rootDirSectors: 1,
+		now:            now,
+	})
+
+	term := sector(termSector)
+	term[0] = 255
+	copy(term[1:6], "CD001")
+	term[6] = 1
+
+	for _, f := range files {
+		copy(image[int(f.sector)*isoSectorSize:], f.data)
+	}
+
+	return image, nil
+}
+
+// volumeDescriptor holds the values written into a primary or Joliet
+// supplementary volume descriptor
+type volumeDescriptor struct {
+	kind           byte // 1 = primary, 2 = supplementary
+	joliet         bool
+	label          string
+	totalSectors   uint32
+	pathTableLen   int
+	lPathTable     uint32
+	mPathTable     uint32
+	rootDirSector  uint32
+	rootDirSectors uint32
+	now            time.Time
+}
+
+// writeVolumeDescriptor fills a 2048-byte volume descriptor sector
+func writeVolumeDescriptor(b []byte, vd volumeDescriptor) {
+	b[0] = vd.kind
+	copy(b[1:6], "CD001")
+	b[6] = 1
+
+	if vd.joliet {
+		copy(b[8:40], jolietString("", 32))
+		copy(b[40:72], jolietString(vd.label, 32))
+		// UCS-2 Level 3 escape sequence
+		copy(b[88:91], "%/E")
+	} else {
+		copy(b[8:40], padString("", 32))
+		copy(b[40:72], padString(vd.label, 32))
+	}
+
+	putBothEndian32(b[80:88], vd.totalSectors)
+	putBothEndian16(b[120:124], 1) // volume set size
+	putBothEndian16(b[124:128], 1) // volume sequence number
+	putBothEndian16(b[128:132], isoSectorSize)
+	putBothEndian32(b[132:140], uint32(vd.pathTableLen))
+	binary.LittleEndian.PutUint32(b[140:144], vd.lPathTable)
+	binary.BigEndian.PutUint32(b[148:152], vd.mPathTable)
+
+	copy(b[156:190], isoDirectoryRecord([]byte{0}, vd.rootDirSector, vd.rootDirSectors*isoSectorSize, true, vd.now))
+
+	// Volume set, publisher, preparer, application and file identifiers
+	for _, field := range [][2]int{{190, 318}, {318, 446}, {446, 574}, {574, 702}, {702, 739}, {739, 776}, {776, 813}} {
+		if vd.joliet {
+			copy(b[field[0]:field[1]], jolietString("", field[1]-field[0]))
+		} else {
+			copy(b[field[0]:field[1]], padString("", field[1]-field[0]))
+		}
+	}
+
+	created := isoDecDateTime(vd.now)
+	copy(b[813:830], created)
+	copy(b[830:847], created)
+	copy(b[847:864], isoDecDateTime(time.Time{}))
+	copy(b[864:881], isoDecDateTime(time.Time{}))
+	b[881] = 1 // file structure version
+}
+
+// buildISODirectory builds the root directory extent including "." and ".."
+func buildISODirectory(self uint32, files []isoFile, now time.Time, joliet bool) []byte {
+	var buf bytes.Buffer
+	buf.Write(isoDirectoryRecord([]byte{0}, self, isoSectorSize, true, now))
+	buf.Write(isoDirectoryRecord([]byte{1}, self, isoSectorSize, true, now))
+	for _, f := range files {
+		var id []byte
+		if joliet {
+			id = []byte(jolietString(f.name, 2*len(utf16.Encode([]rune(f.name)))))
+		} else {
+			id = []byte(strings.ToUpper(f.name) + ";1")
+		}
+		buf.Write(isoDirectoryRecord(id, f.sector, uint32(len(f.data)), false, now))
+	}
+	return buf.Bytes()
+}
+
+// isoDirectoryRecord encodes an ISO 9660 directory record
+func isoDirectoryRecord(id []byte, sector, size uint32, dir bool, now time.Time) []byte {
+	length := 33 + len(id)
+	if length%2 != 0 {
+		length++
+	}
+	r := make([]byte, length)
+	r[0] = byte(length)
+	putBothEndian32(r[2:10], sector)
+	putBothEndian32(r[10:18], size)
+	r[18] = byte(now.Year() - 1900)
+	r[19] = byte(now.Month())
+	r[20] = byte(now.Day())
+	r[21] = byte(now.Hour())
+	r[22] = byte(now.Minute())
+	r[23] = byte(now.Second())
+	if dir {
+		r[25] = 2
+	}
+	putBothEndian16(r[28:32], 1)
+	r[32] = byte(len(id))
+	copy(r[33:], id)
+	return r
+}
+
+// writePathTable writes a path table with only the root directory
+func writePathTable(b []byte, rootSector uint32, order binary.ByteOrder) {
+	b[0] = 1 // directory identifier length
+	order.PutUint32(b[2:6], rootSector)
+	order.PutUint16(b[6:8], 1) // parent directory number
+}
+
+// isoDecDateTime encodes a volume descriptor timestamp
+// The zero time is encoded as "not specified".
+func isoDecDateTime(t time.Time) []byte {
+	b := make([]byte, 17)
+	if t.IsZero() {
+		copy(b, "0000000000000000")
+		return b
+	}
+	copy(b, t.Format("20060102150405")+"00")
+	return b
+}
+
+// padString pads s with spaces to n bytes
+func padString(s string, n int) string {
+	if len(s) > n {
+		s = s[:n]
+	}
+	return s + strings.Repeat(" ", n-len(s))
+}
+
+// jolietString encodes s as UCS-2 big endian padded with spaces to n bytes
+func jolietString(s string, n int) string {
+	var buf bytes.Buffer
+	for _, c := range utf16.Encode([]rune(s)) {
+		if buf.Len()+2 > n {
+			break
+		}
+		_ = binary.Write(&buf, binary.BigEndian, c)
+	}
+	for buf.Len()+2 <= n {
+		buf.Write([]byte{0, ' '})
+	}
+	return buf.String()
+}
+
+// putBothEndian16 writes v as little endian followed by big endian
+func putBothEndian16(b []byte, v uint16) {
+	binary.LittleEndian.PutUint16(b[0:2], v)
+	binary.BigEndian.PutUint16(b[2:4], v)
+}
+
+// putBothEndian32 writes v as little endian followed by big endian
+func putBothEndian32(b []byte, v uint32) {
+	binary.LittleEndian.PutUint32(b[0:4], v)
+	binary.BigEndian.PutUint32(b[4:8], v)
+}
@@ -0,0 +1,66 @@
+package vm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteCloudInitSeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seed.iso")
+	userData := []byte("#cloud-config\nusers: []\n")
+	metaData := []byte("instance-id: test\nlocal-hostname: test\n")
+
+	if err := WriteCloudInitSeed(path, userData, metaData, nil); err != nil {
+		t.Fatalf("WriteCloudInitSeed() error = %v", err)
+	}
+
+	format, err := DetectImageFormat(path)
+	if err != nil {
+		t.Fatalf("DetectImageFormat() error = %v", err)
+	}
+	if format != ImageFormatISO {
+		t.Errorf("DetectImageFormat() = %q, want %q", format, ImageFormatISO)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read seed: %v", err)
+	}
+	if len(data)%isoSectorSize != 0 {
+		t.Errorf("image size %d is not a multiple of %d", len(data), isoSectorSize)
+	}
+
+	// Primary volume identifier
+	label := string(bytes.TrimRight(data[16*isoSectorSize+40:16*isoSectorSize+72], " "))
+	if label != CloudInitSeedLabel {
+		t.Errorf("volume label = %q, want %q", label, CloudInitSeedLabel)
+	}
+
+	for _, want := range [][]byte{userData, metaData, []byte("USER-DATA;1"), []byte("META-DATA;1")} {
+		if !bytes.Contains(data, want) {
+			t.Errorf("seed image does not contain %q", want)
+		}
+	}
+}
+
+func TestBuildISOFileLayout(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), isoSectorSize+1)
+	image, err := buildISO("test", map[string][]byte{"a": large, "b": []byte("b")}, time.Unix(0, 0).UTC())
+	if err != nil {
+		t.Fatalf("buildISO() error = %v", err)
+	}
+
+	// 25 fixed sectors, two sectors for "a" and one for "b"
+	if want := 28 * isoSectorSize; len(image) != want {
+		t.Errorf("len(image) = %d, want %d", len(image), want)
+	}
+	if !bytes.Equal(image[25*isoSectorSize:25*isoSectorSize+len(large)], large) {
+		t.Error("file a is not stored at sector 25")
+	}
+	if image[27*isoSectorSize] != 'b' {
+		t.Error("file b is not stored at sector 27")
+	}
+}
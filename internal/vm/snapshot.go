@@ -0,0 +1,142 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/config"
+)
+
+// SnapshotInfo describes a saved VM snapshot
+type SnapshotInfo struct {
+	Name    string    `json:"name"`    // スナップショット名
+	Created time.Time `json:"created"` // 作成日時
+}
+
+var snapshotNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateSnapshotName checks that a snapshot name is usable with qemu-img
+func ValidateSnapshotName(name string) error {
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// SnapshotDisk returns the disk that holds the VM's snapshots
+// For ISO-installed VMs this is the install target disk
+func (info *VMInfo) SnapshotDisk() string {
+	if info.InstallDisk != "" {
+		return info.InstallDisk
+	}
+	return info.DiskImage
+}
+
+// FindSnapshot returns the snapshot with the given name, or nil
+func (info *VMInfo) FindSnapshot(name string) *SnapshotInfo {
+	for i := range info.Snapshots {
+		if info.Snapshots[i].Name == name {
+			return &info.Snapshots[i]
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot saves the current disk state of a stopped VM as a snapshot
+// Snapshots are qcow2 internal snapshots stored in the VM's own disk (the
+// overlay), so the shared base image is never modified.
+func SaveSnapshot(ctx context.Context, info *VMInfo, name string, verbose bool) error {
+	if err := ValidateSnapshotName(name); err != nil {
+		return err
+	}
+	if info.FindSnapshot(name) != nil {
+		return fmt.Errorf("snapshot %q already exists for VM '%s'", name, info.Name)
+	}
+	if err := checkSnapshotDisk(info); err != nil {
+		return err
+	}
+
+	if err := runQemuImgSnapshot(ctx, verbose, "-c", name, info.SnapshotDisk()); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	info.Snapshots = append(info.Snapshots, SnapshotInfo{Name: name, Created: time.Now()})
+	return SaveVMInfo(info)
+}
+
+// RevertSnapshot restores the disk of a stopped VM to a saved snapshot
+// The snapshot is kept so the VM can be reverted to it again.
+func RevertSnapshot(ctx context.Context, info *VMInfo, name string, verbose bool) error {
+	if info.FindSnapshot(name) == nil {
+		return fmt.Errorf("snapshot %q not found for VM '%s'", name, info.Name)
+	}
+	if err := checkSnapshotDisk(info); err != nil {
+		return err
+	}
+
+	if err := runQemuImgSnapshot(ctx, verbose, "-a", name, info.SnapshotDisk()); err != nil {
+		return fmt.Errorf("failed to revert snapshot: %w", err)
+	}
+	return nil
+}
+
+// DeleteSnapshot deletes a saved snapshot of a stopped VM
+func DeleteSnapshot(ctx context.Context, info *VMInfo, name string, verbose bool) error {
+	if info.FindSnapshot(name) == nil {
+		return fmt.Errorf("snapshot %q not found for VM '%s'", name, info.Name)
+	}
+	if err := checkSnapshotDisk(info); err != nil {
+		return err
+	}
+
+	if err := runQemuImgSnapshot(ctx, verbose, "-d", name, info.SnapshotDisk()); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+
+	snapshots := info.Snapshots[:0]
+	for _, s := range info.Snapshots {
+		if s.Name != name {
+			snapshots = append(snapshots, s)
+		}
+	}
+	info.Snapshots = snapshots
+	return SaveVMInfo(info)
+}
+
+// checkSnapshotDisk verifies that snapshots can be taken of the VM's disk
+func checkSnapshotDisk(info *VMInfo) error {
+	if IsVMRunning(info) {
+		return fmt.Errorf("VM '%s' is running. Stop it first: bootc-man vm stop %s", info.Name, info.Name)
+	}
+
+	disk := info.SnapshotDisk()
+	format, err := DetectImageFormat(disk)
+	if err != nil {
+		return err
+	}
+	if format != ImageFormatQcow2 {
+		return fmt.Errorf("snapshots require a qcow2 VM disk, but %s is %s\n   Recreate the VM on QEMU with qemu-img installed to get a qcow2 overlay disk", disk, format)
+	}
+
+	if _, err := exec.LookPath(config.BinaryQemuImg); err != nil {
+		return fmt.Errorf("qemu-img is not installed. Install it: sudo dnf install qemu-img")
+	}
+	return nil
+}
+
+// runQemuImgSnapshot runs "qemu-img snapshot <op> <name> <disk>"
+func runQemuImgSnapshot(ctx context.Context, verbose bool, op, name, disk string) error {
+	args := []string{"snapshot", op, name, disk}
+	if verbose {
+		fmt.Printf("Running: %s %s\n", config.BinaryQemuImg, strings.Join(args, " "))
+	}
+	output, err := exec.CommandContext(ctx, config.BinaryQemuImg, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
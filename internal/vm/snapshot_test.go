@@ -0,0 +1,104 @@
+package vm
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateSnapshotName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"clean", false},
+		{"before-upgrade_1.0", false},
+		{"", true},
+		{"-leading-dash", true},
+		{"has space", true},
+		{"semi;colon", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSnapshotName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSnapshotName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVMInfoSnapshotDisk(t *testing.T) {
+	info := &VMInfo{DiskImage: "/vms/a.qcow2"}
+	if got := info.SnapshotDisk(); got != "/vms/a.qcow2" {
+		t.Errorf("SnapshotDisk() = %q, want disk image", got)
+	}
+
+	info.InstallDisk = "/vms/a-install.qcow2"
+	if got := info.SnapshotDisk(); got != "/vms/a-install.qcow2" {
+		t.Errorf("SnapshotDisk() = %q, want install disk", got)
+	}
+}
+
+func TestSaveSnapshotRequiresQcow2(t *testing.T) {
+	dir := t.TempDir()
+	disk := filepath.Join(dir, "vm.raw")
+	_ = os.WriteFile(disk, []byte("raw disk"), 0644)
+
+	info := &VMInfo{Name: "raw-vm", DiskImage: disk}
+	if err := SaveSnapshot(context.Background(), info, "clean", false); err == nil {
+		t.Error("SaveSnapshot() expected error for raw disk, got nil")
+	}
+	if len(info.Snapshots) != 0 {
+		t.Errorf("Snapshots = %v, want none", info.Snapshots)
+	}
+}
+
+func TestSnapshotLifecycle(t *testing.T) {
+	if _, err := exec.LookPath("qemu-img"); err != nil {
+		t.Skip("qemu-img not installed")
+	}
+
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+
+	disk := filepath.Join(tmpDir, "vm.qcow2")
+	if output, err := exec.Command("qemu-img", "create", "-q", "-f", "qcow2", disk, "16M").CombinedOutput(); err != nil {
+		t.Fatalf("failed to create qcow2 disk: %v: %s", err, output)
+	}
+
+	ctx := context.Background()
+	info := &VMInfo{Name: "snap-vm", DiskImage: disk}
+
+	if err := SaveSnapshot(ctx, info, "clean", false); err != nil {
+		t.Fatalf("SaveSnapshot() error = %v", err)
+	}
+	if err := SaveSnapshot(ctx, info, "clean", false); err == nil {
+		t.Error("SaveSnapshot() expected error for duplicate name, got nil")
+	}
+
+	loaded, err := LoadVMInfo("snap-vm")
+	if err != nil {
+		t.Fatalf("LoadVMInfo() error = %v", err)
+	}
+	if loaded.FindSnapshot("clean") == nil {
+		t.Fatal("snapshot metadata was not saved with VM info")
+	}
+
+	if err := RevertSnapshot(ctx, loaded, "clean", false); err != nil {
+		t.Errorf("RevertSnapshot() error = %v", err)
+	}
+	if err := RevertSnapshot(ctx, loaded, "missing", false); err == nil {
+		t.Error("RevertSnapshot() expected error for unknown snapshot, got nil")
+	}
+
+	if err := DeleteSnapshot(ctx, loaded, "clean", false); err != nil {
+		t.Fatalf("DeleteSnapshot() error = %v", err)
+	}
+	if loaded.FindSnapshot("clean") != nil {
+		t.Error("snapshot still present after DeleteSnapshot()")
+	}
+}
@@ -0,0 +1,247 @@
+package vm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/sshclient"
+)
+
+// sshKeyName is the file name of generated SSH private keys
+const sshKeyName = "id_ed25519"
+
+// GetSSHDir returns the directory holding bootc-man managed SSH keys and
+// the known_hosts file
+// On macOS/Linux: ~/.local/share/bootc-man/ssh/
+// On Windows: %APPDATA%/bootc-man/ssh/
+func GetSSHDir() (string, error) {
+	baseDir, err := getDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, "ssh"), nil
+}
+
+// VMSSHKeyDir returns the directory of the generated keypair for a VM
+func VMSSHKeyDir(name string) (string, error) {
+	sshDir, err := GetSSHDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(sshDir, name), nil
+}
+
+// EnsureVMSSHKey returns the private key path of the VM's dedicated keypair,
+// generating the keypair on first use
+func EnsureVMSSHKey(name string) (string, error) {
+	keyDir, err := VMSSHKeyDir(name)
+	if err != nil {
+		return "", err
+	}
+	return EnsureSSHKeyPair(keyDir, fmt.Sprintf("bootc-man@%s", name))
+}
+
+// EnsureSSHKeyPair generates an ed25519 keypair without passphrase in dir
+// unless one exists already, and returns the private key path
+func EnsureSSHKeyPair(dir, comment string) (string, error) {
+	keyPath := filepath.Join(dir, sshKeyName)
+	if _, err := os.Stat(keyPath); err == nil {
+		if _, err := os.Stat(keyPath + ".pub"); err == nil {
+			return keyPath, nil
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create SSH key directory: %w", err)
+	}
+	os.Remove(keyPath)
+	os.Remove(keyPath + ".pub")
+
+	cmd := exec.Command(config.BinarySSHKeygen, "-q", "-t", "ed25519", "-N", "", "-C", comment, "-f", keyPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to generate SSH key: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return keyPath, nil
+}
+
+// RemoveVMSSHKey deletes the generated keypair of a VM
+func RemoveVMSSHKey(name string) error {
+	keyDir, err := VMSSHKeyDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(keyDir); err != nil {
+		return fmt.Errorf("failed to remove SSH key: %w", err)
+	}
+	return nil
+}
+
+// KnownHostsFile returns the bootc-man owned known_hosts file
+// VM host keys are recorded here instead of the user's ~/.ssh/known_hosts.
+func KnownHostsFile() (string, error) {
+	sshDir, err := GetSSHDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(sshDir, "known_hosts"), nil
+}
+
+// HostKeyAlias returns the name under which a VM's host key is recorded
+// All VMs are reached via localhost on changing ports, so the key is stored
+// per VM name rather than per host and port.
+func HostKeyAlias(name string) string {
+	return fmt.Sprintf("bootc-man-%s", name)
+}
+
+// SSHHostKeyOptions returns ssh options that verify the VM's host key
+// against the managed known_hosts file, trusting it on first connect
+func SSHHostKeyOptions(name string) []string {
+	knownHosts, err := KnownHostsFile()
+	if err != nil {
+		return []string{"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null"}
+	}
+	// ssh creates the file but not its directory
+	_ = os.MkdirAll(filepath.Dir(knownHosts), 0700)
+	return []string{
+		"-o", fmt.Sprintf("UserKnownHostsFile=%s", knownHosts),
+		"-o", "GlobalKnownHostsFile=/dev/null",
+		"-o", fmt.Sprintf("HostKeyAlias=%s", HostKeyAlias(name)),
+		"-o", "StrictHostKeyChecking=accept-new",
+		"-o", "HashKnownHosts=no",
+	}
+}
+
+// ForgetHostKey removes the recorded host key of a VM
+// Called when the VM disk is recreated, since it then gets a new host key.
+func ForgetHostKey(name string) error {
+	knownHosts, err := KnownHostsFile()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(knownHosts)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	filtered, removed := removeKnownHost(data, HostKeyAlias(name))
+	if !removed {
+		return nil
+	}
+	if err := os.WriteFile(knownHosts, filtered, 0600); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// removeKnownHost drops the known_hosts lines whose host patterns include host
+func removeKnownHost(data []byte, host string) ([]byte, bool) {
+	var out bytes.Buffer
+	removed := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if knownHostsLineMatches(line, host) {
+			removed = true
+			continue
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), removed
+}
+
+// knownHostsLineMatches reports whether a known_hosts line lists host
+func knownHostsLineMatches(line, host string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return false
+	}
+	patterns := fields[0]
+	if strings.HasPrefix(patterns, "@") && len(fields) > 1 {
+		// @cert-authority / @revoked marker
+		patterns = fields[1]
+	}
+	for _, p := range strings.Split(patterns, ",") {
+		if p == host || strings.HasPrefix(p, "["+host+"]:") {
+			return true
+		}
+	}
+	return false
+}
+
+// SSHClientConfig returns the native SSH client configuration for a VM
+// The host key is verified against the managed known_hosts file under the
+// VM's host key alias, and recorded on first connect.
+func SSHClientConfig(name string, cfg SSHConfig) sshclient.Config {
+	knownHosts, _ := KnownHostsFile()
+	return sshclient.Config{
+		Host:              cfg.Host,
+		Port:              cfg.Port,
+		User:              cfg.User,
+		IdentityFiles:     []string{cfg.KeyPath},
+		KnownHostsFile:    knownHosts,
+		HostKeyAlias:      HostKeyAlias(name),
+		AcceptNewHostKeys: true,
+	}
+}
+
+// NewSSHClient returns a native SSH client for a VM
+func NewSSHClient(name string, cfg SSHConfig) *sshclient.Client {
+	return sshclient.New(SSHClientConfig(name, cfg))
+}
+
+// driverSSH caches the SSH client of a driver so that consecutive commands
+// share one connection
+type driverSSH struct {
+	mu     sync.Mutex
+	client *sshclient.Client
+}
+
+// get returns the cached client, replacing it when the VM's SSH
+// configuration (e.g. the forwarded port) has changed
+func (s *driverSSH) get(name string, cfg SSHConfig) *sshclient.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	want := SSHClientConfig(name, cfg)
+	if s.client != nil {
+		got := s.client.Config()
+		if got.Address() == want.Address() && got.User == want.User && got.IdentityFiles[0] == cfg.KeyPath {
+			return s.client
+		}
+		s.client.Close()
+	}
+	s.client = sshclient.New(want)
+	return s.client
+}
+
+// probe opens a fresh connection and runs a trivial command
+// Used while waiting for sshd, where a cached connection may be stale.
+func (s *driverSSH) probe(ctx context.Context, name string, cfg SSHConfig) error {
+	client := s.get(name, cfg)
+	client.Close()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	return client.Run(ctx, "true", nil, nil)
+}
+
+// close closes the cached connection
+func (s *driverSSH) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+}
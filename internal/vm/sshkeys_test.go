@@ -0,0 +1,85 @@
+package vm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnsureSSHKeyPair(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+
+	dir := filepath.Join(t.TempDir(), "test-vm")
+	keyPath, err := EnsureSSHKeyPair(dir, "bootc-man@test-vm")
+	if err != nil {
+		t.Fatalf("EnsureSSHKeyPair() error = %v", err)
+	}
+
+	pub, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		t.Fatalf("public key not written: %v", err)
+	}
+	if !strings.HasPrefix(string(pub), "ssh-ed25519 ") || !strings.Contains(string(pub), "bootc-man@test-vm") {
+		t.Errorf("unexpected public key: %q", pub)
+	}
+	info, err := os.Stat(keyPath)
+	if err != nil {
+		t.Fatalf("private key not written: %v", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		t.Errorf("private key mode = %v, want no group/other access", info.Mode().Perm())
+	}
+
+	// A second call keeps the existing keypair
+	again, err := EnsureSSHKeyPair(dir, "bootc-man@test-vm")
+	if err != nil {
+		t.Fatalf("EnsureSSHKeyPair() second call error = %v", err)
+	}
+	pubAgain, _ := os.ReadFile(again + ".pub")
+	if string(pubAgain) != string(pub) {
+		t.Error("EnsureSSHKeyPair() regenerated an existing keypair")
+	}
+}
+
+func TestRemoveKnownHost(t *testing.T) {
+	known := strings.Join([]string{
+		"bootc-man-web ssh-ed25519 AAAAweb",
+		"bootc-man-web-2 ssh-ed25519 AAAAweb2",
+		"[bootc-man-web]:2222 ssh-rsa AAAAport",
+		"other,bootc-man-web ecdsa-sha2-nistp256 AAAAlist",
+		"@revoked bootc-man-web ssh-ed25519 AAAArevoked",
+		"# bootc-man-web comment",
+		"",
+	}, "\n")
+
+	got, removed := removeKnownHost([]byte(known), "bootc-man-web")
+	if !removed {
+		t.Fatal("removeKnownHost() removed = false, want true")
+	}
+	want := "bootc-man-web-2 ssh-ed25519 AAAAweb2\n# bootc-man-web comment\n"
+	if string(got) != want {
+		t.Errorf("removeKnownHost() = %q, want %q", got, want)
+	}
+
+	if _, removed := removeKnownHost([]byte(want), "bootc-man-db"); removed {
+		t.Error("removeKnownHost() removed = true for unknown host")
+	}
+}
+
+func TestSSHHostKeyOptions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	opts := strings.Join(SSHHostKeyOptions("web"), " ")
+	for _, want := range []string{"HostKeyAlias=bootc-man-web", "StrictHostKeyChecking=accept-new", "UserKnownHostsFile="} {
+		if !strings.Contains(opts, want) {
+			t.Errorf("SSHHostKeyOptions() = %q, missing %q", opts, want)
+		}
+	}
+	if strings.Contains(opts, "UserKnownHostsFile=/dev/null") || strings.Contains(opts, "StrictHostKeyChecking=no") {
+		t.Errorf("SSHHostKeyOptions() disables host key checking: %q", opts)
+	}
+}
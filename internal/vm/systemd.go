@@ -0,0 +1,54 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// systemdStartTimeout is the time a unit gives vm start to boot the VM and
+// reach SSH (emulated guests boot slowly, so this is generous)
+const systemdStartTimeout = 600
+
+// SystemdUnitName returns the name of the systemd user unit of a VM
+func SystemdUnitName(vmName string) string {
+	return fmt.Sprintf("bootc-man-vm-%s.service", vmName)
+}
+
+// SystemdUnit returns a systemd user unit that starts a VM with the user's
+// service manager and shuts it down cleanly when the manager stops
+// vm start exits once the VM is up, so the unit is a oneshot service that
+// remains active; QEMU and its helpers stay in the unit's cgroup and are
+// cleaned up after vm stop. path is the PATH the hypervisor and helper
+// binaries are looked up in.
+func SystemdUnit(vmName, executable, path string, stopTimeout int) string {
+	exe := systemdQuote(executable)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", SystemdUnitName(vmName))
+	fmt.Fprintf(&b, "# autogenerated by bootc-man vm generate systemd\n")
+	fmt.Fprintf(&b, "\n[Unit]\n")
+	fmt.Fprintf(&b, "Description=bootc-man VM %s\n", vmName)
+	fmt.Fprintf(&b, "\n[Service]\n")
+	fmt.Fprintf(&b, "Type=oneshot\n")
+	fmt.Fprintf(&b, "RemainAfterExit=yes\n")
+	if path != "" {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote("PATH="+path))
+	}
+	fmt.Fprintf(&b, "ExecStart=%s vm start %s\n", exe, vmName)
+	fmt.Fprintf(&b, "ExecStop=%s vm stop %s --timeout %d\n", exe, vmName, stopTimeout)
+	fmt.Fprintf(&b, "TimeoutStartSec=%d\n", systemdStartTimeout)
+	fmt.Fprintf(&b, "TimeoutStopSec=%d\n", stopTimeout+30)
+	fmt.Fprintf(&b, "\n[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=default.target\n")
+	return b.String()
+}
+
+// systemdQuote escapes specifiers and quotes a word of a unit file if needed
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if !strings.ContainsAny(s, " \t\"\\") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
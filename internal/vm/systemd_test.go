@@ -0,0 +1,51 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSystemdUnit(t *testing.T) {
+	unit := SystemdUnit("dev", "/usr/bin/bootc-man", "/usr/local/bin:/usr/bin", 60)
+	for _, s := range []string{
+		"# bootc-man-vm-dev.service\n",
+		"Type=oneshot\nRemainAfterExit=yes\n",
+		"Environment=PATH=/usr/local/bin:/usr/bin\n",
+		"ExecStart=/usr/bin/bootc-man vm start dev\n",
+		"ExecStop=/usr/bin/bootc-man vm stop dev --timeout 60\n",
+		"TimeoutStopSec=90\n",
+		"WantedBy=default.target\n",
+	} {
+		if !strings.Contains(unit, s) {
+			t.Errorf("SystemdUnit() missing %q:\n%s", s, unit)
+		}
+	}
+
+	// Paths with spaces are quoted and specifiers are escaped
+	unit = SystemdUnit("dev", "/opt/my tools/bootc-man", "/opt/100%/bin", 60)
+	for _, s := range []string{
+		`ExecStart="/opt/my tools/bootc-man" vm start dev`,
+		"Environment=PATH=/opt/100%%/bin\n",
+	} {
+		if !strings.Contains(unit, s) {
+			t.Errorf("SystemdUnit() missing %q:\n%s", s, unit)
+		}
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/usr/bin/bootc-man", "/usr/bin/bootc-man"},
+		{"/a b", `"/a b"`},
+		{`/a"b c`, `"/a\"b c"`},
+		{"%h/bin", "%%h/bin"},
+	}
+	for _, tt := range tests {
+		if got := systemdQuote(tt.in); got != tt.want {
+			t.Errorf("systemdQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -22,6 +23,7 @@ import (
 type VfkitDriver struct {
 	opts                 VMOptions
 	verbose              bool
+	ssh                  driverSSH
 	cmd                  *exec.Cmd
 	sshConfig            SSHConfig
 	logFile              string
@@ -43,6 +45,15 @@ func generateMACAddressDarwin(vmName string) string {
 	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", hash[0], hash[1], hash[2])
 }
 
+// buildVfkitSharedDirArgs returns the vfkit devices for shared directories
+func buildVfkitSharedDirArgs(mounts []SharedDir) []string {
+	var args []string
+	for _, m := range mounts {
+		args = append(args, "--device", fmt.Sprintf("virtio-fs,sharedDir=%s,mountTag=%s", m.Source, m.Tag))
+	}
+	return args
+}
+
 // NewVfkitDriver creates a new vfkit driver
 func NewVfkitDriver(opts VMOptions, verbose bool) (*VfkitDriver, error) {
 	// Set defaults
@@ -127,6 +138,36 @@ func (d *VfkitDriver) Start(ctx context.Context, opts VMOptions) error {
 	if err := d.Available(); err != nil {
 		return err
 	}
+	for _, m := range d.opts.Mounts {
+		if m.ReadOnly {
+			return fmt.Errorf("read-only mounts are not supported by vfkit: %s", m)
+		}
+	}
+	// Virtualization.framework cannot emulate other CPU architectures
+	arch, err := NormalizeArch(d.opts.Arch)
+	if err != nil {
+		return err
+	}
+	if arch != HostArch() {
+		return fmt.Errorf("vfkit cannot run %s images on a %s host", arch, HostArch())
+	}
+	d.opts.Arch = arch
+	// vfkit's EFI bootloader has no secure boot mode and no TPM device
+	if d.opts.SecureBoot {
+		return fmt.Errorf("secure boot is not supported by vfkit (use the QEMU driver on Linux)")
+	}
+	if d.opts.TPM {
+		return fmt.Errorf("TPM is not supported by vfkit (use the QEMU driver on Linux)")
+	}
+	if d.opts.Lab != nil {
+		return fmt.Errorf("lab networks are not supported by vfkit (use the QEMU driver on Linux)")
+	}
+	if d.opts.Firmware == FirmwareBIOS {
+		return fmt.Errorf("BIOS boot is not supported by vfkit (use the QEMU driver on Linux)")
+	}
+	if d.opts.NICModel != "" && d.opts.NICModel != NICModelVirtio {
+		return fmt.Errorf("NIC model %s is not supported by vfkit (use the QEMU driver on Linux)", d.opts.NICModel)
+	}
 
 	// Start gvproxy for networking
 	if err := d.startGvproxy(ctx); err != nil {
@@ -152,6 +193,20 @@ func (d *VfkitDriver) Start(ctx context.Context, opts VMOptions) error {
 	}
 	args = append(args, "--device", fmt.Sprintf("virtio-blk,path=%s", d.opts.DiskImage))
 
+	// First-boot provisioning data
+	// Ignition is served by vfkit over vsock; the cloud-init seed is a second disk
+	if p := d.opts.Provisioning; p != nil {
+		if p.IgnitionFile != "" {
+			args = append(args, "--ignition", p.IgnitionFile)
+		}
+		if p.CloudInitSeed != "" {
+			args = append(args, "--device", fmt.Sprintf("virtio-blk,path=%s", p.CloudInitSeed))
+		}
+	}
+
+	// Shared host directories (virtio-fs is built into Virtualization.framework)
+	args = append(args, buildVfkitSharedDirArgs(d.opts.Mounts)...)
+
 	// Networking via gvproxy
 	// Unique MAC address per VM allows multiple VMs and avoids conflict with podman machine
 	args = append(args, "--device", fmt.Sprintf("virtio-net,unixSocketPath=%s,mac=%s", d.gvproxySocket, d.macAddress))
@@ -296,10 +351,12 @@ func (d *VfkitDriver) stopGvproxy() {
 	_ = os.Remove(d.gvproxyServiceSocket)
 }
 
-// Stop stops the VM
+// Stop stops the VM immediately
 func (d *VfkitDriver) Stop(ctx context.Context) error {
-	// Try graceful shutdown via RESTful API first
-	if err := d.requestVMState(ctx, "Stopping"); err == nil {
+	d.ssh.close()
+
+	// Try to stop via RESTful API first
+	if err := d.requestVMState(ctx, "HardStop"); err == nil {
 		// Wait for VM to stop
 		for i := 0; i < 10; i++ {
 			time.Sleep(500 * time.Millisecond)
@@ -322,6 +379,42 @@ func (d *VfkitDriver) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Shutdown asks the guest to power off and waits up to timeout
+// The VM is stopped forcibly if the guest does not power off in time.
+func (d *VfkitDriver) Shutdown(ctx context.Context, timeout time.Duration) error {
+	if err := d.requestVMState(ctx, "Stop"); err != nil {
+		_ = d.Stop(ctx)
+		return fmt.Errorf("failed to request guest shutdown: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if state, _ := d.GetState(ctx); state == VMStateStopped {
+			return d.Stop(ctx)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	_ = d.Stop(ctx)
+	return fmt.Errorf("%w (%v), VM was stopped forcibly", ErrShutdownTimeout, timeout)
+}
+
+// Pause suspends VM execution
+func (d *VfkitDriver) Pause(ctx context.Context) error {
+	if err := d.requestVMState(ctx, "Pause"); err != nil {
+		return fmt.Errorf("failed to pause VM: %w", err)
+	}
+	return nil
+}
+
+// Resume continues a paused VM
+func (d *VfkitDriver) Resume(ctx context.Context) error {
+	if err := d.requestVMState(ctx, "Resume"); err != nil {
+		return fmt.Errorf("failed to resume VM: %w", err)
+	}
+	return nil
+}
+
 // GetState returns the current VM state
 func (d *VfkitDriver) GetState(ctx context.Context) (VMState, error) {
 	// Query RESTful API
@@ -350,6 +443,8 @@ func (d *VfkitDriver) GetState(ctx context.Context) (VMState, error) {
 		return VMStateRunning, nil
 	case "VirtualMachineStateStopped":
 		return VMStateStopped, nil
+	case "VirtualMachineStatePaused":
+		return VMStatePaused, nil
 	default:
 		return VMStateUnknown, nil
 	}
@@ -527,32 +622,18 @@ func (d *VfkitDriver) WaitForSSH(ctx context.Context) error {
 
 // testSSHConnection tests if SSH connection works
 func (d *VfkitDriver) testSSHConnection(ctx context.Context) error {
-	args := d.buildSSHArgs("echo connected")
-	cmd := exec.CommandContext(ctx, "ssh", args...)
-	return cmd.Run()
+	return d.ssh.probe(ctx, d.opts.Name, d.sshConfig)
 }
 
-// SSH executes a command via SSH
+// SSH executes a command via SSH and returns the combined output
 func (d *VfkitDriver) SSH(ctx context.Context, command string) (string, error) {
-	args := d.buildSSHArgs(command)
-	cmd := exec.CommandContext(ctx, "ssh", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := d.ssh.get(d.opts.Name, d.sshConfig).CombinedOutput(ctx, command)
 	return string(output), err
 }
 
-// buildSSHArgs builds SSH command arguments
-func (d *VfkitDriver) buildSSHArgs(command string) []string {
-	args := []string{
-		"-i", d.sshConfig.KeyPath,
-		"-p", fmt.Sprintf("%d", d.sshConfig.Port),
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-o", "ConnectTimeout=5",
-		"-o", "BatchMode=yes",
-		fmt.Sprintf("%s@%s", d.sshConfig.User, d.sshConfig.Host),
-		command,
-	}
-	return args
+// RunSSH executes a command via SSH, streaming its output
+func (d *VfkitDriver) RunSSH(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	return d.ssh.get(d.opts.Name, d.sshConfig).Run(ctx, command, stdout, stderr)
 }
 
 // GetSSHConfig returns the SSH configuration
@@ -615,6 +696,12 @@ func (d *VfkitDriver) ToVMInfo(name, pipelineName, pipelineFile, imageTag string
 		PipelineFile:         pipelineFile,
 		ImageTag:             imageTag,
 		DiskImage:            d.opts.DiskImage,
+		CPUs:                 d.opts.CPUs,
+		Memory:               d.opts.Memory,
+		NICModel:             d.opts.NICModel,
+		Provisioning:         d.opts.Provisioning,
+		Mounts:               d.opts.Mounts,
+		Arch:                 HostArch(),
 		Created:              time.Now(),
 		SSHHost:              d.sshConfig.Host,
 		SSHPort:              d.sshConfig.Port,
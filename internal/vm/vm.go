@@ -3,6 +3,7 @@ package vm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/tnk4on/bootc-man/internal/config"
+	"github.com/tnk4on/bootc-man/internal/lock"
 )
 
 // VMInfo represents information about a VM
@@ -29,9 +31,37 @@ type VMInfo struct {
 	LogFile      string    `json:"logFile"`      // シリアルコンソールログファイル
 	State        string    `json:"state"`        // VM状態（Running, Stopped等）
 
+	// Disk layout - optional
+	BaseImage  string         `json:"baseImage,omitempty"`  // 共有ベースイメージ（オーバーレイ/reflinkの元イメージ）
+	DiskMethod string         `json:"diskMethod,omitempty"` // VMディスクの作成方法（overlay, reflink, copy, in-place）
+	Snapshots  []SnapshotInfo `json:"snapshots,omitempty"`  // スナップショット一覧
+
+	// First-boot provisioning - optional
+	Provisioning *Provisioning `json:"provisioning,omitempty"` // Ignition/cloud-initによる初回起動時の設定
+
+	// Port forwarding - optional
+	Ports []PortForward `json:"ports,omitempty"` // 追加のポートフォワード（vm port add / --publish）
+
+	// Shared directories - optional
+	Mounts []SharedDir `json:"mounts,omitempty"` // virtiofsで共有するホストディレクトリ（--mount）
+
+	// Lab network - optional
+	Lab string `json:"lab,omitempty"` // 所属するラボネットワーク名（--network）
+
+	// Resources - optional (VMs started before they were stored use the defaults)
+	CPUs     int    `json:"cpus,omitempty"`     // CPU数
+	Memory   int    `json:"memory,omitempty"`   // メモリサイズ（MB）
+	NICModel string `json:"nicModel,omitempty"` // NICモデル（virtio-net-pci, e1000e等）
+	Profile  string `json:"profile,omitempty"`  // 適用したVMプロファイル名（--profile）
+
 	// Platform-specific fields
-	VMType    string `json:"vmType"`    // VM種別（qemu, vfkit, hyperv）
-	ProcessID int    `json:"processId"` // メインVMプロセスID
+	VMType      string `json:"vmType"`                // VM種別（qemu, vfkit, hyperv）
+	Arch        string `json:"arch,omitempty"`        // ゲストのアーキテクチャ（amd64, arm64）
+	Accelerator string `json:"accelerator,omitempty"` // 使用中のアクセラレータ（kvm, tcg）
+	SecureBoot  bool   `json:"secureBoot,omitempty"`  // セキュアブートを有効化
+	TPM         bool   `json:"tpm,omitempty"`         // 仮想TPM 2.0（swtpm）を接続
+	Firmware    string `json:"firmware,omitempty"`    // ブートファームウェア（uefi, bios）
+	ProcessID   int    `json:"processId"`             // メインVMプロセスID
 
 	// gvproxy related - used for all platforms
 	GvproxySocket        string `json:"gvproxySocket,omitempty"`        // gvproxyソケットパス
@@ -43,7 +73,23 @@ type VMInfo struct {
 	VfkitPID      int    `json:"vfkitPid,omitempty"`      // vfkitプロセスID (deprecated, use ProcessID)
 
 	// Linux (QEMU) specific - optional
-	PIDFile string `json:"pidFile,omitempty"` // QEMUのPIDファイルパス
+	PIDFile       string `json:"pidFile,omitempty"`       // QEMUのPIDファイルパス
+	InstallDisk   string `json:"installDisk,omitempty"`   // ISOインストール先ディスクパス
+	QMPSocket     string `json:"qmpSocket,omitempty"`     // QMP制御ソケットパス
+	ConsoleSocket string `json:"consoleSocket,omitempty"` // シリアルコンソールソケットパス
+
+	// libvirt specific - optional
+	LibvirtURI    string `json:"libvirtUri,omitempty"`    // libvirt接続URI（qemu:///session等）
+	LibvirtDomain string `json:"libvirtDomain,omitempty"` // 一時（transient）ドメイン名
+
+	// External driver specific - optional
+	DriverPlugin string `json:"driverPlugin,omitempty"` // 外部VMドライバー名（bootc-man-driver-<name>）
+}
+
+// UsesGvproxy reports whether the VM is networked through gvproxy
+// libvirt and external driver VMs forward their ports when they start.
+func (info *VMInfo) UsesGvproxy() bool {
+	return info.LibvirtDomain == "" && info.DriverPlugin == ""
 }
 
 // PrerequisitesCheckResult represents the result of prerequisite checking
@@ -86,8 +132,29 @@ func CheckPrerequisites(ctx context.Context, baseDir string, imageTag string) (*
 	return result, nil
 }
 
+// BaseImageChanged reports whether the base image of a VM disk that is a
+// qcow2 overlay was modified after the VM was created. An overlay on top of a
+// replaced base image no longer describes a consistent disk, so the VM must
+// be recreated. Reflinked and copied disks do not depend on their base.
+func (info *VMInfo) BaseImageChanged() bool {
+	if info.BaseImage == "" || info.BaseImage == info.DiskImage {
+		return false
+	}
+	// VMs saved before the method was recorded got overlays as qcow2 disks
+	overlay := info.DiskMethod == DiskMethodOverlay ||
+		(info.DiskMethod == "" && filepath.Ext(info.DiskImage) == "."+ImageFormatQcow2)
+	if !overlay {
+		return false
+	}
+	stat, err := os.Stat(info.BaseImage)
+	if err != nil {
+		return false
+	}
+	return stat.ModTime().After(info.Created)
+}
+
 // FindDiskImageFile finds the disk image file from convert stage artifacts
-// Prefers raw format (for vfkit), falls back to qcow2
+// Prefers raw format (for vfkit), falls back to qcow2, vmdk and iso
 // baseDir is the pipeline base directory (where bootc-ci.yaml is located)
 func FindDiskImageFile(baseDir string, imageTag string) (string, error) {
 	artifactsDir := filepath.Join(baseDir, "output", "images")
@@ -96,69 +163,38 @@ func FindDiskImageFile(baseDir string, imageTag string) (string, error) {
 	imageName := strings.ReplaceAll(imageTag, "/", "_")
 	imageName = strings.ReplaceAll(imageName, ":", "_")
 
-	// First, try to find raw file (preferred for vfkit)
-	expectedRawFile := filepath.Join(artifactsDir, fmt.Sprintf("%s.raw", imageName))
-	if _, err := os.Stat(expectedRawFile); err == nil {
-		return expectedRawFile, nil
-	}
-
-	// Also check in image/ subdirectory (some convert outputs use this structure)
-	expectedRawFileInImage := filepath.Join(artifactsDir, "image", "disk.raw")
-	if _, err := os.Stat(expectedRawFileInImage); err == nil {
-		return expectedRawFileInImage, nil
+	if path := FindDiskImageInDir(artifactsDir, imageName, DiskImageFormats); path != "" {
+		return path, nil
 	}
 
-	// Search recursively for raw files
-	var foundRawFile string
-	err := filepath.Walk(artifactsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".raw") {
-			foundRawFile = path
-			return filepath.SkipAll
-		}
-		return nil
-	})
-
-	if err == nil && foundRawFile != "" {
-		return foundRawFile, nil
-	}
-
-	// Fallback to qcow2
-	expectedQcow2File := filepath.Join(artifactsDir, fmt.Sprintf("%s.qcow2", imageName))
-	if _, err := os.Stat(expectedQcow2File); err == nil {
-		return expectedQcow2File, nil
-	}
-
-	// Search recursively for qcow2 files
-	var foundQcow2File string
-	err = filepath.Walk(artifactsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".qcow2") {
-			foundQcow2File = path
-			return filepath.SkipAll
-		}
-		return nil
-	})
+	return "", fmt.Errorf("no disk image file (raw, qcow2, vmdk or iso) found in %s", artifactsDir)
+}
 
+// GetVMsDir returns the global VMs directory path
+// On macOS/Linux: ~/.local/share/bootc-man/vms/
+// On Windows: %APPDATA%/bootc-man/vms/
+func GetVMsDir() (string, error) {
+	baseDir, err := getDataDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to search for disk image file: %w", err)
+		return "", err
 	}
+	return filepath.Join(baseDir, "vms"), nil
+}
 
-	if foundQcow2File != "" {
-		return foundQcow2File, nil
+// GetImageCacheDir returns the directory of disks converted from container
+// images (vm start --image)
+// On macOS/Linux: ~/.local/share/bootc-man/images/
+// On Windows: %APPDATA%/bootc-man/images/
+func GetImageCacheDir() (string, error) {
+	baseDir, err := getDataDir()
+	if err != nil {
+		return "", err
 	}
-
-	return "", fmt.Errorf("no disk image file (raw or qcow2) found in %s", artifactsDir)
+	return filepath.Join(baseDir, "images"), nil
 }
 
-// GetVMsDir returns the global VMs directory path
-// On macOS/Linux: ~/.local/share/bootc-man/vms/
-// On Windows: %APPDATA%/bootc-man/vms/
-func GetVMsDir() (string, error) {
+// getDataDir returns the bootc-man data directory
+func getDataDir() (string, error) {
 	var baseDir string
 	if runtime.GOOS == "windows" {
 		appData := os.Getenv("APPDATA")
@@ -173,11 +209,93 @@ func GetVMsDir() (string, error) {
 		}
 		baseDir = filepath.Join(homeDir, ".local", "share", "bootc-man")
 	}
-	return filepath.Join(baseDir, "vms"), nil
+	return baseDir, nil
+}
+
+// GetLocksDir returns the directory of bootc-man's lock files
+func GetLocksDir() (string, error) {
+	baseDir, err := getDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, "locks"), nil
+}
+
+// LockVM takes the lifecycle lock of a VM without waiting
+// Commands that start, stop, create or remove a VM hold it, so two of them
+// never act on the same VM at once.
+func LockVM(name string) (*lock.Lock, error) {
+	locksDir, err := GetLocksDir()
+	if err != nil {
+		return nil, err
+	}
+	l, err := lock.TryAcquire(filepath.Join(locksDir, fmt.Sprintf("vm-%s.lock", name)))
+	var held *lock.HeldError
+	if errors.As(err, &held) {
+		if held.PID > 0 {
+			return nil, fmt.Errorf("VM '%s' is in use by another bootc-man process (pid %d)", name, held.PID)
+		}
+		return nil, fmt.Errorf("VM '%s' is in use by another bootc-man process", name)
+	}
+	return l, err
+}
+
+// VMLocked reports whether another process holds the lifecycle lock of a VM
+func VMLocked(name string) bool {
+	locksDir, err := GetLocksDir()
+	if err != nil {
+		return false
+	}
+	return lock.Held(filepath.Join(locksDir, fmt.Sprintf("vm-%s.lock", name)))
+}
+
+// lockVMInfo takes the lock that serializes writes to a VM info file
+func lockVMInfo(name string) (*lock.Lock, error) {
+	locksDir, err := GetLocksDir()
+	if err != nil {
+		return nil, err
+	}
+	return lock.Acquire(filepath.Join(locksDir, fmt.Sprintf("vm-%s.json.lock", name)))
 }
 
 // SaveVMInfo saves VM information to a JSON file in global VMs directory
+// The file is replaced atomically under a lock, so concurrent readers never
+// see a partly written file.
 func SaveVMInfo(vmInfo *VMInfo) error {
+	l, err := lockVMInfo(vmInfo.Name)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	return writeVMInfo(vmInfo)
+}
+
+// UpdateVMInfo loads a VM's information, lets update change it and saves it
+// The whole read-modify-write holds the VM info lock, so concurrent updates
+// of the same VM never overwrite each other. If update returns an error,
+// nothing is saved.
+func UpdateVMInfo(name string, update func(*VMInfo) error) (*VMInfo, error) {
+	l, err := lockVMInfo(name)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Release()
+
+	vmInfo, err := LoadVMInfo(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := update(vmInfo); err != nil {
+		return nil, err
+	}
+	if err := writeVMInfo(vmInfo); err != nil {
+		return nil, err
+	}
+	return vmInfo, nil
+}
+
+// writeVMInfo replaces a VM info file; the caller holds the VM info lock
+func writeVMInfo(vmInfo *VMInfo) error {
 	vmsDir, err := GetVMsDir()
 	if err != nil {
 		return err
@@ -192,7 +310,12 @@ func SaveVMInfo(vmInfo *VMInfo) error {
 		return fmt.Errorf("failed to marshal VM info: %w", err)
 	}
 
-	if err := os.WriteFile(vmInfoFile, data, 0644); err != nil {
+	tmpFile := vmInfoFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write VM info file: %w", err)
+	}
+	if err := os.Rename(tmpFile, vmInfoFile); err != nil {
+		os.Remove(tmpFile)
 		return fmt.Errorf("failed to write VM info file: %w", err)
 	}
 
@@ -264,6 +387,12 @@ func DeleteVMInfo(name string) error {
 	}
 	vmInfoFile := filepath.Join(vmsDir, fmt.Sprintf("%s.json", name))
 
+	l, err := lockVMInfo(name)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
 	if err := os.Remove(vmInfoFile); err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("VM '%s' not found", name)
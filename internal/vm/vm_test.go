@@ -2,9 +2,12 @@ package vm
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -530,3 +533,121 @@ func TestCheckVfkitAvailable(t *testing.T) {
 	// The actual result depends on the system state
 	_ = err
 }
+
+func TestLockVM(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+
+	l, err := LockVM("dev")
+	if err != nil {
+		t.Fatalf("LockVM() error = %v", err)
+	}
+	if _, err := LockVM("dev"); err == nil || !strings.Contains(err.Error(), "in use by another bootc-man process (pid ") {
+		t.Errorf("second LockVM() error = %v, want in use", err)
+	}
+	if !VMLocked("dev") || VMLocked("other") {
+		t.Errorf("VMLocked() = %v, %v, want true, false", VMLocked("dev"), VMLocked("other"))
+	}
+
+	// State writes use their own lock and work while the VM is locked
+	if err := SaveVMInfo(&VMInfo{Name: "dev", State: "Running"}); err != nil {
+		t.Fatalf("SaveVMInfo() error = %v", err)
+	}
+	if info, err := LoadVMInfo("dev"); err != nil || info.State != "Running" {
+		t.Errorf("LoadVMInfo() = %+v, %v", info, err)
+	}
+	vmsDir, _ := GetVMsDir()
+	if _, err := os.Stat(filepath.Join(vmsDir, "dev.json.tmp")); !os.IsNotExist(err) {
+		t.Error("SaveVMInfo() left its temporary file behind")
+	}
+
+	_ = l.Release()
+	if VMLocked("dev") {
+		t.Error("VMLocked() = true after Release()")
+	}
+}
+
+func TestBaseImageChanged(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "disk.raw")
+	if err := os.WriteFile(base, []byte("raw"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The base image was rebuilt after the VMs were created
+	created := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		method string
+		disk   string
+		want   bool
+	}{
+		{"overlay", DiskMethodOverlay, "vm.qcow2", true},
+		{"reflink", DiskMethodReflink, "vm.raw", false},
+		{"copy", DiskMethodCopy, "vm.raw", false},
+		{"legacy overlay", "", "vm.qcow2", true},
+		{"legacy clone", "", "vm.raw", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &VMInfo{DiskImage: filepath.Join(dir, tt.disk), BaseImage: base, DiskMethod: tt.method, Created: created}
+			if got := info.BaseImageChanged(); got != tt.want {
+				t.Errorf("BaseImageChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	info := &VMInfo{DiskImage: filepath.Join(dir, "vm.qcow2"), BaseImage: base, DiskMethod: DiskMethodOverlay, Created: time.Now().Add(time.Hour)}
+	if info.BaseImageChanged() {
+		t.Error("BaseImageChanged() = true for an unchanged base image")
+	}
+}
+
+func TestUpdateVMInfo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+
+	if _, err := UpdateVMInfo("missing", func(*VMInfo) error { return nil }); err == nil {
+		t.Error("UpdateVMInfo() of a missing VM succeeded")
+	}
+	if err := SaveVMInfo(&VMInfo{Name: "dev", State: "Stopped"}); err != nil {
+		t.Fatalf("SaveVMInfo() error = %v", err)
+	}
+
+	// Concurrent updates must not lose each other's changes
+	var wg sync.WaitGroup
+	for port := 8000; port < 8010; port++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := UpdateVMInfo("dev", func(info *VMInfo) error {
+				return info.AddPortForward(PortForward{HostPort: port, GuestPort: 80, Protocol: ProtocolTCP})
+			})
+			if err != nil {
+				t.Errorf("UpdateVMInfo() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A failed update saves nothing
+	errUpdate := errors.New("update failed")
+	_, err := UpdateVMInfo("dev", func(info *VMInfo) error {
+		info.State = "Running"
+		return errUpdate
+	})
+	if !errors.Is(err, errUpdate) {
+		t.Errorf("UpdateVMInfo() error = %v, want %v", err, errUpdate)
+	}
+
+	info, err := LoadVMInfo("dev")
+	if err != nil {
+		t.Fatalf("LoadVMInfo() error = %v", err)
+	}
+	if len(info.Ports) != 10 {
+		t.Errorf("len(Ports) = %d, want 10", len(info.Ports))
+	}
+	if info.State != "Stopped" {
+		t.Errorf("State = %q after a failed update, want Stopped", info.State)
+	}
+}